@@ -0,0 +1,31 @@
+package main
+
+import (
+	"flag"
+	"io"
+	"os"
+
+	cfg "github.com/w-k-s/McMicroservices/kitchen-service/internal/config"
+	"github.com/w-k-s/McMicroservices/kitchen-service/log"
+)
+
+// configdoc writes the reference of the kitchen's settings, e.g. go run ./cmd/configdoc -o docs/configuration.md
+func main() {
+	var output string
+	flag.StringVar(&output, "o", "", "File that the reference is written to. It is written to stdout if empty")
+	flag.Parse()
+
+	var w io.Writer = os.Stdout
+	if len(output) > 0 {
+		f, err := os.Create(output)
+		if err != nil {
+			log.Fatalf("failed to create %q. Reason: %s", output, err)
+		}
+		defer f.Close()
+		w = f
+	}
+
+	if err := cfg.WriteReference(w); err != nil {
+		log.Fatalf("failed to write config reference. Reason: %s", err)
+	}
+}
@@ -5,6 +5,7 @@ import (
 
 	cfg "github.com/w-k-s/McMicroservices/kitchen-service/internal/config"
 	app "github.com/w-k-s/McMicroservices/kitchen-service/internal/server"
+	"github.com/w-k-s/McMicroservices/kitchen-service/log"
 )
 
 var (
@@ -30,5 +31,17 @@ func main() {
 	handler := app.Must(app.NewAppBuilder(config).Build())
 	defer handler.Close()
 
+	// Settings that can be changed while the kitchen is running are applied when the config file,
+	// or the config on the config service, changes.
+	if watcher, err := cfg.NewConfigWatcher(configFileUrl, config); err != nil {
+		log.Err(err).Msg("Config will not be reloaded")
+	} else {
+		watcher.Subscribe(handler.ApplyConfig)
+		if err := watcher.Start(); err != nil {
+			log.Err(err).Msg("Config will not be reloaded")
+		}
+		defer watcher.Close()
+	}
+
 	handler.ListenAndServe()
 }
@@ -0,0 +1,155 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"time"
+)
+
+// AuthConfig selects the keys that the JSON Web Tokens of requests are verified with.
+// Authentication can only be turned off explicitly, so that a missing key is a configuration error rather than an open API.
+type AuthConfig interface {
+	Disabled() bool
+	// Secret is the shared secret of HS256 tokens.
+	Secret() string
+	// JWKSFile and JWKSURL are where a JSON Web Key Set of HS256 and RS256 keys is loaded from.
+	JWKSFile() string
+	JWKSURL() string
+	// JWKSRefreshInterval is how often the JSON Web Key Set is reloaded.
+	JWKSRefreshInterval() time.Duration
+	// Issuer and Audience, if not empty, must be the iss and one of the aud claims of every token.
+	Issuer() string
+	Audience() string
+	// Leeway is how much clock skew is allowed when checking when a token expires.
+	Leeway() time.Duration
+}
+
+type defaultAuthConfig struct {
+	disabled            bool
+	secret              string
+	jwksFile            string
+	jwksURL             string
+	jwksRefreshInterval time.Duration
+	issuer              string
+	audience            string
+	leeway              time.Duration
+}
+
+func makeAuthConfig(b *authConfigBuilder) (AuthConfig, error) {
+	if !b.disabled && len(b.secret) == 0 && len(b.jwksFile) == 0 && len(b.jwksURL) == 0 {
+		return nil, fmt.Errorf("auth requires a secret, a JWKS file or a JWKS URL unless it is disabled")
+	}
+	if len(b.jwksURL) > 0 {
+		if u, err := url.Parse(b.jwksURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+			return nil, fmt.Errorf("auth JWKS URL must be an http or https URL")
+		}
+	}
+	if b.jwksRefreshInterval < 0 {
+		return nil, fmt.Errorf("auth JWKS refresh interval must not be negative")
+	}
+	if b.leeway < 0 {
+		return nil, fmt.Errorf("auth leeway must not be negative")
+	}
+
+	return defaultAuthConfig{
+		b.disabled,
+		b.secret,
+		b.jwksFile,
+		b.jwksURL,
+		b.jwksRefreshInterval,
+		b.issuer,
+		b.audience,
+		b.leeway,
+	}, nil
+}
+
+func (a defaultAuthConfig) Disabled() bool {
+	return a.disabled
+}
+
+func (a defaultAuthConfig) Secret() string {
+	return a.secret
+}
+
+func (a defaultAuthConfig) JWKSFile() string {
+	return a.jwksFile
+}
+
+func (a defaultAuthConfig) JWKSURL() string {
+	return a.jwksURL
+}
+
+func (a defaultAuthConfig) JWKSRefreshInterval() time.Duration {
+	return a.jwksRefreshInterval
+}
+
+func (a defaultAuthConfig) Issuer() string {
+	return a.issuer
+}
+
+func (a defaultAuthConfig) Audience() string {
+	return a.audience
+}
+
+func (a defaultAuthConfig) Leeway() time.Duration {
+	return a.leeway
+}
+
+type authConfigBuilder struct {
+	disabled            bool
+	secret              string
+	jwksFile            string
+	jwksURL             string
+	jwksRefreshInterval time.Duration
+	issuer              string
+	audience            string
+	leeway              time.Duration
+}
+
+func NewAuthConfigBuilder() *authConfigBuilder {
+	return &authConfigBuilder{}
+}
+
+func (b *authConfigBuilder) SetDisabled(disabled bool) *authConfigBuilder {
+	b.disabled = disabled
+	return b
+}
+
+func (b *authConfigBuilder) SetSecret(secret string) *authConfigBuilder {
+	b.secret = secret
+	return b
+}
+
+func (b *authConfigBuilder) SetJWKSFile(path string) *authConfigBuilder {
+	b.jwksFile = path
+	return b
+}
+
+func (b *authConfigBuilder) SetJWKSURL(url string) *authConfigBuilder {
+	b.jwksURL = url
+	return b
+}
+
+func (b *authConfigBuilder) SetJWKSRefreshInterval(interval time.Duration) *authConfigBuilder {
+	b.jwksRefreshInterval = interval
+	return b
+}
+
+func (b *authConfigBuilder) SetIssuer(issuer string) *authConfigBuilder {
+	b.issuer = issuer
+	return b
+}
+
+func (b *authConfigBuilder) SetAudience(audience string) *authConfigBuilder {
+	b.audience = audience
+	return b
+}
+
+func (b *authConfigBuilder) SetLeeway(leeway time.Duration) *authConfigBuilder {
+	b.leeway = leeway
+	return b
+}
+
+func (b *authConfigBuilder) Build() (AuthConfig, error) {
+	return makeAuthConfig(b)
+}
@@ -2,10 +2,12 @@ package config
 
 import (
 	"fmt"
+	"io/ioutil"
 	"net/http"
 	"strings"
 	"time"
 
+	"github.com/lalamove/nui/nlogger"
 	"github.com/lalamove/nui/nstrings"
 	"github.com/w-k-s/McMicroservices/kitchen-service/log"
 	"github.com/w-k-s/konfig"
@@ -15,12 +17,23 @@ import (
 	"github.com/w-k-s/konfig/parser"
 	"github.com/w-k-s/konfig/parser/kpjson"
 	"github.com/w-k-s/konfig/parser/kpyaml"
+	"github.com/w-k-s/konfig/watcher/kwfile"
 )
 
 type Config struct {
-	server ServerConfig
-	broker BrokerConfig
-	db     DBConfig
+	server    ServerConfig
+	broker    BrokerConfig
+	db        DBConfig
+	prep      PreparationConfig
+	queue     QueueConfig
+	stock     StockConfig
+	auth      AuthConfig
+	telemetry TelemetryConfig
+	logging   LoggingConfig
+	reload    ReloadConfig
+	// settings are the values of the settings of the Schema that the config was loaded with, as they are reported by the config.
+	// A config that was not loaded from a config source has none.
+	settings map[string]string
 }
 
 func (c Config) Server() ServerConfig {
@@ -35,11 +48,46 @@ func (c Config) Broker() BrokerConfig {
 	return c.broker
 }
 
-func NewConfig(serverConfig ServerConfig, brokerConfig BrokerConfig, dbConfig DBConfig) (*Config, error) {
+func (c Config) Preparation() PreparationConfig {
+	return c.prep
+}
+
+func (c Config) Queue() QueueConfig {
+	return c.queue
+}
+
+func (c Config) Stock() StockConfig {
+	return c.stock
+}
+
+func (c Config) Auth() AuthConfig {
+	return c.auth
+}
+
+func (c Config) Telemetry() TelemetryConfig {
+	return c.telemetry
+}
+
+func (c Config) Logging() LoggingConfig {
+	return c.logging
+}
+
+func (c Config) Reload() ReloadConfig {
+	return c.reload
+}
+
+func NewConfig(serverConfig ServerConfig, brokerConfig BrokerConfig, dbConfig DBConfig, prepConfig PreparationConfig, queueConfig QueueConfig, stockConfig StockConfig, authConfig AuthConfig, telemetryConfig TelemetryConfig, loggingConfig LoggingConfig, reloadConfig ReloadConfig) (*Config, error) {
 	config := &Config{
-		server: serverConfig,
-		broker: brokerConfig,
-		db:     dbConfig,
+		server:    serverConfig,
+		broker:    brokerConfig,
+		db:        dbConfig,
+		prep:      prepConfig,
+		queue:     queueConfig,
+		stock:     stockConfig,
+		auth:      authConfig,
+		telemetry: telemetryConfig,
+		logging:   loggingConfig,
+		reload:    reloadConfig,
 	}
 
 	return config, nil
@@ -50,76 +98,139 @@ func LoadConfig(configFilePath string) (*Config, error) {
 }
 
 func LoadConfigWithClient(configFilePath string, httpClient klhttp.Client) (*Config, error) {
+	source, err := newConfigSource(configFilePath, httpClient)
+	if err != nil {
+		return nil, err
+	}
+	return source.Load()
+}
+
+// configSource is the config file, or the config service, that the config is loaded from.
+type configSource struct {
+	path       string
+	parser     parser.Func
+	httpClient klhttp.Client
+}
+
+func newConfigSource(configFilePath string, httpClient klhttp.Client) (configSource, error) {
 	if len(configFilePath) == 0 {
 		configFilePath = "file://" + DefaultConfigFilePath()
 	}
 
-	store := konfig.New(konfig.DefaultConfig())
-	var loader konfig.LoaderWatcher
-
 	var fileParser parser.Func
 	if strings.HasSuffix(configFilePath, "json") {
 		fileParser = kpjson.Parser
 	} else if strings.HasSuffix(configFilePath, "yaml") {
 		fileParser = kpyaml.Parser
 	} else {
-		return nil, fmt.Errorf("config file path must have a json or yaml extension")
+		return configSource{}, fmt.Errorf("config file path must have a json or yaml extension")
+	}
+
+	if !strings.HasPrefix(configFilePath, "file://") && !strings.HasPrefix(configFilePath, "http://") {
+		return configSource{}, fmt.Errorf("config file must start with file:// or http://")
 	}
 
-	if strings.HasPrefix(configFilePath, "file://") {
-		absolutePath := strings.Replace(configFilePath, "file://", "", 1)
+	return configSource{configFilePath, fileParser, httpClient}, nil
+}
+
+func (s configSource) isFile() bool {
+	return strings.HasPrefix(s.path, "file://")
+}
+
+func (s configSource) filePath() string {
+	return strings.Replace(s.path, "file://", "", 1)
+}
+
+// Load reads the config source, and the environment variables that override it, into a new store
+// so that a config that fails to load does not replace the values that were loaded before it.
+func (s configSource) Load() (*Config, error) {
+	store := konfig.New(konfig.DefaultConfig())
+	seedDefaults(store)
+	var loader konfig.LoaderWatcher
+
+	if s.isFile() {
 		loader = klfile.New(
 			&klfile.Config{
 				Files: []klfile.File{
 					{
-						Parser: fileParser,
-						Path:   absolutePath,
+						Parser: s.parser,
+						Path:   s.filePath(),
 					},
 				},
 			},
 		)
-	} else if strings.HasPrefix(configFilePath, "http://") {
+	} else {
 		loader = klhttp.New(
 			&klhttp.Config{
-				Client: httpClient,
+				Client: s.httpClient,
 				Sources: []klhttp.Source{
 					{
-						URL:    configFilePath,
+						URL:    s.path,
 						Method: "GET",
-						Parser: fileParser,
+						Parser: s.parser,
 					},
 				},
 			},
 		)
-	} else {
-		return nil, fmt.Errorf("config file must start with file:// or http://")
 	}
 
 	store.RegisterLoaderWatcher(loader)
 
-	// Override file config with env config
-	// KNOWN BUG: This doesn't work for configs keys that are camel cased
-	// e.g. The env var APP_BROKER_CONSUMER_AUTOOFFSETRESET does not override broker.consumer.autoOffsetReset
-	store.RegisterLoader(
-		klenv.New(&klenv.Config{
-			Regexp:         "^APP_.*",
-			SliceSeparator: ",",
-			Replacer: nstrings.ReplacerFunc(func(s string) string {
-				return strings.ToLower(strings.Replace(strings.TrimPrefix(s, "APP_"), "_", ".", -1))
+	// Override file config with the env vars of the schema, e.g. APP_BROKER_CONSUMER_AUTOOFFSETRESET overrides broker.consumer.autoOffsetReset
+	if vars := environment(); len(vars) > 0 {
+		names := make([]string, 0, len(vars))
+		for name := range vars {
+			names = append(names, name)
+		}
+		store.RegisterLoader(
+			klenv.New(&klenv.Config{
+				Vars:           names,
+				SliceSeparator: ",",
+				Replacer: nstrings.ReplacerFunc(func(s string) string {
+					return vars[s]
+				}),
 			}),
-		}),
-	)
+		)
+	}
 
 	if err := store.Load(); err != nil {
-		return nil, fmt.Errorf("failed to load config file from path '%s'. Reason: %w", configFilePath, err)
+		return nil, fmt.Errorf("failed to load config file from path '%s'. Reason: %w", s.path, err)
 	}
 
+	if err := validateStore(store); err != nil {
+		return nil, fmt.Errorf("invalid config in '%s': %w", s.path, err)
+	}
+
+	return newConfigFromStore(store)
+}
+
+// Watcher signals when the config source may have changed: when the config file is written,
+// or every interval for the config service.
+func (s configSource) Watcher(interval time.Duration) konfig.Watcher {
+	if s.isFile() {
+		return kwfile.New(&kwfile.Config{
+			Files:  []string{s.filePath()},
+			Rate:   interval,
+			Logger: nlogger.NewProvider(nlogger.New(ioutil.Discard, "")),
+		})
+	}
+	return newPollWatcher(interval)
+}
+
+func newConfigFromStore(store konfig.Store) (*Config, error) {
 	var (
-		serverConfig   ServerConfig
-		consumerConfig consumerConfig
-		brokerConfig   BrokerConfig
-		dbConfig       DBConfig
-		err            error
+		serverConfig    ServerConfig
+		consumerConfig  consumerConfig
+		brokerConfig    BrokerConfig
+		dbConfig        DBConfig
+		prepConfig      PreparationConfig
+		queueConfig     QueueConfig
+		stockConfig     StockConfig
+		authConfig      AuthConfig
+		telemetryConfig TelemetryConfig
+		loggingConfig   LoggingConfig
+		reloadConfig    ReloadConfig
+		err             error
 	)
 	if serverConfig, err = NewServerConfigBuilder().
 		SetPort(store.Int("server.port")).
@@ -158,7 +269,71 @@ func LoadConfigWithClient(configFilePath string, httpClient klhttp.Client) (*Con
 		return nil, fmt.Errorf("failed to load server config: %w", err)
 	}
 
-	return &Config{serverConfig, brokerConfig, dbConfig}, nil
+	if prepConfig, err = NewPreparationConfigBuilder().
+		SetEstimator(store.String("preparation.estimator")).
+		SetBase(store.Duration("preparation.base") * time.Second).
+		SetPerItem(store.Duration("preparation.perItem") * time.Second).
+		SetLearnedWindow(store.Duration("preparation.learned.windowHours") * time.Hour).
+		SetLearnedMinSamples(store.Int("preparation.learned.minSamples")).
+		Build(); err != nil {
+		return nil, fmt.Errorf("failed to load preparation config: %w", err)
+	}
+
+	if queueConfig, err = NewQueueConfig(
+		store.Duration("queue.maxWait")*time.Second,
+		store.Duration("queue.maxOrderAge")*time.Second,
+		uint(store.Int("queue.batch.size")),
+		store.Duration("queue.batch.windowMillis")*time.Millisecond,
+		uint(store.Int("queue.capacity")),
+	); err != nil {
+		return nil, fmt.Errorf("failed to load queue config: %w", err)
+	}
+
+	if stockConfig, err = NewStockConfig(
+		store.Int("stock.bulkIncreaseThreshold"),
+		store.Duration("stock.idempotencyKeyRetentionHours")*time.Hour,
+	); err != nil {
+		return nil, fmt.Errorf("failed to load stock config: %w", err)
+	}
+
+	if authConfig, err = NewAuthConfigBuilder().
+		SetDisabled(store.Bool("auth.disabled")).
+		SetSecret(store.String("auth.secret")).
+		SetJWKSFile(store.String("auth.jwks.file")).
+		SetJWKSURL(store.String("auth.jwks.url")).
+		SetJWKSRefreshInterval(store.Duration("auth.jwks.refresh") * time.Second).
+		SetIssuer(store.String("auth.issuer")).
+		SetAudience(store.String("auth.audience")).
+		SetLeeway(store.Duration("auth.leeway") * time.Second).
+		Build(); err != nil {
+		return nil, fmt.Errorf("failed to load auth config: %w", err)
+	}
+
+	if telemetryConfig, err = NewTelemetryConfigBuilder().
+		SetExporter(store.String("telemetry.exporter")).
+		SetServiceName(store.String("telemetry.service")).
+		SetSampleRatio(store.Float("telemetry.sampling")).
+		SetOTLPEndpoint(store.String("telemetry.otlp.endpoint")).
+		SetOTLPInsecure(store.Bool("telemetry.otlp.insecure")).
+		SetFile(store.String("telemetry.file")).
+		Build(); err != nil {
+		return nil, fmt.Errorf("failed to load telemetry config: %w", err)
+	}
+
+	if loggingConfig, err = NewLoggingConfig(
+		store.String("logging.level"),
+	); err != nil {
+		return nil, fmt.Errorf("failed to load logging config: %w", err)
+	}
+
+	if reloadConfig, err = NewReloadConfig(
+		store.Bool("reload.disabled"),
+		store.Duration("reload.interval")*time.Second,
+	); err != nil {
+		return nil, fmt.Errorf("failed to load reload config: %w", err)
+	}
+
+	return &Config{serverConfig, brokerConfig, dbConfig, prepConfig, queueConfig, stockConfig, authConfig, telemetryConfig, loggingConfig, reloadConfig, settings(store)}, nil
 }
 
 func Must(config *Config, err error) *Config {
@@ -6,6 +6,7 @@ import (
 	"log"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
@@ -41,6 +42,9 @@ broker:
   consumer:
     groupId: "group_id"
     autoOffsetReset: "earliest"
+
+auth:
+  secret: "all work and no play"
 `
 
 func createTestConfigFile(content string, path string) error {
@@ -93,6 +97,304 @@ func (suite *ConfigTestSuite) Test_GIVEN_configFilePathIsNotProvided_WHEN_loadin
 	assert.Equal(suite.T(), "group_id", config.Broker().ConsumerConfig().GroupId())
 	assert.Equal(suite.T(), Earliest, config.Broker().ConsumerConfig().AutoOffsetReset())
 	assert.Equal(suite.T(), "plaintext", config.Broker().SecurityProtocol())
+	assert.Equal(suite.T(), 200, config.Stock().BulkIncreaseThreshold())
+	assert.Equal(suite.T(), 24*time.Hour, config.Stock().IdempotencyKeyRetention())
+}
+
+func (suite *ConfigTestSuite) Test_GIVEN_preparationIsNotConfigured_WHEN_loadingConfig_THEN_linearEstimatorIsUsedWithDefaults() {
+	// WHEN
+	config, err := LoadConfig("")
+
+	// THEN
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), EstimatorLinear, config.Preparation().Estimator())
+	assert.Equal(suite.T(), time.Duration(0), config.Preparation().Base())
+	assert.Equal(suite.T(), 5*time.Second, config.Preparation().PerItem())
+	assert.Equal(suite.T(), 7*24*time.Hour, config.Preparation().LearnedWindow())
+	assert.Equal(suite.T(), 5, config.Preparation().LearnedMinSamples())
+}
+
+func (suite *ConfigTestSuite) Test_GIVEN_preparationIsConfigured_WHEN_loadingConfig_THEN_preparationConfigIsParsed() {
+	// GIVEN
+	assert.Nil(suite.T(), createTestConfigFile(configFileContents+`
+preparation:
+  estimator: "learned"
+  base: 10
+  perItem: 3
+  learned:
+    windowHours: 24
+    minSamples: 20
+`, DefaultConfigFilePath()))
+
+	// WHEN
+	config, err := LoadConfig("")
+
+	// THEN
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), EstimatorLearned, config.Preparation().Estimator())
+	assert.Equal(suite.T(), 10*time.Second, config.Preparation().Base())
+	assert.Equal(suite.T(), 3*time.Second, config.Preparation().PerItem())
+	assert.Equal(suite.T(), 24*time.Hour, config.Preparation().LearnedWindow())
+	assert.Equal(suite.T(), 20, config.Preparation().LearnedMinSamples())
+}
+
+func (suite *ConfigTestSuite) Test_GIVEN_perItemIsZero_WHEN_loadingConfig_THEN_perItemIsZero() {
+	// GIVEN
+	assert.Nil(suite.T(), createTestConfigFile(configFileContents+`
+preparation:
+  perItem: 0
+`, DefaultConfigFilePath()))
+
+	// WHEN
+	config, err := LoadConfig("")
+
+	// THEN
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), time.Duration(0), config.Preparation().PerItem())
+}
+
+func (suite *ConfigTestSuite) Test_GIVEN_queueIsConfigured_WHEN_loadingConfig_THEN_maxWaitIsParsed() {
+	// GIVEN
+	assert.Nil(suite.T(), createTestConfigFile(configFileContents+`
+queue:
+  maxWait: 120
+  maxOrderAge: 1800
+  batch:
+    size: 20
+    windowMillis: 25
+`, DefaultConfigFilePath()))
+
+	// WHEN
+	config, err := LoadConfig("")
+
+	// THEN
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), 2*time.Minute, config.Queue().MaxWait())
+	assert.Equal(suite.T(), 30*time.Minute, config.Queue().MaxOrderAge())
+	assert.Equal(suite.T(), uint(20), config.Queue().BatchSize())
+	assert.Equal(suite.T(), 25*time.Millisecond, config.Queue().BatchWindow())
+}
+
+func (suite *ConfigTestSuite) Test_GIVEN_queueIsNotConfigured_WHEN_loadingConfig_THEN_defaultsOfSchemaAreUsed() {
+	// WHEN
+	config, err := LoadConfig("")
+
+	// THEN
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), 5*time.Minute, config.Queue().MaxWait())
+	assert.Equal(suite.T(), time.Duration(0), config.Queue().MaxOrderAge())
+	assert.Equal(suite.T(), uint(1), config.Queue().BatchSize())
+	assert.Equal(suite.T(), 50*time.Millisecond, config.Queue().BatchWindow())
+	assert.Equal(suite.T(), uint(1000), config.Queue().Capacity())
+}
+
+func (suite *ConfigTestSuite) Test_GIVEN_queueCapacityIsZero_WHEN_loadingConfig_THEN_capacityIsZero() {
+	// GIVEN
+	assert.Nil(suite.T(), createTestConfigFile(configFileContents+`
+queue:
+  capacity: 0
+`, DefaultConfigFilePath()))
+
+	// WHEN
+	config, err := LoadConfig("")
+
+	// THEN
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), uint(0), config.Queue().Capacity())
+}
+
+func (suite *ConfigTestSuite) Test_GIVEN_batchSizeIsZero_WHEN_loadingConfig_THEN_errorIsReturned() {
+	// GIVEN
+	assert.Nil(suite.T(), createTestConfigFile(configFileContents+`
+queue:
+  batch:
+    size: 0
+`, DefaultConfigFilePath()))
+
+	// WHEN
+	config, err := LoadConfig("")
+
+	// THEN
+	assert.Nil(suite.T(), config)
+	assert.NotNil(suite.T(), err)
+	assert.Contains(suite.T(), err.Error(), "queue batch size must be at least 1")
+}
+
+func (suite *ConfigTestSuite) Test_GIVEN_negativeBulkIncreaseThreshold_WHEN_loadingConfig_THEN_errorIsReturned() {
+	// GIVEN
+	assert.Nil(suite.T(), createTestConfigFile(configFileContents+`
+stock:
+  bulkIncreaseThreshold: -1
+`, DefaultConfigFilePath()))
+
+	// WHEN
+	config, err := LoadConfig("")
+
+	// THEN
+	assert.Nil(suite.T(), config)
+	assert.NotNil(suite.T(), err)
+	assert.Contains(suite.T(), err.Error(), "stock bulk increase threshold must not be negative")
+}
+
+func (suite *ConfigTestSuite) Test_GIVEN_zeroIdempotencyKeyRetention_WHEN_loadingConfig_THEN_errorIsReturned() {
+	// GIVEN
+	assert.Nil(suite.T(), createTestConfigFile(configFileContents+`
+stock:
+  idempotencyKeyRetentionHours: 0
+`, DefaultConfigFilePath()))
+
+	// WHEN
+	config, err := LoadConfig("")
+
+	// THEN
+	assert.Nil(suite.T(), config)
+	assert.NotNil(suite.T(), err)
+	assert.Contains(suite.T(), err.Error(), "stock idempotency key retention must be greater than 0")
+}
+
+func (suite *ConfigTestSuite) Test_GIVEN_authIsConfigured_WHEN_loadingConfig_THEN_authConfigIsParsed() {
+	// GIVEN
+	assert.Nil(suite.T(), createTestConfigFile(strings.Replace(configFileContents, `auth:
+  secret: "all work and no play"
+`, `auth:
+  jwks:
+    url: "https://auth.overlook.com/.well-known/jwks.json"
+    refresh: 600
+  issuer: "auth-service"
+  audience: "kitchen-service"
+  leeway: 5
+`, 1), DefaultConfigFilePath()))
+
+	// WHEN
+	config, err := LoadConfig("")
+
+	// THEN
+	assert.Nil(suite.T(), err)
+	assert.False(suite.T(), config.Auth().Disabled())
+	assert.Equal(suite.T(), "", config.Auth().Secret())
+	assert.Equal(suite.T(), "https://auth.overlook.com/.well-known/jwks.json", config.Auth().JWKSURL())
+	assert.Equal(suite.T(), 10*time.Minute, config.Auth().JWKSRefreshInterval())
+	assert.Equal(suite.T(), "auth-service", config.Auth().Issuer())
+	assert.Equal(suite.T(), "kitchen-service", config.Auth().Audience())
+	assert.Equal(suite.T(), 5*time.Second, config.Auth().Leeway())
+}
+
+func (suite *ConfigTestSuite) Test_GIVEN_authIsNotConfigured_WHEN_loadingConfig_THEN_errorIsReturned() {
+	// GIVEN
+	assert.Nil(suite.T(), createTestConfigFile(strings.Replace(configFileContents, `auth:
+  secret: "all work and no play"
+`, "", 1), DefaultConfigFilePath()))
+
+	// WHEN
+	config, err := LoadConfig("")
+
+	// THEN
+	assert.Nil(suite.T(), config)
+	assert.NotNil(suite.T(), err)
+	assert.Equal(suite.T(), "failed to load auth config: auth requires a secret, a JWKS file or a JWKS URL unless it is disabled", err.Error())
+}
+
+func (suite *ConfigTestSuite) Test_GIVEN_authIsDisabled_WHEN_loadingConfig_THEN_noKeyIsRequired() {
+	// GIVEN
+	assert.Nil(suite.T(), createTestConfigFile(strings.Replace(configFileContents, `auth:
+  secret: "all work and no play"
+`, `auth:
+  disabled: true
+`, 1), DefaultConfigFilePath()))
+
+	// WHEN
+	config, err := LoadConfig("")
+
+	// THEN
+	assert.Nil(suite.T(), err)
+	assert.True(suite.T(), config.Auth().Disabled())
+	assert.Equal(suite.T(), time.Hour, config.Auth().JWKSRefreshInterval())
+	assert.Equal(suite.T(), 30*time.Second, config.Auth().Leeway())
+}
+
+func (suite *ConfigTestSuite) Test_GIVEN_telemetryIsNotConfigured_WHEN_loadingConfig_THEN_spansAreNotExported() {
+	// GIVEN
+	assert.Nil(suite.T(), createTestConfigFile(configFileContents, DefaultConfigFilePath()))
+
+	// WHEN
+	config, err := LoadConfig("")
+
+	// THEN
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), ExporterNone, config.Telemetry().Exporter())
+	assert.Equal(suite.T(), "kitchen-service", config.Telemetry().ServiceName())
+	assert.Equal(suite.T(), 1.0, config.Telemetry().SampleRatio())
+}
+
+func (suite *ConfigTestSuite) Test_GIVEN_samplingIsZero_WHEN_loadingConfig_THEN_sampleRatioIsZero() {
+	// GIVEN
+	assert.Nil(suite.T(), createTestConfigFile(configFileContents+`
+telemetry:
+  sampling: 0
+`, DefaultConfigFilePath()))
+
+	// WHEN
+	config, err := LoadConfig("")
+
+	// THEN
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), 0.0, config.Telemetry().SampleRatio())
+}
+
+func (suite *ConfigTestSuite) Test_GIVEN_otlpExporterIsConfigured_WHEN_loadingConfig_THEN_telemetryConfigIsParsed() {
+	// GIVEN
+	assert.Nil(suite.T(), createTestConfigFile(configFileContents+`
+telemetry:
+  exporter: "otlp"
+  service: "kitchen-service-canary"
+  sampling: 0.25
+  otlp:
+    endpoint: "otel-collector:4318"
+    insecure: true
+`, DefaultConfigFilePath()))
+
+	// WHEN
+	config, err := LoadConfig("")
+
+	// THEN
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), ExporterOTLP, config.Telemetry().Exporter())
+	assert.Equal(suite.T(), "kitchen-service-canary", config.Telemetry().ServiceName())
+	assert.Equal(suite.T(), 0.25, config.Telemetry().SampleRatio())
+	assert.Equal(suite.T(), "otel-collector:4318", config.Telemetry().OTLPEndpoint())
+	assert.True(suite.T(), config.Telemetry().OTLPInsecure())
+}
+
+func (suite *ConfigTestSuite) Test_GIVEN_otlpExporterWithoutEndpoint_WHEN_loadingConfig_THEN_errorIsReturned() {
+	// GIVEN
+	assert.Nil(suite.T(), createTestConfigFile(configFileContents+`
+telemetry:
+  exporter: "otlp"
+`, DefaultConfigFilePath()))
+
+	// WHEN
+	config, err := LoadConfig("")
+
+	// THEN
+	assert.Nil(suite.T(), config)
+	assert.NotNil(suite.T(), err)
+	assert.Equal(suite.T(), "failed to load telemetry config: telemetry OTLP endpoint is required when the exporter is otlp", err.Error())
+}
+
+func (suite *ConfigTestSuite) Test_GIVEN_unknownEstimator_WHEN_loadingConfig_THEN_errorIsReturned() {
+	// GIVEN
+	assert.Nil(suite.T(), createTestConfigFile(configFileContents+`
+preparation:
+  estimator: "magic"
+`, DefaultConfigFilePath()))
+
+	// WHEN
+	config, err := LoadConfig("")
+
+	// THEN
+	assert.Nil(suite.T(), config)
+	assert.NotNil(suite.T(), err)
+	assert.Contains(suite.T(), err.Error(), "Preparation estimator must be linear, recipe or learned")
 }
 
 func (suite *ConfigTestSuite) Test_GIVEN_defaultLocalConfig_WHEN_environmentVariableForSameConfig_THEN_localFileConfigOverridenWithEnvironmentVariableConfig() {
@@ -110,9 +412,7 @@ func (suite *ConfigTestSuite) Test_GIVEN_defaultLocalConfig_WHEN_environmentVari
 	assert.Nil(suite.T(), err)
 	assert.Equal(suite.T(), "jack.torrence", config.Database().Username())
 	assert.Equal(suite.T(), "MySecretPassword", config.Database().Password())
-
-	// Known issue
-	//assert.Equal(suite.T(), Newest, config.Broker().ConsumerConfig().AutoOffsetReset())
+	assert.Equal(suite.T(), Newest, config.Broker().ConsumerConfig().AutoOffsetReset())
 }
 
 func (suite *ConfigTestSuite) Test_GIVEN_configFilePathIsNotProvided_WHEN_configFileDoesNotExistAtDefaultPath_THEN_errorIsReturned() {
@@ -165,6 +465,9 @@ broker:
   consumer:
     groupId: "group_id"
     autoOffsetReset: "earliest"
+
+auth:
+  secret: "all work and no play"
 `
 	assert.Nil(suite.T(), createTestConfigFile(customConfigFileContents, DefaultConfigFilePath()))
 
@@ -5,7 +5,10 @@ import (
 	"io"
 	"os"
 	"path/filepath"
+	"strings"
 
+	"github.com/gobuffalo/validate"
+	"github.com/gobuffalo/validate/validators"
 	"github.com/w-k-s/McMicroservices/kitchen-service/log"
 	"gopkg.in/natefinch/lumberjack.v2"
 )
@@ -31,3 +34,30 @@ func ConfigureLogging() (log.Logger, error) {
 
 	return log.NewLogger(multiWriter), nil
 }
+
+// LoggingConfig controls what the kitchen logs. It can be changed while the kitchen is running.
+type LoggingConfig interface {
+	// Level is the least severe level that is logged: debug, info, warn or error.
+	Level() string
+}
+
+type defaultLoggingConfig struct {
+	level string
+}
+
+func NewLoggingConfig(level string) (LoggingConfig, error) {
+	level = strings.ToLower(level)
+	errors := validate.Validate(
+		&validators.StringInclusion{Name: "Logging Level", Field: level, List: []string{"debug", "info", "warn", "error"}, Message: "Logging level must be debug, info, warn or error"},
+	)
+
+	if errors.HasAny() {
+		return nil, errors
+	}
+
+	return defaultLoggingConfig{level}, nil
+}
+
+func (l defaultLoggingConfig) Level() string {
+	return l.level
+}
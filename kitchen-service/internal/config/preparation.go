@@ -0,0 +1,119 @@
+package config
+
+import (
+	"time"
+
+	"github.com/gobuffalo/validate"
+	"github.com/gobuffalo/validate/validators"
+)
+
+const (
+	EstimatorLinear  = "linear"
+	EstimatorRecipe  = "recipe"
+	EstimatorLearned = "learned"
+)
+
+// PreparationConfig selects how the preparation time of an order is estimated.
+// The linear model (base + perItem for each topping) is also used by the other estimators
+// for toppings that they can not estimate.
+type PreparationConfig interface {
+	Estimator() string
+	Base() time.Duration
+	PerItem() time.Duration
+	// LearnedWindow is how far back the learned estimator looks for actual preparation times.
+	LearnedWindow() time.Duration
+	// LearnedMinSamples is the number of times that a topping must have been prepared before the learned estimator uses its actual preparation time.
+	LearnedMinSamples() int
+}
+
+type defaultPreparationConfig struct {
+	estimator         string
+	base              time.Duration
+	perItem           time.Duration
+	learnedWindow     time.Duration
+	learnedMinSamples int
+}
+
+func makePreparationConfig(b *preparationConfigBuilder) (PreparationConfig, error) {
+
+	errors := validate.Validate(
+		&validators.StringInclusion{Name: "Preparation Estimator", Field: b.estimator, List: []string{EstimatorLinear, EstimatorRecipe, EstimatorLearned}, Message: "Preparation estimator must be linear, recipe or learned"},
+		&validators.IntIsGreaterThan{Name: "Preparation Base", Field: int(b.base), Compared: -1, Message: "Preparation base must not be negative"},
+		&validators.IntIsGreaterThan{Name: "Preparation Per Item", Field: int(b.perItem), Compared: -1, Message: "Preparation per item must not be negative"},
+		&validators.IntIsGreaterThan{Name: "Learned Window", Field: int(b.learnedWindow), Compared: 0, Message: "Learned window must be greater than 0"},
+		&validators.IntIsGreaterThan{Name: "Learned Min Samples", Field: b.learnedMinSamples, Compared: -1, Message: "Learned min samples must not be negative"},
+	)
+
+	if errors.HasAny() {
+		return nil, errors
+	}
+
+	return defaultPreparationConfig{
+		b.estimator,
+		b.base,
+		b.perItem,
+		b.learnedWindow,
+		b.learnedMinSamples,
+	}, nil
+}
+
+func (p defaultPreparationConfig) Estimator() string {
+	return p.estimator
+}
+
+func (p defaultPreparationConfig) Base() time.Duration {
+	return p.base
+}
+
+func (p defaultPreparationConfig) PerItem() time.Duration {
+	return p.perItem
+}
+
+func (p defaultPreparationConfig) LearnedWindow() time.Duration {
+	return p.learnedWindow
+}
+
+func (p defaultPreparationConfig) LearnedMinSamples() int {
+	return p.learnedMinSamples
+}
+
+type preparationConfigBuilder struct {
+	estimator         string
+	base              time.Duration
+	perItem           time.Duration
+	learnedWindow     time.Duration
+	learnedMinSamples int
+}
+
+func NewPreparationConfigBuilder() *preparationConfigBuilder {
+	return &preparationConfigBuilder{}
+}
+
+func (b *preparationConfigBuilder) SetEstimator(estimator string) *preparationConfigBuilder {
+	b.estimator = estimator
+	return b
+}
+
+func (b *preparationConfigBuilder) SetBase(base time.Duration) *preparationConfigBuilder {
+	b.base = base
+	return b
+}
+
+func (b *preparationConfigBuilder) SetPerItem(perItem time.Duration) *preparationConfigBuilder {
+	b.perItem = perItem
+	return b
+}
+
+func (b *preparationConfigBuilder) SetLearnedWindow(window time.Duration) *preparationConfigBuilder {
+	b.learnedWindow = window
+	return b
+}
+
+func (b *preparationConfigBuilder) SetLearnedMinSamples(minSamples int) *preparationConfigBuilder {
+	b.learnedMinSamples = minSamples
+	return b
+}
+
+func (b *preparationConfigBuilder) Build() (PreparationConfig, error) {
+	return makePreparationConfig(b)
+}
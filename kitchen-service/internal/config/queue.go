@@ -0,0 +1,67 @@
+package config
+
+import (
+	"fmt"
+	"time"
+)
+
+// QueueConfig controls the order in which the kitchen takes on new orders.
+type QueueConfig interface {
+	// MaxWait is how long an order can wait before it is taken on ahead of orders with earlier deadlines.
+	MaxWait() time.Duration
+	// MaxOrderAge is how long after it was created, or received if its creation time is not known,
+	// an order that does not have an expiry time expires. Such orders never expire when it is 0.
+	MaxOrderAge() time.Duration
+	// BatchSize is the most orders that are scheduled in one transaction.
+	// Orders are scheduled one at a time when it is 1.
+	BatchSize() uint
+	// BatchWindow is how long the kitchen waits for a batch to fill before scheduling the orders it has.
+	BatchWindow() time.Duration
+	// Capacity is the most orders that can wait in the queue or be in preparation. No more orders are consumed while the queue is full.
+	// There is no limit when it is 0.
+	Capacity() uint
+}
+
+type defaultQueueConfig struct {
+	maxWait     time.Duration
+	maxOrderAge time.Duration
+	batchSize   uint
+	batchWindow time.Duration
+	capacity    uint
+}
+
+func NewQueueConfig(maxWait time.Duration, maxOrderAge time.Duration, batchSize uint, batchWindow time.Duration, capacity uint) (QueueConfig, error) {
+	if maxWait < 0 {
+		return nil, fmt.Errorf("queue max wait must not be negative")
+	}
+	if maxOrderAge < 0 {
+		return nil, fmt.Errorf("queue max order age must not be negative")
+	}
+	if batchSize == 0 {
+		return nil, fmt.Errorf("queue batch size must be at least 1")
+	}
+	if batchWindow < 0 {
+		return nil, fmt.Errorf("queue batch window must not be negative")
+	}
+	return defaultQueueConfig{maxWait, maxOrderAge, batchSize, batchWindow, capacity}, nil
+}
+
+func (q defaultQueueConfig) MaxWait() time.Duration {
+	return q.maxWait
+}
+
+func (q defaultQueueConfig) MaxOrderAge() time.Duration {
+	return q.maxOrderAge
+}
+
+func (q defaultQueueConfig) BatchSize() uint {
+	return q.batchSize
+}
+
+func (q defaultQueueConfig) BatchWindow() time.Duration {
+	return q.batchWindow
+}
+
+func (q defaultQueueConfig) Capacity() uint {
+	return q.capacity
+}
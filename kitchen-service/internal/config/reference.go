@@ -0,0 +1,55 @@
+package config
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// WriteReference writes the Markdown reference of the settings in the Schema.
+func WriteReference(w io.Writer) error {
+	var b strings.Builder
+	b.WriteString(`# Configuration
+
+<!-- Generated by go generate ./internal/config from the Schema in internal/config/schema.go. DO NOT EDIT. -->
+
+The kitchen service loads its config from the YAML or JSON file, or the config service URL, given with ` + "`-uri`" + `,
+e.g. ` + "`-uri=http://config-service/kitchen-service-default.yaml`" + `. It defaults to ` + "`~/.kitchen/config.yaml`" + `.
+
+Each setting can be overridden with its environment variable. Lists are separated by commas,
+e.g. ` + "`APP_BROKER_BOOTSTRAPSERVERS=kafka-1:9092,kafka-2:9092`" + `.
+
+The config source is checked for changes every ` + "`reload.interval`" + `. Reloadable settings are applied while the
+kitchen is running; changes to other settings are logged and rejected until the kitchen is restarted.
+
+| Key | Type | Default | Environment variable | Required | Reloadable | Description |
+| --- | --- | --- | --- | --- | --- | --- |
+`)
+	for _, key := range Schema {
+		fmt.Fprintf(&b, "| `%s` | %s | %s | `%s` | %s | %s | %s |\n",
+			key.Name,
+			key.typeName(),
+			code(key.Default),
+			key.Env,
+			yesNo(key.Required),
+			yesNo(key.Reloadable),
+			key.Description,
+		)
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func code(v interface{}) string {
+	if v == nil {
+		return ""
+	}
+	return fmt.Sprintf("`%v`", v)
+}
+
+func yesNo(b bool) string {
+	if b {
+		return "yes"
+	}
+	return "no"
+}
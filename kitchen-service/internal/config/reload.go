@@ -0,0 +1,36 @@
+package config
+
+import (
+	"fmt"
+	"time"
+)
+
+// ReloadConfig controls how often the kitchen checks its config source for changes.
+// Changes to the logging, preparation, queue and stock settings are applied while the kitchen is running;
+// other changes are only applied when the kitchen is restarted.
+type ReloadConfig interface {
+	// Disabled is true if the config is only loaded when the kitchen starts.
+	Disabled() bool
+	// Interval is how often the config file, or the config service, is checked for changes.
+	Interval() time.Duration
+}
+
+type defaultReloadConfig struct {
+	disabled bool
+	interval time.Duration
+}
+
+func NewReloadConfig(disabled bool, interval time.Duration) (ReloadConfig, error) {
+	if !disabled && interval <= 0 {
+		return nil, fmt.Errorf("reload interval must be greater than 0")
+	}
+	return defaultReloadConfig{disabled, interval}, nil
+}
+
+func (r defaultReloadConfig) Disabled() bool {
+	return r.disabled
+}
+
+func (r defaultReloadConfig) Interval() time.Duration {
+	return r.interval
+}
@@ -0,0 +1,263 @@
+package config
+
+//go:generate go run ../../cmd/configdoc -o ../../docs/configuration.md
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/gobuffalo/validate"
+	"github.com/spf13/cast"
+	"github.com/w-k-s/konfig"
+)
+
+type KeyType string
+
+const (
+	TypeString      KeyType = "string"
+	TypeStringSlice KeyType = "[]string"
+	TypeInt         KeyType = "int"
+	TypeFloat       KeyType = "float"
+	TypeBool        KeyType = "bool"
+)
+
+// Key is a setting of the config file.
+type Key struct {
+	// Name is the path of the setting in the config file, e.g. broker.consumer.autoOffsetReset.
+	Name string
+	Type KeyType
+	// Unit is the unit of a duration that is configured as a number, e.g. time.Second. It is zero for other settings.
+	Unit time.Duration
+	// Default is the value that is used when the setting is not configured, in Unit if it is a duration.
+	// It is nil if the setting does not have a default, or its default depends on the environment.
+	Default interface{}
+	// Env is the environment variable that overrides the setting.
+	// Lists are separated by commas, e.g. APP_BROKER_BOOTSTRAPSERVERS=kafka-1:9092,kafka-2:9092.
+	Env         string
+	Description string
+	Required    bool
+	// Secret settings are masked when they are logged.
+	Secret bool
+	// Reloadable settings can be changed while the kitchen is running.
+	Reloadable bool
+}
+
+// Schema lists every setting of the kitchen, in the order that they are documented.
+var Schema = []Key{
+	{Name: "server.port", Type: TypeInt, Env: "APP_SERVER_PORT", Required: true,
+		Description: "Port that the HTTP server listens on. Must be greater than 1023."},
+	{Name: "server.readTimeout", Type: TypeInt, Unit: time.Second, Default: 10, Env: "APP_SERVER_READTIMEOUT",
+		Description: "Longest time to read a request, including its body."},
+	{Name: "server.writeTimeout", Type: TypeInt, Unit: time.Second, Default: 10, Env: "APP_SERVER_WRITETIMEOUT",
+		Description: "Longest time to write a response."},
+	{Name: "server.maxHeaderBytes", Type: TypeInt, Default: 1 << 20, Env: "APP_SERVER_MAXHEADERBYTES",
+		Description: "Largest size of the headers of a request."},
+	{Name: "server.shutdownGracePeriod", Type: TypeInt, Unit: time.Second, Default: 5, Env: "APP_SERVER_SHUTDOWNGRACEPERIOD",
+		Description: "How long requests in progress are given to complete when the kitchen is stopped."},
+
+	{Name: "database.username", Type: TypeString, Env: "APP_DATABASE_USERNAME", Required: true,
+		Description: "PostgreSQL user."},
+	{Name: "database.password", Type: TypeString, Env: "APP_DATABASE_PASSWORD", Required: true, Secret: true,
+		Description: "Password of the PostgreSQL user."},
+	{Name: "database.host", Type: TypeString, Env: "APP_DATABASE_HOST", Required: true,
+		Description: "Host of the PostgreSQL server."},
+	{Name: "database.port", Type: TypeInt, Env: "APP_DATABASE_PORT", Required: true,
+		Description: "Port of the PostgreSQL server."},
+	{Name: "database.name", Type: TypeString, Env: "APP_DATABASE_NAME", Required: true,
+		Description: "Database that the kitchen schema is created in."},
+	{Name: "database.sslmode", Type: TypeString, Env: "APP_DATABASE_SSLMODE", Required: true,
+		Description: "disable, require, verify-ca or verify-full."},
+	{Name: "database.migrationDir", Type: TypeString, Env: "APP_DATABASE_MIGRATIONDIR",
+		Description: "Directory of the SQL migrations. Defaults to ~/.kitchen/migrations."},
+
+	{Name: "broker.bootstrapServers", Type: TypeStringSlice, Env: "APP_BROKER_BOOTSTRAPSERVERS", Required: true,
+		Description: "Kafka brokers that the kitchen connects to first, as host:port."},
+	{Name: "broker.securityProtocol", Type: TypeString, Default: "plaintext", Env: "APP_BROKER_SECURITYPROTOCOL",
+		Description: "Protocol used to communicate with the brokers."},
+	{Name: "broker.consumer.groupId", Type: TypeString, Env: "APP_BROKER_CONSUMER_GROUPID",
+		Description: "Consumer group of the kitchen."},
+	{Name: "broker.consumer.autoOffsetReset", Type: TypeString, Env: "APP_BROKER_CONSUMER_AUTOOFFSETRESET", Required: true,
+		Description: "Where a consumer without a committed offset starts reading: earliest or newest."},
+
+	{Name: "preparation.estimator", Type: TypeString, Default: EstimatorLinear, Env: "APP_PREPARATION_ESTIMATOR", Reloadable: true,
+		Description: "How preparation times are estimated: linear, recipe or learned."},
+	{Name: "preparation.base", Type: TypeInt, Unit: time.Second, Default: 0, Env: "APP_PREPARATION_BASE", Reloadable: true,
+		Description: "Time that the linear model adds to the first topping of an order."},
+	{Name: "preparation.perItem", Type: TypeInt, Unit: time.Second, Default: 5, Env: "APP_PREPARATION_PERITEM", Reloadable: true,
+		Description: "Time that the linear model takes for each topping."},
+	{Name: "preparation.learned.windowHours", Type: TypeInt, Unit: time.Hour, Default: 168, Env: "APP_PREPARATION_LEARNED_WINDOWHOURS", Reloadable: true,
+		Description: "How far back the learned model looks for actual preparation times. Must be greater than 0."},
+	{Name: "preparation.learned.minSamples", Type: TypeInt, Default: 5, Env: "APP_PREPARATION_LEARNED_MINSAMPLES", Reloadable: true,
+		Description: "Times that a topping must have been prepared before the learned model uses its actual preparation time."},
+
+	{Name: "queue.maxWait", Type: TypeInt, Unit: time.Second, Default: 300, Env: "APP_QUEUE_MAXWAIT", Reloadable: true,
+		Description: "How long an order can wait before it is taken on ahead of orders with earlier deadlines."},
+	{Name: "queue.maxOrderAge", Type: TypeInt, Unit: time.Second, Default: 0, Env: "APP_QUEUE_MAXORDERAGE", Reloadable: true,
+		Description: "How long after it was created an order without an expiry time expires. Orders without an expiry time never expire when it is 0."},
+	{Name: "queue.batch.size", Type: TypeInt, Default: 1, Env: "APP_QUEUE_BATCH_SIZE", Reloadable: true,
+		Description: "Most orders that are scheduled in one transaction. Must be at least 1."},
+	{Name: "queue.batch.windowMillis", Type: TypeInt, Unit: time.Millisecond, Default: 50, Env: "APP_QUEUE_BATCH_WINDOWMILLIS", Reloadable: true,
+		Description: "How long the kitchen waits for a batch to fill."},
+	{Name: "queue.capacity", Type: TypeInt, Default: 1000, Env: "APP_QUEUE_CAPACITY", Reloadable: true,
+		Description: "Most orders that can wait to be scheduled or be in preparation. No more orders are consumed while the queue is full. There is no limit when it is 0."},
+
+	{Name: "stock.bulkIncreaseThreshold", Type: TypeInt, Default: 200, Env: "APP_STOCK_BULKINCREASETHRESHOLD", Reloadable: true,
+		Description: "Number of items above which a delivery is copied into stock in bulk instead of adding each item in turn."},
+	{Name: "stock.idempotencyKeyRetentionHours", Type: TypeInt, Unit: time.Hour, Default: 24, Env: "APP_STOCK_IDEMPOTENCYKEYRETENTIONHOURS", Reloadable: true,
+		Description: "How long a delivery is replayed for retries with the same idempotency key. Older keys are deleted. Must be greater than 0."},
+
+	{Name: "auth.disabled", Type: TypeBool, Default: false, Env: "APP_AUTH_DISABLED",
+		Description: "Allows anyone who can reach the kitchen to use the API."},
+	{Name: "auth.secret", Type: TypeString, Env: "APP_AUTH_SECRET", Secret: true,
+		Description: "HMAC secret of the tokens. A secret, a JWKS file or a JWKS URL is required unless auth is disabled."},
+	{Name: "auth.jwks.file", Type: TypeString, Env: "APP_AUTH_JWKS_FILE",
+		Description: "JSON Web Key Set file of the keys that sign the tokens."},
+	{Name: "auth.jwks.url", Type: TypeString, Env: "APP_AUTH_JWKS_URL",
+		Description: "URL of the JSON Web Key Set of the keys that sign the tokens."},
+	{Name: "auth.jwks.refresh", Type: TypeInt, Unit: time.Second, Default: 3600, Env: "APP_AUTH_JWKS_REFRESH",
+		Description: "How often the JSON Web Key Set is reloaded."},
+	{Name: "auth.issuer", Type: TypeString, Env: "APP_AUTH_ISSUER",
+		Description: "Issuer that tokens must have. Any issuer is accepted if it is empty."},
+	{Name: "auth.audience", Type: TypeString, Env: "APP_AUTH_AUDIENCE",
+		Description: "Audience that tokens must have. Any audience is accepted if it is empty."},
+	{Name: "auth.leeway", Type: TypeInt, Unit: time.Second, Default: 30, Env: "APP_AUTH_LEEWAY",
+		Description: "Clock skew that is allowed when the expiry of a token is checked."},
+
+	{Name: "telemetry.exporter", Type: TypeString, Default: string(ExporterNone), Env: "APP_TELEMETRY_EXPORTER",
+		Description: "Where spans are exported: none, otlp or stdout."},
+	{Name: "telemetry.service", Type: TypeString, Default: "kitchen-service", Env: "APP_TELEMETRY_SERVICE",
+		Description: "service.name resource of the spans."},
+	{Name: "telemetry.sampling", Type: TypeFloat, Default: 1, Env: "APP_TELEMETRY_SAMPLING",
+		Description: "Fraction of new traces that are sampled, between 0 and 1. At 0, only traces that callers sampled are sampled."},
+	{Name: "telemetry.otlp.endpoint", Type: TypeString, Env: "APP_TELEMETRY_OTLP_ENDPOINT",
+		Description: "host:port of the OpenTelemetry collector. Required when the exporter is otlp."},
+	{Name: "telemetry.otlp.insecure", Type: TypeBool, Default: false, Env: "APP_TELEMETRY_OTLP_INSECURE",
+		Description: "Sends spans to the collector over HTTP instead of HTTPS."},
+	{Name: "telemetry.file", Type: TypeString, Env: "APP_TELEMETRY_FILE",
+		Description: "File that the stdout exporter writes to. Spans are written to stdout if it is empty."},
+
+	{Name: "logging.level", Type: TypeString, Default: "info", Env: "APP_LOGGING_LEVEL", Reloadable: true,
+		Description: "Least severe level that is logged: debug, info, warn or error."},
+
+	{Name: "reload.disabled", Type: TypeBool, Default: false, Env: "APP_RELOAD_DISABLED",
+		Description: "Only loads the config when the kitchen starts."},
+	{Name: "reload.interval", Type: TypeInt, Unit: time.Second, Default: 30, Env: "APP_RELOAD_INTERVAL",
+		Description: "How often the config file, or the config service, is checked for changes. Must be greater than 0 unless reloading is disabled."},
+}
+
+// LookupKey returns the setting with the name, and false if the kitchen does not have the setting.
+func LookupKey(name string) (Key, bool) {
+	for _, key := range Schema {
+		if key.Name == name {
+			return key, true
+		}
+	}
+	return Key{}, false
+}
+
+// environment maps the environment variables of the schema that are set to the settings that they override.
+func environment() map[string]string {
+	vars := map[string]string{}
+	for _, key := range Schema {
+		if _, ok := os.LookupEnv(key.Env); ok {
+			vars[key.Env] = key.Name
+		}
+	}
+	return vars
+}
+
+// validateStore checks that the required settings are configured, and that every setting can be read as its type.
+func validateStore(store konfig.Store) error {
+	validators := []validate.Validator{}
+	for _, key := range Schema {
+		validators = append(validators, &schemaKeyValidator{key, store.Get(key.Name)})
+	}
+	if errors := validate.Validate(validators...); errors.HasAny() {
+		return errors
+	}
+	return nil
+}
+
+// seedDefaults sets every setting that has a default to its default, so that the config file and the environment override it
+// and a setting can be configured as zero.
+func seedDefaults(store konfig.Store) {
+	for _, key := range Schema {
+		if key.Default != nil {
+			store.Set(key.Name, key.Default)
+		}
+	}
+}
+
+// settings are the values of every setting of the Schema in the store, as they are reported by the config.
+func settings(store konfig.Store) map[string]string {
+	values := map[string]string{}
+	for _, key := range Schema {
+		values[key.Name] = key.format(store.Get(key.Name))
+	}
+	return values
+}
+
+type schemaKeyValidator struct {
+	Key   Key
+	Value interface{}
+}
+
+func (v *schemaKeyValidator) IsValid(errors *validate.Errors) {
+	if v.Value == nil || v.Value == "" {
+		if v.Key.Required {
+			errors.Add(v.Key.Name, fmt.Sprintf("%s is required. Set it in the config file or with %s", v.Key.Name, v.Key.Env))
+		}
+		return
+	}
+
+	var err error
+	switch v.Key.Type {
+	case TypeString:
+		_, err = cast.ToStringE(v.Value)
+	case TypeStringSlice:
+		_, err = cast.ToStringSliceE(v.Value)
+	case TypeInt:
+		_, err = cast.ToIntE(v.Value)
+	case TypeFloat:
+		_, err = cast.ToFloat64E(v.Value)
+	case TypeBool:
+		_, err = cast.ToBoolE(v.Value)
+	}
+	if err != nil {
+		errors.Add(v.Key.Name, fmt.Sprintf("%s must be of type %s. Got %v", v.Key.Name, v.Key.typeName(), v.Value))
+	}
+}
+
+func (k Key) typeName() string {
+	switch k.Unit {
+	case time.Millisecond:
+		return string(k.Type) + " (milliseconds)"
+	case time.Second:
+		return string(k.Type) + " (seconds)"
+	case time.Hour:
+		return string(k.Type) + " (hours)"
+	default:
+		return string(k.Type)
+	}
+}
+
+// defaultValue is the default as it is reported by the config, e.g. 10s for 10 seconds, or empty if the setting does not have a default.
+func (k Key) defaultValue() string {
+	return k.format(k.Default)
+}
+
+// format is a value of the setting as it is reported by the config, e.g. 10s for 10 seconds, or empty if it is not set.
+func (k Key) format(value interface{}) string {
+	if value == nil {
+		return ""
+	}
+	if k.Unit != 0 {
+		return fmt.Sprint(time.Duration(cast.ToInt64(value)) * k.Unit)
+	}
+	if k.Type == TypeStringSlice {
+		return strings.Join(cast.ToStringSlice(value), ",")
+	}
+	return fmt.Sprint(value)
+}
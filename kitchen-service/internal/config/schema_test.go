@@ -0,0 +1,136 @@
+package config
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type SchemaTestSuite struct {
+	suite.Suite
+	path string
+}
+
+func TestSchemaTestSuite(t *testing.T) {
+	suite.Run(t, new(SchemaTestSuite))
+}
+
+// -- SETUP
+
+func (suite *SchemaTestSuite) SetupTest() {
+	suite.path = filepath.Join(suite.T().TempDir(), "kitchen-service.yaml")
+	assert.Nil(suite.T(), createTestConfigFile(configFileContents, suite.path))
+}
+
+func (suite *SchemaTestSuite) uri() string {
+	return "file://" + suite.path
+}
+
+// -- SUITE
+
+func (suite *SchemaTestSuite) Test_GIVEN_schema_WHEN_listingKeys_THEN_namesAndEnvironmentVariablesAreUnique() {
+	names := map[string]bool{}
+	vars := map[string]bool{}
+	for _, key := range Schema {
+		assert.False(suite.T(), names[key.Name], key.Name)
+		assert.False(suite.T(), vars[key.Env], key.Env)
+		assert.True(suite.T(), strings.HasPrefix(key.Env, "APP_"), key.Env)
+		assert.NotEmpty(suite.T(), key.Description, key.Name)
+		names[key.Name] = true
+		vars[key.Env] = true
+	}
+}
+
+func (suite *SchemaTestSuite) Test_GIVEN_config_WHEN_listingValues_THEN_everySettingIsInSchema() {
+	// GIVEN
+	config, err := LoadConfig(suite.uri())
+	assert.Nil(suite.T(), err)
+
+	// WHEN
+	values := config.values()
+
+	// THEN
+	assert.Len(suite.T(), values, len(Schema))
+	for name := range values {
+		_, ok := LookupKey(name)
+		assert.True(suite.T(), ok, name)
+	}
+}
+
+func (suite *SchemaTestSuite) Test_GIVEN_settingsAreNotConfigured_WHEN_loadingConfig_THEN_defaultsOfSchemaAreUsed() {
+	// WHEN
+	config, err := LoadConfig(suite.uri())
+
+	// THEN
+	assert.Nil(suite.T(), err)
+	values := config.values()
+	for _, key := range Schema {
+		if key.Default != nil {
+			assert.Equal(suite.T(), key.defaultValue(), values[key.Name], key.Name)
+		}
+	}
+}
+
+func (suite *SchemaTestSuite) Test_GIVEN_environmentVariableForCamelCasedKey_WHEN_loadingConfig_THEN_keyIsOverridden() {
+	// GIVEN
+	os.Setenv("APP_BROKER_BOOTSTRAPSERVERS", "kafka-1:9092,kafka-2:9092")
+	defer os.Unsetenv("APP_BROKER_BOOTSTRAPSERVERS")
+	os.Setenv("APP_QUEUE_BATCH_WINDOWMILLIS", "200")
+	defer os.Unsetenv("APP_QUEUE_BATCH_WINDOWMILLIS")
+
+	// WHEN
+	config, err := LoadConfig(suite.uri())
+
+	// THEN
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), []string{"kafka-1:9092", "kafka-2:9092"}, config.Broker().BootstrapServers())
+	assert.Equal(suite.T(), "200ms", config.Queue().BatchWindow().String())
+}
+
+func (suite *SchemaTestSuite) Test_GIVEN_requiredSettingIsMissing_WHEN_loadingConfig_THEN_errorNamesSettingAndEnvironmentVariable() {
+	// GIVEN
+	assert.Nil(suite.T(), createTestConfigFile(strings.Replace(configFileContents, `host: "localhost"`, "", 1), suite.path))
+
+	// WHEN
+	config, err := LoadConfig(suite.uri())
+
+	// THEN
+	assert.Nil(suite.T(), config)
+	assert.NotNil(suite.T(), err)
+	assert.Equal(suite.T(), "invalid config in '"+suite.uri()+"': database.host is required. Set it in the config file or with APP_DATABASE_HOST", err.Error())
+}
+
+func (suite *SchemaTestSuite) Test_GIVEN_settingHasWrongType_WHEN_loadingConfig_THEN_errorIsReturned() {
+	// GIVEN
+	assert.Nil(suite.T(), createTestConfigFile(configFileContents+`
+queue:
+  maxWait: "soon"
+`, suite.path))
+
+	// WHEN
+	config, err := LoadConfig(suite.uri())
+
+	// THEN
+	assert.Nil(suite.T(), config)
+	assert.NotNil(suite.T(), err)
+	assert.Contains(suite.T(), err.Error(), "queue.maxWait must be of type int (seconds). Got soon")
+}
+
+func (suite *SchemaTestSuite) Test_GIVEN_schema_WHEN_writingReference_THEN_committedReferenceIsUpToDate() {
+	// GIVEN
+	committed, err := ioutil.ReadFile(filepath.Join("..", "..", "docs", "configuration.md"))
+	assert.Nil(suite.T(), err)
+
+	// WHEN
+	var reference bytes.Buffer
+	assert.Nil(suite.T(), WriteReference(&reference))
+
+	// THEN
+	assert.Equal(suite.T(), string(committed), reference.String(), "run go generate ./internal/config")
+}
@@ -0,0 +1,39 @@
+package config
+
+import (
+	"fmt"
+	"time"
+)
+
+// StockConfig controls how stock is stored. It can be changed while the kitchen is running.
+type StockConfig interface {
+	// BulkIncreaseThreshold is the number of items above which a delivery is copied into stock in bulk
+	// instead of adding each item in turn.
+	BulkIncreaseThreshold() int
+	// IdempotencyKeyRetention is how long the response to a delivery is replayed for retries with the same idempotency key.
+	// Keys are deleted once they are older.
+	IdempotencyKeyRetention() time.Duration
+}
+
+type defaultStockConfig struct {
+	bulkIncreaseThreshold   int
+	idempotencyKeyRetention time.Duration
+}
+
+func NewStockConfig(bulkIncreaseThreshold int, idempotencyKeyRetention time.Duration) (StockConfig, error) {
+	if bulkIncreaseThreshold < 0 {
+		return nil, fmt.Errorf("stock bulk increase threshold must not be negative")
+	}
+	if idempotencyKeyRetention <= 0 {
+		return nil, fmt.Errorf("stock idempotency key retention must be greater than 0")
+	}
+	return defaultStockConfig{bulkIncreaseThreshold, idempotencyKeyRetention}, nil
+}
+
+func (s defaultStockConfig) BulkIncreaseThreshold() int {
+	return s.bulkIncreaseThreshold
+}
+
+func (s defaultStockConfig) IdempotencyKeyRetention() time.Duration {
+	return s.idempotencyKeyRetention
+}
@@ -0,0 +1,140 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+)
+
+type TelemetryExporter string
+
+const (
+	// ExporterNone records spans so that their ids are logged and propagated, but does not export them.
+	ExporterNone TelemetryExporter = "none"
+	// ExporterOTLP sends spans to an OpenTelemetry collector over OTLP/HTTP.
+	ExporterOTLP TelemetryExporter = "otlp"
+	// ExporterStdout writes spans as JSON to stdout, or to a file, for local debugging.
+	ExporterStdout TelemetryExporter = "stdout"
+)
+
+// TelemetryConfig selects where the spans of the kitchen are exported to.
+type TelemetryConfig interface {
+	Exporter() TelemetryExporter
+	// ServiceName is the service.name resource of the spans.
+	ServiceName() string
+	// SampleRatio is the fraction of new traces that are sampled. Traces that were started by a caller follow the caller's decision.
+	SampleRatio() float64
+	// OTLPEndpoint is the host and port of the collector, e.g. otel-collector:4318.
+	OTLPEndpoint() string
+	// OTLPInsecure sends spans over HTTP instead of HTTPS.
+	OTLPInsecure() bool
+	// File is where the stdout exporter writes spans. Spans are written to stdout if it is empty.
+	File() string
+}
+
+type defaultTelemetryConfig struct {
+	exporter     TelemetryExporter
+	serviceName  string
+	sampleRatio  float64
+	otlpEndpoint string
+	otlpInsecure bool
+	file         string
+}
+
+func makeTelemetryConfig(b *telemetryConfigBuilder) (TelemetryConfig, error) {
+	exporter := TelemetryExporter(strings.ToLower(b.exporter))
+	switch exporter {
+	case ExporterNone, ExporterStdout:
+	case ExporterOTLP:
+		if len(b.otlpEndpoint) == 0 {
+			return nil, fmt.Errorf("telemetry OTLP endpoint is required when the exporter is otlp")
+		}
+	default:
+		return nil, fmt.Errorf("telemetry exporter must be none, otlp or stdout")
+	}
+	if len(b.serviceName) == 0 {
+		return nil, fmt.Errorf("telemetry service name is required")
+	}
+	if b.sampleRatio < 0 || b.sampleRatio > 1 {
+		return nil, fmt.Errorf("telemetry sample ratio must be between 0 and 1")
+	}
+
+	return defaultTelemetryConfig{
+		exporter,
+		b.serviceName,
+		b.sampleRatio,
+		b.otlpEndpoint,
+		b.otlpInsecure,
+		b.file,
+	}, nil
+}
+
+func (t defaultTelemetryConfig) Exporter() TelemetryExporter {
+	return t.exporter
+}
+
+func (t defaultTelemetryConfig) ServiceName() string {
+	return t.serviceName
+}
+
+func (t defaultTelemetryConfig) SampleRatio() float64 {
+	return t.sampleRatio
+}
+
+func (t defaultTelemetryConfig) OTLPEndpoint() string {
+	return t.otlpEndpoint
+}
+
+func (t defaultTelemetryConfig) OTLPInsecure() bool {
+	return t.otlpInsecure
+}
+
+func (t defaultTelemetryConfig) File() string {
+	return t.file
+}
+
+type telemetryConfigBuilder struct {
+	exporter     string
+	serviceName  string
+	sampleRatio  float64
+	otlpEndpoint string
+	otlpInsecure bool
+	file         string
+}
+
+func NewTelemetryConfigBuilder() *telemetryConfigBuilder {
+	return &telemetryConfigBuilder{}
+}
+
+func (b *telemetryConfigBuilder) SetExporter(exporter string) *telemetryConfigBuilder {
+	b.exporter = exporter
+	return b
+}
+
+func (b *telemetryConfigBuilder) SetServiceName(serviceName string) *telemetryConfigBuilder {
+	b.serviceName = serviceName
+	return b
+}
+
+func (b *telemetryConfigBuilder) SetSampleRatio(sampleRatio float64) *telemetryConfigBuilder {
+	b.sampleRatio = sampleRatio
+	return b
+}
+
+func (b *telemetryConfigBuilder) SetOTLPEndpoint(endpoint string) *telemetryConfigBuilder {
+	b.otlpEndpoint = endpoint
+	return b
+}
+
+func (b *telemetryConfigBuilder) SetOTLPInsecure(insecure bool) *telemetryConfigBuilder {
+	b.otlpInsecure = insecure
+	return b
+}
+
+func (b *telemetryConfigBuilder) SetFile(file string) *telemetryConfigBuilder {
+	b.file = file
+	return b
+}
+
+func (b *telemetryConfigBuilder) Build() (TelemetryConfig, error) {
+	return makeTelemetryConfig(b)
+}
@@ -0,0 +1,285 @@
+package config
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/w-k-s/McMicroservices/kitchen-service/log"
+	"github.com/w-k-s/konfig"
+	"github.com/w-k-s/konfig/loader/klhttp"
+)
+
+// ConfigListener is called with the config before and after a reload that changed settings that can be changed
+// while the kitchen is running.
+type ConfigListener func(previous *Config, current *Config)
+
+// ConfigChange is a setting whose value in the config source is different from the value that the kitchen is using.
+type ConfigChange struct {
+	Key      string
+	Previous string
+	Current  string
+	// Reloadable is false if the kitchen keeps using the previous value until it is restarted.
+	Reloadable bool
+}
+
+// ConfigWatcher reloads the config when its source changes and notifies its listeners.
+// Only the logging, preparation, queue and stock settings are reloaded; changes to other settings are rejected
+// and logged once.
+type ConfigWatcher struct {
+	source    configSource
+	reloadMu  sync.Mutex
+	mu        sync.Mutex
+	config    *Config
+	listeners []ConfigListener
+	watcher   konfig.Watcher
+	// rejected is the rejected value of each setting whose change has been logged.
+	rejected map[string]string
+}
+
+func NewConfigWatcher(configFilePath string, config *Config) (*ConfigWatcher, error) {
+	return NewConfigWatcherWithClient(configFilePath, http.DefaultClient, config)
+}
+
+func NewConfigWatcherWithClient(configFilePath string, httpClient klhttp.Client, config *Config) (*ConfigWatcher, error) {
+	if config == nil {
+		return nil, fmt.Errorf("configuration is required. Got %v", nil)
+	}
+	source, err := newConfigSource(configFilePath, httpClient)
+	if err != nil {
+		return nil, err
+	}
+	return &ConfigWatcher{
+		source:   source,
+		config:   config,
+		rejected: map[string]string{},
+	}, nil
+}
+
+// Config is the config that the kitchen is using, including the settings that have been reloaded.
+func (w *ConfigWatcher) Config() *Config {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.config
+}
+
+// Subscribe adds a listener that is called after every reload that changes the config.
+func (w *ConfigWatcher) Subscribe(listener ConfigListener) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.listeners = append(w.listeners, listener)
+}
+
+// Start watches the config source in the background. It does nothing if reloading is disabled.
+func (w *ConfigWatcher) Start() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.config.Reload().Disabled() || w.watcher != nil {
+		return nil
+	}
+
+	watcher := w.source.Watcher(w.config.Reload().Interval())
+	if err := watcher.Start(); err != nil {
+		return fmt.Errorf("failed to watch config file '%s'. Reason: %w", w.source.path, err)
+	}
+	w.watcher = watcher
+
+	go w.watch(watcher)
+	return nil
+}
+
+func (w *ConfigWatcher) watch(watcher konfig.Watcher) {
+	for {
+		select {
+		case <-watcher.Done():
+			if err := watcher.Err(); err != nil {
+				log.Err(err).Msgf("Stopped watching config file '%s'", w.source.path)
+			}
+			return
+		case <-watcher.Watch():
+			// Errors are logged by Reload, and the current config is kept.
+			_, _ = w.Reload()
+		}
+	}
+}
+
+// Close stops watching the config source.
+func (w *ConfigWatcher) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.watcher == nil {
+		return nil
+	}
+	err := w.watcher.Close()
+	w.watcher = nil
+	return err
+}
+
+// Reload loads the config source and applies the changes to the settings that can be changed while the kitchen is running.
+// It returns every setting that changed, including those that were rejected.
+func (w *ConfigWatcher) Reload() ([]ConfigChange, error) {
+	w.reloadMu.Lock()
+	defer w.reloadMu.Unlock()
+
+	loaded, err := w.source.Load()
+	if err != nil {
+		log.Err(err).Msg("Failed to reload config. The current config is still used")
+		return nil, err
+	}
+
+	w.mu.Lock()
+	previous := w.config
+	changes := DiffConfig(previous, loaded)
+	reloaded := false
+	for _, change := range changes {
+		if change.Reloadable {
+			reloaded = true
+			log.Info().
+				Str("key", change.Key).
+				Str("previous", change.Previous).
+				Str("current", change.Current).
+				Msg("Config changed")
+		}
+	}
+	for _, change := range w.unreported(changes) {
+		log.Err(fmt.Errorf("%s can not be changed while the kitchen is running", change.Key)).
+			Str("key", change.Key).
+			Str("previous", change.Previous).
+			Str("rejected", change.Current).
+			Msg("Config change rejected. Restart the kitchen to apply it")
+	}
+	if !reloaded {
+		w.mu.Unlock()
+		return changes, nil
+	}
+	current := previous.withReloadable(loaded)
+	w.config = current
+	listeners := append([]ConfigListener{}, w.listeners...)
+	w.mu.Unlock()
+
+	for _, listener := range listeners {
+		listener(previous, current)
+	}
+	return changes, nil
+}
+
+// unreported returns the rejected changes that have not been logged yet, and records them as logged.
+// A setting whose change is reverted is logged again if it is changed again.
+func (w *ConfigWatcher) unreported(changes []ConfigChange) []ConfigChange {
+	rejected := map[string]string{}
+	unreported := []ConfigChange{}
+	for _, change := range changes {
+		if change.Reloadable {
+			continue
+		}
+		rejected[change.Key] = change.Current
+		if logged, ok := w.rejected[change.Key]; !ok || logged != change.Current {
+			unreported = append(unreported, change)
+		}
+	}
+	w.rejected = rejected
+	return unreported
+}
+
+// withReloadable is a copy of the config with the settings of loaded that can be changed while the kitchen is running.
+func (c Config) withReloadable(loaded *Config) *Config {
+	c.logging = loaded.logging
+	c.prep = loaded.prep
+	c.queue = loaded.queue
+	c.stock = loaded.stock
+
+	settings := map[string]string{}
+	for _, key := range Schema {
+		if key.Reloadable {
+			settings[key.Name] = loaded.settings[key.Name]
+		} else {
+			settings[key.Name] = c.settings[key.Name]
+		}
+	}
+	c.settings = settings
+	return &c
+}
+
+// DiffConfig lists the settings whose values are different in current than in previous, in the order of the Schema.
+// The values of secrets are masked.
+func DiffConfig(previous *Config, current *Config) []ConfigChange {
+	changes := []ConfigChange{}
+	before := previous.values()
+	after := current.values()
+	for _, key := range Schema {
+		if before[key.Name] == after[key.Name] {
+			continue
+		}
+		change := ConfigChange{
+			Key:        key.Name,
+			Previous:   before[key.Name],
+			Current:    after[key.Name],
+			Reloadable: key.Reloadable,
+		}
+		if key.Secret {
+			change.Previous, change.Current = "******", "******"
+		}
+		changes = append(changes, change)
+	}
+	return changes
+}
+
+// values are the values of each setting of the Schema that the config was loaded with, including defaults.
+func (c Config) values() map[string]string {
+	return c.settings
+}
+
+// pollWatcher signals every interval, for config sources that can not be watched such as the config service.
+// konfig's poll watcher is not used because it stops watching after the first request that fails.
+type pollWatcher struct {
+	interval time.Duration
+	watch    chan struct{}
+	done     chan struct{}
+	once     sync.Once
+}
+
+func newPollWatcher(interval time.Duration) *pollWatcher {
+	return &pollWatcher{
+		interval: interval,
+		watch:    make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+}
+
+func (p *pollWatcher) Start() error {
+	go func() {
+		ticker := time.NewTicker(p.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-p.done:
+				return
+			case <-ticker.C:
+				select {
+				case p.watch <- struct{}{}:
+				case <-p.done:
+					return
+				}
+			}
+		}
+	}()
+	return nil
+}
+
+func (p *pollWatcher) Done() <-chan struct{} {
+	return p.done
+}
+
+func (p *pollWatcher) Watch() <-chan struct{} {
+	return p.watch
+}
+
+func (p *pollWatcher) Close() error {
+	p.once.Do(func() { close(p.done) })
+	return nil
+}
+
+func (p *pollWatcher) Err() error {
+	return nil
+}
@@ -0,0 +1,237 @@
+package config
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type ConfigWatcherTestSuite struct {
+	suite.Suite
+	path string
+}
+
+func TestConfigWatcherTestSuite(t *testing.T) {
+	suite.Run(t, new(ConfigWatcherTestSuite))
+}
+
+// -- SETUP
+
+func (suite *ConfigWatcherTestSuite) SetupTest() {
+	suite.path = filepath.Join(suite.T().TempDir(), "kitchen-service.yaml")
+	assert.Nil(suite.T(), createTestConfigFile(configFileContents, suite.path))
+}
+
+func (suite *ConfigWatcherTestSuite) uri() string {
+	return "file://" + suite.path
+}
+
+func (suite *ConfigWatcherTestSuite) mustWatcher(contents string) *ConfigWatcher {
+	assert.Nil(suite.T(), createTestConfigFile(contents, suite.path))
+	config, err := LoadConfig(suite.uri())
+	assert.Nil(suite.T(), err)
+	watcher, err := NewConfigWatcher(suite.uri(), config)
+	assert.Nil(suite.T(), err)
+	return watcher
+}
+
+// -- SUITE
+
+func (suite *ConfigWatcherTestSuite) Test_GIVEN_reloadableSettingsChanged_WHEN_reloading_THEN_newValuesAreUsedAndSubscribersAreNotified() {
+	// GIVEN
+	watcher := suite.mustWatcher(configFileContents)
+	var previous, current *Config
+	watcher.Subscribe(func(p *Config, c *Config) {
+		previous, current = p, c
+	})
+	assert.Nil(suite.T(), createTestConfigFile(configFileContents+`
+logging:
+  level: "error"
+
+preparation:
+  perItem: 8
+
+queue:
+  maxWait: 60
+  batch:
+    size: 10
+
+stock:
+  bulkIncreaseThreshold: 0
+`, suite.path))
+
+	// WHEN
+	changes, err := watcher.Reload()
+
+	// THEN
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), []ConfigChange{
+		{Key: "preparation.perItem", Previous: "5s", Current: "8s", Reloadable: true},
+		{Key: "queue.maxWait", Previous: "5m0s", Current: "1m0s", Reloadable: true},
+		{Key: "queue.batch.size", Previous: "1", Current: "10", Reloadable: true},
+		{Key: "stock.bulkIncreaseThreshold", Previous: "200", Current: "0", Reloadable: true},
+		{Key: "logging.level", Previous: "info", Current: "error", Reloadable: true},
+	}, changes)
+	assert.Equal(suite.T(), "info", previous.Logging().Level())
+	assert.Equal(suite.T(), current, watcher.Config())
+	assert.Equal(suite.T(), "error", watcher.Config().Logging().Level())
+	assert.Equal(suite.T(), 8*time.Second, watcher.Config().Preparation().PerItem())
+	assert.Equal(suite.T(), time.Minute, watcher.Config().Queue().MaxWait())
+	assert.Equal(suite.T(), uint(10), watcher.Config().Queue().BatchSize())
+	assert.Equal(suite.T(), 0, watcher.Config().Stock().BulkIncreaseThreshold())
+}
+
+func (suite *ConfigWatcherTestSuite) Test_GIVEN_rejectedChangeWasLogged_WHEN_reloadingAgain_THEN_changeIsNotLoggedAgain() {
+	// GIVEN
+	watcher := suite.mustWatcher(configFileContents)
+	assert.Nil(suite.T(), createTestConfigFile(strings.Replace(configFileContents, "port: 8080", "port: 9090", 1), suite.path))
+	_, err := watcher.Reload()
+	assert.Nil(suite.T(), err)
+
+	// WHEN
+	changes, err := watcher.Reload()
+
+	// THEN
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), 1, len(changes))
+	assert.Empty(suite.T(), watcher.unreported(changes))
+	assert.Equal(suite.T(), []ConfigChange{
+		{Key: "server.port", Previous: "8080", Current: "9191", Reloadable: false},
+	}, watcher.unreported([]ConfigChange{
+		{Key: "server.port", Previous: "8080", Current: "9191", Reloadable: false},
+	}))
+}
+
+func (suite *ConfigWatcherTestSuite) Test_GIVEN_settingThatRequiresRestartChanged_WHEN_reloading_THEN_changeIsRejected() {
+	// GIVEN
+	watcher := suite.mustWatcher(configFileContents)
+	notified := false
+	watcher.Subscribe(func(p *Config, c *Config) {
+		notified = true
+	})
+	assert.Nil(suite.T(), createTestConfigFile(strings.Replace(configFileContents, "port: 8080", "port: 9090", 1), suite.path))
+
+	// WHEN
+	changes, err := watcher.Reload()
+
+	// THEN
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), []ConfigChange{
+		{Key: "server.port", Previous: "8080", Current: "9090", Reloadable: false},
+	}, changes)
+	assert.Equal(suite.T(), 8080, watcher.Config().Server().Port())
+	assert.False(suite.T(), notified)
+}
+
+func (suite *ConfigWatcherTestSuite) Test_GIVEN_reloadableAndRestartSettingsChanged_WHEN_reloading_THEN_onlyReloadableSettingsAreApplied() {
+	// GIVEN
+	watcher := suite.mustWatcher(configFileContents)
+	assert.Nil(suite.T(), createTestConfigFile(strings.Replace(configFileContents, `password: "password"`, `password: "redrum"`, 1)+`
+queue:
+  maxOrderAge: 1800
+`, suite.path))
+
+	// WHEN
+	changes, err := watcher.Reload()
+
+	// THEN
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), []ConfigChange{
+		{Key: "database.password", Previous: "******", Current: "******", Reloadable: false},
+		{Key: "queue.maxOrderAge", Previous: "0s", Current: "30m0s", Reloadable: true},
+	}, changes)
+	assert.Equal(suite.T(), "password", watcher.Config().Database().Password())
+	assert.Equal(suite.T(), 30*time.Minute, watcher.Config().Queue().MaxOrderAge())
+}
+
+func (suite *ConfigWatcherTestSuite) Test_GIVEN_invalidConfig_WHEN_reloading_THEN_currentConfigIsKept() {
+	// GIVEN
+	watcher := suite.mustWatcher(configFileContents)
+	config := watcher.Config()
+	assert.Nil(suite.T(), createTestConfigFile(configFileContents+`
+preparation:
+  estimator: "guess"
+`, suite.path))
+
+	// WHEN
+	changes, err := watcher.Reload()
+
+	// THEN
+	assert.NotNil(suite.T(), err)
+	assert.Empty(suite.T(), changes)
+	assert.Equal(suite.T(), config, watcher.Config())
+}
+
+func (suite *ConfigWatcherTestSuite) Test_GIVEN_watcherIsStarted_WHEN_configFileChanges_THEN_configIsReloaded() {
+	// GIVEN
+	watcher := suite.mustWatcher(configFileContents + `
+reload:
+  interval: 1
+`)
+	reloaded := make(chan *Config, 1)
+	watcher.Subscribe(func(p *Config, c *Config) {
+		reloaded <- c
+	})
+	assert.Nil(suite.T(), watcher.Start())
+	defer watcher.Close()
+
+	// WHEN
+	assert.Nil(suite.T(), createTestConfigFile(configFileContents+`
+reload:
+  interval: 1
+
+logging:
+  level: "warn"
+`, suite.path))
+
+	// THEN
+	select {
+	case config := <-reloaded:
+		assert.Equal(suite.T(), "warn", config.Logging().Level())
+	case <-time.After(5 * time.Second):
+		suite.T().Fatal("config was not reloaded")
+	}
+}
+
+func (suite *ConfigWatcherTestSuite) Test_GIVEN_reloadIsDisabled_WHEN_watcherIsStarted_THEN_configIsNotWatched() {
+	// GIVEN
+	watcher := suite.mustWatcher(configFileContents + `
+reload:
+  disabled: true
+`)
+
+	// WHEN
+	err := watcher.Start()
+
+	// THEN
+	assert.Nil(suite.T(), err)
+	assert.Nil(suite.T(), watcher.watcher)
+	assert.Nil(suite.T(), watcher.Close())
+}
+
+func (suite *ConfigWatcherTestSuite) Test_GIVEN_configService_WHEN_reloading_THEN_configIsFetchedAgain() {
+	// GIVEN
+	client := &MockClient{Status: 200, Content: configFileContents}
+	config, err := LoadConfigWithClient(uri, client)
+	assert.Nil(suite.T(), err)
+	watcher, err := NewConfigWatcherWithClient(uri, client, config)
+	assert.Nil(suite.T(), err)
+	client.Content = configFileContents + `
+preparation:
+  estimator: "recipe"
+`
+
+	// WHEN
+	changes, err := watcher.Reload()
+
+	// THEN
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), []ConfigChange{
+		{Key: "preparation.estimator", Previous: "linear", Current: "recipe", Reloadable: true},
+	}, changes)
+	assert.Equal(suite.T(), EstimatorRecipe, watcher.Config().Preparation().Estimator())
+}
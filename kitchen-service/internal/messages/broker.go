@@ -1,13 +1,22 @@
 package messages
 
 import (
+	"context"
 	"fmt"
 	"log"
+	"strings"
 
 	"github.com/Shopify/sarama"
 	cfg "github.com/w-k-s/McMicroservices/kitchen-service/internal/config"
+	"github.com/w-k-s/McMicroservices/kitchen-service/pkg/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.10.0"
+	"go.opentelemetry.io/otel/trace"
 )
 
+// messagingKafkaOffsetKey is the offset of a message in its partition. It is not one of the semantic conventions yet.
+const messagingKafkaOffsetKey = attribute.Key("messaging.kafka.message.offset")
+
 type ConsumerFactory func(cfg.BrokerConfig) (sarama.Consumer, error)
 type ProducerFactory func(cfg.BrokerConfig) (sarama.SyncProducer, error)
 
@@ -50,3 +59,75 @@ func saramaOffset(autoOffsetReset cfg.AutoOffsetReset) int64 {
 		panic(fmt.Sprintf("autoOffsetReset %q can not be mapped to a sarama offset", autoOffsetReset))
 	}
 }
+
+// TraceHeaders are the record headers that continue the trace of ctx, if any, in the consumers of a message.
+func TraceHeaders(ctx context.Context) []sarama.RecordHeader {
+	trace, ok := tracing.FromContext(ctx)
+	if !ok {
+		return nil
+	}
+	return []sarama.RecordHeader{
+		{Key: []byte(tracing.HeaderTraceParent), Value: []byte(trace.TraceParent())},
+		{Key: []byte(tracing.HeaderRequestId), Value: []byte(trace.RequestId())},
+	}
+}
+
+// ExtractTrace returns a copy of ctx in which the next span continues the trace in the headers of a message,
+// or starts a new trace if the message does not have one.
+func ExtractTrace(ctx context.Context, message *sarama.ConsumerMessage) context.Context {
+	return tracing.Extract(
+		ctx,
+		HeaderValue(message, tracing.HeaderTraceParent),
+		HeaderValue(message, tracing.HeaderRequestId),
+	)
+}
+
+// StartConsumerSpan starts a span for consuming a message, continuing the trace in the headers of the message.
+func StartConsumerSpan(ctx context.Context, message *sarama.ConsumerMessage) (context.Context, trace.Span) {
+	return tracing.Start(
+		ExtractTrace(ctx, message),
+		message.Topic+" receive",
+		trace.WithSpanKind(trace.SpanKindConsumer),
+		trace.WithAttributes(
+			semconv.MessagingSystemKey.String("kafka"),
+			semconv.MessagingDestinationKey.String(message.Topic),
+			semconv.MessagingDestinationKindTopic,
+			semconv.MessagingOperationReceive,
+			semconv.MessagingKafkaPartitionKey.Int64(int64(message.Partition)),
+			messagingKafkaOffsetKey.Int64(message.Offset),
+		),
+	)
+}
+
+// StartProducerSpan starts a span for publishing a message to a topic.
+// The headers of the message should be the TraceHeaders of the returned context, so that consumers continue the span.
+func StartProducerSpan(ctx context.Context, topic string) (context.Context, trace.Span) {
+	return tracing.Start(
+		ctx,
+		topic+" send",
+		trace.WithSpanKind(trace.SpanKindProducer),
+		trace.WithAttributes(
+			semconv.MessagingSystemKey.String("kafka"),
+			semconv.MessagingDestinationKey.String(topic),
+			semconv.MessagingDestinationKindTopic,
+		),
+	)
+}
+
+// SetPublished records where a message was published on its producer span.
+func SetPublished(span trace.Span, partition int32, offset int64) {
+	span.SetAttributes(
+		semconv.MessagingKafkaPartitionKey.Int64(int64(partition)),
+		messagingKafkaOffsetKey.Int64(offset),
+	)
+}
+
+// HeaderValue is the value of the first header of the message with the key, ignoring case, or empty if there is none.
+func HeaderValue(message *sarama.ConsumerMessage, key string) string {
+	for _, header := range message.Headers {
+		if header != nil && strings.EqualFold(string(header.Key), key) {
+			return string(header.Value)
+		}
+	}
+	return ""
+}
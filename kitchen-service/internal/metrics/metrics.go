@@ -0,0 +1,123 @@
+package metrics
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/Shopify/sarama"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+)
+
+const namespace = "kitchen"
+
+const (
+	OutcomeReady  = "ready"
+	OutcomeFailed = "failed"
+
+	OperationConsume = "consume"
+	OperationProduce = "produce"
+)
+
+var (
+	// OrdersExpired counts the orders that were rejected because they were received too late to be prepared.
+	OrdersExpired = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "orders_expired_total",
+		Help:      "Number of orders that expired before they could be prepared.",
+	}, []string{"location"})
+
+	// Orders counts the orders that were handled by outcome. The reason is empty for orders that are ready.
+	Orders = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "orders_total",
+		Help:      "Number of orders that were ready or failed, by the reason that they failed.",
+	}, []string{"location", "outcome", "reason"})
+
+	// OrderPreparationTime measures how long orders take to prepare once they are scheduled.
+	OrderPreparationTime = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "order_preparation_seconds",
+		Help:      "Time from when an order is scheduled until it is ready.",
+		Buckets:   prometheus.ExponentialBuckets(15, 2, 8),
+	}, []string{"location"})
+
+	// HTTPRequestDuration measures requests by the template of their route, so that requests for different resources are grouped together.
+	HTTPRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "Time taken to serve HTTP requests.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+
+	MessagesConsumed = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "kafka_messages_consumed_total",
+		Help:      "Number of Kafka messages consumed.",
+	}, []string{"topic"})
+
+	MessagesProduced = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "kafka_messages_produced_total",
+		Help:      "Number of Kafka messages published.",
+	}, []string{"topic"})
+
+	// MessagesFailed counts the messages that could not be handled when they were consumed, or could not be published.
+	MessagesFailed = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "kafka_messages_failed_total",
+		Help:      "Number of Kafka messages that failed to be consumed or produced.",
+	}, []string{"topic", "operation"})
+
+	// ConsumerLag is the number of messages in a partition that have not been consumed yet.
+	ConsumerLag = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "kafka_consumer_lag",
+		Help:      "Number of messages in a partition that have not been consumed yet.",
+	}, []string{"topic", "partition"})
+)
+
+// kitchenCollectors are the metrics of the kitchen that are not tied to an application.
+var kitchenCollectors = []prometheus.Collector{
+	OrdersExpired,
+	Orders,
+	OrderPreparationTime,
+	HTTPRequestDuration,
+	MessagesConsumed,
+	MessagesProduced,
+	MessagesFailed,
+	ConsumerLag,
+}
+
+// MessageConsumed counts a message that was consumed from a partition and records how far the consumer is behind the partition.
+func MessageConsumed(pc sarama.PartitionConsumer, message *sarama.ConsumerMessage) {
+	MessagesConsumed.WithLabelValues(message.Topic).Inc()
+
+	// The high water mark is the offset that the next message will be published at.
+	lag := pc.HighWaterMarkOffset() - message.Offset - 1
+	if lag < 0 {
+		lag = 0
+	}
+	ConsumerLag.WithLabelValues(message.Topic, strconv.Itoa(int(message.Partition))).Set(float64(lag))
+}
+
+// NewRegistry returns a registry of the metrics of the kitchen, the Go runtime and the process,
+// and of the collectors that belong to an application, such as the collector of its connection pool.
+// Each application has its own registry, so that the collectors of one application do not replace those of another.
+// A collector that can not be registered, e.g. because it is already registered, returns the error of the registry.
+func NewRegistry(appCollectors ...prometheus.Collector) (*prometheus.Registry, error) {
+	registry := prometheus.NewRegistry()
+
+	all := append([]prometheus.Collector{
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	}, kitchenCollectors...)
+	all = append(all, appCollectors...)
+
+	for _, collector := range all {
+		if err := registry.Register(collector); err != nil {
+			return nil, fmt.Errorf("failed to register metrics collector. Reason: %w", err)
+		}
+	}
+	return registry, nil
+}
@@ -0,0 +1,58 @@
+package metrics
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type RegistryTestSuite struct {
+	suite.Suite
+}
+
+func TestRegistryTestSuite(t *testing.T) {
+	suite.Run(t, new(RegistryTestSuite))
+}
+
+// -- SUITE
+
+func (suite *RegistryTestSuite) Test_GIVEN_registryOfAnApplication_WHEN_anotherRegistryIsCreatedWithTheSameCollector_THEN_bothRegistriesGatherIt() {
+	// GIVEN
+	collector := prometheus.NewGauge(prometheus.GaugeOpts{Namespace: namespace, Name: "test_gauge", Help: "Test gauge."})
+	first, err := NewRegistry(collector)
+	assert.Nil(suite.T(), err)
+
+	// WHEN
+	second, err := NewRegistry(collector)
+
+	// THEN
+	assert.Nil(suite.T(), err)
+	for _, registry := range []*prometheus.Registry{first, second} {
+		families, err := registry.Gather()
+		assert.Nil(suite.T(), err)
+		assert.True(suite.T(), hasFamily(families, "kitchen_test_gauge"))
+	}
+}
+
+func (suite *RegistryTestSuite) Test_GIVEN_collectorOfTheKitchen_WHEN_itIsRegisteredAgain_THEN_alreadyRegisteredErrorIsReturned() {
+	// WHEN
+	registry, err := NewRegistry(Orders)
+
+	// THEN
+	var registered prometheus.AlreadyRegisteredError
+	assert.Nil(suite.T(), registry)
+	assert.True(suite.T(), errors.As(err, &registered))
+}
+
+func hasFamily(families []*dto.MetricFamily, name string) bool {
+	for _, family := range families {
+		if family.GetName() == name {
+			return true
+		}
+	}
+	return false
+}
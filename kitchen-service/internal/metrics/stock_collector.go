@@ -0,0 +1,58 @@
+package metrics
+
+import (
+	"context"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/w-k-s/McMicroservices/kitchen-service/log"
+	db "github.com/w-k-s/McMicroservices/kitchen-service/pkg/persistence"
+)
+
+// stockCollectTimeout bounds how long a scrape waits for the stock levels.
+const stockCollectTimeout = 5 * time.Second
+
+// stockCollector reads the units of every item at every location when the metrics are scraped,
+// so that the gauges are current however the stock was changed.
+type stockCollector struct {
+	stockDao db.StockDao
+	units    *prometheus.Desc
+}
+
+func NewStockCollector(stockDao db.StockDao) prometheus.Collector {
+	return stockCollector{
+		stockDao: stockDao,
+		units: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "", "stock_units"),
+			"Number of units of an item in stock at a location.",
+			[]string{"location", "item"},
+			nil,
+		),
+	}
+}
+
+func (c stockCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.units
+}
+
+func (c stockCollector) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), stockCollectTimeout)
+	defer cancel()
+
+	tx, err := c.stockDao.BeginTx()
+	if err != nil {
+		log.Err(err).Msg("Failed to collect stock levels")
+		return
+	}
+	defer db.DeferRollback(tx, "CollectStockLevels")
+
+	levels, err := tx.GetStockLevels(ctx)
+	if err != nil {
+		log.Err(err).Msg("Failed to collect stock levels")
+		return
+	}
+
+	for _, level := range levels {
+		ch <- prometheus.MustNewConstMetric(c.units, prometheus.GaugeValue, float64(level.Units), string(level.Location), level.ItemName)
+	}
+}
@@ -1,6 +1,7 @@
 package persistence
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"time"
@@ -69,3 +70,37 @@ func PingWithBackOff(db *sql.DB) error {
 	}
 	return nil
 }
+
+// nullTime stores the zero time as NULL.
+func nullTime(t time.Time) sql.NullTime {
+	return sql.NullTime{Time: t, Valid: !t.IsZero()}
+}
+
+// ServerVersion is the version of the database server, e.g. 11.6.
+func ServerVersion(ctx context.Context, db *sql.DB) (string, error) {
+	var version string
+	if err := db.QueryRowContext(ctx, "SHOW server_version").Scan(&version); err != nil {
+		return "", fmt.Errorf("failed to load server version. Reason: %w", err)
+	}
+	return version, nil
+}
+
+// MigrationVersion is the version of the last migration that was applied, and whether that migration failed part way.
+// The migrations table is found the way that RunMigrations finds it, on the search path of the connection.
+func MigrationVersion(ctx context.Context, db *sql.DB) (uint, bool, error) {
+	var (
+		version uint
+		dirty   bool
+	)
+	err := db.QueryRowContext(
+		ctx,
+		"SELECT version, dirty FROM schema_migrations LIMIT 1",
+	).Scan(&version, &dirty)
+	if err == sql.ErrNoRows {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, fmt.Errorf("failed to load migration version. Reason: %w", err)
+	}
+	return version, dirty, nil
+}
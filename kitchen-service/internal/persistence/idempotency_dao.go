@@ -0,0 +1,117 @@
+package persistence
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	k "github.com/w-k-s/McMicroservices/kitchen-service/pkg/kitchen"
+)
+
+// ClaimIdempotencyKey stores the request unless a request with the same key was stored within the idempotency key retention of the transaction.
+// If it was, the stored request is returned and claimed is false.
+// A request that is being claimed by another transaction is waited for, so that a retry sees the response of the first attempt.
+// Keys that are older than the retention are deleted first, so that keys are not kept forever.
+func (tx defaultPurchasingTx) ClaimIdempotencyKey(ctx context.Context, request k.IdempotentRequest) (k.IdempotentRequest, bool, error) {
+	retentionSeconds := int64(tx.idempotencyKeyRetention.Seconds())
+	if _, err := tx.ExecContext(
+		ctx,
+		`DELETE FROM
+			kitchen.idempotency_key
+		WHERE
+			created_at < NOW() - $1::INTEGER * INTERVAL '1 second'`,
+		retentionSeconds,
+	); err != nil {
+		return k.IdempotentRequest{}, false, k.NewSystemError("failed to delete expired idempotency keys", err)
+	}
+
+	var scope string
+	err := tx.QueryRowContext(
+		ctx,
+		`INSERT INTO
+			kitchen.idempotency_key AS i (scope, key, request_hash)
+		VALUES
+			($1, $2, $3)
+		ON CONFLICT
+			ON CONSTRAINT pk_idempotency_key
+		DO UPDATE SET
+			request_hash = EXCLUDED.request_hash,
+			response = NULL,
+			created_at = NOW()
+		WHERE
+			i.created_at < NOW() - $4::INTEGER * INTERVAL '1 second'
+		RETURNING
+			i.scope`,
+		request.Scope(),
+		request.Key(),
+		request.RequestHash(),
+		retentionSeconds,
+	).Scan(&scope)
+
+	if err == nil {
+		return request, true, nil
+	}
+	if err != sql.ErrNoRows {
+		return k.IdempotentRequest{}, false, k.NewSystemError(fmt.Sprintf("failed to claim idempotency key %q", request.Key()), err)
+	}
+
+	var (
+		requestHash string
+		response    []byte
+	)
+	if err = tx.QueryRowContext(
+		ctx,
+		`SELECT
+			i.request_hash,
+			i.response
+		FROM
+			kitchen.idempotency_key i
+		WHERE
+			i.scope = $1
+		AND
+			i.key = $2`,
+		request.Scope(),
+		request.Key(),
+	).Scan(&requestHash, &response); err != nil {
+		return k.IdempotentRequest{}, false, k.NewSystemError(fmt.Sprintf("failed to load idempotency key %q", request.Key()), err)
+	}
+
+	stored, err := k.NewStoredIdempotentRequest(request.Scope(), request.Key(), requestHash, response)
+	if err != nil {
+		return k.IdempotentRequest{}, false, err
+	}
+	return stored, false, nil
+}
+
+// SaveIdempotentResponse stores the response to a request that was claimed in the transaction.
+func (tx defaultPurchasingTx) SaveIdempotentResponse(ctx context.Context, request k.IdempotentRequest) error {
+	res, err := tx.ExecContext(
+		ctx,
+		`UPDATE
+			kitchen.idempotency_key
+		SET
+			response = $3
+		WHERE
+			scope = $1
+		AND
+			key = $2
+		AND
+			request_hash = $4`,
+		request.Scope(),
+		request.Key(),
+		request.Response(),
+		request.RequestHash(),
+	)
+	if err != nil {
+		return k.NewSystemError(fmt.Sprintf("failed to save response to idempotency key %q", request.Key()), err)
+	}
+
+	var rowsAffected int64
+	if rowsAffected, err = res.RowsAffected(); err != nil {
+		return k.NewSystemError("failed to get result of idempotency key update", err)
+	}
+	if rowsAffected == 0 {
+		return k.NewNotFoundError(fmt.Sprintf("idempotency key %q has not been claimed", request.Key()))
+	}
+	return nil
+}
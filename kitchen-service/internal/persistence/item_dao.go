@@ -0,0 +1,271 @@
+package persistence
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+
+	"github.com/lib/pq"
+	k "github.com/w-k-s/McMicroservices/kitchen-service/pkg/kitchen"
+)
+
+// GetItems returns the item that each of the names is the name, an alias or the SKU of, keyed by the k.ItemKey of the name.
+// A name is matched against the names of items first, then their aliases and then their SKUs,
+// so a name that matches the name of one item and the alias or SKU of another is the first item.
+// Names that are not known are left out.
+func (tx defaultStockTx) GetItems(ctx context.Context, names []string) (map[string]k.Item, error) {
+	items := map[string]k.Item{}
+	if len(names) == 0 {
+		return items, nil
+	}
+
+	keys := []string{}
+	for _, name := range names {
+		keys = append(keys, k.ItemKey(name))
+	}
+
+	rows, err := tx.QueryContext(
+		ctx,
+		`WITH matched AS (
+			SELECT
+				n.key, i.id AS item_id, 0 AS rank
+			FROM
+				UNNEST($1::VARCHAR[]) AS n (key)
+			JOIN
+				kitchen.item i ON LOWER(i.name) = n.key
+			UNION ALL
+			SELECT
+				n.key, a.item_id, 1 AS rank
+			FROM
+				UNNEST($1::VARCHAR[]) AS n (key)
+			JOIN
+				kitchen.item_alias a ON LOWER(a.alias) = n.key
+			UNION ALL
+			SELECT
+				n.key, i.id AS item_id, 2 AS rank
+			FROM
+				UNNEST($1::VARCHAR[]) AS n (key)
+			JOIN
+				kitchen.item i ON LOWER(i.sku) = n.key
+		)
+		SELECT DISTINCT ON (m.key)
+			m.key,
+			i.id,
+			COALESCE(i.sku, ''),
+			i.name,
+			ARRAY(SELECT a.alias FROM kitchen.item_alias a WHERE a.item_id = i.id ORDER BY a.alias)
+		FROM
+			matched m
+		JOIN
+			kitchen.item i ON i.id = m.item_id
+		ORDER BY
+			m.key, m.rank`,
+		pq.Array(keys),
+	)
+	if err != nil {
+		return nil, k.NewSystemError("failed to load items", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			key     string
+			id      uint64
+			sku     string
+			name    string
+			aliases []string
+			item    k.Item
+		)
+
+		if err = rows.Scan(&key, &id, &sku, &name, pq.Array(&aliases)); err != nil {
+			log.Printf("Error processing item %q. Reason: %s", key, err)
+			continue
+		}
+
+		if item, err = k.NewItem(id, sku, name, aliases); err != nil {
+			log.Printf("Error creating item %q from database. Reason: %q", name, err)
+			continue
+		}
+
+		items[key] = item
+	}
+
+	return items, nil
+}
+
+// SaveItem creates the item if no item has its name.
+// Otherwise the SKU and aliases of the item with its name are replaced. The canonical name of an item is never changed.
+func (tx defaultStockTx) SaveItem(ctx context.Context, item k.Item) (k.Item, error) {
+	names := append([]string{item.Name()}, item.Aliases()...)
+	known, err := tx.GetItems(ctx, names)
+	if err != nil {
+		return k.Item{}, err
+	}
+
+	var id uint64
+	if existing, ok := known[k.ItemKey(item.Name())]; ok {
+		id = existing.Id()
+		if item, err = k.NewItem(id, item.Sku(), existing.Name(), item.Aliases()); err != nil {
+			return k.Item{}, err
+		}
+	}
+
+	for _, alias := range item.Aliases() {
+		if other, ok := known[k.ItemKey(alias)]; ok && other.Id() != id {
+			return k.Item{}, k.InvalidError{
+				Cause:  fmt.Errorf("Invalid item"),
+				Fields: map[string]string{"aliases": fmt.Sprintf("%q is already a name of %q", alias, other.Name())},
+			}
+		}
+	}
+
+	if len(item.Sku()) > 0 {
+		var other string
+		err = tx.QueryRowContext(
+			ctx,
+			`SELECT
+				i.name
+			FROM
+				kitchen.item i
+			WHERE
+				LOWER(i.sku) = LOWER($1)
+			AND
+				i.id <> $2`,
+			item.Sku(),
+			id,
+		).Scan(&other)
+		if err == nil {
+			return k.Item{}, k.InvalidError{
+				Cause:  fmt.Errorf("Invalid item"),
+				Fields: map[string]string{"sku": fmt.Sprintf("SKU %q is already the SKU of %q", item.Sku(), other)},
+			}
+		}
+		if err != sql.ErrNoRows {
+			return k.Item{}, k.NewSystemError(fmt.Sprintf("failed to check SKU %q", item.Sku()), err)
+		}
+	}
+
+	if id == 0 {
+		err = tx.QueryRowContext(
+			ctx,
+			`INSERT INTO
+				kitchen.item (sku, name)
+			VALUES
+				(NULLIF($1, ''), $2)
+			RETURNING
+				id`,
+			item.Sku(),
+			item.Name(),
+		).Scan(&id)
+	} else {
+		_, err = tx.ExecContext(
+			ctx,
+			`UPDATE
+				kitchen.item
+			SET
+				sku = NULLIF($1, ''),
+				updated_at = NOW()
+			WHERE
+				id = $2`,
+			item.Sku(),
+			id,
+		)
+	}
+	if err != nil {
+		return k.Item{}, k.NewSystemError(fmt.Sprintf("failed to save item %q", item.Name()), err)
+	}
+
+	if _, err = tx.ExecContext(
+		ctx,
+		`DELETE FROM
+			kitchen.item_alias
+		WHERE
+			item_id = $1`,
+		id,
+	); err != nil {
+		return k.Item{}, k.NewSystemError(fmt.Sprintf("failed to clear aliases of %q", item.Name()), err)
+	}
+
+	for _, alias := range item.Aliases() {
+		if _, err = tx.ExecContext(
+			ctx,
+			`INSERT INTO
+				kitchen.item_alias (alias, item_id)
+			VALUES
+				($1, $2)`,
+			alias,
+			id,
+		); err != nil {
+			return k.Item{}, k.NewSystemError(fmt.Sprintf("failed to add alias %q to %q", alias, item.Name()), err)
+		}
+	}
+
+	return k.NewItem(id, item.Sku(), item.Name(), item.Aliases())
+}
+
+// createItems creates an item for each of the names that is not the name or alias of an item yet.
+func (tx defaultStockTx) createItems(ctx context.Context, names []string) error {
+	normalized := []string{}
+	for _, name := range names {
+		normalized = append(normalized, k.NormalizeItemName(name))
+	}
+
+	if _, err := tx.ExecContext(
+		ctx,
+		`INSERT INTO
+			kitchen.item (name)
+		SELECT DISTINCT ON (LOWER(n.name))
+			n.name
+		FROM
+			UNNEST($1::VARCHAR[]) AS n (name)
+		WHERE NOT EXISTS (
+			SELECT 1 FROM kitchen.item_alias a WHERE LOWER(a.alias) = LOWER(n.name)
+		)
+		ORDER BY
+			LOWER(n.name), n.name
+		ON CONFLICT ((LOWER(name))) DO NOTHING`,
+		pq.Array(normalized),
+	); err != nil {
+		return k.NewSystemError("failed to create items", err)
+	}
+	return nil
+}
+
+// identify matches each item of stock to the item that its name is a name or alias of, so that it is known by its canonical name.
+// If create is true, items are created for stock that does not match an item. Otherwise, the stock keeps its name.
+func (tx defaultStockTx) identify(ctx context.Context, stock k.Stock, create bool) (k.Stock, error) {
+	names := []string{}
+	for _, item := range stock {
+		names = append(names, item.Name())
+	}
+
+	if create {
+		if err := tx.createItems(ctx, names); err != nil {
+			return nil, err
+		}
+	}
+
+	items, err := tx.GetItems(ctx, names)
+	if err != nil {
+		return nil, err
+	}
+
+	identified := k.Stock{}
+	for _, stockItem := range stock {
+		if item, ok := items[k.ItemKey(stockItem.Name())]; ok {
+			stockItem = stockItem.OfItem(item)
+		}
+		identified = append(identified, stockItem)
+	}
+	return identified, nil
+}
+
+// canonicalNames returns the canonical name of each of the names. Names that are not known are returned as they are.
+func (tx defaultStockTx) canonicalNames(ctx context.Context, names ...string) ([]string, error) {
+	items, err := tx.GetItems(ctx, names)
+	if err != nil {
+		return nil, err
+	}
+	return k.CanonicalNames(names, items), nil
+}
@@ -0,0 +1,334 @@
+package persistence
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"time"
+
+	k "github.com/w-k-s/McMicroservices/kitchen-service/pkg/kitchen"
+	dao "github.com/w-k-s/McMicroservices/kitchen-service/pkg/persistence"
+)
+
+type defaultOrderDao struct {
+	*RootDao
+}
+
+func MustOpenOrderDao(pool *sql.DB) dao.OrderDao {
+	if pool == nil {
+		log.Fatalf("database is null")
+	}
+	return &defaultOrderDao{&RootDao{pool}}
+}
+
+func (o *defaultOrderDao) BeginTx() (dao.OrderTx, error) {
+	return OrderTx(o.pool.Begin())
+}
+
+func OrderTx(tx *sql.Tx, err error) (dao.OrderTx, error) {
+	if err != nil {
+		return nil, k.NewSystemError("failed to begin transaction", err)
+	}
+	return defaultOrderTx{defaultStockTx{tracedTx{tx}, DefaultBulkIncreaseThreshold}}, nil
+}
+
+type defaultOrderTx struct {
+	defaultStockTx
+}
+
+func (tx defaultOrderTx) SaveOrder(ctx context.Context, order k.Order) error {
+	_, err := tx.ExecContext(
+		ctx,
+		`INSERT INTO
+			kitchen.kitchen_order (id, status, failure_reason, location, estimator, priority, deadline)
+		VALUES
+			($1, $2, NULLIF($3, ''), $4, NULLIF($5, ''), $6, $7)`,
+		order.Id(),
+		order.Status(),
+		order.FailureReason(),
+		order.Location(),
+		order.Estimator(),
+		order.Priority(),
+		nullTime(order.Deadline()),
+	)
+	if err != nil {
+		return k.NewSystemError(fmt.Sprintf("failed to save order %d", order.Id()), err)
+	}
+
+	for _, item := range order.Items() {
+		_, err = tx.ExecContext(
+			ctx,
+			`INSERT INTO
+				kitchen.kitchen_order_item (order_id, item_name, units, unit_cost)
+			SELECT
+				$1, $2, $3, COALESCE(MAX(s.unit_cost), 0)
+			FROM
+				kitchen.stock s
+			WHERE
+				LOWER(s.item_name) = LOWER($2)
+			AND
+				s.location = $4`,
+			order.Id(),
+			item.Name(),
+			item.Units(),
+			order.Location(),
+		)
+		if err != nil {
+			return k.NewSystemError(fmt.Sprintf("failed to save item %q of order %d", item.Name(), order.Id()), err)
+		}
+	}
+
+	for _, substitution := range order.Substitutions() {
+		_, err = tx.ExecContext(
+			ctx,
+			`INSERT INTO
+				kitchen.kitchen_order_substitution (order_id, item_name, substitute_name, units)
+			VALUES
+				($1, $2, $3, $4)`,
+			order.Id(),
+			substitution.Original,
+			substitution.Substitute,
+			substitution.Units,
+		)
+		if err != nil {
+			return k.NewSystemError(fmt.Sprintf("failed to save substitution of %q with %q for order %d", substitution.Original, substitution.Substitute, order.Id()), err)
+		}
+	}
+
+	return tx.saveOrderTasks(ctx, order)
+}
+
+func (tx defaultOrderTx) UpdateOrderStatus(ctx context.Context, id uint64, status k.OrderStatus, failureReason string) error {
+	var (
+		res          sql.Result
+		rowsAffected int64
+		err          error
+	)
+
+	res, err = tx.ExecContext(
+		ctx,
+		`UPDATE
+			kitchen.kitchen_order
+		SET
+			status = $2,
+			failure_reason = NULLIF($3, ''),
+			updated_at = NOW()
+		WHERE
+			id = $1`,
+		id,
+		status,
+		failureReason,
+	)
+	if err != nil {
+		return k.NewSystemError(fmt.Sprintf("failed to update status of order %d", id), err)
+	}
+	if rowsAffected, err = res.RowsAffected(); err != nil {
+		return k.NewSystemError("failed to get result of order update", err)
+	}
+	if rowsAffected == 0 {
+		return k.NewNotFoundError(fmt.Sprintf("order %d does not exist", id))
+	}
+	return nil
+}
+
+func (tx defaultOrderTx) GetOrder(ctx context.Context, id uint64) (k.Order, error) {
+	var (
+		status        string
+		failureReason string
+		location      k.Location
+		estimator     string
+		priority      k.OrderPriority
+		deadline      sql.NullTime
+		createdAt     time.Time
+	)
+
+	err := tx.QueryRowContext(
+		ctx,
+		`SELECT
+			o.status,
+			COALESCE(o.failure_reason, ''),
+			o.location,
+			COALESCE(o.estimator, ''),
+			o.priority,
+			o.deadline,
+			o.created_at
+		FROM
+			kitchen.kitchen_order o
+		WHERE
+			o.id = $1`,
+		id,
+	).Scan(&status, &failureReason, &location, &estimator, &priority, &deadline, &createdAt)
+
+	if err == sql.ErrNoRows {
+		return k.Order{}, k.NewNotFoundError(fmt.Sprintf("order %d does not exist", id))
+	}
+	if err != nil {
+		return k.Order{}, k.NewSystemError(fmt.Sprintf("failed to load order %d", id), err)
+	}
+
+	items, err := tx.getOrderItems(ctx, id)
+	if err != nil {
+		return k.Order{}, err
+	}
+
+	substitutions, err := tx.getOrderSubstitutions(ctx, id)
+	if err != nil {
+		return k.Order{}, err
+	}
+
+	tasks, err := tx.getOrderTasks(ctx, id)
+	if err != nil {
+		return k.Order{}, err
+	}
+
+	order, err := k.NewOrder(id, k.OrderStatus(status), failureReason, items, createdAt)
+	if err != nil {
+		return k.Order{}, err
+	}
+
+	return order.WithSubstitutions(substitutions).WithTasks(tasks).EstimatedBy(estimator).WithDeadline(priority, deadline.Time).AtLocation(location), nil
+}
+
+func (tx defaultOrderTx) getOrderItems(ctx context.Context, orderId uint64) ([]k.OrderItem, error) {
+	rows, err := tx.QueryContext(
+		ctx,
+		`SELECT
+			i.item_name,
+			i.units,
+			i.unit_cost
+		FROM
+			kitchen.kitchen_order_item i
+		WHERE
+			i.order_id = $1
+		ORDER BY
+			i.item_name`,
+		orderId,
+	)
+	if err != nil {
+		return nil, k.NewSystemError(fmt.Sprintf("failed to load items of order %d", orderId), err)
+	}
+	defer rows.Close()
+
+	items := make([]k.OrderItem, 0)
+	for rows.Next() {
+		var (
+			name     string
+			units    uint
+			unitCost k.Money
+		)
+
+		if err = rows.Scan(&name, &units, &unitCost); err != nil {
+			log.Printf("Error processing item %q of order %d. Reason: %s", name, orderId, err)
+			continue
+		}
+
+		items = append(items, k.NewOrderItem(name, units, unitCost))
+	}
+
+	return items, nil
+}
+
+func (tx defaultOrderTx) getOrderSubstitutions(ctx context.Context, orderId uint64) (k.Substitutions, error) {
+	rows, err := tx.QueryContext(
+		ctx,
+		`SELECT
+			s.item_name,
+			s.substitute_name,
+			s.units
+		FROM
+			kitchen.kitchen_order_substitution s
+		WHERE
+			s.order_id = $1
+		ORDER BY
+			s.item_name, s.substitute_name`,
+		orderId,
+	)
+	if err != nil {
+		return nil, k.NewSystemError(fmt.Sprintf("failed to load substitutions of order %d", orderId), err)
+	}
+	defer rows.Close()
+
+	substitutions := make(k.Substitutions, 0)
+	for rows.Next() {
+		var substitution k.Substitution
+		if err = rows.Scan(&substitution.Original, &substitution.Substitute, &substitution.Units); err != nil {
+			log.Printf("Error processing substitution of %q for order %d. Reason: %s", substitution.Original, orderId, err)
+			continue
+		}
+		substitutions = append(substitutions, substitution)
+	}
+
+	return substitutions, nil
+}
+
+func (tx defaultOrderTx) GetCostOfGoodsReport(ctx context.Context, day time.Time) (k.CostOfGoodsReport, error) {
+	var (
+		from   = time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, time.UTC)
+		to     = from.AddDate(0, 0, 1)
+		orders uint
+		err    error
+	)
+
+	if err = tx.QueryRowContext(
+		ctx,
+		`SELECT
+			COUNT(*)
+		FROM
+			kitchen.kitchen_order o
+		WHERE
+			o.status <> 'FAILED'
+		AND
+			o.created_at >= $1
+		AND
+			o.created_at < $2`,
+		from,
+		to,
+	).Scan(&orders); err != nil {
+		return k.CostOfGoodsReport{}, k.NewSystemError("failed to count orders for cost of goods report", err)
+	}
+
+	rows, err := tx.QueryContext(
+		ctx,
+		`SELECT
+			i.item_name,
+			SUM(i.units),
+			SUM(i.units * i.unit_cost)
+		FROM
+			kitchen.kitchen_order_item i
+		JOIN
+			kitchen.kitchen_order o ON o.id = i.order_id
+		WHERE
+			o.status <> 'FAILED'
+		AND
+			o.created_at >= $1
+		AND
+			o.created_at < $2
+		GROUP BY
+			i.item_name
+		ORDER BY
+			i.item_name`,
+		from,
+		to,
+	)
+	if err != nil {
+		return k.CostOfGoodsReport{}, k.NewSystemError("failed to load cost of goods report", err)
+	}
+	defer rows.Close()
+
+	lines := make([]k.CostOfGoodsLine, 0)
+	for rows.Next() {
+		var line k.CostOfGoodsLine
+		if err = rows.Scan(&line.Name, &line.Units, &line.CostOfGoods); err != nil {
+			log.Printf("Error processing cost of goods of %q. Reason: %s", line.Name, err)
+			continue
+		}
+		lines = append(lines, line)
+	}
+
+	return k.CostOfGoodsReport{
+		Date:   from,
+		Orders: orders,
+		Lines:  lines,
+	}, nil
+}
@@ -0,0 +1,413 @@
+package persistence
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"time"
+
+	k "github.com/w-k-s/McMicroservices/kitchen-service/pkg/kitchen"
+	dao "github.com/w-k-s/McMicroservices/kitchen-service/pkg/persistence"
+)
+
+type defaultPurchasingDao struct {
+	*RootDao
+	bulkIncreaseThreshold   *BulkIncreaseThreshold
+	idempotencyKeyRetention *IdempotencyKeyRetention
+}
+
+// MustOpenPurchasingDao returns a dao whose transactions copy deliveries of more than bulkIncreaseThreshold items into stock in bulk
+// and keep idempotency keys for idempotencyKeyRetention.
+func MustOpenPurchasingDao(pool *sql.DB, bulkIncreaseThreshold *BulkIncreaseThreshold, idempotencyKeyRetention *IdempotencyKeyRetention) dao.PurchasingDao {
+	if pool == nil {
+		log.Fatalf("database is null")
+	}
+	return &defaultPurchasingDao{&RootDao{pool}, bulkIncreaseThreshold, idempotencyKeyRetention}
+}
+
+func (p *defaultPurchasingDao) BeginTx() (dao.PurchasingTx, error) {
+	tx, err := p.pool.Begin()
+	return purchasingTx(tx, err, p.bulkIncreaseThreshold.Get(), p.idempotencyKeyRetention.Get())
+}
+
+func PurchasingTx(tx *sql.Tx, err error) (dao.PurchasingTx, error) {
+	return purchasingTx(tx, err, DefaultBulkIncreaseThreshold, DefaultIdempotencyKeyRetention)
+}
+
+func purchasingTx(tx *sql.Tx, err error, bulkIncreaseThreshold int, idempotencyKeyRetention time.Duration) (dao.PurchasingTx, error) {
+	if err != nil {
+		return nil, k.NewSystemError("failed to begin transaction", err)
+	}
+	return defaultPurchasingTx{defaultStockTx{tracedTx{tx}, bulkIncreaseThreshold}, idempotencyKeyRetention}, nil
+}
+
+type defaultPurchasingTx struct {
+	defaultStockTx
+	idempotencyKeyRetention time.Duration
+}
+
+// SaveSupplier adds a supplier, or returns a k.ConflictError if there is already a supplier with its name.
+func (tx defaultPurchasingTx) SaveSupplier(ctx context.Context, supplier k.Supplier) (k.Supplier, error) {
+	var id uint64
+
+	err := tx.QueryRowContext(
+		ctx,
+		`INSERT INTO
+			kitchen.supplier (name, email)
+		VALUES
+			($1, NULLIF($2, ''))
+		ON CONFLICT
+			ON CONSTRAINT uq_supplier_name
+		DO NOTHING
+		RETURNING id`,
+		supplier.Name(),
+		supplier.Email(),
+	).Scan(&id)
+
+	if err == sql.ErrNoRows {
+		return k.Supplier{}, k.NewConflictError(fmt.Sprintf("supplier %q already exists", supplier.Name()))
+	}
+	if err != nil {
+		return k.Supplier{}, k.NewSystemError(fmt.Sprintf("failed to save supplier %q", supplier.Name()), err)
+	}
+
+	return k.NewSupplier(id, supplier.Name(), supplier.Email())
+}
+
+func (tx defaultPurchasingTx) GetSupplier(ctx context.Context, id uint64) (k.Supplier, error) {
+	var (
+		name  string
+		email string
+	)
+
+	err := tx.QueryRowContext(
+		ctx,
+		`SELECT
+			s.name,
+			COALESCE(s.email, '')
+		FROM
+			kitchen.supplier s
+		WHERE
+			s.id = $1`,
+		id,
+	).Scan(&name, &email)
+
+	if err == sql.ErrNoRows {
+		return k.Supplier{}, k.NewNotFoundError(fmt.Sprintf("supplier %d does not exist", id))
+	}
+	if err != nil {
+		return k.Supplier{}, k.NewSystemError(fmt.Sprintf("failed to load supplier %d", id), err)
+	}
+
+	return k.NewSupplier(id, name, email)
+}
+
+func (tx defaultPurchasingTx) GetSuppliers(ctx context.Context) ([]k.Supplier, error) {
+	rows, err := tx.QueryContext(
+		ctx,
+		`SELECT
+			s.id,
+			s.name,
+			COALESCE(s.email, '')
+		FROM
+			kitchen.supplier s
+		ORDER BY
+			s.name`,
+	)
+	if err != nil {
+		return nil, k.NewSystemError("failed to load suppliers", err)
+	}
+	defer rows.Close()
+
+	suppliers := make([]k.Supplier, 0)
+	for rows.Next() {
+		var (
+			id    uint64
+			name  string
+			email string
+		)
+
+		if err = rows.Scan(&id, &name, &email); err != nil {
+			log.Printf("Error processing supplier %d. Reason: %s", id, err)
+			continue
+		}
+
+		var supplier k.Supplier
+		if supplier, err = k.NewSupplier(id, name, email); err != nil {
+			log.Printf("Error creating supplier with id: %d, name: %q from database. Reason: %q", id, name, err)
+			continue
+		}
+
+		suppliers = append(suppliers, supplier)
+	}
+
+	return suppliers, nil
+}
+
+func (tx defaultPurchasingTx) SavePurchaseOrder(ctx context.Context, po k.PurchaseOrder) (k.PurchaseOrder, error) {
+	var id uint64
+
+	err := tx.QueryRowContext(
+		ctx,
+		`INSERT INTO
+			kitchen.purchase_order (supplier_id, status, expected_delivery_date)
+		VALUES
+			($1, $2, $3)
+		RETURNING id`,
+		po.SupplierId(),
+		po.Status(),
+		po.ExpectedDeliveryDate(),
+	).Scan(&id)
+
+	if err != nil {
+		return k.PurchaseOrder{}, k.NewSystemError(fmt.Sprintf("failed to save purchase order for supplier %d", po.SupplierId()), err)
+	}
+
+	if err = tx.saveLines(ctx, id, po.Lines()); err != nil {
+		return k.PurchaseOrder{}, err
+	}
+
+	return k.NewPurchaseOrder(id, po.SupplierId(), po.Status(), po.ExpectedDeliveryDate(), po.Lines())
+}
+
+func (tx defaultPurchasingTx) UpdatePurchaseOrder(ctx context.Context, po k.PurchaseOrder) error {
+	var (
+		res          sql.Result
+		rowsAffected int64
+		err          error
+	)
+
+	res, err = tx.ExecContext(
+		ctx,
+		`UPDATE
+			kitchen.purchase_order
+		SET
+			status = $2,
+			received_at = CASE WHEN $2 = 'RECEIVED' THEN NOW() ELSE received_at END
+		WHERE
+			id = $1
+		AND
+			status = 'OPEN'`,
+		po.Id(),
+		po.Status(),
+	)
+	if err != nil {
+		return k.NewSystemError(fmt.Sprintf("failed to update purchase order %d", po.Id()), err)
+	}
+	if rowsAffected, err = res.RowsAffected(); err != nil {
+		return k.NewSystemError("failed to get result of purchase order update", err)
+	}
+	if rowsAffected == 0 {
+		return k.NewConflictError(fmt.Sprintf("purchase order %d does not exist or is no longer open", po.Id()))
+	}
+
+	return tx.saveLines(ctx, po.Id(), po.Lines())
+}
+
+func (tx defaultPurchasingTx) saveLines(ctx context.Context, purchaseOrderId uint64, lines []k.PurchaseOrderLine) error {
+	for _, line := range lines {
+		_, err := tx.ExecContext(
+			ctx,
+			`INSERT INTO
+				kitchen.purchase_order_line (purchase_order_id, item_name, ordered_units, received_units)
+			VALUES
+				($1, $2, $3, $4)
+			ON CONFLICT
+				ON CONSTRAINT pk_purchase_order_line
+			DO UPDATE SET
+				ordered_units = EXCLUDED.ordered_units,
+				received_units = EXCLUDED.received_units`,
+			purchaseOrderId,
+			line.ItemName(),
+			line.OrderedUnits(),
+			line.ReceivedUnits(),
+		)
+		if err != nil {
+			return k.NewSystemError(fmt.Sprintf("failed to save line %q of purchase order %d", line.ItemName(), purchaseOrderId), err)
+		}
+	}
+	return nil
+}
+
+func (tx defaultPurchasingTx) GetPurchaseOrder(ctx context.Context, id uint64) (k.PurchaseOrder, error) {
+	orders, err := tx.queryPurchaseOrders(
+		ctx,
+		`SELECT
+			po.id,
+			po.supplier_id,
+			po.status,
+			po.expected_delivery_date
+		FROM
+			kitchen.purchase_order po
+		WHERE
+			po.id = $1`,
+		id,
+	)
+	if err != nil {
+		return k.PurchaseOrder{}, err
+	}
+	if len(orders) == 0 {
+		return k.PurchaseOrder{}, k.NewNotFoundError(fmt.Sprintf("purchase order %d does not exist", id))
+	}
+	return orders[0], nil
+}
+
+func (tx defaultPurchasingTx) GetPurchaseOrders(ctx context.Context, status k.PurchaseOrderStatus) ([]k.PurchaseOrder, error) {
+	return tx.queryPurchaseOrders(
+		ctx,
+		`SELECT
+			po.id,
+			po.supplier_id,
+			po.status,
+			po.expected_delivery_date
+		FROM
+			kitchen.purchase_order po
+		WHERE
+			($1 = '' OR po.status = $1)
+		ORDER BY
+			po.expected_delivery_date, po.id`,
+		status,
+	)
+}
+
+func (tx defaultPurchasingTx) GetOldestOpenPurchaseOrder(ctx context.Context, supplierId uint64) (k.PurchaseOrder, error) {
+	orders, err := tx.queryPurchaseOrders(
+		ctx,
+		`SELECT
+			po.id,
+			po.supplier_id,
+			po.status,
+			po.expected_delivery_date
+		FROM
+			kitchen.purchase_order po
+		WHERE
+			po.supplier_id = $1
+		AND
+			po.status = 'OPEN'
+		ORDER BY
+			po.expected_delivery_date, po.id
+		LIMIT 1
+		FOR UPDATE`,
+		supplierId,
+	)
+	if err != nil {
+		return k.PurchaseOrder{}, err
+	}
+	if len(orders) == 0 {
+		return k.PurchaseOrder{}, k.NewNotFoundError(fmt.Sprintf("supplier %d has no open purchase orders", supplierId))
+	}
+	return orders[0], nil
+}
+
+func (tx defaultPurchasingTx) queryPurchaseOrders(ctx context.Context, query string, args ...interface{}) ([]k.PurchaseOrder, error) {
+	type purchaseOrderHeader struct {
+		id                   uint64
+		supplierId           uint64
+		status               string
+		expectedDeliveryDate time.Time
+	}
+
+	rows, err := tx.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, k.NewSystemError("failed to load purchase orders", err)
+	}
+
+	headers := []purchaseOrderHeader{}
+	for rows.Next() {
+		var header purchaseOrderHeader
+		if err = rows.Scan(&header.id, &header.supplierId, &header.status, &header.expectedDeliveryDate); err != nil {
+			log.Printf("Error processing purchase order %d. Reason: %s", header.id, err)
+			continue
+		}
+		headers = append(headers, header)
+	}
+	rows.Close()
+
+	// Lines are loaded after the headers have been read because
+	// a transaction can only have one active result set at a time.
+	orders := make([]k.PurchaseOrder, 0, len(headers))
+	for _, header := range headers {
+		var (
+			lines []k.PurchaseOrderLine
+			order k.PurchaseOrder
+		)
+		if lines, err = tx.getLines(ctx, header.id); err != nil {
+			return nil, err
+		}
+		if order, err = k.NewPurchaseOrder(
+			header.id,
+			header.supplierId,
+			k.PurchaseOrderStatus(header.status),
+			header.expectedDeliveryDate,
+			lines,
+		); err != nil {
+			log.Printf("Error creating purchase order with id: %d from database. Reason: %q", header.id, err)
+			continue
+		}
+		orders = append(orders, order)
+	}
+
+	return orders, nil
+}
+
+func (tx defaultPurchasingTx) getLines(ctx context.Context, purchaseOrderId uint64) ([]k.PurchaseOrderLine, error) {
+	rows, err := tx.QueryContext(
+		ctx,
+		`SELECT
+			l.item_name,
+			l.ordered_units,
+			l.received_units
+		FROM
+			kitchen.purchase_order_line l
+		WHERE
+			l.purchase_order_id = $1
+		ORDER BY
+			l.ordered_units = 0, l.item_name`,
+		purchaseOrderId,
+	)
+	if err != nil {
+		return nil, k.NewSystemError(fmt.Sprintf("failed to load lines of purchase order %d", purchaseOrderId), err)
+	}
+	defer rows.Close()
+
+	lines := make([]k.PurchaseOrderLine, 0)
+	for rows.Next() {
+		var (
+			itemName      string
+			orderedUnits  uint
+			receivedUnits uint
+		)
+
+		if err = rows.Scan(&itemName, &orderedUnits, &receivedUnits); err != nil {
+			log.Printf("Error processing line %q of purchase order %d. Reason: %s", itemName, purchaseOrderId, err)
+			continue
+		}
+
+		var line k.PurchaseOrderLine
+		if line, err = k.NewPurchaseOrderLine(itemName, orderedUnits, receivedUnits); err != nil {
+			log.Printf("Error creating line %q of purchase order %d from database. Reason: %q", itemName, purchaseOrderId, err)
+			continue
+		}
+
+		lines = append(lines, line)
+	}
+
+	return lines, nil
+}
+
+func (tx defaultPurchasingTx) SaveFailedDelivery(ctx context.Context, delivery []byte, reason string) error {
+	_, err := tx.ExecContext(
+		ctx,
+		`INSERT INTO
+			kitchen.failed_delivery (delivery, reason)
+		VALUES
+			($1, $2)`,
+		string(delivery),
+		reason,
+	)
+	if err != nil {
+		return k.NewSystemError("failed to save failed delivery", err)
+	}
+	return nil
+}
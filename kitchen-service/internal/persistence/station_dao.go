@@ -0,0 +1,351 @@
+package persistence
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/lib/pq"
+	k "github.com/w-k-s/McMicroservices/kitchen-service/pkg/kitchen"
+)
+
+func (tx defaultOrderTx) GetStations(ctx context.Context) ([]k.KitchenStation, error) {
+	rows, err := tx.QueryContext(
+		ctx,
+		`SELECT
+			s.name,
+			s.capacity
+		FROM
+			kitchen.station s
+		ORDER BY
+			s.name`,
+	)
+	if err != nil {
+		return nil, k.NewSystemError("failed to load stations", err)
+	}
+	defer rows.Close()
+
+	stations := make([]k.KitchenStation, 0)
+	for rows.Next() {
+		var (
+			name     k.Station
+			capacity uint
+			station  k.KitchenStation
+		)
+
+		if err = rows.Scan(&name, &capacity); err != nil {
+			log.Printf("Error processing station %q. Reason: %s", name, err)
+			continue
+		}
+
+		if station, err = k.NewKitchenStation(name, capacity); err != nil {
+			log.Printf("Error processing station %q. Reason: %s", name, err)
+			continue
+		}
+
+		stations = append(stations, station)
+	}
+
+	return stations, nil
+}
+
+func (tx defaultOrderTx) SaveStation(ctx context.Context, station k.KitchenStation) error {
+	_, err := tx.ExecContext(
+		ctx,
+		`INSERT INTO
+			kitchen.station (name, capacity)
+		VALUES
+			($1, $2)
+		ON CONFLICT (name) DO UPDATE SET
+			capacity = EXCLUDED.capacity,
+			updated_at = NOW()`,
+		station.Name(),
+		station.Capacity(),
+	)
+	if err != nil {
+		return k.NewSystemError(fmt.Sprintf("failed to save station %q", station.Name()), err)
+	}
+	return nil
+}
+
+func (tx defaultOrderTx) GetToppingTasks(ctx context.Context) ([]k.ToppingTask, error) {
+	rows, err := tx.QueryContext(
+		ctx,
+		`SELECT
+			t.item_name,
+			t.station,
+			t.duration_seconds
+		FROM
+			kitchen.topping_task t
+		ORDER BY
+			t.item_name`,
+	)
+	if err != nil {
+		return nil, k.NewSystemError("failed to load topping tasks", err)
+	}
+	defer rows.Close()
+
+	tasks := make([]k.ToppingTask, 0)
+	for rows.Next() {
+		var (
+			itemName        string
+			station         k.Station
+			durationSeconds int64
+			task            k.ToppingTask
+		)
+
+		if err = rows.Scan(&itemName, &station, &durationSeconds); err != nil {
+			log.Printf("Error processing topping task %q. Reason: %s", itemName, err)
+			continue
+		}
+
+		if task, err = k.NewToppingTask(itemName, station, time.Duration(durationSeconds)*time.Second); err != nil {
+			log.Printf("Error processing topping task %q. Reason: %s", itemName, err)
+			continue
+		}
+
+		tasks = append(tasks, task)
+	}
+
+	return tasks, nil
+}
+
+func (tx defaultOrderTx) SaveToppingTask(ctx context.Context, task k.ToppingTask) error {
+	if err := tx.createItems(ctx, []string{task.ItemName()}); err != nil {
+		return err
+	}
+	canonical, err := tx.canonicalNames(ctx, task.ItemName())
+	if err != nil {
+		return err
+	}
+	if task, err = k.NewToppingTask(canonical[0], task.Station(), task.Duration()); err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(
+		ctx,
+		`INSERT INTO
+			kitchen.topping_task (item_name, station, duration_seconds)
+		VALUES
+			($1, $2, $3)
+		ON CONFLICT (item_name) DO UPDATE SET
+			station = EXCLUDED.station,
+			duration_seconds = EXCLUDED.duration_seconds,
+			updated_at = NOW()`,
+		task.ItemName(),
+		task.Station(),
+		int64(task.Duration()/time.Second),
+	)
+	if err != nil {
+		return k.NewSystemError(fmt.Sprintf("failed to save topping task %q", task.ItemName()), err)
+	}
+	return nil
+}
+
+func (tx defaultOrderTx) DeleteToppingTask(ctx context.Context, itemName string) error {
+	var (
+		res          sql.Result
+		rowsAffected int64
+		canonical    []string
+		err          error
+	)
+
+	if canonical, err = tx.canonicalNames(ctx, itemName); err != nil {
+		return err
+	}
+	itemName = canonical[0]
+
+	if res, err = tx.ExecContext(
+		ctx,
+		`DELETE FROM
+			kitchen.topping_task
+		WHERE
+			item_name = $1`,
+		itemName,
+	); err != nil {
+		return k.NewSystemError(fmt.Sprintf("failed to delete topping task %q", itemName), err)
+	}
+	if rowsAffected, err = res.RowsAffected(); err != nil {
+		return k.NewSystemError("failed to get result of topping task delete", err)
+	}
+	if rowsAffected == 0 {
+		return k.NewNotFoundError(fmt.Sprintf("topping %q is not assigned to a station", itemName))
+	}
+	return nil
+}
+
+func (tx defaultOrderTx) GetStationLoad(ctx context.Context, station k.KitchenStation, location k.Location, now time.Time) (k.StationLoad, error) {
+	if _, err := tx.ExecContext(
+		ctx,
+		`SELECT pg_advisory_xact_lock(hashtext($1 || '/' || $2))`,
+		string(location),
+		string(station.Name()),
+	); err != nil {
+		return k.StationLoad{}, k.NewSystemError(fmt.Sprintf("failed to lock station %q at %q", station.Name(), location), err)
+	}
+
+	rows, err := tx.QueryContext(
+		ctx,
+		`SELECT
+			t.slot,
+			MAX(t.ends_at)
+		FROM
+			kitchen.station_task t
+		WHERE
+			t.location = $1
+		AND
+			t.station = $2
+		AND
+			t.ends_at > $3
+		GROUP BY
+			t.slot`,
+		location,
+		station.Name(),
+		now,
+	)
+	if err != nil {
+		return k.StationLoad{}, k.NewSystemError(fmt.Sprintf("failed to load tasks of station %q at %q", station.Name(), location), err)
+	}
+	defer rows.Close()
+
+	busyUntil := map[uint]time.Time{}
+	for rows.Next() {
+		var (
+			slot  uint
+			until time.Time
+		)
+		if err = rows.Scan(&slot, &until); err != nil {
+			log.Printf("Error processing load of station %q. Reason: %s", station.Name(), err)
+			continue
+		}
+		busyUntil[slot] = until
+	}
+
+	return k.NewStationLoad(station, busyUntil, now), nil
+}
+
+func (tx defaultOrderTx) saveOrderTasks(ctx context.Context, order k.Order) error {
+	for _, task := range order.Tasks() {
+		_, err := tx.ExecContext(
+			ctx,
+			`INSERT INTO
+				kitchen.station_task (order_id, item_name, station, location, slot, starts_at, ends_at)
+			VALUES
+				($1, $2, $3, $4, $5, $6, $7)`,
+			order.Id(),
+			task.ItemName,
+			task.Station,
+			order.Location(),
+			task.Slot,
+			task.StartsAt,
+			task.EndsAt,
+		)
+		if err != nil {
+			return k.NewSystemError(fmt.Sprintf("failed to save %q task of order %d", task.ItemName, order.Id()), err)
+		}
+	}
+	return nil
+}
+
+func (tx defaultOrderTx) getOrderTasks(ctx context.Context, orderId uint64) (k.Tasks, error) {
+	rows, err := tx.QueryContext(
+		ctx,
+		`SELECT
+			t.item_name,
+			t.station,
+			t.slot,
+			t.starts_at,
+			t.ends_at
+		FROM
+			kitchen.station_task t
+		WHERE
+			t.order_id = $1
+		ORDER BY
+			t.id`,
+		orderId,
+	)
+	if err != nil {
+		return nil, k.NewSystemError(fmt.Sprintf("failed to load tasks of order %d", orderId), err)
+	}
+	defer rows.Close()
+
+	tasks := make(k.Tasks, 0)
+	for rows.Next() {
+		task := k.Task{OrderId: orderId}
+		if err = rows.Scan(&task.ItemName, &task.Station, &task.Slot, &task.StartsAt, &task.EndsAt); err != nil {
+			log.Printf("Error processing task %q of order %d. Reason: %s", task.ItemName, orderId, err)
+			continue
+		}
+		tasks = append(tasks, task)
+	}
+
+	return tasks, nil
+}
+
+// GetPreparationSamples shares the time that a ticket took, from when it was started until a cook bumped it,
+// equally between the items on the ticket. Bumps that the kitchen made itself are left out, because they happen when the ticket was estimated to be ready.
+func (tx defaultOrderTx) GetPreparationSamples(ctx context.Context, names []string, since time.Time) (map[string]k.PreparationSample, error) {
+	rows, err := tx.QueryContext(
+		ctx,
+		`WITH ticket AS (
+			SELECT
+				c.order_id,
+				c.station,
+				c.items,
+				MIN(s.created_at) AS started_at,
+				MIN(b.created_at) AS bumped_at
+			FROM
+				kitchen.ticket_event c
+			JOIN
+				kitchen.ticket_event s ON s.order_id = c.order_id AND s.station = c.station AND s.type = $3
+			JOIN
+				kitchen.ticket_event b ON b.order_id = c.order_id AND b.station = c.station AND b.type = $4 AND NOT b.automatic
+			WHERE
+				c.type = $5
+			AND
+				c.created_at >= $2
+			GROUP BY
+				c.order_id, c.station, c.items
+		)
+		SELECT
+			i.item_name,
+			COUNT(*),
+			AVG(EXTRACT(EPOCH FROM (t.bumped_at - t.started_at)) / CARDINALITY(t.items))
+		FROM
+			ticket t,
+			UNNEST(t.items) AS i(item_name)
+		WHERE
+			i.item_name = ANY($1)
+		AND
+			t.bumped_at > t.started_at
+		GROUP BY
+			i.item_name`,
+		pq.Array(names),
+		since,
+		k.TicketStarted,
+		k.TicketBumped,
+		k.TicketCreated,
+	)
+	if err != nil {
+		return nil, k.NewSystemError("failed to load preparation times", err)
+	}
+	defer rows.Close()
+
+	samples := map[string]k.PreparationSample{}
+	for rows.Next() {
+		var (
+			sample         k.PreparationSample
+			averageSeconds float64
+		)
+		if err = rows.Scan(&sample.ItemName, &sample.Samples, &averageSeconds); err != nil {
+			log.Printf("Error processing preparation time of %q. Reason: %s", sample.ItemName, err)
+			continue
+		}
+		sample.Average = time.Duration(averageSeconds * float64(time.Second))
+		samples[sample.ItemName] = sample
+	}
+
+	return samples, nil
+}
@@ -5,145 +5,1161 @@ import (
 	"database/sql"
 	"fmt"
 	"log"
+	"strings"
+	"sync/atomic"
+	"time"
 
+	"github.com/lib/pq"
 	k "github.com/w-k-s/McMicroservices/kitchen-service/pkg/kitchen"
 	dao "github.com/w-k-s/McMicroservices/kitchen-service/pkg/persistence"
 )
 
+// DefaultBulkIncreaseThreshold is the bulk increase threshold of transactions that are not begun by a dao.
+const DefaultBulkIncreaseThreshold = 200
+
+// BulkIncreaseThreshold is the number of items above which Increase copies the stock into a temporary table
+// and merges it into the stock in one statement instead of adding each item in turn.
+// It can be changed while the kitchen is running, e.g. when the stock config is reloaded;
+// transactions keep the threshold that they were begun with.
+type BulkIncreaseThreshold struct {
+	threshold int64
+}
+
+func NewBulkIncreaseThreshold(threshold int) *BulkIncreaseThreshold {
+	return &BulkIncreaseThreshold{int64(threshold)}
+}
+
+// Set replaces the threshold of the transactions that are begun from now on.
+func (t *BulkIncreaseThreshold) Set(threshold int) {
+	atomic.StoreInt64(&t.threshold, int64(threshold))
+}
+
+func (t *BulkIncreaseThreshold) Get() int {
+	return int(atomic.LoadInt64(&t.threshold))
+}
+
+// DefaultIdempotencyKeyRetention is the idempotency key retention of transactions that are not begun by a dao.
+const DefaultIdempotencyKeyRetention = 24 * time.Hour
+
+// IdempotencyKeyRetention is how long a request is replayed for retries with the same idempotency key before the key is deleted.
+// It can be changed while the kitchen is running, e.g. when the stock config is reloaded;
+// transactions keep the retention that they were begun with.
+type IdempotencyKeyRetention struct {
+	retention int64
+}
+
+func NewIdempotencyKeyRetention(retention time.Duration) *IdempotencyKeyRetention {
+	return &IdempotencyKeyRetention{int64(retention)}
+}
+
+// Set replaces the retention of the transactions that are begun from now on.
+func (r *IdempotencyKeyRetention) Set(retention time.Duration) {
+	atomic.StoreInt64(&r.retention, int64(retention))
+}
+
+func (r *IdempotencyKeyRetention) Get() time.Duration {
+	return time.Duration(atomic.LoadInt64(&r.retention))
+}
+
 type defaultStockDao struct {
 	*RootDao
+	bulkIncreaseThreshold *BulkIncreaseThreshold
 }
 
-func MustOpenStockDao(pool *sql.DB) dao.StockDao {
+// MustOpenStockDao returns a dao whose transactions copy deliveries of more than bulkIncreaseThreshold items into stock in bulk.
+func MustOpenStockDao(pool *sql.DB, bulkIncreaseThreshold *BulkIncreaseThreshold) dao.StockDao {
 	if pool == nil {
 		log.Fatalf("database is null")
 	}
-	return &defaultStockDao{&RootDao{pool}}
+	return &defaultStockDao{&RootDao{pool}, bulkIncreaseThreshold}
 }
 
 func (s *defaultStockDao) BeginTx() (dao.StockTx, error) {
-	return StockTx(s.pool.Begin())
+	tx, err := s.pool.Begin()
+	return stockTx(tx, err, s.bulkIncreaseThreshold.Get())
 }
 
 func StockTx(tx *sql.Tx, err error) (dao.StockTx, error) {
+	return stockTx(tx, err, DefaultBulkIncreaseThreshold)
+}
+
+func stockTx(tx *sql.Tx, err error, bulkIncreaseThreshold int) (dao.StockTx, error) {
 	if err != nil {
 		return nil, k.NewSystemError("failed to begin transaction", err)
 	}
-	return defaultStockTx{tx}, nil
+	return defaultStockTx{tracedTx{tx}, bulkIncreaseThreshold}, nil
 }
 
 type defaultStockTx struct {
-	*sql.Tx
+	tracedTx
+	bulkIncreaseThreshold int
 }
 
+// Increase adds the stock to the location of each item and records a receipt for each item.
+// Items are created for stock that is not known yet, and known stock is recorded under the canonical name of its item.
+// The unit cost of an item is maintained as a weighted average of the units in stock and the units received.
+// Items received with an unknown (zero) unit cost do not change the average.
+// Deliveries of more items than the bulk increase threshold of the transaction are copied into the database in bulk.
 func (tx defaultStockTx) Increase(ctx context.Context, stock k.Stock) error {
 	var err error
+	if stock, err = tx.identify(ctx, stock, true); err != nil {
+		return err
+	}
+
+	if len(stock) > tx.bulkIncreaseThreshold {
+		return tx.increaseInBulk(ctx, stock)
+	}
 
 	for _, item := range stock {
+		if err = tx.addStock(ctx, item); err != nil {
+			return err
+		}
+
 		_, err = tx.ExecContext(
 			ctx,
 			`INSERT INTO 
-				kitchen.stock (item_name, units) 
+				kitchen.stock_receipt (item_name, units, unit_cost, location) 
 			VALUES 
-				($1,$2) 
-			ON CONFLICT 
-				ON CONSTRAINT uq_stock_name 
-			DO UPDATE SET 
-				units = (
-					SELECT 
-						units+$2
-					FROM 
-						kitchen.stock 
-					WHERE 
-						LOWER(item_name) = LOWER($1)
-				)`,
+				($1,$2,$3,$4)`,
 			item.Name(),
 			item.Units(),
+			item.UnitCost(),
+			item.Location(),
 		)
 
 		if err != nil {
-			return k.NewSystemError(fmt.Sprintf("Failed to increase stock of %q", item.Name()), err)
+			return k.NewSystemError(fmt.Sprintf("Failed to record receipt of %q", item.Name()), err)
 		}
 	}
 
 	return nil
 }
 
+// increaseInBulk has the same effect as adding each item in turn, except that repeated items are combined
+// before they are added and so received at the average cost of the units that have a known cost.
+func (tx defaultStockTx) increaseInBulk(ctx context.Context, stock k.Stock) error {
+	if _, err := tx.ExecContext(
+		ctx,
+		`CREATE TEMPORARY TABLE IF NOT EXISTS stock_delivery (
+			ordinal INTEGER NOT NULL,
+			item_id BIGINT NOT NULL,
+			item_name VARCHAR (255) NOT NULL,
+			units INTEGER NOT NULL,
+			unit_cost NUMERIC (14, 4) NOT NULL,
+			location VARCHAR (64) NOT NULL
+		) ON COMMIT DROP`,
+	); err != nil {
+		return k.NewSystemError("failed to create delivery table", err)
+	}
+
+	// The table is only dropped when the transaction ends, so it may still hold an earlier delivery.
+	if _, err := tx.ExecContext(ctx, `TRUNCATE stock_delivery`); err != nil {
+		return k.NewSystemError("failed to clear delivery table", err)
+	}
+
+	copyStmt, err := tx.PrepareContext(ctx, pq.CopyIn("stock_delivery", "ordinal", "item_id", "item_name", "units", "unit_cost", "location"))
+	if err != nil {
+		return k.NewSystemError("failed to start copying delivery", err)
+	}
+	for i, item := range stock {
+		if _, err = copyStmt.ExecContext(ctx, i, item.ItemId(), item.Name(), item.Units(), item.UnitCost(), item.Location()); err != nil {
+			copyStmt.Close()
+			return k.NewSystemError(fmt.Sprintf("failed to copy %q", item.Name()), err)
+		}
+	}
+	if _, err = copyStmt.ExecContext(ctx); err != nil {
+		copyStmt.Close()
+		return k.NewSystemError("failed to copy delivery", err)
+	}
+	if err = copyStmt.Close(); err != nil {
+		return k.NewSystemError("failed to copy delivery", err)
+	}
+
+	if _, err = tx.ExecContext(
+		ctx,
+		`WITH receipt AS (
+			INSERT INTO 
+				kitchen.stock_receipt (item_name, units, unit_cost, location) 
+			SELECT 
+				d.item_name, d.units, d.unit_cost, d.location
+			FROM 
+				stock_delivery d
+			ORDER BY 
+				d.ordinal
+		)
+		INSERT INTO 
+			kitchen.stock AS s (item_id, item_name, units, unit_cost, location) 
+		SELECT 
+			d.item_id,
+			d.item_name,
+			SUM(d.units),
+			COALESCE(ROUND(SUM(d.units * d.unit_cost) FILTER (WHERE d.unit_cost > 0) / SUM(d.units) FILTER (WHERE d.unit_cost > 0), 4), 0),
+			d.location
+		FROM 
+			stock_delivery d
+		GROUP BY 
+			d.location, d.item_id, d.item_name
+		ON CONFLICT 
+			ON CONSTRAINT uq_stock_location_item 
+		DO UPDATE SET 
+			units = s.units + EXCLUDED.units,
+			version = s.version + 1,
+			unit_cost = CASE 
+				WHEN EXCLUDED.unit_cost = 0 THEN s.unit_cost
+				WHEN s.unit_cost = 0 OR s.units <= 0 THEN EXCLUDED.unit_cost
+				ELSE ROUND((s.units * s.unit_cost + EXCLUDED.units * EXCLUDED.unit_cost) / (s.units + EXCLUDED.units), 4)
+			END`,
+	); err != nil {
+		return k.NewSystemError("failed to merge delivery into stock", err)
+	}
+
+	return nil
+}
+
+func (tx defaultStockTx) addStock(ctx context.Context, item k.StockItem) error {
+	_, err := tx.ExecContext(
+		ctx,
+		`INSERT INTO 
+			kitchen.stock AS s (item_name, units, unit_cost, location, item_id) 
+		VALUES 
+			($1,$2,$3,$4,$5) 
+		ON CONFLICT 
+			ON CONSTRAINT uq_stock_location_item 
+		DO UPDATE SET 
+			units = s.units + EXCLUDED.units,
+			version = s.version + 1,
+			unit_cost = CASE 
+				WHEN EXCLUDED.unit_cost = 0 THEN s.unit_cost
+				WHEN s.unit_cost = 0 OR s.units <= 0 THEN EXCLUDED.unit_cost
+				ELSE ROUND((s.units * s.unit_cost + EXCLUDED.units * EXCLUDED.unit_cost) / (s.units + EXCLUDED.units), 4)
+			END`,
+		item.Name(),
+		item.Units(),
+		item.UnitCost(),
+		item.Location(),
+		item.ItemId(),
+	)
+
+	if err != nil {
+		return k.NewSystemError(fmt.Sprintf("Failed to increase stock of %q at %q", item.Name(), item.Location()), err)
+	}
+	return nil
+}
+
+// Decrease takes the stock from the location of each item in a single statement.
+// Either every item is taken or, if any item is short, none of them are.
+// Stock is matched to items by their names and aliases without regard to case.
 func (tx defaultStockTx) Decrease(ctx context.Context, stock k.Stock) error {
+	if len(stock) == 0 {
+		return nil
+	}
+
+	var err error
+	if stock, err = tx.identify(ctx, stock, false); err != nil {
+		return err
+	}
+
+	if len(stock) == 1 {
+		_, err := tx.removeStock(ctx, stock[0])
+		return err
+	}
+
+	// Repeated items are combined because UPDATE ... FROM only updates a row once.
+	type key struct {
+		name     string
+		location k.Location
+	}
 	var (
-		res          sql.Result
-		rowsAffected int64
-		err          error
+		keys     = []key{}
+		units    = map[key]uint{}
+		versions = map[key]k.StockItem{}
+		values   = []string{}
+		args     = []interface{}{}
 	)
-
 	for _, item := range stock {
-		res, err = tx.ExecContext(
-			ctx,
-			`UPDATE 
-				kitchen.stock 
-			SET 
-				units = units - $2
-			WHERE 
-				item_name = $1
-			AND 
-				units >= $2`,
-			item.Name(),
-			item.Units(),
-		)
+		itemKey := key{item.Name(), item.Location()}
+		if _, ok := units[itemKey]; !ok {
+			keys = append(keys, itemKey)
+		}
+		units[itemKey] += item.Units()
+		if item.Version() != 0 {
+			versions[itemKey] = item
+		}
+	}
+	for i, itemKey := range keys {
+		values = append(values, fmt.Sprintf("($%d, $%d, $%d::INTEGER, $%d::BIGINT)", 4*i+1, 4*i+2, 4*i+3, 4*i+4))
+		args = append(args, itemKey.name, itemKey.location, units[itemKey], versions[itemKey].Version())
+	}
 
-		if err != nil {
-			return k.NewSystemError(fmt.Sprintf("failed to update stock of %q", item.Name()), err)
+	// A concurrent order can take the last units of an item after this statement has started,
+	// so the statement is rolled back on its own if it does not take every item.
+	if err = tx.Savepoint(ctx, savepointDecrease); err != nil {
+		return err
+	}
+
+	rows, err := tx.QueryContext(
+		ctx,
+		`UPDATE 
+			kitchen.stock s
+		SET 
+			units = s.units - d.units,
+			version = s.version + 1
+		FROM 
+			(VALUES `+strings.Join(values, ", ")+`) AS d (item_name, location, units, version)
+		WHERE 
+			s.item_name = d.item_name
+		AND 
+			s.location = d.location
+		AND 
+			s.units >= d.units
+		AND 
+			(d.version = 0 OR s.version = d.version)
+		RETURNING
+			s.item_name, s.location`,
+		args...,
+	)
+	if err != nil {
+		return k.NewSystemError("failed to update stock", err)
+	}
+
+	taken := map[key]bool{}
+	for rows.Next() {
+		var itemKey key
+		if err = rows.Scan(&itemKey.name, &itemKey.location); err != nil {
+			rows.Close()
+			return k.NewSystemError("failed to read updated stock", err)
+		}
+		taken[itemKey] = true
+	}
+	if err = rows.Close(); err != nil {
+		return k.NewSystemError("failed to update stock", err)
+	}
+	if err = rows.Err(); err != nil {
+		return k.NewSystemError("failed to update stock", err)
+	}
+
+	for _, itemKey := range keys {
+		if taken[itemKey] {
+			continue
 		}
-		if rowsAffected, err = res.RowsAffected(); err != nil {
-			return k.NewSystemError("failed to get result of stock update", err)
+		if err = tx.RollbackToSavepoint(ctx, savepointDecrease); err != nil {
+			return err
 		}
-		if rowsAffected == 0 {
-			return k.InvalidError{Cause: fmt.Errorf("insufficient stock of %q", item.Name())}
+		if item, ok := versions[itemKey]; ok {
+			if err = tx.checkVersion(ctx, item); err != nil {
+				return err
+			}
 		}
+		return insufficientStock(itemKey.name, itemKey.location)
+	}
+
+	return tx.ReleaseSavepoint(ctx, savepointDecrease)
+}
+
+// removeStock takes the item from the stock at its location and returns the unit cost of the units that were taken.
+// An item that has a version is only taken if the stock is still at that version.
+func (tx defaultStockTx) removeStock(ctx context.Context, item k.StockItem) (k.Money, error) {
+	var unitCost k.Money
+
+	err := tx.QueryRowContext(
+		ctx,
+		`UPDATE 
+			kitchen.stock 
+		SET 
+			units = units - $2,
+			version = version + 1
+		WHERE 
+			item_name = $1
+		AND 
+			location = $3
+		AND 
+			units >= $2
+		AND 
+			($4 = 0 OR version = $4)
+		RETURNING
+			unit_cost`,
+		item.Name(),
+		item.Units(),
+		item.Location(),
+		item.Version(),
+	).Scan(&unitCost)
+
+	if err == sql.ErrNoRows {
+		if err = tx.checkVersion(ctx, item); err != nil {
+			return 0, err
+		}
+		return 0, insufficientStock(item.Name(), item.Location())
+	}
+	if err != nil {
+		return 0, k.NewSystemError(fmt.Sprintf("failed to update stock of %q", item.Name()), err)
+	}
+	return unitCost, nil
+}
+
+func insufficientStock(name string, location k.Location) error {
+	if location == k.DefaultLocation {
+		return k.InvalidError{Cause: fmt.Errorf("insufficient stock of %q", name)}
+	}
+	return k.InvalidError{Cause: fmt.Errorf("insufficient stock of %q at %q", name, location)}
+}
+
+const savepointDecrease = "decrease_stock"
+
+// Savepoint marks a point in the transaction that later changes can be rolled back to
+// without rolling back the rest of the transaction.
+func (tx defaultStockTx) Savepoint(ctx context.Context, name string) error {
+	if _, err := tx.ExecContext(ctx, "SAVEPOINT "+pq.QuoteIdentifier(name)); err != nil {
+		return k.NewSystemError(fmt.Sprintf("failed to create savepoint %q", name), err)
 	}
 	return nil
 }
 
-func (tx defaultStockTx) Get(ctx context.Context) (k.Stock, error) {
+func (tx defaultStockTx) RollbackToSavepoint(ctx context.Context, name string) error {
+	if _, err := tx.ExecContext(ctx, "ROLLBACK TO SAVEPOINT "+pq.QuoteIdentifier(name)); err != nil {
+		return k.NewSystemError(fmt.Sprintf("failed to roll back to savepoint %q", name), err)
+	}
+	return tx.ReleaseSavepoint(ctx, name)
+}
+
+func (tx defaultStockTx) ReleaseSavepoint(ctx context.Context, name string) error {
+	if _, err := tx.ExecContext(ctx, "RELEASE SAVEPOINT "+pq.QuoteIdentifier(name)); err != nil {
+		return k.NewSystemError(fmt.Sprintf("failed to release savepoint %q", name), err)
+	}
+	return nil
+}
+
+// Get returns the page of stock that the query selects. Names are compared byte by byte so that the order is the same as sort.Sort.
+func (tx defaultStockTx) Get(ctx context.Context, query k.StockQuery) (k.StockPage, error) {
 	var (
-		rows *sql.Rows
-		err  error
+		rows       *sql.Rows
+		err        error
+		conditions = []string{"s.location = $1"}
+		args       = []interface{}{query.Location()}
+		direction  = "ASC"
+		comparison = ">"
 	)
 
+	param := func(value interface{}) string {
+		args = append(args, value)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	if len(query.NamePrefix()) > 0 {
+		conditions = append(conditions, fmt.Sprintf(`LOWER(s.item_name) LIKE %s || '%%'`, param(likePattern(strings.ToLower(query.NamePrefix())))))
+	}
+	if query.UnitsBelow() > 0 {
+		conditions = append(conditions, fmt.Sprintf("s.units < %s", param(query.UnitsBelow())))
+	}
+	if query.Sort().Descending() {
+		direction = "DESC"
+		comparison = "<"
+	}
+
+	orderBy := fmt.Sprintf(`s.item_name COLLATE "C" %s`, direction)
+	if after := query.After(); after != nil {
+		if query.Sort().ByUnits() {
+			conditions = append(conditions, fmt.Sprintf(`(s.units, s.item_name COLLATE "C") %s (%s::INTEGER, %s COLLATE "C")`, comparison, param(after.Units), param(after.Name)))
+		} else {
+			conditions = append(conditions, fmt.Sprintf(`s.item_name COLLATE "C" %s %s`, comparison, param(after.Name)))
+		}
+	}
+	if query.Sort().ByUnits() {
+		orderBy = fmt.Sprintf("s.units %s, %s", direction, orderBy)
+	}
+
 	rows, err = tx.QueryContext(
 		ctx,
 		`SELECT 
+			s.item_id,
+			COALESCE(i.sku, ''),
 			s.item_name,
-			s.units
+			s.units,
+			s.unit_cost,
+			s.version
 		FROM 
-			kitchen.stock s`,
+			kitchen.stock s
+		JOIN
+			kitchen.item i ON i.id = s.item_id
+		WHERE
+			`+strings.Join(conditions, "\n\t\tAND\n\t\t\t")+`
+		ORDER BY
+			`+orderBy+`
+		LIMIT `+param(query.Limit()+1),
+		args...,
 	)
 	if err != nil {
 		log.Printf("Failed to load stock. Reason: %q\n", err)
-		return nil, k.NewSystemError("Failed to load stock", err)
+		return k.StockPage{}, k.NewSystemError("Failed to load stock", err)
 	}
 	defer rows.Close()
 
 	items := make([]k.StockItem, 0)
+	more := false
 	for rows.Next() {
+		if uint(len(items)) == query.Limit() {
+			more = true
+			break
+		}
+
 		var (
-			name  string
-			count uint
+			itemId   uint64
+			sku      string
+			name     string
+			count    uint
+			unitCost k.Money
+			version  uint64
 		)
 
-		if err = rows.Scan(&name, &count); err != nil {
+		if err = rows.Scan(&itemId, &sku, &name, &count, &unitCost, &version); err != nil {
 			log.Printf("Error processing stock item %q. Reason: %s", name, err)
 			continue
 		}
 
 		var item k.StockItem
-		if item, err = k.NewStockItem(name, count); err != nil {
+		if item, err = k.NewStockLevel(name, count); err != nil {
 			log.Printf("Error creating stock item with name: %q,  units: %d from database. Reason: %q", name, count, err)
 			continue
 		}
+		if item, err = item.WithUnitCost(unitCost); err != nil {
+			log.Printf("Error creating stock item with name: %q,  unit cost: %s from database. Reason: %q", name, unitCost, err)
+			continue
+		}
 
-		items = append(items, item)
+		var identity k.Item
+		if identity, err = k.NewItem(itemId, sku, name, nil); err != nil {
+			log.Printf("Error creating item with name: %q, sku: %q from database. Reason: %q", name, sku, err)
+			continue
+		}
+
+		items = append(items, item.OfItem(identity).AtLocation(query.Location()).AtVersion(version))
+	}
+
+	page := k.StockPage{Stock: items}
+	if more && len(items) > 0 {
+		next := query.CursorOf(items[len(items)-1])
+		page.Next = &next
 	}
+	return page, nil
+}
+
+// likePattern escapes the wildcards of LIKE in s.
+func likePattern(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
+
+func (tx defaultStockTx) GetStockLevels(ctx context.Context) ([]k.StockLevel, error) {
+	rows, err := tx.QueryContext(
+		ctx,
+		`SELECT 
+			s.location,
+			s.item_name,
+			s.units
+		FROM 
+			kitchen.stock s
+		ORDER BY
+			s.location, s.item_name`,
+	)
+	if err != nil {
+		return nil, k.NewSystemError("Failed to load stock levels", err)
+	}
+	defer rows.Close()
+
+	levels := []k.StockLevel{}
+	for rows.Next() {
+		var level k.StockLevel
+		if err = rows.Scan(&level.Location, &level.ItemName, &level.Units); err != nil {
+			log.Printf("Error processing stock level of %q. Reason: %s", level.ItemName, err)
+			continue
+		}
+		levels = append(levels, level)
+	}
+
+	return levels, nil
+}
+
+// GetStockItem returns the stock of the item that name is a name or alias of at the location.
+func (tx defaultStockTx) GetStockItem(ctx context.Context, name string, location k.Location) (k.StockItem, error) {
+	items, err := tx.GetItems(ctx, []string{name})
+	if err != nil {
+		return k.StockItem{}, err
+	}
+	identity, ok := items[k.ItemKey(name)]
+	if !ok {
+		return k.StockItem{}, k.NewNotFoundError(fmt.Sprintf("there is no stock of %q at %q", name, location))
+	}
+
+	var (
+		units    uint
+		unitCost k.Money
+		version  uint64
+	)
+
+	err = tx.QueryRowContext(
+		ctx,
+		`SELECT 
+			s.units,
+			s.unit_cost,
+			s.version
+		FROM 
+			kitchen.stock s
+		WHERE
+			s.item_id = $1
+		AND
+			s.location = $2`,
+		identity.Id(),
+		location,
+	).Scan(&units, &unitCost, &version)
+
+	if err == sql.ErrNoRows {
+		return k.StockItem{}, k.NewNotFoundError(fmt.Sprintf("there is no stock of %q at %q", identity.Name(), location))
+	}
+	if err != nil {
+		return k.StockItem{}, k.NewSystemError(fmt.Sprintf("failed to load stock of %q at %q", identity.Name(), location), err)
+	}
+
+	var item k.StockItem
+	if item, err = k.NewStockLevel(identity.Name(), units); err != nil {
+		return k.StockItem{}, err
+	}
+	if item, err = item.WithUnitCost(unitCost); err != nil {
+		return k.StockItem{}, err
+	}
+	return item.OfItem(identity).AtLocation(location).AtVersion(version), nil
+}
+
+// Set replaces the units and unit cost of the stock at its location without recording a receipt.
+// An item that has a version is only replaced if it is still at that version.
+// Otherwise, the stock is created if it does not exist yet.
+func (tx defaultStockTx) Set(ctx context.Context, item k.StockItem) (k.StockItem, error) {
+	identified, err := tx.identify(ctx, k.Stock{item}, item.Version() == 0)
+	if err != nil {
+		return k.StockItem{}, err
+	}
+	item = identified[0]
+
+	var version uint64
+	if item.Version() == 0 {
+		err = tx.QueryRowContext(
+			ctx,
+			`INSERT INTO 
+				kitchen.stock AS s (item_name, units, unit_cost, location, item_id) 
+			VALUES 
+				($1,$2,$3,$4,$5) 
+			ON CONFLICT 
+				ON CONSTRAINT uq_stock_location_item 
+			DO UPDATE SET 
+				units = EXCLUDED.units,
+				unit_cost = EXCLUDED.unit_cost,
+				version = s.version + 1
+			RETURNING
+				s.version`,
+			item.Name(),
+			item.Units(),
+			item.UnitCost(),
+			item.Location(),
+			item.ItemId(),
+		).Scan(&version)
+	} else {
+		err = tx.QueryRowContext(
+			ctx,
+			`UPDATE 
+				kitchen.stock 
+			SET 
+				units = $1,
+				unit_cost = $2,
+				version = version + 1
+			WHERE 
+				item_id = $3
+			AND 
+				location = $4
+			AND 
+				version = $5
+			RETURNING
+				version`,
+			item.Units(),
+			item.UnitCost(),
+			item.ItemId(),
+			item.Location(),
+			item.Version(),
+		).Scan(&version)
 
-	return items, nil
+		if err == sql.ErrNoRows {
+			if err = tx.checkVersion(ctx, item); err != nil {
+				return k.StockItem{}, err
+			}
+			return k.StockItem{}, k.NewNotFoundError(fmt.Sprintf("there is no stock of %q at %q", item.Name(), item.Location()))
+		}
+	}
+	if err != nil {
+		return k.StockItem{}, k.NewSystemError(fmt.Sprintf("failed to set stock of %q at %q", item.Name(), item.Location()), err)
+	}
+
+	return item.AtVersion(version), nil
+}
+
+// Create adds stock of an item that is not in stock at its location yet, or returns a k.ConflictError if it is.
+func (tx defaultStockTx) Create(ctx context.Context, item k.StockItem) (k.StockItem, error) {
+	identified, err := tx.identify(ctx, k.Stock{item}, true)
+	if err != nil {
+		return k.StockItem{}, err
+	}
+	item = identified[0]
+
+	var version uint64
+	err = tx.QueryRowContext(
+		ctx,
+		`INSERT INTO 
+			kitchen.stock AS s (item_name, units, unit_cost, location, item_id) 
+		VALUES 
+			($1,$2,$3,$4,$5) 
+		ON CONFLICT 
+			ON CONSTRAINT uq_stock_location_item 
+		DO UPDATE SET 
+			units = EXCLUDED.units,
+			unit_cost = EXCLUDED.unit_cost,
+			version = s.version + 1
+		WHERE
+			s.units = 0
+		RETURNING
+			s.version`,
+		item.Name(),
+		item.Units(),
+		item.UnitCost(),
+		item.Location(),
+		item.ItemId(),
+	).Scan(&version)
+
+	if err == sql.ErrNoRows {
+		return k.StockItem{}, k.NewConflictError(fmt.Sprintf("%q is already in stock at %q", item.Name(), item.Location()))
+	}
+	if err != nil {
+		return k.StockItem{}, k.NewSystemError(fmt.Sprintf("failed to create stock of %q at %q", item.Name(), item.Location()), err)
+	}
+
+	return item.AtVersion(version), nil
+}
+
+// Delete removes the item from stock at its location.
+// An item that has a version is only removed if it is still at that version.
+func (tx defaultStockTx) Delete(ctx context.Context, item k.StockItem) error {
+	identified, err := tx.identify(ctx, k.Stock{item}, false)
+	if err != nil {
+		return err
+	}
+	item = identified[0]
+
+	res, err := tx.ExecContext(
+		ctx,
+		`DELETE FROM
+			kitchen.stock
+		WHERE
+			item_id = $1
+		AND
+			location = $2
+		AND
+			($3 = 0 OR version = $3)`,
+		item.ItemId(),
+		item.Location(),
+		item.Version(),
+	)
+	if err != nil {
+		return k.NewSystemError(fmt.Sprintf("failed to delete stock of %q at %q", item.Name(), item.Location()), err)
+	}
+
+	var rowsAffected int64
+	if rowsAffected, err = res.RowsAffected(); err != nil {
+		return k.NewSystemError("failed to get result of stock delete", err)
+	}
+	if rowsAffected == 0 {
+		if err = tx.checkVersion(ctx, item); err != nil {
+			return err
+		}
+		return k.NewNotFoundError(fmt.Sprintf("there is no stock of %q at %q", item.Name(), item.Location()))
+	}
+	return nil
+}
+
+// checkVersion returns a k.ConflictError if the stock of an item that has a version has changed since that version,
+// or a k.NotFoundError if there is no stock of the item.
+func (tx defaultStockTx) checkVersion(ctx context.Context, item k.StockItem) error {
+	if item.Version() == 0 {
+		return nil
+	}
+
+	var version uint64
+	err := tx.QueryRowContext(
+		ctx,
+		`SELECT 
+			s.version
+		FROM 
+			kitchen.stock s
+		WHERE
+			s.item_name = $1
+		AND
+			s.location = $2`,
+		item.Name(),
+		item.Location(),
+	).Scan(&version)
+
+	if err == sql.ErrNoRows {
+		return k.NewNotFoundError(fmt.Sprintf("there is no stock of %q at %q", item.Name(), item.Location()))
+	}
+	if err != nil {
+		return k.NewSystemError(fmt.Sprintf("failed to load stock of %q at %q", item.Name(), item.Location()), err)
+	}
+	if version != item.Version() {
+		return k.NewConflictError(fmt.Sprintf("stock of %q at %q is at version %d, not version %d", item.Name(), item.Location(), version, item.Version()))
+	}
+	return nil
+}
+
+// Transfer moves the stock between locations and records the movement out of one location and into the other.
+// Units arrive at the destination at the average cost of the units at the source.
+func (tx defaultStockTx) Transfer(ctx context.Context, transfer k.Transfer) (k.Transfer, error) {
+	var (
+		id        uint64
+		createdAt time.Time
+		err       error
+	)
+
+	if err = tx.QueryRowContext(
+		ctx,
+		`INSERT INTO 
+			kitchen.stock_transfer (from_location, to_location) 
+		VALUES 
+			($1, $2) 
+		RETURNING 
+			id, created_at`,
+		transfer.FromLocation(),
+		transfer.ToLocation(),
+	).Scan(&id, &createdAt); err != nil {
+		return k.Transfer{}, k.NewSystemError(fmt.Sprintf("failed to record transfer from %q to %q", transfer.FromLocation(), transfer.ToLocation()), err)
+	}
+
+	var stock k.Stock
+	if stock, err = tx.identify(ctx, transfer.Stock(), false); err != nil {
+		return k.Transfer{}, err
+	}
+
+	moved := k.Stock{}
+	for _, item := range stock {
+		var unitCost k.Money
+		if unitCost, err = tx.removeStock(ctx, item.AtLocation(transfer.FromLocation())); err != nil {
+			return k.Transfer{}, err
+		}
+
+		if item, err = item.WithUnitCost(unitCost); err != nil {
+			return k.Transfer{}, err
+		}
+
+		if err = tx.addStock(ctx, item.AtLocation(transfer.ToLocation())); err != nil {
+			return k.Transfer{}, err
+		}
+
+		moved = append(moved, item)
+	}
+
+	if transfer, err = k.NewTransfer(id, transfer.FromLocation(), transfer.ToLocation(), moved, createdAt); err != nil {
+		return k.Transfer{}, err
+	}
+
+	for _, movement := range transfer.Movements() {
+		if _, err = tx.ExecContext(
+			ctx,
+			`INSERT INTO 
+				kitchen.stock_movement (transfer_id, location, item_name, units, unit_cost, created_at) 
+			VALUES 
+				($1, $2, $3, $4, $5, $6)`,
+			id,
+			movement.Location,
+			movement.ItemName,
+			movement.Units,
+			movement.UnitCost,
+			createdAt,
+		); err != nil {
+			return k.Transfer{}, k.NewSystemError(fmt.Sprintf("failed to record movement of %q at %q", movement.ItemName, movement.Location), err)
+		}
+	}
+
+	return transfer, nil
+}
+
+// GetTransfer rebuilds a transfer from the movements into its destination.
+func (tx defaultStockTx) GetTransfer(ctx context.Context, id uint64) (k.Transfer, error) {
+	var (
+		fromLocation k.Location
+		toLocation   k.Location
+		createdAt    time.Time
+	)
+
+	err := tx.QueryRowContext(
+		ctx,
+		`SELECT 
+			t.from_location,
+			t.to_location,
+			t.created_at
+		FROM 
+			kitchen.stock_transfer t
+		WHERE 
+			t.id = $1`,
+		id,
+	).Scan(&fromLocation, &toLocation, &createdAt)
+
+	if err == sql.ErrNoRows {
+		return k.Transfer{}, k.NewNotFoundError(fmt.Sprintf("transfer %d does not exist", id))
+	}
+	if err != nil {
+		return k.Transfer{}, k.NewSystemError(fmt.Sprintf("failed to load transfer %d", id), err)
+	}
+
+	rows, err := tx.QueryContext(
+		ctx,
+		`SELECT 
+			m.item_name,
+			m.units,
+			m.unit_cost
+		FROM 
+			kitchen.stock_movement m
+		WHERE 
+			m.transfer_id = $1
+		AND 
+			m.units > 0
+		ORDER BY
+			m.id`,
+		id,
+	)
+	if err != nil {
+		return k.Transfer{}, k.NewSystemError(fmt.Sprintf("failed to load movements of transfer %d", id), err)
+	}
+	defer rows.Close()
+
+	stock := k.Stock{}
+	for rows.Next() {
+		var (
+			name     string
+			units    uint
+			unitCost k.Money
+		)
+
+		if err = rows.Scan(&name, &units, &unitCost); err != nil {
+			log.Printf("Error processing movement of %q in transfer %d. Reason: %s", name, id, err)
+			continue
+		}
+
+		var item k.StockItem
+		if item, err = k.NewStockItem(name, units); err != nil {
+			log.Printf("Error creating stock item with name: %q,  units: %d from database. Reason: %q", name, units, err)
+			continue
+		}
+		if item, err = item.WithUnitCost(unitCost); err != nil {
+			log.Printf("Error creating stock item with name: %q,  unit cost: %s from database. Reason: %q", name, unitCost, err)
+			continue
+		}
+
+		stock = append(stock, item)
+	}
+
+	return k.NewTransfer(id, fromLocation, toLocation, stock, createdAt)
+}
+
+// GetDietaryTags returns the tags of the items with the names, keyed by the names as they were given.
+func (tx defaultStockTx) GetDietaryTags(ctx context.Context, names []string) (map[string]k.DietaryTags, error) {
+	canonical, err := tx.canonicalNames(ctx, names...)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := tx.QueryContext(
+		ctx,
+		`SELECT 
+			t.item_name,
+			t.tag
+		FROM 
+			kitchen.ingredient_tag t
+		WHERE 
+			t.item_name = ANY($1)
+		ORDER BY
+			t.item_name, t.tag`,
+		pq.Array(canonical),
+	)
+	if err != nil {
+		return nil, k.NewSystemError("Failed to load dietary tags", err)
+	}
+	defer rows.Close()
+
+	byName := map[string]k.DietaryTags{}
+	for rows.Next() {
+		var (
+			name  string
+			value string
+		)
+
+		if err = rows.Scan(&name, &value); err != nil {
+			log.Printf("Error processing dietary tag of %q. Reason: %s", name, err)
+			continue
+		}
+
+		var tag k.DietaryTag
+		if tag, err = k.ParseDietaryTag(value); err != nil {
+			log.Printf("Error creating dietary tag %q of %q from database. Reason: %q", value, name, err)
+			continue
+		}
+
+		byName[name] = append(byName[name], tag)
+	}
+
+	tags := map[string]k.DietaryTags{}
+	for i, name := range names {
+		if nameTags, ok := byName[canonical[i]]; ok {
+			tags[name] = nameTags
+		}
+	}
+
+	return tags, nil
+}
+
+func (tx defaultStockTx) SetDietaryTags(ctx context.Context, name string, tags k.DietaryTags) error {
+	if err := tx.createItems(ctx, []string{name}); err != nil {
+		return err
+	}
+	canonical, err := tx.canonicalNames(ctx, name)
+	if err != nil {
+		return err
+	}
+	name = canonical[0]
+
+	if _, err := tx.ExecContext(
+		ctx,
+		`DELETE FROM 
+			kitchen.ingredient_tag 
+		WHERE 
+			item_name = $1`,
+		name,
+	); err != nil {
+		return k.NewSystemError(fmt.Sprintf("failed to clear dietary tags of %q", name), err)
+	}
+
+	for _, tag := range tags {
+		if _, err := tx.ExecContext(
+			ctx,
+			`INSERT INTO 
+				kitchen.ingredient_tag (item_name, tag) 
+			VALUES 
+				($1, $2)`,
+			name,
+			tag,
+		); err != nil {
+			return k.NewSystemError(fmt.Sprintf("failed to tag %q as %q", name, tag), err)
+		}
+	}
+
+	return nil
+}
+
+func (tx defaultStockTx) GetSubstitutionRules(ctx context.Context) (k.SubstitutionRules, error) {
+	rows, err := tx.QueryContext(
+		ctx,
+		`SELECT 
+			r.item_name,
+			r.substitute_name,
+			r.priority
+		FROM 
+			kitchen.substitution_rule r
+		ORDER BY
+			r.item_name, r.priority, r.substitute_name`,
+	)
+	if err != nil {
+		return nil, k.NewSystemError("Failed to load substitution rules", err)
+	}
+	defer rows.Close()
+
+	rules := make(k.SubstitutionRules, 0)
+	for rows.Next() {
+		var (
+			itemName       string
+			substituteName string
+			priority       uint
+		)
+
+		if err = rows.Scan(&itemName, &substituteName, &priority); err != nil {
+			log.Printf("Error processing substitution rule of %q. Reason: %s", itemName, err)
+			continue
+		}
+
+		var rule k.SubstitutionRule
+		if rule, err = k.NewSubstitutionRule(itemName, substituteName, priority); err != nil {
+			log.Printf("Error creating substitution rule of %q with %q from database. Reason: %q", itemName, substituteName, err)
+			continue
+		}
+
+		rules = append(rules, rule)
+	}
+
+	return rules, nil
+}
+
+func (tx defaultStockTx) SaveSubstitutionRule(ctx context.Context, rule k.SubstitutionRule) error {
+	if err := tx.createItems(ctx, []string{rule.ItemName(), rule.SubstituteName()}); err != nil {
+		return err
+	}
+	canonical, err := tx.canonicalNames(ctx, rule.ItemName(), rule.SubstituteName())
+	if err != nil {
+		return err
+	}
+	if rule, err = k.NewSubstitutionRule(canonical[0], canonical[1], rule.Priority()); err != nil {
+		return err
+	}
+
+	if _, err := tx.ExecContext(
+		ctx,
+		`INSERT INTO 
+			kitchen.substitution_rule (item_name, substitute_name, priority) 
+		VALUES 
+			($1, $2, $3)
+		ON CONFLICT 
+			ON CONSTRAINT pk_substitution_rule
+		DO UPDATE SET 
+			priority = EXCLUDED.priority`,
+		rule.ItemName(),
+		rule.SubstituteName(),
+		rule.Priority(),
+	); err != nil {
+		return k.NewSystemError(fmt.Sprintf("failed to save substitution of %q with %q", rule.ItemName(), rule.SubstituteName()), err)
+	}
+	return nil
+}
+
+func (tx defaultStockTx) DeleteSubstitutionRule(ctx context.Context, itemName string, substituteName string) error {
+	var (
+		res          sql.Result
+		rowsAffected int64
+		canonical    []string
+		err          error
+	)
+
+	if canonical, err = tx.canonicalNames(ctx, itemName, substituteName); err != nil {
+		return err
+	}
+	itemName, substituteName = canonical[0], canonical[1]
+
+	if res, err = tx.ExecContext(
+		ctx,
+		`DELETE FROM 
+			kitchen.substitution_rule 
+		WHERE 
+			item_name = $1 
+		AND 
+			substitute_name = $2`,
+		itemName,
+		substituteName,
+	); err != nil {
+		return k.NewSystemError(fmt.Sprintf("failed to delete substitution of %q with %q", itemName, substituteName), err)
+	}
+	if rowsAffected, err = res.RowsAffected(); err != nil {
+		return k.NewSystemError("failed to get result of substitution rule delete", err)
+	}
+	if rowsAffected == 0 {
+		return k.NewNotFoundError(fmt.Sprintf("%q is not a substitute for %q", substituteName, itemName))
+	}
+	return nil
 }
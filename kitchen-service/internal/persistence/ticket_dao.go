@@ -0,0 +1,168 @@
+package persistence
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/lib/pq"
+	k "github.com/w-k-s/McMicroservices/kitchen-service/pkg/kitchen"
+)
+
+const ticketEventColumns = `
+	e.id,
+	e.type,
+	e.order_id,
+	e.station,
+	e.location,
+	e.items,
+	e.created_at,
+	e.automatic`
+
+func (tx defaultOrderTx) SaveTicketEvent(ctx context.Context, event k.TicketEvent) (k.TicketEvent, error) {
+	var (
+		id        uint64
+		createdAt time.Time
+	)
+
+	if err := tx.QueryRowContext(
+		ctx,
+		`INSERT INTO
+			kitchen.ticket_event (type, order_id, station, location, items, created_at, automatic)
+		VALUES
+			($1, $2, $3, $4, $5, $6, $7)
+		RETURNING
+			id, created_at`,
+		event.Type(),
+		event.OrderId(),
+		event.Station(),
+		event.Location(),
+		pq.Array(event.Items()),
+		event.OccurredAt(),
+		event.Automatic(),
+	).Scan(&id, &createdAt); err != nil {
+		return k.TicketEvent{}, k.NewSystemError(fmt.Sprintf("failed to save %s event of order %d", event.Type(), event.OrderId()), err)
+	}
+
+	saved, err := k.NewTicketEvent(id, event.Type(), event.OrderId(), event.Station(), event.Location(), event.Items(), createdAt)
+	if err != nil {
+		return k.TicketEvent{}, err
+	}
+	return saved.WithAutomatic(event.Automatic()), nil
+}
+
+func (tx defaultOrderTx) GetTicketEvents(ctx context.Context, afterId uint64, overlap time.Duration, station k.Station, location k.Location) ([]k.TicketEvent, error) {
+	return tx.queryTicketEvents(
+		ctx,
+		`SELECT `+ticketEventColumns+`
+		FROM
+			kitchen.ticket_event e
+		WHERE
+			(
+				e.id > $1
+			OR
+				e.id < $1 AND e.created_at >= (
+					SELECT
+						l.created_at - MAKE_INTERVAL(secs => $4)
+					FROM
+						kitchen.ticket_event l
+					WHERE
+						l.id = $1
+				)
+			)
+		AND
+			($2::TEXT = '' OR e.station = $2::TEXT)
+		AND
+			($3::TEXT = '' OR e.location = $3::TEXT)
+		ORDER BY
+			e.id`,
+		afterId,
+		station,
+		location,
+		overlap.Seconds(),
+	)
+}
+
+func (tx defaultOrderTx) GetOpenTicketEvents(ctx context.Context, station k.Station, location k.Location) ([]k.TicketEvent, error) {
+	return tx.queryTicketEvents(
+		ctx,
+		`WITH latest AS (
+			SELECT DISTINCT ON (l.order_id, l.station)
+				l.order_id,
+				l.station,
+				l.type
+			FROM
+				kitchen.ticket_event l
+			ORDER BY
+				l.order_id, l.station, l.id DESC
+		)
+		SELECT `+ticketEventColumns+`
+		FROM
+			kitchen.ticket_event e
+		JOIN
+			latest ON latest.order_id = e.order_id AND latest.station = e.station
+		WHERE
+			latest.type <> $1
+		AND
+			($2::TEXT = '' OR e.station = $2::TEXT)
+		AND
+			($3::TEXT = '' OR e.location = $3::TEXT)
+		ORDER BY
+			e.id`,
+		k.TicketBumped,
+		station,
+		location,
+	)
+}
+
+func (tx defaultOrderTx) GetLatestTicketEvents(ctx context.Context, orderId uint64) ([]k.TicketEvent, error) {
+	return tx.queryTicketEvents(
+		ctx,
+		`SELECT DISTINCT ON (e.station) `+ticketEventColumns+`
+		FROM
+			kitchen.ticket_event e
+		WHERE
+			e.order_id = $1
+		ORDER BY
+			e.station, e.id DESC`,
+		orderId,
+	)
+}
+
+func (tx defaultOrderTx) queryTicketEvents(ctx context.Context, query string, args ...interface{}) ([]k.TicketEvent, error) {
+	rows, err := tx.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, k.NewSystemError("failed to load ticket events", err)
+	}
+	defer rows.Close()
+
+	events := make([]k.TicketEvent, 0)
+	for rows.Next() {
+		var (
+			id        uint64
+			eventType k.TicketEventType
+			orderId   uint64
+			station   k.Station
+			location  k.Location
+			items     []string
+			createdAt time.Time
+			automatic bool
+		)
+
+		if err = rows.Scan(&id, &eventType, &orderId, &station, &location, pq.Array(&items), &createdAt, &automatic); err != nil {
+			log.Printf("Error processing ticket event %d. Reason: %s", id, err)
+			continue
+		}
+
+		var event k.TicketEvent
+		if event, err = k.NewTicketEvent(id, eventType, orderId, station, location, items, createdAt); err != nil {
+			log.Printf("Error creating ticket event %d from database. Reason: %q", id, err)
+			continue
+		}
+
+		events = append(events, event.WithAutomatic(automatic))
+	}
+
+	return events, nil
+}
@@ -0,0 +1,66 @@
+package persistence
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+
+	"github.com/w-k-s/McMicroservices/kitchen-service/pkg/tracing"
+	semconv "go.opentelemetry.io/otel/semconv/v1.10.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracedTx starts a client span for each statement that is executed, queried or prepared in the transaction.
+// The spans of queries end when the query returns, not when its rows have been read.
+type tracedTx struct {
+	*sql.Tx
+}
+
+func (tx tracedTx) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	ctx, span := startStatementSpan(ctx, query)
+	result, err := tx.Tx.ExecContext(ctx, query, args...)
+	tracing.End(span, err)
+	return result, err
+}
+
+func (tx tracedTx) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	ctx, span := startStatementSpan(ctx, query)
+	rows, err := tx.Tx.QueryContext(ctx, query, args...)
+	tracing.End(span, err)
+	return rows, err
+}
+
+func (tx tracedTx) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	ctx, span := startStatementSpan(ctx, query)
+	row := tx.Tx.QueryRowContext(ctx, query, args...)
+	tracing.End(span, row.Err())
+	return row
+}
+
+func (tx tracedTx) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	ctx, span := startStatementSpan(ctx, query)
+	stmt, err := tx.Tx.PrepareContext(ctx, query)
+	tracing.End(span, err)
+	return stmt, err
+}
+
+func startStatementSpan(ctx context.Context, query string) (context.Context, trace.Span) {
+	operation := statementOperation(query)
+	return tracing.Start(ctx, operation,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemPostgreSQL,
+			semconv.DBOperationKey.String(operation),
+			semconv.DBStatementKey.String(query),
+		),
+	)
+}
+
+// statementOperation is the first keyword of a statement, e.g. SELECT, which names the span of the statement.
+func statementOperation(query string) string {
+	fields := strings.Fields(query)
+	if len(fields) == 0 {
+		return "SQL"
+	}
+	return strings.ToUpper(fields[0])
+}
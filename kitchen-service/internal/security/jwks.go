@@ -0,0 +1,108 @@
+package security
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"time"
+
+	"github.com/MicahParks/keyfunc"
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/w-k-s/McMicroservices/kitchen-service/log"
+)
+
+// minRefreshInterval limits how often a key set is reloaded because a token was signed with a key it does not have.
+const minRefreshInterval = 30 * time.Second
+
+var errNoKey = errors.New("no key for the token")
+
+// KeySet holds the keys that tokens are verified with.
+type KeySet interface {
+	// Keyfunc returns the key that the token was signed with, or an error if the key set does not have it.
+	Keyfunc(token *jwt.Token) (interface{}, error)
+	// Close stops reloading the keys.
+	Close()
+}
+
+type secretKeySet []byte
+
+// NewSecretKeySet verifies HS256 tokens that were signed with the shared secret.
+func NewSecretKeySet(secret []byte) KeySet {
+	return secretKeySet(secret)
+}
+
+func (s secretKeySet) Keyfunc(token *jwt.Token) (interface{}, error) {
+	if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+		return nil, errNoKey
+	}
+	return []byte(s), nil
+}
+
+func (s secretKeySet) Close() {}
+
+type keySets []KeySet
+
+// NewKeySets verifies tokens with the key of the first key set that has a key for the token.
+func NewKeySets(sets ...KeySet) KeySet {
+	return keySets(sets)
+}
+
+func (s keySets) Keyfunc(token *jwt.Token) (interface{}, error) {
+	for _, set := range s {
+		if key, err := set.Keyfunc(token); err == nil {
+			return key, nil
+		}
+	}
+	return nil, errNoKey
+}
+
+func (s keySets) Close() {
+	for _, set := range s {
+		set.Close()
+	}
+}
+
+// jwksKeySet is a JSON Web Key Set that is reloaded every refresh interval,
+// and sooner if a token is signed with a key that it does not have.
+type jwksKeySet struct {
+	*keyfunc.JWKS
+}
+
+func (s jwksKeySet) Close() {
+	s.EndBackground()
+}
+
+// NewJWKSFile loads a JSON Web Key Set from a file.
+func NewJWKSFile(path string, refreshInterval time.Duration) (KeySet, error) {
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load JSON Web Key Set from %q. Reason: %w", path, err)
+	}
+	transport := &http.Transport{}
+	transport.RegisterProtocol("file", http.NewFileTransport(http.Dir("/")))
+	fileUrl := url.URL{Scheme: "file", Path: filepath.ToSlash(path)}
+	return NewJWKSURL(fileUrl.String(), &http.Client{Transport: transport}, refreshInterval)
+}
+
+// NewJWKSURL loads a JSON Web Key Set from a URL, such as the jwks_uri of an identity provider.
+func NewJWKSURL(url string, client *http.Client, refreshInterval time.Duration) (KeySet, error) {
+	jwks, err := keyfunc.Get(url, keyfunc.Options{
+		Client:            client,
+		RefreshInterval:   refreshInterval,
+		RefreshRateLimit:  minRefreshInterval,
+		RefreshTimeout:    client.Timeout,
+		RefreshUnknownKID: true,
+		RefreshErrorHandler: func(err error) {
+			// The keys that were loaded before are kept until the source can be read again.
+			log.Err(err).
+				Str("source", url).
+				Msg("Failed to refresh JSON Web Key Set")
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to load JSON Web Key Set from %q. Reason: %w", url, err)
+	}
+	return jwksKeySet{jwks}, nil
+}
@@ -0,0 +1,85 @@
+package security
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/w-k-s/McMicroservices/kitchen-service/pkg/auth"
+	k "github.com/w-k-s/McMicroservices/kitchen-service/pkg/kitchen"
+)
+
+// Verifier checks the signature and claims of JSON Web Tokens.
+type Verifier struct {
+	keys     KeySet
+	parser   *jwt.Parser
+	issuer   string
+	audience string
+	leeway   time.Duration
+	now      func() time.Time
+}
+
+// NewVerifier accepts tokens that were signed with one of the keys.
+// If issuer or audience is not empty, the token must have been issued by the issuer for the audience.
+// Leeway allows for clock skew when checking when the token expires.
+func NewVerifier(keys KeySet, issuer string, audience string, leeway time.Duration) *Verifier {
+	return &Verifier{
+		keys: keys,
+		// The claims are validated by validate, so that the leeway is allowed for.
+		parser:   jwt.NewParser(jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg(), jwt.SigningMethodRS256.Alg()}), jwt.WithoutClaimsValidation()),
+		issuer:   issuer,
+		audience: audience,
+		leeway:   leeway,
+		now:      time.Now,
+	}
+}
+
+// Verify returns the claims of the token, or a k.UnauthorizedError if the token can not be trusted.
+func (v *Verifier) Verify(ctx context.Context, token string) (auth.Claims, error) {
+	var claims auth.TokenClaims
+	if _, err := v.parser.ParseWithClaims(token, &claims, v.keys.Keyfunc); err != nil {
+		switch {
+		case errors.Is(err, jwt.ErrTokenMalformed):
+			return auth.Claims{}, k.NewUnauthorizedError("token is not a JSON Web Token")
+		case errors.Is(err, jwt.ErrTokenUnverifiable):
+			return auth.Claims{}, k.NewUnauthorizedError("token was not signed with a trusted key")
+		default:
+			return auth.Claims{}, k.NewUnauthorizedError("token signature is invalid")
+		}
+	}
+	if err := v.validate(&claims); err != nil {
+		return auth.Claims{}, err
+	}
+	return claims.Claims(), nil
+}
+
+func (v *Verifier) validate(claims *auth.TokenClaims) error {
+	now := v.now()
+
+	if claims.ExpiresAt == nil {
+		return k.NewUnauthorizedError("token does not expire")
+	}
+	if !claims.VerifyExpiresAt(now.Add(-v.leeway), true) {
+		return k.NewUnauthorizedError("token has expired")
+	}
+	if !claims.VerifyNotBefore(now.Add(v.leeway), false) {
+		return k.NewUnauthorizedError("token is not valid yet")
+	}
+	if len(claims.Subject) == 0 {
+		return k.NewUnauthorizedError("token does not have a subject")
+	}
+	if len(v.issuer) > 0 && !claims.VerifyIssuer(v.issuer, true) {
+		return k.NewUnauthorizedError(fmt.Sprintf("token was not issued by %q", v.issuer))
+	}
+	if len(v.audience) > 0 && !claims.VerifyAudience(v.audience, true) {
+		return k.NewUnauthorizedError(fmt.Sprintf("token is not intended for %q", v.audience))
+	}
+	return nil
+}
+
+// Close stops reloading the key sets.
+func (v *Verifier) Close() {
+	v.keys.Close()
+}
@@ -0,0 +1,282 @@
+package security
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+	k "github.com/w-k-s/McMicroservices/kitchen-service/pkg/kitchen"
+)
+
+type JWTTestSuite struct {
+	suite.Suite
+	now        time.Time
+	rsaKey     *rsa.PrivateKey
+	secret     []byte
+	jwks       string
+	jwksServer *httptest.Server
+}
+
+func TestJWTTestSuite(t *testing.T) {
+	suite.Run(t, new(JWTTestSuite))
+}
+
+func (suite *JWTTestSuite) SetupSuite() {
+	var err error
+	suite.now = time.Date(2022, 3, 1, 12, 0, 0, 0, time.UTC)
+	suite.secret = []byte("all work and no play")
+	suite.rsaKey, err = rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		panic(err)
+	}
+
+	jwks := fmt.Sprintf(`{"keys":[{"kty":"RSA","kid":"kitchen-1","alg":"RS256","use":"sig","n":%q,"e":%q}]}`,
+		base64.RawURLEncoding.EncodeToString(suite.rsaKey.PublicKey.N.Bytes()),
+		base64.RawURLEncoding.EncodeToString(big.NewInt(int64(suite.rsaKey.PublicKey.E)).Bytes()),
+	)
+	suite.jwks = jwks
+	suite.jwksServer = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(jwks))
+	}))
+}
+
+func (suite *JWTTestSuite) TearDownSuite() {
+	suite.jwksServer.Close()
+}
+
+func (suite *JWTTestSuite) verifier(keys KeySet) *Verifier {
+	verifier := NewVerifier(keys, "auth-service", "kitchen-service", 30*time.Second)
+	verifier.now = func() time.Time { return suite.now }
+	return verifier
+}
+
+func (suite *JWTTestSuite) claims() map[string]interface{} {
+	return map[string]interface{}{
+		"sub":   "jack.torrence",
+		"iss":   "auth-service",
+		"aud":   []string{"kitchen-service", "order-service"},
+		"iat":   suite.now.Add(-time.Minute).Unix(),
+		"exp":   suite.now.Add(time.Hour).Unix(),
+		"roles": []string{"cook"},
+		"scope": "stock:read stock:write",
+	}
+}
+
+func (suite *JWTTestSuite) sign(header map[string]interface{}, claims map[string]interface{}) string {
+	var (
+		method jwt.SigningMethod = jwt.SigningMethodNone
+		key    interface{}       = jwt.UnsafeAllowNoneSignatureType
+	)
+	switch header["alg"] {
+	case jwt.SigningMethodHS256.Alg():
+		method, key = jwt.SigningMethodHS256, suite.secret
+	case jwt.SigningMethodRS256.Alg():
+		method, key = jwt.SigningMethodRS256, suite.rsaKey
+	}
+	token := jwt.NewWithClaims(method, jwt.MapClaims(claims))
+	for name, value := range header {
+		token.Header[name] = value
+	}
+	signed, err := token.SignedString(key)
+	if err != nil {
+		panic(err)
+	}
+	return signed
+}
+
+// -- SUITE
+
+func (suite *JWTTestSuite) Test_GIVEN_tokenSignedWithSecret_WHEN_tokenIsVerified_THEN_claimsAreReturned() {
+	// GIVEN
+	token := suite.sign(map[string]interface{}{"alg": "HS256", "typ": "JWT"}, suite.claims())
+
+	// WHEN
+	claims, err := suite.verifier(NewSecretKeySet(suite.secret)).Verify(context.Background(), token)
+
+	// THEN
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), "jack.torrence", claims.Subject)
+	assert.Equal(suite.T(), "auth-service", claims.Issuer)
+	assert.Equal(suite.T(), []string{"kitchen-service", "order-service"}, claims.Audience)
+	assert.Equal(suite.T(), []string{"stock:read", "stock:write"}, claims.Scopes)
+	assert.Equal(suite.T(), suite.now.Add(time.Hour), claims.ExpiresAt)
+	assert.True(suite.T(), claims.HasRole("cook"))
+	assert.False(suite.T(), claims.HasRole("admin"))
+}
+
+func (suite *JWTTestSuite) Test_GIVEN_tokenSignedWithRSAKeyFromJWKSURL_WHEN_tokenIsVerified_THEN_claimsAreReturned() {
+	// GIVEN
+	keys, err := NewJWKSURL(suite.jwksServer.URL, suite.jwksServer.Client(), time.Hour)
+	assert.Nil(suite.T(), err)
+	defer keys.Close()
+	claims := suite.claims()
+	claims["aud"] = "kitchen-service"
+	claims["roles"] = "manager"
+	token := suite.sign(map[string]interface{}{"alg": "RS256", "kid": "kitchen-1"}, claims)
+
+	// WHEN
+	verified, err := suite.verifier(keys).Verify(context.Background(), token)
+
+	// THEN
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), []string{"kitchen-service"}, verified.Audience)
+	assert.Equal(suite.T(), []string{"manager"}, verified.Roles)
+}
+
+func (suite *JWTTestSuite) Test_GIVEN_tokenSignedWithUnknownKeyId_WHEN_tokenIsVerified_THEN_tokenIsRejected() {
+	// GIVEN
+	keys, err := NewJWKSURL(suite.jwksServer.URL, suite.jwksServer.Client(), time.Hour)
+	assert.Nil(suite.T(), err)
+	defer keys.Close()
+	token := suite.sign(map[string]interface{}{"alg": "RS256", "kid": "kitchen-2"}, suite.claims())
+
+	// WHEN
+	_, err = suite.verifier(keys).Verify(context.Background(), token)
+
+	// THEN
+	assert.IsType(suite.T(), k.UnauthorizedError{}, err)
+	assert.Equal(suite.T(), "token was not signed with a trusted key", err.Error())
+}
+
+func (suite *JWTTestSuite) Test_GIVEN_rsaTokenAndOnlyASecret_WHEN_tokenIsVerified_THEN_tokenIsRejected() {
+	// GIVEN
+	token := suite.sign(map[string]interface{}{"alg": "RS256"}, suite.claims())
+
+	// WHEN
+	_, err := suite.verifier(NewSecretKeySet(suite.secret)).Verify(context.Background(), token)
+
+	// THEN
+	assert.IsType(suite.T(), k.UnauthorizedError{}, err)
+	assert.Equal(suite.T(), "token was not signed with a trusted key", err.Error())
+}
+
+func (suite *JWTTestSuite) Test_GIVEN_unsignedToken_WHEN_tokenIsVerified_THEN_tokenIsRejected() {
+	// GIVEN
+	token := suite.sign(map[string]interface{}{"alg": "none"}, suite.claims())
+
+	// WHEN
+	_, err := suite.verifier(NewSecretKeySet(suite.secret)).Verify(context.Background(), token)
+
+	// THEN
+	assert.IsType(suite.T(), k.UnauthorizedError{}, err)
+	assert.Equal(suite.T(), "token signature is invalid", err.Error())
+}
+
+func (suite *JWTTestSuite) Test_GIVEN_tamperedToken_WHEN_tokenIsVerified_THEN_tokenIsRejected() {
+	// GIVEN
+	token := suite.sign(map[string]interface{}{"alg": "HS256"}, suite.claims())
+	claims := suite.claims()
+	claims["roles"] = []string{"admin"}
+	tampered := suite.sign(map[string]interface{}{"alg": "HS256"}, claims)
+	token = tampered[:len(tampered)-43] + token[len(token)-43:]
+
+	// WHEN
+	_, err := suite.verifier(NewSecretKeySet(suite.secret)).Verify(context.Background(), token)
+
+	// THEN
+	assert.IsType(suite.T(), k.UnauthorizedError{}, err)
+	assert.Equal(suite.T(), "token signature is invalid", err.Error())
+}
+
+func (suite *JWTTestSuite) Test_GIVEN_expiredToken_WHEN_tokenIsVerified_THEN_tokenIsRejected() {
+	// GIVEN
+	claims := suite.claims()
+	claims["exp"] = suite.now.Add(-time.Minute).Unix()
+	token := suite.sign(map[string]interface{}{"alg": "HS256"}, claims)
+
+	// WHEN
+	_, err := suite.verifier(NewSecretKeySet(suite.secret)).Verify(context.Background(), token)
+
+	// THEN
+	assert.IsType(suite.T(), k.UnauthorizedError{}, err)
+	assert.Equal(suite.T(), "token has expired", err.Error())
+}
+
+func (suite *JWTTestSuite) Test_GIVEN_tokenExpiredWithinLeeway_WHEN_tokenIsVerified_THEN_tokenIsAccepted() {
+	// GIVEN
+	claims := suite.claims()
+	claims["exp"] = suite.now.Add(-10 * time.Second).Unix()
+	token := suite.sign(map[string]interface{}{"alg": "HS256"}, claims)
+
+	// WHEN
+	_, err := suite.verifier(NewSecretKeySet(suite.secret)).Verify(context.Background(), token)
+
+	// THEN
+	assert.Nil(suite.T(), err)
+}
+
+func (suite *JWTTestSuite) Test_GIVEN_tokenForAnotherAudience_WHEN_tokenIsVerified_THEN_tokenIsRejected() {
+	// GIVEN
+	claims := suite.claims()
+	claims["aud"] = "order-service"
+	token := suite.sign(map[string]interface{}{"alg": "HS256"}, claims)
+
+	// WHEN
+	_, err := suite.verifier(NewSecretKeySet(suite.secret)).Verify(context.Background(), token)
+
+	// THEN
+	assert.IsType(suite.T(), k.UnauthorizedError{}, err)
+	assert.Equal(suite.T(), `token is not intended for "kitchen-service"`, err.Error())
+}
+
+func (suite *JWTTestSuite) Test_GIVEN_tokenWithoutExpiry_WHEN_tokenIsVerified_THEN_tokenIsRejected() {
+	// GIVEN
+	claims := suite.claims()
+	delete(claims, "exp")
+	token := suite.sign(map[string]interface{}{"alg": "HS256"}, claims)
+
+	// WHEN
+	_, err := suite.verifier(NewSecretKeySet(suite.secret)).Verify(context.Background(), token)
+
+	// THEN
+	assert.IsType(suite.T(), k.UnauthorizedError{}, err)
+	assert.Equal(suite.T(), "token does not expire", err.Error())
+}
+
+func (suite *JWTTestSuite) Test_GIVEN_jwksFileWithUnsupportedKeys_WHEN_tokenIsVerified_THEN_signingKeyIsUsed() {
+	// GIVEN
+	path := filepath.Join(suite.T().TempDir(), "jwks.json")
+	jwks := suite.jwks[:len(suite.jwks)-2] + `,
+		{"kty":"oct","kid":"encryption","use":"enc","k":"c2VjcmV0"},
+		{"kty":"EC","kid":"curve","crv":"P-256"}
+	]}`
+	assert.Nil(suite.T(), ioutil.WriteFile(path, []byte(jwks), 0600))
+	keys, err := NewJWKSFile(path, time.Hour)
+	assert.Nil(suite.T(), err)
+	defer keys.Close()
+	token := suite.sign(map[string]interface{}{"alg": "RS256", "kid": "kitchen-1"}, suite.claims())
+
+	// WHEN
+	claims, err := suite.verifier(keys).Verify(context.Background(), token)
+
+	// THEN
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), "jack.torrence", claims.Subject)
+}
+
+func (suite *JWTTestSuite) Test_GIVEN_hmacTokenWithIdOfRSAKey_WHEN_tokenIsVerified_THEN_tokenIsRejected() {
+	// GIVEN
+	keys, err := NewJWKSURL(suite.jwksServer.URL, suite.jwksServer.Client(), time.Hour)
+	assert.Nil(suite.T(), err)
+	defer keys.Close()
+	token := suite.sign(map[string]interface{}{"alg": "HS256", "kid": "kitchen-1"}, suite.claims())
+
+	// WHEN
+	_, err = suite.verifier(NewKeySets(keys, NewSecretKeySet([]byte("redrum")))).Verify(context.Background(), token)
+
+	// THEN
+	assert.IsType(suite.T(), k.UnauthorizedError{}, err)
+	assert.Equal(suite.T(), "token signature is invalid", err.Error())
+}
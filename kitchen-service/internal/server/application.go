@@ -7,13 +7,23 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"time"
 
 	"github.com/gorilla/mux"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	cfg "github.com/w-k-s/McMicroservices/kitchen-service/internal/config"
 	msg "github.com/w-k-s/McMicroservices/kitchen-service/internal/messages"
+	"github.com/w-k-s/McMicroservices/kitchen-service/internal/metrics"
 	db "github.com/w-k-s/McMicroservices/kitchen-service/internal/persistence"
+	"github.com/w-k-s/McMicroservices/kitchen-service/internal/security"
+	"github.com/w-k-s/McMicroservices/kitchen-service/internal/telemetry"
 	"github.com/w-k-s/McMicroservices/kitchen-service/log"
 	svc "github.com/w-k-s/McMicroservices/kitchen-service/pkg/services"
+	"github.com/w-k-s/McMicroservices/kitchen-service/pkg/tracing"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.10.0"
+	"go.opentelemetry.io/otel/trace"
 )
 
 type appBuilder struct {
@@ -68,6 +78,14 @@ type App struct {
 	mux             *mux.Router
 	pool            *sql.DB
 	logger          log.Logger
+	tickets         *svc.TicketFeed
+	estimator       *svc.ReloadableEstimator
+	bulkThreshold   *db.BulkIncreaseThreshold
+	keyRetention    *db.IdempotencyKeyRetention
+	tracerProvider  *sdktrace.TracerProvider
+	health          *HealthChecks
+	brokerHealth    *brokerHealthChecker
+	verifier        *security.Verifier
 }
 
 func (app *App) Config() *cfg.Config {
@@ -82,12 +100,33 @@ func newApp(b *appBuilder) (*App, error) {
 	if err != nil {
 		return nil, err
 	}
+	if err = log.SetLevel(b.config.Logging().Level()); err != nil {
+		return nil, err
+	}
+
+	tracerProvider, err := telemetry.NewTracerProvider(b.config.Telemetry())
+	if err != nil {
+		return nil, err
+	}
+	tracing.SetTracerProvider(tracerProvider)
 
 	pool := db.Must(db.OpenPool(b.config.Database()))
 	db.MustRunMigrations(pool, b.config.Database())
 
 	mux := mux.NewRouter()
 	mux.Use(loggingMiddleware(logger))
+	mux.Use(metricsMiddleware())
+
+	var verifier *security.Verifier
+	if b.config.Auth().Disabled() {
+		logger.Printf("Authentication is disabled. Anyone who can reach the service can use the API")
+	} else {
+		if verifier, err = newVerifier(b.config.Auth()); err != nil {
+			return nil, fmt.Errorf("failed to configure authentication. Reason: %w", err)
+		}
+		mux.Use(authenticationMiddleware(verifier))
+	}
+	mux.Use(authorizationMiddleware())
 
 	app := &App{
 		config:          b.config,
@@ -96,11 +135,29 @@ func newApp(b *appBuilder) (*App, error) {
 		producerFactory: b.GetProducerFactory(),
 		pool:            pool,
 		logger:          logger,
+		tickets:         svc.NewTicketFeed(),
+		estimator:       svc.NewReloadableEstimator(preparationEstimator(b.config.Preparation())),
+		bulkThreshold:   db.NewBulkIncreaseThreshold(b.config.Stock().BulkIncreaseThreshold()),
+		keyRetention:    db.NewIdempotencyKeyRetention(b.config.Stock().IdempotencyKeyRetention()),
+		tracerProvider:  tracerProvider,
+		health:          NewHealthChecks(),
+		brokerHealth:    newBrokerHealthChecker(b.config.Broker(), brokerHealthCheckTimeout),
+		verifier:        verifier,
 	}
 
 	app.registerHealthEndpoint()
+	if err := app.registerMetricsEndpoint(); err != nil {
+		return nil, err
+	}
 	app.registerStockEndpoint()
 	app.registerOrderEndpoint()
+	app.registerPurchasingEndpoint()
+	app.registerTicketEndpoint()
+	app.registerStationEndpoint()
+
+	if err := checkRoutePermissions(mux); err != nil {
+		return nil, err
+	}
 
 	logger.Printf("--- Application Initialized ---")
 	return app, nil
@@ -142,6 +199,20 @@ func (app *App) ListenAndServe() {
 	}
 }
 
+// ApplyConfig applies the settings that can be changed while the application is running:
+// the log level, the preparation estimator, the order queue, and the bulk increase threshold and idempotency key retention of stock.
+// It can be subscribed to a cfg.ConfigWatcher.
+func (app *App) ApplyConfig(previous *cfg.Config, current *cfg.Config) {
+	if err := log.SetLevel(current.Logging().Level()); err != nil {
+		app.logger.Printf("Failed to change log level. Reason: %q", err.Error())
+	}
+	app.estimator.Set(preparationEstimator(current.Preparation()))
+	defaultOrderHandler.SetQueueConfig(current.Queue())
+	app.bulkThreshold.Set(current.Stock().BulkIncreaseThreshold())
+	app.keyRetention.Set(current.Stock().IdempotencyKeyRetention())
+	app.logger.Printf("Applied reloaded config")
+}
+
 func (app *App) Router() *mux.Router {
 	return app.mux
 }
@@ -158,53 +229,298 @@ func (app *App) Close() {
 	if err := defaultStockHandler.Close(); err != nil {
 		app.logger.Printf("Error while closing stock handler: %q", err)
 	}
+	if err := app.brokerHealth.Close(); err != nil {
+		app.logger.Printf("Error while closing broker health check: %q", err)
+	}
+	if app.verifier != nil {
+		app.verifier.Close()
+	}
 	if err := app.pool.Close(); err != nil {
 		app.logger.Printf("Failed to close connection pool. Reason: %q", err.Error())
 	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), app.config.Server().ShutdownGracePeriod())
+	defer cancel()
+	if err := app.tracerProvider.Shutdown(ctx); err != nil {
+		app.logger.Printf("Failed to export remaining spans. Reason: %q", err.Error())
+	}
 }
 
+// loggingMiddleware continues the trace of the request, or starts a new one, in a server span named after the matched route,
+// and logs the ids of the trace with every event.
+// The request id is echoed in the response so that callers can find the logs of their request.
 func loggingMiddleware(logger log.Logger) mux.MiddlewareFunc {
 	return mux.MiddlewareFunc(func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			req := r.WithContext(logger.
-				WithFields(map[string]interface{}{
-					"TraceId": "TODO",
-				}).
-				WithContext(r.Context()),
+			route := routeTemplate(r)
+			ctx := tracing.Extract(logger.WithContext(r.Context()), r.Header.Get(tracing.HeaderTraceParent), r.Header.Get(tracing.HeaderRequestId))
+			ctx, span := tracing.Start(ctx, r.Method+" "+route,
+				trace.WithSpanKind(trace.SpanKindServer),
+				trace.WithAttributes(
+					semconv.HTTPMethodKey.String(r.Method),
+					semconv.HTTPRouteKey.String(route),
+					semconv.HTTPTargetKey.String(r.URL.RequestURI()),
+				),
 			)
-			next.ServeHTTP(w, req)
+			defer span.End()
+
+			if t, ok := tracing.FromContext(ctx); ok {
+				w.Header().Set(tracing.HeaderRequestId, t.RequestId())
+			}
+
+			recorder := newStatusRecorder(w)
+			next.ServeHTTP(recorder, r.WithContext(ctx))
+
+			span.SetAttributes(semconv.HTTPAttributesFromHTTPStatusCode(recorder.status)...)
+			span.SetStatus(semconv.SpanStatusFromHTTPStatusCodeAndSpanKind(recorder.status, trace.SpanKindServer))
 		})
 	})
 }
 
+// routeTemplate is the path template of the route that matched the request, e.g. /kitchen/api/v1/stock/{name},
+// so that requests for different resources are grouped together.
+func routeTemplate(r *http.Request) string {
+	if route := mux.CurrentRoute(r); route != nil {
+		if template, err := route.GetPathTemplate(); err == nil {
+			return template
+		}
+	}
+	return r.URL.Path
+}
+
+func newVerifier(authConfig cfg.AuthConfig) (*security.Verifier, error) {
+	// The key sets are tried in order, so the keys with ids come before the secret that can verify any HS256 token.
+	keySets := []security.KeySet{}
+	if len(authConfig.JWKSFile()) > 0 {
+		keySet, err := security.NewJWKSFile(authConfig.JWKSFile(), authConfig.JWKSRefreshInterval())
+		if err != nil {
+			return nil, err
+		}
+		keySets = append(keySets, keySet)
+	}
+	if len(authConfig.JWKSURL()) > 0 {
+		keySet, err := security.NewJWKSURL(authConfig.JWKSURL(), &http.Client{Timeout: 5 * time.Second}, authConfig.JWKSRefreshInterval())
+		if err != nil {
+			security.NewKeySets(keySets...).Close()
+			return nil, err
+		}
+		keySets = append(keySets, keySet)
+	}
+	if len(authConfig.Secret()) > 0 {
+		keySets = append(keySets, security.NewSecretKeySet([]byte(authConfig.Secret())))
+	}
+	return security.NewVerifier(
+		security.NewKeySets(keySets...),
+		authConfig.Issuer(),
+		authConfig.Audience(),
+		authConfig.Leeway(),
+	), nil
+}
+
+// brokerHealthCheckTimeout is how long the Kafka health check may take. The client of the check is configured to give up within it.
+const brokerHealthCheckTimeout = 3 * time.Second
+
+// registerHealthEndpoint registers the checks of the database and the brokers. The consumers register their own checks when they start.
+// There is no check of outbox lag because the kitchen publishes its events straight to the broker and does not have an outbox.
 func (app *App) registerHealthEndpoint() {
-	healthHandler := MustHealthHandler(app.pool)
+	app.health.Register(HealthCheck{
+		Name:     "database",
+		Checker:  databaseHealthChecker(app.pool),
+		Critical: true,
+	})
+	app.health.Register(HealthCheck{
+		Name:    "kafka",
+		Checker: app.brokerHealth,
+		Timeout: brokerHealthCheckTimeout,
+	})
+
+	healthHandler := MustHealthHandler(app.health, app.verifier != nil)
 
 	app.mux.HandleFunc("/health", healthHandler.CheckHealth).
 		Methods("GET")
+	app.mux.HandleFunc("/health/live", healthHandler.CheckLiveness).
+		Methods("GET")
+	app.mux.HandleFunc("/health/ready", healthHandler.CheckReadiness).
+		Methods("GET")
+}
+
+// RegisterHealthCheck adds a check to the readiness of the application.
+func (app *App) RegisterHealthCheck(check HealthCheck) {
+	app.health.Register(check)
+}
+
+// registerMetricsEndpoint serves the metrics of the registry of the application.
+func (app *App) registerMetricsEndpoint() error {
+	registry, err := metrics.NewRegistry(
+		collectors.NewDBStatsCollector(app.pool, "kitchen"),
+		metrics.NewStockCollector(db.MustOpenStockDao(app.pool, app.bulkThreshold)),
+	)
+	if err != nil {
+		return err
+	}
+
+	app.mux.Handle("/metrics", promhttp.HandlerFor(registry, promhttp.HandlerOpts{})).
+		Methods("GET")
+	return nil
 }
 
 func (app *App) registerStockEndpoint() {
-	stockDao := db.MustOpenStockDao(app.pool)
-	stockService := svc.MustStockService(stockDao)
+	stockDao := db.MustOpenStockDao(app.pool, app.bulkThreshold)
+	purchasingDao := db.MustOpenPurchasingDao(app.pool, app.bulkThreshold, app.keyRetention)
+	stockService := svc.MustStockService(stockDao, purchasingDao)
+	consumer := msg.MustConsumer(app.consumerFactory(app.config.Broker()))
 	defaultStockHandler = NewStockHandler(
 		stockService,
-		msg.MustConsumer(app.consumerFactory(app.config.Broker())),
+		consumer,
 		app.logger,
 	)
+	app.health.Register(HealthCheck{
+		Name:    "inventoryConsumer",
+		Checker: consumerHealthChecker(consumer, TopicInventoryDelivery),
+	})
 
 	stockRouter := app.mux.PathPrefix("/kitchen/api/v1/stock").Subrouter()
 	stockRouter.HandleFunc("", defaultStockHandler.GetStock).
 		Methods("GET")
+	stockRouter.HandleFunc("", defaultStockHandler.CreateStockItem).
+		Methods("POST")
+	stockRouter.HandleFunc("/{name}", defaultStockHandler.GetStockItem).
+		Methods("GET")
+	stockRouter.HandleFunc("/{name}", defaultStockHandler.SetStockItem).
+		Methods("PUT")
+	stockRouter.HandleFunc("/{name}", defaultStockHandler.DeleteStockItem).
+		Methods("DELETE")
+	stockRouter.HandleFunc("/{name}/tags", defaultStockHandler.GetDietaryTags).
+		Methods("GET")
+	stockRouter.HandleFunc("/{name}/tags", defaultStockHandler.SetDietaryTags).
+		Methods("PUT")
+
+	itemRouter := app.mux.PathPrefix("/kitchen/api/v1/items").Subrouter()
+	itemRouter.HandleFunc("/{name}", defaultStockHandler.GetItem).
+		Methods("GET")
+	itemRouter.HandleFunc("/{name}", defaultStockHandler.SaveItem).
+		Methods("PUT")
+
+	locationRouter := app.mux.PathPrefix("/kitchen/api/v1/locations").Subrouter()
+	locationRouter.HandleFunc("/{location}/stock", defaultStockHandler.GetStock).
+		Methods("GET")
+	locationRouter.HandleFunc("/{location}/stock", defaultStockHandler.CreateStockItem).
+		Methods("POST")
+	locationRouter.HandleFunc("/{location}/stock/{name}", defaultStockHandler.GetStockItem).
+		Methods("GET")
+	locationRouter.HandleFunc("/{location}/stock/{name}", defaultStockHandler.SetStockItem).
+		Methods("PUT")
+	locationRouter.HandleFunc("/{location}/stock/{name}", defaultStockHandler.DeleteStockItem).
+		Methods("DELETE")
+
+	deliveryRouter := app.mux.PathPrefix("/kitchen/api/v1/deliveries").Subrouter()
+	deliveryRouter.HandleFunc("", defaultStockHandler.ReceiveDelivery).
+		Methods("POST")
+
+	transferRouter := app.mux.PathPrefix("/kitchen/api/v1/transfers").Subrouter()
+	transferRouter.HandleFunc("", defaultStockHandler.TransferStock).
+		Methods("POST")
+	transferRouter.HandleFunc("/{id:[0-9]+}", defaultStockHandler.GetTransfer).
+		Methods("GET")
+
+	substitutionRouter := app.mux.PathPrefix("/kitchen/api/v1/substitutions").Subrouter()
+	substitutionRouter.HandleFunc("", defaultStockHandler.GetSubstitutionRules).
+		Methods("GET")
+	substitutionRouter.HandleFunc("/{name}/{substitute}", defaultStockHandler.SaveSubstitutionRule).
+		Methods("PUT")
+	substitutionRouter.HandleFunc("/{name}/{substitute}", defaultStockHandler.DeleteSubstitutionRule).
+		Methods("DELETE")
 }
 
 func (app *App) registerOrderEndpoint() {
-	stockDao := db.MustOpenStockDao(app.pool)
-	orderService := svc.MustOrderService(stockDao)
+	orderDao := db.MustOpenOrderDao(app.pool)
+	orderService := svc.MustOrderService(orderDao, app.tickets, app.estimator)
+	consumer := msg.MustConsumer(app.consumerFactory(app.config.Broker()))
 	defaultOrderHandler = NewOrderHandler(
 		orderService,
-		msg.MustConsumer(app.consumerFactory(app.config.Broker())),
+		consumer,
 		msg.MustProducer(app.producerFactory(app.config.Broker())),
+		app.config.Queue(),
 		app.logger,
 	)
+	app.health.Register(HealthCheck{
+		Name:    "orderConsumer",
+		Checker: consumerHealthChecker(consumer, TopicCreateOrder),
+	})
+
+	orderRouter := app.mux.PathPrefix("/kitchen/api/v1/orders").Subrouter()
+	orderRouter.HandleFunc("/{id:[0-9]+}", defaultOrderHandler.GetOrder).
+		Methods("GET")
+
+	reportRouter := app.mux.PathPrefix("/kitchen/api/v1/reports").Subrouter()
+	reportRouter.HandleFunc("/cogs", defaultOrderHandler.GetCostOfGoodsReport).
+		Methods("GET")
+}
+
+func (app *App) registerTicketEndpoint() {
+	orderDao := db.MustOpenOrderDao(app.pool)
+	ticketHandler := NewTicketHandler(svc.MustTicketService(orderDao, app.tickets), app.config.Server().WriteTimeout())
+
+	ticketRouter := app.mux.PathPrefix("/kitchen/api/v1/tickets").Subrouter()
+	ticketRouter.HandleFunc("/stream", ticketHandler.StreamTickets).
+		Methods("GET")
+	ticketRouter.HandleFunc("/{orderId:[0-9]+}/bump", ticketHandler.BumpTicket).
+		Methods("POST")
+	ticketRouter.HandleFunc("/{orderId:[0-9]+}/recall", ticketHandler.RecallTicket).
+		Methods("POST")
+}
+
+func (app *App) registerStationEndpoint() {
+	orderDao := db.MustOpenOrderDao(app.pool)
+	stationHandler := NewStationHandler(svc.MustStationService(orderDao))
+
+	stationRouter := app.mux.PathPrefix("/kitchen/api/v1/stations").Subrouter()
+	stationRouter.HandleFunc("", stationHandler.GetStations).
+		Methods("GET")
+	stationRouter.HandleFunc("/{name}", stationHandler.SaveStation).
+		Methods("PUT")
+
+	toppingRouter := app.mux.PathPrefix("/kitchen/api/v1/toppings").Subrouter()
+	toppingRouter.HandleFunc("/{name}/task", stationHandler.SaveToppingTask).
+		Methods("PUT")
+	toppingRouter.HandleFunc("/{name}/task", stationHandler.DeleteToppingTask).
+		Methods("DELETE")
+}
+
+func (app *App) registerPurchasingEndpoint() {
+	purchasingDao := db.MustOpenPurchasingDao(app.pool, app.bulkThreshold, app.keyRetention)
+	purchasingService := svc.MustPurchasingService(purchasingDao)
+	purchasingHandler := NewPurchasingHandler(purchasingService)
+
+	supplierRouter := app.mux.PathPrefix("/kitchen/api/v1/suppliers").Subrouter()
+	supplierRouter.HandleFunc("", purchasingHandler.GetSuppliers).
+		Methods("GET")
+	supplierRouter.HandleFunc("", purchasingHandler.CreateSupplier).
+		Methods("POST")
+
+	purchaseOrderRouter := app.mux.PathPrefix("/kitchen/api/v1/purchase-orders").Subrouter()
+	purchaseOrderRouter.HandleFunc("", purchasingHandler.GetPurchaseOrders).
+		Methods("GET")
+	purchaseOrderRouter.HandleFunc("", purchasingHandler.CreatePurchaseOrder).
+		Methods("POST")
+	purchaseOrderRouter.HandleFunc("/{id:[0-9]+}", purchasingHandler.GetPurchaseOrder).
+		Methods("GET")
+	purchaseOrderRouter.HandleFunc("/{id:[0-9]+}/cancel", purchasingHandler.CancelPurchaseOrder).
+		Methods("POST")
+}
+
+func preparationEstimator(prepConfig cfg.PreparationConfig) svc.PreparationEstimator {
+	linear := svc.LinearEstimator{Base: prepConfig.Base(), PerItem: prepConfig.PerItem()}
+	switch prepConfig.Estimator() {
+	case cfg.EstimatorRecipe:
+		return svc.RecipeEstimator{Fallback: linear}
+	case cfg.EstimatorLearned:
+		return svc.LearnedEstimator{
+			Window:     prepConfig.LearnedWindow(),
+			MinSamples: uint(prepConfig.LearnedMinSamples()),
+			Fallback:   svc.RecipeEstimator{Fallback: linear},
+		}
+	default:
+		return linear
+	}
 }
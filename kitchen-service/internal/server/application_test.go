@@ -0,0 +1,132 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/Shopify/sarama"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+	msg "github.com/w-k-s/McMicroservices/kitchen-service/internal/messages"
+	"github.com/w-k-s/McMicroservices/kitchen-service/log"
+	k "github.com/w-k-s/McMicroservices/kitchen-service/pkg/kitchen"
+	"github.com/w-k-s/McMicroservices/kitchen-service/pkg/tracing"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	semconv "go.opentelemetry.io/otel/semconv/v1.10.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+type TracingTestSuite struct {
+	suite.Suite
+	router   *mux.Router
+	recorder *tracetest.SpanRecorder
+}
+
+func TestTracingTestSuite(t *testing.T) {
+	suite.Run(t, new(TracingTestSuite))
+}
+
+const testTraceParent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+
+func (suite *TracingTestSuite) SetupTest() {
+	suite.recorder = tracetest.NewSpanRecorder()
+	tracing.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(suite.recorder)))
+
+	suite.router = mux.NewRouter()
+	suite.router.Use(loggingMiddleware(log.NewLogger(os.Stdout)))
+	suite.router.HandleFunc("/kitchen/api/v1/stock/{name}", func(w http.ResponseWriter, req *http.Request) {
+		Handler{}.MustEncodeProblem(w, req, k.NewForbiddenError("not today"))
+	})
+}
+
+// -- SUITE
+
+func (suite *TracingTestSuite) Test_GIVEN_traceParentAndRequestId_WHEN_requestFails_THEN_idsAreEchoedInProblem() {
+	// GIVEN
+	req := httptest.NewRequest("GET", "/kitchen/api/v1/stock/Cheese", nil)
+	req.Header.Set(tracing.HeaderTraceParent, testTraceParent)
+	req.Header.Set(tracing.HeaderRequestId, "order-42")
+
+	// WHEN
+	w := httptest.NewRecorder()
+	suite.router.ServeHTTP(w, req)
+
+	// THEN
+	var problem map[string]interface{}
+	assert.Nil(suite.T(), json.Unmarshal(w.Body.Bytes(), &problem))
+	assert.Equal(suite.T(), "order-42", w.Header().Get(tracing.HeaderRequestId))
+	assert.Equal(suite.T(), "4bf92f3577b34da6a3ce929d0e0e4736", problem["traceId"])
+	assert.Equal(suite.T(), "order-42", problem["requestId"])
+}
+
+func (suite *TracingTestSuite) Test_GIVEN_noTraceHeaders_WHEN_requestFails_THEN_generatedIdsAreEchoed() {
+	// GIVEN
+	req := httptest.NewRequest("GET", "/kitchen/api/v1/stock/Cheese", nil)
+
+	// WHEN
+	w := httptest.NewRecorder()
+	suite.router.ServeHTTP(w, req)
+
+	// THEN
+	var problem map[string]interface{}
+	assert.Nil(suite.T(), json.Unmarshal(w.Body.Bytes(), &problem))
+	assert.Len(suite.T(), problem["traceId"], 32)
+	assert.Equal(suite.T(), problem["traceId"], problem["requestId"])
+	assert.Equal(suite.T(), problem["requestId"], w.Header().Get(tracing.HeaderRequestId))
+}
+
+func (suite *TracingTestSuite) Test_GIVEN_request_WHEN_served_THEN_serverSpanIsNamedAfterRoute() {
+	// GIVEN
+	req := httptest.NewRequest("GET", "/kitchen/api/v1/stock/Cheese", nil)
+	req.Header.Set(tracing.HeaderTraceParent, testTraceParent)
+
+	// WHEN
+	w := httptest.NewRecorder()
+	suite.router.ServeHTTP(w, req)
+
+	// THEN
+	ended := suite.recorder.Ended()
+	assert.Len(suite.T(), ended, 1)
+	assert.Equal(suite.T(), "GET /kitchen/api/v1/stock/{name}", ended[0].Name())
+	assert.Equal(suite.T(), trace.SpanKindServer, ended[0].SpanKind())
+	assert.Equal(suite.T(), "4bf92f3577b34da6a3ce929d0e0e4736", ended[0].SpanContext().TraceID().String())
+	assert.Contains(suite.T(), ended[0].Attributes(), semconv.HTTPRouteKey.String("/kitchen/api/v1/stock/{name}"))
+	assert.Contains(suite.T(), ended[0].Attributes(), semconv.HTTPStatusCodeKey.Int(http.StatusForbidden))
+}
+
+func (suite *TracingTestSuite) Test_GIVEN_tracedContext_WHEN_messageIsPublishedAndConsumed_THEN_traceIsContinued() {
+	// GIVEN
+	ctx, span := tracing.Start(tracing.Extract(context.Background(), testTraceParent, "order-42"), "OrderService.ScheduleOrder")
+	defer span.End()
+	traced, _ := tracing.FromContext(ctx)
+
+	// WHEN
+	ctx, producerSpan := msg.StartProducerSpan(ctx, TopicOrderReady)
+	producerSpan.End()
+	produced, _ := tracing.FromContext(ctx)
+	headers := msg.TraceHeaders(ctx)
+	consumed := &sarama.ConsumerMessage{Topic: TopicOrderReady}
+	for i := range headers {
+		consumed.Headers = append(consumed.Headers, &headers[i])
+	}
+	ctx, consumerSpan := msg.StartConsumerSpan(context.Background(), consumed)
+	consumerSpan.End()
+	continued, _ := tracing.FromContext(ctx)
+
+	// THEN
+	assert.Equal(suite.T(), traced.TraceId(), continued.TraceId())
+	assert.Equal(suite.T(), traced.SpanId(), produced.ParentId())
+	assert.Equal(suite.T(), produced.SpanId(), continued.ParentId())
+	assert.Equal(suite.T(), "order-42", continued.RequestId())
+}
+
+func (suite *TracingTestSuite) Test_GIVEN_untracedContext_WHEN_messageIsPublished_THEN_noHeadersAreAdded() {
+	// THEN
+	assert.Empty(suite.T(), msg.TraceHeaders(context.Background()))
+}
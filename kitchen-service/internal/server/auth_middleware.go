@@ -0,0 +1,64 @@
+package server
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"github.com/w-k-s/McMicroservices/kitchen-service/internal/security"
+	"github.com/w-k-s/McMicroservices/kitchen-service/pkg/auth"
+	k "github.com/w-k-s/McMicroservices/kitchen-service/pkg/kitchen"
+)
+
+const bearerPrefix = "Bearer "
+
+// authenticationMiddleware rejects requests that do not carry a valid bearer token
+// and puts the claims of the token in the context of the requests that do.
+// Health checks are not authenticated so that probes do not need a token,
+// but the claims of a valid token are put in the context so that the caller is shown the details of the checks.
+func authenticationMiddleware(verifier *security.Verifier) mux.MiddlewareFunc {
+	return mux.MiddlewareFunc(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			token, hasToken := bearerToken(req)
+
+			if isPublic(req.URL.Path) {
+				if hasToken {
+					if claims, err := verifier.Verify(req.Context(), token); err == nil {
+						req = req.WithContext(auth.WithClaims(req.Context(), claims))
+					}
+				}
+				next.ServeHTTP(w, req)
+				return
+			}
+
+			if !hasToken {
+				w.Header().Set("WWW-Authenticate", "Bearer")
+				Handler{}.MustEncodeProblem(w, req, k.NewUnauthorizedError("a bearer token is required"))
+				return
+			}
+
+			claims, err := verifier.Verify(req.Context(), token)
+			if err != nil {
+				w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+				Handler{}.MustEncodeProblem(w, req, err)
+				return
+			}
+
+			next.ServeHTTP(w, req.WithContext(auth.WithClaims(req.Context(), claims)))
+		})
+	})
+}
+
+// bearerToken returns the token in the Authorization header, if the header has a bearer token.
+func bearerToken(req *http.Request) (string, bool) {
+	header := req.Header.Get("Authorization")
+	if len(header) < len(bearerPrefix) || !strings.EqualFold(header[:len(bearerPrefix)], bearerPrefix) {
+		return "", false
+	}
+	return strings.TrimSpace(header[len(bearerPrefix):]), true
+}
+
+// isPublic is true for the paths that anyone can request, so that probes and scrapers do not need tokens.
+func isPublic(path string) bool {
+	return path == "/health" || strings.HasPrefix(path, "/health/") || path == "/metrics"
+}
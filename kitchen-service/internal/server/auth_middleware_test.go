@@ -0,0 +1,151 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+	"github.com/w-k-s/McMicroservices/kitchen-service/internal/security"
+	"github.com/w-k-s/McMicroservices/kitchen-service/pkg/auth"
+)
+
+const testSecret = "all work and no play"
+
+type AuthMiddlewareTestSuite struct {
+	suite.Suite
+	router *mux.Router
+}
+
+func TestAuthMiddlewareTestSuite(t *testing.T) {
+	suite.Run(t, new(AuthMiddlewareTestSuite))
+}
+
+func (suite *AuthMiddlewareTestSuite) SetupTest() {
+	verifier := security.NewVerifier(security.NewSecretKeySet([]byte(testSecret)), "", "", time.Minute)
+
+	suite.router = mux.NewRouter()
+	suite.router.Use(authenticationMiddleware(verifier))
+	suite.router.HandleFunc("/health", func(w http.ResponseWriter, req *http.Request) {
+		claims, _ := auth.ClaimsFromContext(req.Context())
+		_, _ = w.Write([]byte(claims.Subject))
+	})
+	suite.router.HandleFunc("/kitchen/api/v1/stock", func(w http.ResponseWriter, req *http.Request) {
+		claims, _ := auth.ClaimsFromContext(req.Context())
+		_, _ = w.Write([]byte(claims.Subject))
+	})
+}
+
+func signedToken(claims map[string]interface{}) string {
+	token, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims(claims)).SignedString([]byte(testSecret))
+	return token
+}
+
+// -- SUITE
+
+func (suite *AuthMiddlewareTestSuite) Test_GIVEN_noToken_WHEN_stockIsRequested_THEN_unauthorizedProblemIsReturned() {
+	// GIVEN
+	req := httptest.NewRequest("GET", "/kitchen/api/v1/stock", nil)
+
+	// WHEN
+	w := httptest.NewRecorder()
+	suite.router.ServeHTTP(w, req)
+
+	// THEN
+	assert.Equal(suite.T(), 401, w.Code)
+	assert.Equal(suite.T(), "Bearer", w.Header().Get("WWW-Authenticate"))
+	assert.Equal(suite.T(), "application/problem+json", w.Header().Get("Content-Type"))
+	assert.JSONEq(suite.T(), `{
+		"type": "/api/v1/problems/Unauthorized",
+		"status": 401,
+		"instance": "/kitchen/api/v1/stock",
+		"title": "Unauthorized",
+		"detail": "a bearer token is required"
+	}`, w.Body.String())
+}
+
+func (suite *AuthMiddlewareTestSuite) Test_GIVEN_invalidToken_WHEN_stockIsRequested_THEN_unauthorizedProblemIsReturned() {
+	// GIVEN
+	req := httptest.NewRequest("GET", "/kitchen/api/v1/stock", nil)
+	req.Header.Set("Authorization", "Bearer "+signedToken(map[string]interface{}{
+		"sub": "jack.torrence",
+		"exp": time.Now().Add(-time.Hour).Unix(),
+	}))
+
+	// WHEN
+	w := httptest.NewRecorder()
+	suite.router.ServeHTTP(w, req)
+
+	// THEN
+	assert.Equal(suite.T(), 401, w.Code)
+	assert.Equal(suite.T(), `Bearer error="invalid_token"`, w.Header().Get("WWW-Authenticate"))
+	assert.Contains(suite.T(), w.Body.String(), `"detail":"token has expired"`)
+}
+
+func (suite *AuthMiddlewareTestSuite) Test_GIVEN_validToken_WHEN_stockIsRequested_THEN_claimsAreInRequestContext() {
+	// GIVEN
+	req := httptest.NewRequest("GET", "/kitchen/api/v1/stock", nil)
+	req.Header.Set("Authorization", fmt.Sprintf("bearer %s", signedToken(map[string]interface{}{
+		"sub": "jack.torrence",
+		"exp": time.Now().Add(time.Hour).Unix(),
+	})))
+
+	// WHEN
+	w := httptest.NewRecorder()
+	suite.router.ServeHTTP(w, req)
+
+	// THEN
+	assert.Equal(suite.T(), 200, w.Code)
+	assert.Equal(suite.T(), "jack.torrence", w.Body.String())
+}
+
+func (suite *AuthMiddlewareTestSuite) Test_GIVEN_noToken_WHEN_healthIsRequested_THEN_requestIsNotAuthenticated() {
+	// GIVEN
+	req := httptest.NewRequest("GET", "/health", nil)
+
+	// WHEN
+	w := httptest.NewRecorder()
+	suite.router.ServeHTTP(w, req)
+
+	// THEN
+	assert.Equal(suite.T(), 200, w.Code)
+}
+
+func (suite *AuthMiddlewareTestSuite) Test_GIVEN_validToken_WHEN_healthIsRequested_THEN_claimsAreInRequestContext() {
+	// GIVEN
+	req := httptest.NewRequest("GET", "/health", nil)
+	req.Header.Set("Authorization", "Bearer "+signedToken(map[string]interface{}{
+		"sub": "jack.torrence",
+		"exp": time.Now().Add(time.Hour).Unix(),
+	}))
+
+	// WHEN
+	w := httptest.NewRecorder()
+	suite.router.ServeHTTP(w, req)
+
+	// THEN
+	assert.Equal(suite.T(), 200, w.Code)
+	assert.Equal(suite.T(), "jack.torrence", w.Body.String())
+}
+
+func (suite *AuthMiddlewareTestSuite) Test_GIVEN_invalidToken_WHEN_healthIsRequested_THEN_requestIsNotAuthenticated() {
+	// GIVEN
+	req := httptest.NewRequest("GET", "/health", nil)
+	req.Header.Set("Authorization", "Bearer "+signedToken(map[string]interface{}{
+		"sub": "jack.torrence",
+		"exp": time.Now().Add(-time.Hour).Unix(),
+	}))
+
+	// WHEN
+	w := httptest.NewRecorder()
+	suite.router.ServeHTTP(w, req)
+
+	// THEN
+	assert.Equal(suite.T(), 200, w.Code)
+	assert.Empty(suite.T(), w.Body.String())
+}
@@ -9,6 +9,7 @@ import (
 	"github.com/w-k-s/McMicroservices/kitchen-service/log"
 
 	k "github.com/w-k-s/McMicroservices/kitchen-service/pkg/kitchen"
+	"github.com/w-k-s/McMicroservices/kitchen-service/pkg/tracing"
 	"schneider.vip/problem"
 )
 
@@ -47,6 +48,9 @@ func (h Handler) MustEncodeProblem(w http.ResponseWriter, req *http.Request, err
 	for key, value := range errorFields(err) {
 		opts = append(opts, problem.Custom(key, value))
 	}
+	if trace, ok := tracing.FromContext(req.Context()); ok {
+		opts = append(opts, problem.Custom("traceId", trace.TraceId()), problem.Custom("requestId", trace.RequestId()))
+	}
 
 	if _, problemError := problem.New(
 		problem.Type(fmt.Sprintf("/api/v1/problems/%s", code)),
@@ -64,6 +68,16 @@ func (h Handler) MustEncodeProblem(w http.ResponseWriter, req *http.Request, err
 func httpStatus(err error) int {
 	if isInvalid(err) {
 		return 400
+	} else if isUnauthorized(err) {
+		return 401
+	} else if isForbidden(err) {
+		return 403
+	} else if isNotFound(err) {
+		return 404
+	} else if isPreconditionFailed(err) {
+		return 412
+	} else if isConflict(err) {
+		return 409
 	} else {
 		return 500
 	}
@@ -89,6 +103,56 @@ func isInvalid(err error) bool {
 	return false
 }
 
+func isNotFound(err error) bool {
+	type hasNotFound interface {
+		IsNotFoundError() bool
+	}
+	if notFoundError, ok := err.(hasNotFound); ok {
+		return notFoundError.IsNotFoundError()
+	}
+	return false
+}
+
+func isUnauthorized(err error) bool {
+	type hasUnauthorized interface {
+		IsUnauthorizedError() bool
+	}
+	if unauthorizedError, ok := err.(hasUnauthorized); ok {
+		return unauthorizedError.IsUnauthorizedError()
+	}
+	return false
+}
+
+func isForbidden(err error) bool {
+	type hasForbidden interface {
+		IsForbiddenError() bool
+	}
+	if forbiddenError, ok := err.(hasForbidden); ok {
+		return forbiddenError.IsForbiddenError()
+	}
+	return false
+}
+
+func isConflict(err error) bool {
+	type hasConflict interface {
+		IsConflictError() bool
+	}
+	if conflictError, ok := err.(hasConflict); ok {
+		return conflictError.IsConflictError()
+	}
+	return false
+}
+
+func isPreconditionFailed(err error) bool {
+	type hasPreconditionFailed interface {
+		IsPreconditionFailed() bool
+	}
+	if preconditionError, ok := err.(hasPreconditionFailed); ok {
+		return preconditionError.IsPreconditionFailed()
+	}
+	return false
+}
+
 func errorFields(err error) map[string]string {
 	type hasInvalidFields interface {
 		InvalidFields() map[string]string
@@ -0,0 +1,334 @@
+package server
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/Shopify/sarama"
+	cfg "github.com/w-k-s/McMicroservices/kitchen-service/internal/config"
+	dao "github.com/w-k-s/McMicroservices/kitchen-service/internal/persistence"
+	"github.com/w-k-s/McMicroservices/kitchen-service/log"
+)
+
+const (
+	defaultHealthCheckTimeout  = 2 * time.Second
+	defaultHealthCheckCacheFor = 5 * time.Second
+)
+
+// HealthChecker checks that a dependency of the kitchen can be used, and describes the dependency, e.g. with its version.
+// A checker returns a DegradedError if the dependency can be used, but not as well as it should be.
+type HealthChecker interface {
+	CheckHealth(ctx context.Context) (HealthDetails, error)
+}
+
+type HealthCheckerFunc func(ctx context.Context) (HealthDetails, error)
+
+func (f HealthCheckerFunc) CheckHealth(ctx context.Context) (HealthDetails, error) {
+	return f(ctx)
+}
+
+// HealthDetails describe a dependency in the details of its component in the health report.
+type HealthDetails map[string]interface{}
+
+// DegradedError means that a dependency can be used, but not as well as it should be, e.g. because some partitions are not being consumed.
+type DegradedError struct {
+	Cause error
+}
+
+func (e DegradedError) Error() string {
+	return e.Cause.Error()
+}
+
+func (e DegradedError) Unwrap() error {
+	return e.Cause
+}
+
+// HealthCheck is a checker that is registered under a name, with how long it may take and how long its result is reused.
+type HealthCheck struct {
+	Name    string
+	Checker HealthChecker
+	// Timeout is how long the check may take before it is DOWN. The default is 2 seconds.
+	Timeout time.Duration
+	// CacheFor is how long a result is reused so that frequent probes do not load the dependency. The default is 5 seconds.
+	CacheFor time.Duration
+	// Critical checks that fail make the kitchen DOWN, so that it is taken out of service.
+	// Other checks that fail make it DEGRADED.
+	Critical bool
+}
+
+type registeredHealthCheck struct {
+	HealthCheck
+	mu          sync.Mutex
+	health      ComponentHealth
+	checkedAt   time.Time
+	lastSuccess time.Time
+}
+
+// HealthChecks runs the checks that are registered with it side by side.
+type HealthChecks struct {
+	mu     sync.RWMutex
+	checks []*registeredHealthCheck
+	now    func() time.Time
+}
+
+func NewHealthChecks() *HealthChecks {
+	return &HealthChecks{now: time.Now}
+}
+
+// Register adds a check to the report. A check that is registered again under the same name replaces the earlier one.
+func (h *HealthChecks) Register(check HealthCheck) {
+	if check.Timeout <= 0 {
+		check.Timeout = defaultHealthCheckTimeout
+	}
+	if check.CacheFor <= 0 {
+		check.CacheFor = defaultHealthCheckCacheFor
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for i, registered := range h.checks {
+		if registered.Name == check.Name {
+			h.checks[i] = &registeredHealthCheck{HealthCheck: check}
+			return
+		}
+	}
+	h.checks = append(h.checks, &registeredHealthCheck{HealthCheck: check})
+}
+
+// Report returns the health of each check, reusing results that were checked recently.
+func (h *HealthChecks) Report(ctx context.Context) HealthReport {
+	h.mu.RLock()
+	checks := append([]*registeredHealthCheck{}, h.checks...)
+	h.mu.RUnlock()
+
+	var (
+		mu         sync.Mutex
+		wg         sync.WaitGroup
+		components = make(map[string]ComponentHealth)
+	)
+	for _, check := range checks {
+		wg.Add(1)
+		go func(check *registeredHealthCheck) {
+			defer wg.Done()
+			health := check.run(ctx, h.now)
+			mu.Lock()
+			components[check.Name] = health
+			mu.Unlock()
+		}(check)
+	}
+	wg.Wait()
+
+	report := HealthReport{Components: components}
+	report.Status = report.StatusReport().overallStatus()
+	return report
+}
+
+func (c *registeredHealthCheck) run(ctx context.Context, now func() time.Time) ComponentHealth {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !c.checkedAt.IsZero() && now().Sub(c.checkedAt) < c.CacheFor {
+		return c.health
+	}
+
+	start := now()
+	details, err := c.check(ctx)
+	c.checkedAt = now()
+
+	health := ComponentHealth{Status: up, Details: HealthDetails{}}
+	for key, value := range details {
+		health.Details[key] = value
+	}
+	health.Details["latency"] = c.checkedAt.Sub(start).String()
+	if err == nil {
+		c.lastSuccess = c.checkedAt
+	} else {
+		health.Status = c.failedStatus(err)
+		health.Details["error"] = err.Error()
+	}
+	if !c.lastSuccess.IsZero() {
+		health.Details["lastSuccess"] = c.lastSuccess.UTC().Format(time.RFC3339)
+	}
+
+	c.health = health
+	return c.health
+}
+
+// check is not bound to the request, so that a probe that hangs up does not leave a DOWN result in the cache.
+func (c *registeredHealthCheck) check(ctx context.Context) (HealthDetails, error) {
+	checkCtx, cancel := context.WithTimeout(context.Background(), c.Timeout)
+	defer cancel()
+
+	type result struct {
+		details HealthDetails
+		err     error
+	}
+
+	// Checkers that do not honour the context, such as Kafka metadata requests, are abandoned when they time out.
+	results := make(chan result, 1)
+	go func() {
+		details, err := c.Checker.CheckHealth(checkCtx)
+		results <- result{details, err}
+	}()
+
+	select {
+	case r := <-results:
+		if r.err != nil {
+			log.ErrCtx(ctx, r.err).
+				Str("check", c.Name).
+				Msg("Health check failed")
+		}
+		return r.details, r.err
+	case <-checkCtx.Done():
+		err := fmt.Errorf("health check timed out after %s", c.Timeout)
+		log.ErrCtx(ctx, err).
+			Str("check", c.Name).
+			Msg("Health check failed")
+		return nil, err
+	}
+}
+
+func (c *registeredHealthCheck) failedStatus(err error) status {
+	var degradedErr DegradedError
+	if errors.As(err, &degradedErr) || !c.Critical {
+		return degraded
+	}
+	return down
+}
+
+// databaseHealthChecker pings the database once, unlike PingWithBackOff, and describes the server and the migrations that were applied.
+// A schema that was left dirty by a failed migration is not healthy.
+func databaseHealthChecker(db *sql.DB) HealthChecker {
+	return HealthCheckerFunc(func(ctx context.Context) (HealthDetails, error) {
+		details := HealthDetails{"database": "PostgreSQL"}
+		if err := db.PingContext(ctx); err != nil {
+			return details, err
+		}
+
+		version, err := dao.ServerVersion(ctx, db)
+		if err != nil {
+			return details, err
+		}
+		details["version"] = version
+
+		migration, dirty, err := dao.MigrationVersion(ctx, db)
+		if err != nil {
+			return details, err
+		}
+		details["migrationVersion"] = migration
+		if dirty {
+			return details, fmt.Errorf("migration %d failed part way and left the schema dirty", migration)
+		}
+		return details, nil
+	})
+}
+
+// brokerHealthChecker refreshes the metadata of the Kafka cluster.
+// Its client is created by the first check so that the kitchen can start while the brokers are unavailable.
+type brokerHealthChecker struct {
+	brokerConfig cfg.BrokerConfig
+	saramaConfig *sarama.Config
+	// running is 1 while a check is in progress. A check that is abandoned when it times out keeps running,
+	// and the next checks are skipped until it finishes rather than queueing behind it.
+	running int32
+	mu      sync.Mutex
+	client  sarama.Client
+}
+
+// newBrokerHealthChecker configures the client so that a check finishes within the timeout.
+func newBrokerHealthChecker(brokerConfig cfg.BrokerConfig, timeout time.Duration) *brokerHealthChecker {
+	return &brokerHealthChecker{
+		brokerConfig: brokerConfig,
+		saramaConfig: brokerHealthConfig(timeout),
+	}
+}
+
+// brokerHealthConfig splits the timeout between the metadata request and the connection timeouts,
+// because a request that starts just before Metadata.Timeout can still take Net.DialTimeout, Net.WriteTimeout and Net.ReadTimeout.
+func brokerHealthConfig(timeout time.Duration) *sarama.Config {
+	config := sarama.NewConfig()
+	config.Net.DialTimeout = timeout / 4
+	config.Net.WriteTimeout = timeout / 4
+	config.Net.ReadTimeout = timeout / 4
+	config.Metadata.Timeout = timeout / 4
+	config.Metadata.Retry.Max = 0
+	// The brokers are all that is checked, so the metadata of the topics is not fetched.
+	config.Metadata.Full = false
+	return config
+}
+
+func (b *brokerHealthChecker) CheckHealth(ctx context.Context) (HealthDetails, error) {
+	if !atomic.CompareAndSwapInt32(&b.running, 0, 1) {
+		return nil, fmt.Errorf("the previous Kafka health check has not finished")
+	}
+	defer atomic.StoreInt32(&b.running, 0)
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.client == nil {
+		client, err := sarama.NewClient(b.brokerConfig.BootstrapServers(), b.saramaConfig)
+		if err != nil {
+			return nil, err
+		}
+		b.client = client
+	}
+	if err := b.client.RefreshMetadata(); err != nil {
+		return nil, err
+	}
+
+	brokerIds := []int32{}
+	for _, broker := range b.client.Brokers() {
+		brokerIds = append(brokerIds, broker.ID())
+	}
+	sort.Slice(brokerIds, func(i, j int) bool { return brokerIds[i] < brokerIds[j] })
+
+	details := HealthDetails{"brokerIds": brokerIds}
+	if len(brokerIds) == 0 {
+		return details, fmt.Errorf("no Kafka brokers are available")
+	}
+	return details, nil
+}
+
+func (b *brokerHealthChecker) Close() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.client == nil {
+		return nil
+	}
+	return b.client.Close()
+}
+
+// consumerHealthChecker checks that a partition consumer is running for every partition of the topic.
+// The kitchen consumes partitions directly rather than as a member of a consumer group, so this stands in for group membership.
+func consumerHealthChecker(consumer sarama.Consumer, topic string) HealthChecker {
+	return HealthCheckerFunc(func(ctx context.Context) (HealthDetails, error) {
+		details := HealthDetails{"topic": topic}
+		partitions, err := consumer.Partitions(topic)
+		if err != nil {
+			return details, err
+		}
+		details["partitions"] = len(partitions)
+
+		consumed := consumer.HighWaterMarks()[topic]
+		missing := []string{}
+		for _, partition := range partitions {
+			if _, ok := consumed[partition]; !ok {
+				missing = append(missing, strconv.Itoa(int(partition)))
+			}
+		}
+		if len(missing) > 0 {
+			return details, DegradedError{fmt.Errorf("partitions %s of %q are not being consumed", strings.Join(missing, ", "), topic)}
+		}
+		return details, nil
+	})
+}
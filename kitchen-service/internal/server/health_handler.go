@@ -1,68 +1,77 @@
 package server
 
 import (
-	"context"
-	"database/sql"
 	"encoding/json"
 	"fmt"
 	"net/http"
 
 	"github.com/w-k-s/McMicroservices/kitchen-service/log"
-
-	dao "github.com/w-k-s/McMicroservices/kitchen-service/internal/persistence"
+	"github.com/w-k-s/McMicroservices/kitchen-service/pkg/auth"
 )
 
 type healthHandler struct {
 	Handler
-	db *sql.DB
+	checks       *HealthChecks
+	authRequired bool
 }
 
-func MustHealthHandler(db *sql.DB) healthHandler {
-	if db == nil {
-		log.Fatalf("healthHandler received db: nil. non-nil db expected")
+// MustHealthHandler reports the health checks. If authRequired is true, only callers with a verified token are shown the details of each check.
+func MustHealthHandler(checks *HealthChecks, authRequired bool) healthHandler {
+	if checks == nil {
+		log.Fatalf("healthHandler received checks: nil. non-nil checks expected")
 	}
-	return healthHandler{Handler{}, db}
+	return healthHandler{Handler{}, checks, authRequired}
 }
 
-func (h healthHandler) CheckHealth(w http.ResponseWriter, req *http.Request) {
-
-	report := make(StatusReport)
-	report["database"] = h.databaseStatusReport(req.Context())
+// CheckLiveness reports that the process can serve requests. It does not check dependencies,
+// so that the kitchen is not restarted when a dependency is unavailable.
+func (h healthHandler) CheckLiveness(w http.ResponseWriter, req *http.Request) {
+	h.MustEncodeJson(w, StatusReport{"status": up}, http.StatusOK)
+}
 
-	h.MustEncodeJson(w, report, report.overallStatus().HttpCode())
+// CheckReadiness reports the status of each registered check. The kitchen is ready unless a critical check is DOWN.
+// The detailed report, with errors and versions of the dependencies, is requested with ?details=true.
+// Anyone can request the health of the kitchen, so the details are only shown to authenticated callers.
+func (h healthHandler) CheckReadiness(w http.ResponseWriter, req *http.Request) {
+	report := h.checks.Report(req.Context())
+	if req.URL.Query().Get("details") == "true" && h.mayViewDetails(req) {
+		h.MustEncodeJson(w, report, report.Status.HttpCode())
+		return
+	}
+	h.MustEncodeJson(w, report.StatusReport(), report.Status.HttpCode())
 }
 
-func (h healthHandler) databaseStatusReport(ctx context.Context) status {
-	if err := dao.PingWithBackOff(h.db); err != nil {
-		log.ErrCtx(ctx, err).
-			Msg("Ping failed for health check")
-		return down
+func (h healthHandler) mayViewDetails(req *http.Request) bool {
+	if !h.authRequired {
+		return true
 	}
-	return up
+	_, authenticated := auth.ClaimsFromContext(req.Context())
+	return authenticated
+}
+
+// CheckHealth is CheckReadiness, for callers of /health.
+func (h healthHandler) CheckHealth(w http.ResponseWriter, req *http.Request) {
+	h.CheckReadiness(w, req)
 }
 
-type status bool
+type status string
 
 const (
-	up   status = true
-	down status = false
+	up       status = "UP"
+	degraded status = "DEGRADED"
+	down     status = "DOWN"
 )
 
 func (s status) String() string {
-	if s == up {
-		return "UP"
-	}
-	return "DOWN"
+	return string(s)
 }
 
 func (s status) MarshalJSON() ([]byte, error) {
 	switch s {
-	case up:
-		fallthrough
-	case down:
+	case up, degraded, down:
 		return json.Marshal(s.String())
 	default:
-		return nil, fmt.Errorf("invalid status: %s", s)
+		return nil, fmt.Errorf("invalid status: %s", string(s))
 	}
 }
 
@@ -71,32 +80,58 @@ func (s *status) UnmarshalJSON(b []byte) error {
 	if err := json.Unmarshal(b, &str); err != nil {
 		return err
 	}
-	switch str {
-	case "UP":
-		*s = up
-	case "DOWN":
-		*s = down
+	switch status(str) {
+	case up, degraded, down:
+		*s = status(str)
 	default:
 		return fmt.Errorf("invalid status: %s", str)
 	}
 	return nil
 }
 
+// HttpCode is OK for a DEGRADED kitchen, so that it keeps serving the requests that it can.
 func (s status) HttpCode() int {
 	switch s {
-	case up:
+	case up, degraded:
 		return http.StatusOK
 	default:
 		return http.StatusInternalServerError
 	}
 }
 
+// StatusReport is the simple form of the health report, with only the status of each component.
 type StatusReport map[string]status
 
+// overallStatus is DOWN if any status is DOWN, otherwise DEGRADED if any status is DEGRADED.
 func (report StatusReport) overallStatus() status {
 	overall := up
 	for _, status := range report {
-		overall = overall && status
+		switch status {
+		case down:
+			return down
+		case degraded:
+			overall = degraded
+		}
 	}
 	return overall
 }
+
+// ComponentHealth is the health of a dependency, with details such as how long it took to check and when it was last healthy.
+type ComponentHealth struct {
+	Status  status        `json:"status"`
+	Details HealthDetails `json:"details,omitempty"`
+}
+
+// HealthReport is the detailed form of the health report, in the format of the Spring Boot Actuator health endpoint of the other services.
+type HealthReport struct {
+	Status     status                     `json:"status"`
+	Components map[string]ComponentHealth `json:"components"`
+}
+
+func (report HealthReport) StatusReport() StatusReport {
+	statusReport := make(StatusReport)
+	for name, component := range report.Components {
+		statusReport[name] = component.Status
+	}
+	return statusReport
+}
@@ -1,11 +1,18 @@
 package server
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"net/http/httptest"
+	"sync/atomic"
 	"testing"
+	"time"
 
+	"github.com/gorilla/mux"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/suite"
+	"github.com/w-k-s/McMicroservices/kitchen-service/pkg/auth"
 )
 
 type HealthHandlerTestSuite struct {
@@ -51,3 +58,214 @@ func (suite *HealthHandlerTestSuite) Test_GIVEN_statusReport_WHEN_statusIsDown_T
 	statusJson := string(bytes)
 	assert.Equal(suite.T(), "{\"database\":\"DOWN\"}", statusJson)
 }
+
+func (suite *HealthHandlerTestSuite) Test_GIVEN_statusReport_WHEN_statusIsDegraded_THEN_jsonEncodedCorrectlyAndHttpStatusIsOk() {
+	// GIVEN
+	report := make(StatusReport)
+
+	// WHEN
+	report["database"] = up
+	report["kafka"] = degraded
+
+	// THEN
+	bytes, err := json.Marshal(report)
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), degraded, report.overallStatus())
+	assert.Equal(suite.T(), 200, report.overallStatus().HttpCode())
+	assert.Equal(suite.T(), "{\"database\":\"UP\",\"kafka\":\"DEGRADED\"}", string(bytes))
+
+	report["database"] = down
+	assert.Equal(suite.T(), down, report.overallStatus())
+}
+
+func (suite *HealthHandlerTestSuite) Test_GIVEN_failingChecks_WHEN_reported_THEN_onlyCriticalChecksAreDown() {
+	// GIVEN
+	failing := HealthCheckerFunc(func(ctx context.Context) (HealthDetails, error) {
+		return nil, errors.New("connection refused")
+	})
+	checks := NewHealthChecks()
+	checks.Register(HealthCheck{Name: "database", Checker: failing, Critical: true})
+	checks.Register(HealthCheck{Name: "kafka", Checker: failing})
+	checks.Register(HealthCheck{Name: "orderConsumer", Checker: HealthCheckerFunc(func(ctx context.Context) (HealthDetails, error) {
+		return nil, DegradedError{errors.New("partitions 1 of \"order_created\" are not being consumed")}
+	}), Critical: true})
+
+	// WHEN
+	report := checks.Report(context.Background())
+
+	// THEN
+	assert.Equal(suite.T(), StatusReport{"database": down, "kafka": degraded, "orderConsumer": degraded}, report.StatusReport())
+	assert.Equal(suite.T(), down, report.Status)
+	assert.Equal(suite.T(), "connection refused", report.Components["database"].Details["error"])
+}
+
+func (suite *HealthHandlerTestSuite) Test_GIVEN_slowCheck_WHEN_reported_THEN_checkIsDownAfterItsTimeout() {
+	// GIVEN
+	release := make(chan struct{})
+	defer close(release)
+	checks := NewHealthChecks()
+	checks.Register(HealthCheck{
+		Name: "kafka",
+		Checker: HealthCheckerFunc(func(ctx context.Context) (HealthDetails, error) {
+			<-release
+			return nil, nil
+		}),
+		Timeout:  10 * time.Millisecond,
+		Critical: true,
+	})
+
+	// WHEN
+	start := time.Now()
+	report := checks.Report(context.Background())
+
+	// THEN
+	assert.Equal(suite.T(), down, report.Components["kafka"].Status)
+	assert.Less(suite.T(), time.Since(start), time.Second)
+}
+
+func (suite *HealthHandlerTestSuite) Test_GIVEN_recentResult_WHEN_reported_THEN_resultIsReusedUntilItExpires() {
+	// GIVEN
+	var calls int32
+	now := time.Date(2022, 5, 1, 12, 0, 0, 0, time.UTC)
+	checks := NewHealthChecks()
+	checks.now = func() time.Time { return now }
+	checks.Register(HealthCheck{
+		Name: "database",
+		Checker: HealthCheckerFunc(func(ctx context.Context) (HealthDetails, error) {
+			atomic.AddInt32(&calls, 1)
+			return nil, nil
+		}),
+		CacheFor: 5 * time.Second,
+	})
+
+	// WHEN
+	checks.Report(context.Background())
+	now = now.Add(4 * time.Second)
+	checks.Report(context.Background())
+
+	// THEN
+	assert.Equal(suite.T(), int32(1), atomic.LoadInt32(&calls))
+
+	// WHEN
+	now = now.Add(time.Second)
+	checks.Report(context.Background())
+
+	// THEN
+	assert.Equal(suite.T(), int32(2), atomic.LoadInt32(&calls))
+}
+
+func (suite *HealthHandlerTestSuite) Test_GIVEN_checks_WHEN_detailsAreRequested_THEN_detailedReportIsInActuatorFormat() {
+	// GIVEN
+	healthy := true
+	now := time.Date(2022, 5, 1, 12, 0, 0, 0, time.UTC)
+	checks := NewHealthChecks()
+	checks.now = func() time.Time { return now }
+	checks.Register(HealthCheck{
+		Name: "database",
+		Checker: HealthCheckerFunc(func(ctx context.Context) (HealthDetails, error) {
+			now = now.Add(15 * time.Millisecond)
+			if !healthy {
+				return HealthDetails{"database": "PostgreSQL"}, errors.New("connection refused")
+			}
+			return HealthDetails{"database": "PostgreSQL", "version": "11.6", "migrationVersion": 13}, nil
+		}),
+		CacheFor: time.Second,
+		Critical: true,
+	})
+	router := mux.NewRouter()
+	router.HandleFunc("/health/ready", MustHealthHandler(checks, true).CheckReadiness)
+	checks.Report(context.Background())
+
+	// WHEN
+	healthy = false
+	now = now.Add(time.Minute)
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest("GET", "/health/ready?details=true", nil)
+	router.ServeHTTP(w, req.WithContext(auth.WithClaims(req.Context(), auth.Claims{Subject: "ops"})))
+
+	// THEN
+	assert.Equal(suite.T(), 500, w.Code)
+	assert.JSONEq(suite.T(), `{
+		"status": "DOWN",
+		"components": {
+			"database": {
+				"status": "DOWN",
+				"details": {
+					"database": "PostgreSQL",
+					"latency": "15ms",
+					"error": "connection refused",
+					"lastSuccess": "2022-05-01T12:00:00Z"
+				}
+			}
+		}
+	}`, w.Body.String())
+}
+
+func (suite *HealthHandlerTestSuite) Test_GIVEN_checks_WHEN_readinessIsRequested_THEN_onlyStatusOfEachComponentIsReported() {
+	// GIVEN
+	checks := NewHealthChecks()
+	checks.Register(HealthCheck{
+		Name: "database",
+		Checker: HealthCheckerFunc(func(ctx context.Context) (HealthDetails, error) {
+			return HealthDetails{"version": "11.6"}, nil
+		}),
+	})
+	router := mux.NewRouter()
+	router.HandleFunc("/health/ready", MustHealthHandler(checks, false).CheckReadiness)
+
+	// WHEN
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/health/ready", nil))
+
+	// THEN
+	assert.Equal(suite.T(), 200, w.Code)
+	assert.JSONEq(suite.T(), `{"database":"UP"}`, w.Body.String())
+}
+
+func (suite *HealthHandlerTestSuite) Test_GIVEN_anonymousCaller_WHEN_detailsAreRequested_THEN_onlyStatusOfEachComponentIsReported() {
+	// GIVEN
+	checks := NewHealthChecks()
+	checks.Register(HealthCheck{
+		Name: "database",
+		Checker: HealthCheckerFunc(func(ctx context.Context) (HealthDetails, error) {
+			return HealthDetails{"version": "11.6"}, errors.New("connection refused")
+		}),
+		Critical: true,
+	})
+	router := mux.NewRouter()
+	router.HandleFunc("/health/ready", MustHealthHandler(checks, true).CheckReadiness)
+
+	// WHEN
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/health/ready?details=true", nil))
+
+	// THEN
+	assert.Equal(suite.T(), 500, w.Code)
+	assert.JSONEq(suite.T(), `{"database":"DOWN"}`, w.Body.String())
+}
+
+func (suite *HealthHandlerTestSuite) Test_GIVEN_timeout_WHEN_brokerHealthIsConfigured_THEN_metadataRequestFinishesWithinTimeout() {
+	// GIVEN
+	timeout := 3 * time.Second
+
+	// WHEN
+	config := brokerHealthConfig(timeout)
+
+	// THEN
+	assert.Nil(suite.T(), config.Validate())
+	assert.Equal(suite.T(), 0, config.Metadata.Retry.Max)
+	assert.LessOrEqual(suite.T(), config.Metadata.Timeout+config.Net.DialTimeout+config.Net.WriteTimeout+config.Net.ReadTimeout, timeout)
+}
+
+func (suite *HealthHandlerTestSuite) Test_GIVEN_runningBrokerCheck_WHEN_checkedAgain_THEN_checkIsSkipped() {
+	// GIVEN
+	checker := newBrokerHealthChecker(nil, time.Second)
+	checker.running = 1
+
+	// WHEN
+	_, err := checker.CheckHealth(context.Background())
+
+	// THEN
+	assert.EqualError(suite.T(), err, "the previous Kafka health check has not finished")
+	assert.Nil(suite.T(), checker.client)
+}
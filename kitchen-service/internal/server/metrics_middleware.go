@@ -0,0 +1,25 @@
+package server
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/w-k-s/McMicroservices/kitchen-service/internal/metrics"
+)
+
+// metricsMiddleware measures how long each request takes, labelled by the route that matched it and the status of the response.
+func metricsMiddleware() mux.MiddlewareFunc {
+	return mux.MiddlewareFunc(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			start := time.Now()
+			recorder := newStatusRecorder(w)
+			next.ServeHTTP(recorder, req)
+
+			metrics.HTTPRequestDuration.
+				WithLabelValues(req.Method, routeTemplate(req), strconv.Itoa(recorder.status)).
+				Observe(time.Since(start).Seconds())
+		})
+	})
+}
@@ -0,0 +1,55 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+	"github.com/w-k-s/McMicroservices/kitchen-service/internal/metrics"
+)
+
+type MetricsTestSuite struct {
+	suite.Suite
+	router *mux.Router
+}
+
+func TestMetricsTestSuite(t *testing.T) {
+	suite.Run(t, new(MetricsTestSuite))
+}
+
+func (suite *MetricsTestSuite) SetupTest() {
+	suite.router = mux.NewRouter()
+	suite.router.Use(metricsMiddleware())
+	suite.router.HandleFunc("/kitchen/api/v1/transfers/{id:[0-9]+}", func(w http.ResponseWriter, req *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}).Methods("GET")
+}
+
+func sampleCount(observer prometheus.Observer) uint64 {
+	var m dto.Metric
+	if err := observer.(prometheus.Metric).Write(&m); err != nil {
+		return 0
+	}
+	return m.GetHistogram().GetSampleCount()
+}
+
+// -- SUITE
+
+func (suite *MetricsTestSuite) Test_GIVEN_requestsForDifferentResources_WHEN_served_THEN_durationsAreLabelledByRoute() {
+	// GIVEN
+	observer := metrics.HTTPRequestDuration.WithLabelValues("GET", "/kitchen/api/v1/transfers/{id:[0-9]+}", "404")
+	before := sampleCount(observer)
+
+	// WHEN
+	for _, path := range []string{"/kitchen/api/v1/transfers/1", "/kitchen/api/v1/transfers/2"} {
+		suite.router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", path, nil))
+	}
+
+	// THEN
+	assert.Equal(suite.T(), before+2, sampleCount(observer))
+}
@@ -4,22 +4,43 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
 
 	"github.com/w-k-s/McMicroservices/kitchen-service/log"
 
 	"github.com/Shopify/sarama"
+	"github.com/gorilla/mux"
+	cfg "github.com/w-k-s/McMicroservices/kitchen-service/internal/config"
+	msg "github.com/w-k-s/McMicroservices/kitchen-service/internal/messages"
+	"github.com/w-k-s/McMicroservices/kitchen-service/internal/metrics"
+	k "github.com/w-k-s/McMicroservices/kitchen-service/pkg/kitchen"
 	svc "github.com/w-k-s/McMicroservices/kitchen-service/pkg/services"
+	"github.com/w-k-s/McMicroservices/kitchen-service/pkg/tracing"
 	"go.uber.org/multierr"
 )
 
 const (
-	TopicCreateOrder string = "order_created"
-	TopicOrderReady  string = "order_ready"
-	TopicOrderFailed string = "order_failed"
+	TopicCreateOrder    string = "order_created"
+	TopicOrderPreparing string = "order_preparing"
+	TopicOrderReady     string = "order_ready"
+	TopicOrderFailed    string = "order_failed"
+	TopicOrderAtRisk    string = "order_at_risk"
+
+	// HeaderLocation routes an order to a location when the order itself does not specify one.
+	HeaderLocation string = "location"
 )
 
 type OrderHandler interface {
-	HandleOrderMessage(ctx context.Context, request []byte) (string, []byte)
+	HandleOrderMessage(ctx context.Context, message *sarama.ConsumerMessage) (string, []byte)
+	GetOrder(w http.ResponseWriter, req *http.Request)
+	GetCostOfGoodsReport(w http.ResponseWriter, req *http.Request)
+	// SetQueueConfig changes how the orders that are received from now on are queued and scheduled.
+	SetQueueConfig(queueConfig cfg.QueueConfig)
 	Close() error
 }
 
@@ -28,29 +49,64 @@ type orderHandler struct {
 	orderService svc.OrderService
 	consumer     sarama.Consumer
 	producer     sarama.SyncProducer
+	queue        *orderQueue
+	queueConfig  *queueSettings
 	cancelFunc   context.CancelFunc
+	// reportedAtRisk holds the ids of the orders that were reported at risk while they were queued,
+	// so that they are not reported again when they are scheduled.
+	reportedAtRisk *sync.Map
+}
+
+// queueSettings is the queue config of a handler, which can be changed while the handler is running.
+type queueSettings struct {
+	mu     sync.RWMutex
+	config cfg.QueueConfig
+}
+
+func (s *queueSettings) get() cfg.QueueConfig {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.config
+}
+
+func (s *queueSettings) set(config cfg.QueueConfig) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.config = config
 }
 
 func NewOrderHandler(
 	orderService svc.OrderService,
 	consumer sarama.Consumer,
 	producer sarama.SyncProducer,
+	queueConfig cfg.QueueConfig,
 	logger log.Logger,
 ) OrderHandler {
 	ctx, cancelFunc := context.WithCancel(logger.WithContext(context.Background()))
 
 	orderHandler := &orderHandler{
-		orderService: orderService,
-		consumer:     consumer,
-		producer:     producer,
-		cancelFunc:   cancelFunc,
+		orderService:   orderService,
+		consumer:       consumer,
+		producer:       producer,
+		queueConfig:    &queueSettings{config: queueConfig},
+		cancelFunc:     cancelFunc,
+		reportedAtRisk: &sync.Map{},
 	}
+	orderHandler.queue = newOrderQueue(queueConfig.MaxWait(), queueConfig.Capacity(), func(req svc.OrderRequest, deadline time.Time) {
+		orderHandler.reportQueuedOrderAtRisk(ctx, req, deadline)
+	})
 
 	orderHandler.listenForNewOrderEvents(ctx)
 
 	return orderHandler
 }
 
+func (oh orderHandler) SetQueueConfig(queueConfig cfg.QueueConfig) {
+	oh.queueConfig.set(queueConfig)
+	oh.queue.SetMaxWait(queueConfig.MaxWait())
+	oh.queue.SetCapacity(queueConfig.Capacity())
+}
+
 func (oh orderHandler) Close() error {
 	return multierr.Combine(
 		oh.consumer.Close(),
@@ -86,67 +142,215 @@ func (oh orderHandler) listenForNewOrderEvents(ctx context.Context) {
 
 		go func(pc sarama.PartitionConsumer) {
 			for message := range pc.Messages() {
+				metrics.MessageConsumed(pc, message)
 				messageChannel <- message
 			}
 		}(pc)
 	}
 
-	// Hanldle the messages
+	// Queue the messages so that the most urgent order is handled next.
+	// No more messages are read while the queue is full, so that the partition consumers stop fetching orders.
 	go func() {
 		for {
 			select {
 			case <-ctx.Done():
 				return // returning not to leak the goroutine
 			case message := <-messageChannel:
-				topic, reply := oh.HandleOrderMessage(ctx, message.Value)
-				oh.publishResponse(ctx, topic, reply)
+				if orderRequest, ok := oh.decodeOrderMessage(ctx, message); ok {
+					if !oh.queue.Push(ctx, orderRequest) {
+						return
+					}
+				}
 				continue
 			}
 		}
 	}()
+
+	// Handle the orders in batches so that busy periods take fewer transactions
+	go func() {
+		for {
+			queueConfig := oh.queueConfig.get()
+			orderRequests, ok := oh.queue.PopBatch(ctx, queueConfig.BatchSize(), queueConfig.BatchWindow())
+			if !ok {
+				return // returning not to leak the goroutine
+			}
+			for _, orderRequest := range orderRequests {
+				log.InfoCtx(ctx).
+					UInt64("orderId", orderRequest.OrderId).
+					Int32("queued", int32(oh.queue.Len())).
+					Msg("Order taken from queue")
+			}
+			oh.handleOrders(ctx, orderRequests)
+		}
+	}()
 }
 
-func (oh orderHandler) HandleOrderMessage(ctx context.Context, request []byte) (string, []byte) {
+// HandleOrderMessage handles an order immediately, without queueing it.
+func (oh orderHandler) HandleOrderMessage(ctx context.Context, message *sarama.ConsumerMessage) (string, []byte) {
+	orderRequest, ok := oh.decodeOrderMessage(ctx, message)
+	if !ok {
+		return "", []byte{}
+	}
+	return oh.handleOrder(ctx, orderRequest)
+}
+
+func (oh orderHandler) decodeOrderMessage(ctx context.Context, message *sarama.ConsumerMessage) (svc.OrderRequest, bool) {
+	var err error
+	ctx, span := msg.StartConsumerSpan(ctx, message)
+	defer func() { tracing.End(span, err) }()
+
+	request := message.Value
 	log.InfoCtx(ctx).
 		Str("message", string(request)).
 		Msgf("Order Message received")
 	decoder := json.NewDecoder(bytes.NewReader(request))
 	decoder.UseNumber()
 
-	var (
-		orderRequest  svc.OrderRequest
-		orderResponse svc.OrderResponse
-		err           error
-	)
+	var orderRequest svc.OrderRequest
 	if err = decoder.Decode(&orderRequest); err != nil {
 		// TODO: This should probably go in to a failed-to-process queue
 		log.ErrCtx(ctx, err).Msg("Failed to decode order request")
-		return "", []byte{}
+		metrics.MessagesFailed.WithLabelValues(message.Topic, metrics.OperationConsume).Inc()
+		return svc.OrderRequest{}, false
+	}
+
+	if len(orderRequest.Location) == 0 {
+		orderRequest.Location = msg.HeaderValue(message, HeaderLocation)
 	}
 
-	if orderResponse, err = oh.orderService.ProcessOrder(ctx, orderRequest); err != nil {
+	orderRequest.ReceivedAt = message.Timestamp
+	orderRequest.Trace, _ = tracing.FromContext(ctx)
+	return orderRequest.WithMaxAge(oh.queueConfig.get().MaxOrderAge()), true
+}
+
+func (oh orderHandler) handleOrder(ctx context.Context, orderRequest svc.OrderRequest) (string, []byte) {
+	ctx = tracing.WithTraceContext(ctx, orderRequest.Trace)
+	orderResponse, err := oh.orderService.ScheduleOrder(ctx, orderRequest)
+	return oh.handleScheduledOrder(ctx, orderRequest, svc.OrderResult{Response: orderResponse, Err: err})
+}
+
+// handleOrders schedules a batch of orders together and hands each scheduled order to a worker of its own,
+// which prepares the order and publishes it as soon as it is ready. It returns once the batch is scheduled,
+// so that the next batch is taken from the queue while the orders of this one are being prepared.
+// Each order keeps its room in the queue until it is published, so the number of workers is bounded by the capacity of the queue.
+func (oh orderHandler) handleOrders(ctx context.Context, orderRequests []svc.OrderRequest) {
+	results := oh.orderService.ScheduleOrders(ctx, orderRequests)
+
+	for i, result := range results {
+		go func(orderRequest svc.OrderRequest, result svc.OrderResult) {
+			defer oh.queue.Done()
+			ctx := tracing.WithTraceContext(ctx, orderRequest.Trace)
+			topic, reply := oh.handleScheduledOrder(ctx, orderRequest, result)
+			oh.publishResponse(ctx, topic, reply)
+		}(orderRequests[i], result)
+	}
+}
+
+func (oh orderHandler) handleScheduledOrder(ctx context.Context, orderRequest svc.OrderRequest, result svc.OrderResult) (string, []byte) {
+	_, reportedAtRisk := oh.reportedAtRisk.LoadAndDelete(orderRequest.OrderId)
+	orderResponse := result.Response
+	location := orderLocation(orderRequest)
+	if result.Err != nil {
+		if orderResponse.Expired {
+			metrics.OrdersExpired.WithLabelValues(location).Inc()
+		}
+		metrics.Orders.WithLabelValues(location, metrics.OutcomeFailed, failureReason(orderResponse, result.Err)).Inc()
 		return TopicOrderFailed, oh.MustMarshal(json.Marshal(orderResponse))
 	}
+
+	// Orders that have been redelivered may already be ready.
+	if orderResponse.Status == k.OrderStatusPreparing {
+		oh.publishResponse(ctx, TopicOrderPreparing, oh.MustMarshal(json.Marshal(orderResponse)))
+		if orderResponse.AtRisk && !reportedAtRisk {
+			oh.publishResponse(ctx, TopicOrderAtRisk, oh.MustMarshal(json.Marshal(orderResponse)))
+		}
+		start := time.Now()
+		orderResponse = oh.orderService.PrepareOrder(ctx, orderResponse)
+		metrics.OrderPreparationTime.WithLabelValues(location).Observe(time.Since(start).Seconds())
+	}
+	metrics.Orders.WithLabelValues(location, metrics.OutcomeReady, "").Inc()
 	return TopicOrderReady, oh.MustMarshal(json.Marshal(orderResponse))
 }
 
+// reportQueuedOrderAtRisk publishes an order that is still waiting in the queue at its deadline as at risk.
+func (oh orderHandler) reportQueuedOrderAtRisk(ctx context.Context, orderRequest svc.OrderRequest, deadline time.Time) {
+	if ctx.Err() != nil {
+		return
+	}
+	ctx = tracing.WithTraceContext(ctx, orderRequest.Trace)
+	oh.reportedAtRisk.Store(orderRequest.OrderId, struct{}{})
+
+	log.InfoCtx(ctx).
+		UInt64("orderId", orderRequest.OrderId).
+		Int32("queued", int32(oh.queue.Len())).
+		Msg("Queued order is at risk")
+
+	oh.publishResponse(ctx, TopicOrderAtRisk, oh.MustMarshal(json.Marshal(svc.OrderResponse{
+		OrderId:  orderRequest.OrderId,
+		Status:   k.OrderStatusQueued,
+		Deadline: &deadline,
+		AtRisk:   true,
+	})))
+}
+
+func (oh orderHandler) GetOrder(w http.ResponseWriter, req *http.Request) {
+	var (
+		id   uint64
+		resp svc.OrderRecordResponse
+		err  error
+	)
+
+	if id, err = strconv.ParseUint(mux.Vars(req)["id"], 10, 64); err != nil {
+		oh.MustEncodeProblem(w, req, k.InvalidError{Cause: fmt.Errorf("invalid order id %q", mux.Vars(req)["id"])})
+		return
+	}
+
+	if resp, err = oh.orderService.GetOrder(req.Context(), id); err != nil {
+		oh.MustEncodeProblem(w, req, err)
+		return
+	}
+
+	oh.MustEncodeJson(w, resp, http.StatusOK)
+}
+
+func (oh orderHandler) GetCostOfGoodsReport(w http.ResponseWriter, req *http.Request) {
+	var (
+		resp svc.CostOfGoodsReportResponse
+		err  error
+	)
+
+	if resp, err = oh.orderService.GetCostOfGoodsReport(req.Context(), req.URL.Query().Get("date")); err != nil {
+		oh.MustEncodeProblem(w, req, err)
+		return
+	}
+
+	oh.MustEncodeJson(w, resp, http.StatusOK)
+}
+
 func (oh orderHandler) publishResponse(ctx context.Context, topic string, body []byte) {
 	var (
 		partition int32
 		offset    int64
 		err       error
 	)
+	ctx, span := msg.StartProducerSpan(ctx, topic)
+	defer func() { tracing.End(span, err) }()
+
 	message := &sarama.ProducerMessage{
-		Topic: topic,
-		Value: sarama.StringEncoder(body),
+		Topic:   topic,
+		Value:   sarama.StringEncoder(body),
+		Headers: msg.TraceHeaders(ctx),
 	}
 	if partition, offset, err = oh.producer.SendMessage(message); err != nil {
 		log.ErrCtx(ctx, err).
 			Str("message", string(body)).
 			Str("topic", topic).
 			Msgf("Failed to publish message")
+		metrics.MessagesFailed.WithLabelValues(topic, metrics.OperationProduce).Inc()
 		return
 	}
+	metrics.MessagesProduced.WithLabelValues(topic).Inc()
+	msg.SetPublished(span, partition, offset)
 	log.InfoCtx(ctx).
 		Str("message", string(body)).
 		Str("topic", topic).
@@ -154,3 +358,28 @@ func (oh orderHandler) publishResponse(ctx context.Context, topic string, body [
 		Int64("offset", offset).
 		Msg("Message published")
 }
+
+// failureReason is the reason label of an order that failed.
+// Reasons are kept to a few kinds so that the number of series stays small.
+func failureReason(orderResponse svc.OrderResponse, err error) string {
+	var invalid k.InvalidError
+	switch {
+	case orderResponse.Expired:
+		return "expired"
+	case len(orderResponse.Conflicts) > 0:
+		return "dietary_conflict"
+	case errors.As(err, &invalid):
+		return "rejected"
+	default:
+		return "error"
+	}
+}
+
+// orderLocation is the location label of an order. Orders with an invalid location are labelled with the default location.
+func orderLocation(orderRequest svc.OrderRequest) string {
+	location, err := k.ParseLocation(orderRequest.Location)
+	if err != nil {
+		return string(k.DefaultLocation)
+	}
+	return string(location)
+}
@@ -0,0 +1,91 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Shopify/sarama"
+	"github.com/Shopify/sarama/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+	k "github.com/w-k-s/McMicroservices/kitchen-service/pkg/kitchen"
+	svc "github.com/w-k-s/McMicroservices/kitchen-service/pkg/services"
+)
+
+type OrderHandlerTestSuite struct {
+	suite.Suite
+}
+
+func TestOrderHandlerTestSuite(t *testing.T) {
+	suite.Run(t, new(OrderHandlerTestSuite))
+}
+
+// cookingOrderService schedules every order and prepares it when the test releases it.
+type cookingOrderService struct {
+	svc.OrderService
+	started chan uint64
+	release chan struct{}
+}
+
+func (s cookingOrderService) ScheduleOrders(ctx context.Context, reqs []svc.OrderRequest) []svc.OrderResult {
+	results := []svc.OrderResult{}
+	for _, req := range reqs {
+		results = append(results, svc.OrderResult{Response: svc.OrderResponse{OrderId: req.OrderId, Status: k.OrderStatusPreparing}})
+	}
+	return results
+}
+
+func (s cookingOrderService) PrepareOrder(ctx context.Context, scheduled svc.OrderResponse) svc.OrderResponse {
+	s.started <- scheduled.OrderId
+	<-s.release
+	return svc.OrderResponse{OrderId: scheduled.OrderId, Status: k.OrderStatusReady}
+}
+
+// -- SUITE
+
+func (suite *OrderHandlerTestSuite) Test_GIVEN_batchOfOrders_WHEN_handled_THEN_batchIsScheduledWithoutWaitingForOrdersToBeReady() {
+	// GIVEN
+	orderService := cookingOrderService{started: make(chan uint64, 1), release: make(chan struct{})}
+	ready := make(chan string, 1)
+	producer := mocks.NewSyncProducer(suite.T(), nil).
+		ExpectSendMessageAndSucceed().
+		ExpectSendMessageWithMessageCheckerFunctionAndSucceed(func(message *sarama.ProducerMessage) error {
+			ready <- message.Topic
+			return nil
+		})
+	handler := orderHandler{orderService: orderService, producer: producer, queue: newOrderQueue(time.Hour, 0, nil), reportedAtRisk: &sync.Map{}}
+
+	// WHEN
+	handled := make(chan struct{})
+	go func() {
+		handler.handleOrders(context.Background(), []svc.OrderRequest{{OrderId: 1}})
+		close(handled)
+	}()
+
+	// THEN
+	select {
+	case <-handled:
+	case <-time.After(time.Second):
+		suite.FailNow("handleOrders waited for the order to be prepared")
+	}
+	assert.Equal(suite.T(), uint64(1), <-orderService.started)
+
+	close(orderService.release)
+	select {
+	case topic := <-ready:
+		assert.Equal(suite.T(), TopicOrderReady, topic)
+	case <-time.After(time.Second):
+		suite.FailNow("order was not published when it was ready")
+	}
+}
+
+func (suite *OrderHandlerTestSuite) Test_GIVEN_failedOrder_WHEN_reasonIsLabelled_THEN_reasonIsOneOfFewKinds() {
+	// THEN
+	assert.Equal(suite.T(), "expired", failureReason(svc.OrderResponse{Expired: true}, k.ExpiredError{}))
+	assert.Equal(suite.T(), "dietary_conflict", failureReason(svc.OrderResponse{Conflicts: map[string]string{"Ham": "vegetarian"}}, k.InvalidError{}))
+	assert.Equal(suite.T(), "rejected", failureReason(svc.OrderResponse{}, k.InvalidError{Cause: errors.New("not enough Cheese")}))
+	assert.Equal(suite.T(), "error", failureReason(svc.OrderResponse{}, k.NewSystemError("failed to save order", errors.New("connection reset"))))
+}
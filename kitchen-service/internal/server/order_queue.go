@@ -0,0 +1,248 @@
+package server
+
+import (
+	"container/heap"
+	"context"
+	"sync"
+	"time"
+
+	svc "github.com/w-k-s/McMicroservices/kitchen-service/pkg/services"
+)
+
+// orderQueue hands out orders earliest deadline first.
+// An order that has waited for maxWait is taken on as if its deadline was then,
+// so that orders with distant deadlines are not starved by a steady stream of urgent orders.
+// Orders that are equally urgent are handed out in the order that they were received.
+// An order that is still queued at its deadline is reported to atRisk.
+// An order takes up room in the queue from when it is pushed until it is done, including while it is being prepared,
+// so that a full kitchen stops taking on orders.
+type orderQueue struct {
+	mu       sync.Mutex
+	orders   queuedOrders
+	ready    chan struct{}
+	space    chan struct{}
+	maxWait  time.Duration
+	capacity uint
+	// taken is the number of orders that have been taken from the queue and are not done yet.
+	taken  uint
+	seq    uint64
+	atRisk func(req svc.OrderRequest, deadline time.Time)
+}
+
+type queuedOrder struct {
+	request svc.OrderRequest
+	dueAt   time.Time
+	seq     uint64
+	// lateTimer fires at the deadline of the order. It is stopped when the order is taken from the queue.
+	lateTimer *time.Timer
+}
+
+// newOrderQueue holds at most capacity orders that are queued or not done yet, or any number of orders if capacity is 0.
+func newOrderQueue(maxWait time.Duration, capacity uint, atRisk func(req svc.OrderRequest, deadline time.Time)) *orderQueue {
+	return &orderQueue{
+		orders:   queuedOrders{},
+		ready:    make(chan struct{}, 1),
+		space:    make(chan struct{}, 1),
+		maxWait:  maxWait,
+		capacity: capacity,
+		atRisk:   atRisk,
+	}
+}
+
+// Push queues an order, waiting for room if the queue is full. It returns false if ctx is done first.
+// Orders with an invalid priority are due immediately so that they are rejected quickly.
+func (q *orderQueue) Push(ctx context.Context, req svc.OrderRequest) bool {
+	if req.ReceivedAt.IsZero() {
+		req.ReceivedAt = time.Now()
+	}
+
+	for {
+		q.mu.Lock()
+		if q.hasSpace() {
+			break
+		}
+		q.mu.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return false
+		case <-q.space:
+		}
+	}
+
+	q.seq++
+	order := queuedOrder{request: req, dueAt: req.ReceivedAt.Add(q.maxWait), seq: q.seq}
+	if _, deadline, err := req.Deadline(); err != nil {
+		order.dueAt = req.ReceivedAt
+	} else {
+		if deadline.Before(order.dueAt) {
+			order.dueAt = deadline
+		}
+		if q.atRisk != nil {
+			seq := q.seq
+			order.lateTimer = time.AfterFunc(time.Until(deadline), func() { q.reportLate(seq, deadline) })
+		}
+	}
+
+	heap.Push(&q.orders, order)
+	hasSpace := q.hasSpace()
+	q.mu.Unlock()
+
+	wake(q.ready)
+	// Wake the next Push if there is still room.
+	if hasSpace {
+		wake(q.space)
+	}
+	return true
+}
+
+func (q *orderQueue) hasSpace() bool {
+	return q.capacity == 0 || uint(q.orders.Len())+q.taken < q.capacity
+}
+
+// reportLate reports the order with the sequence number as at risk if it has not been taken from the queue.
+func (q *orderQueue) reportLate(seq uint64, deadline time.Time) {
+	q.mu.Lock()
+	var (
+		req    svc.OrderRequest
+		queued bool
+	)
+	for _, order := range q.orders {
+		if order.seq == seq {
+			req, queued = order.request, true
+			break
+		}
+	}
+	q.mu.Unlock()
+
+	if queued {
+		q.atRisk(req, deadline)
+	}
+}
+
+// SetMaxWait changes the longest wait of the orders that are queued from now on.
+func (q *orderQueue) SetMaxWait(maxWait time.Duration) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.maxWait = maxWait
+}
+
+// SetCapacity changes the most orders that can be queued or not done. Orders that are already queued are kept if there are more.
+func (q *orderQueue) SetCapacity(capacity uint) {
+	q.mu.Lock()
+	q.capacity = capacity
+	q.mu.Unlock()
+
+	wake(q.space)
+}
+
+// Pop waits for the most urgent order. It returns false if ctx is done first.
+// The order keeps its room in the queue until Done is called for it.
+func (q *orderQueue) Pop(ctx context.Context) (svc.OrderRequest, bool) {
+	for {
+		q.mu.Lock()
+		if q.orders.Len() > 0 {
+			order := heap.Pop(&q.orders).(queuedOrder)
+			q.taken++
+			remaining := q.orders.Len()
+			q.mu.Unlock()
+
+			if order.lateTimer != nil {
+				order.lateTimer.Stop()
+			}
+			// Wake the next Pop if orders are still waiting.
+			if remaining > 0 {
+				wake(q.ready)
+			}
+			return order.request, true
+		}
+		q.mu.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return svc.OrderRequest{}, false
+		case <-q.ready:
+		}
+	}
+}
+
+// PopBatch waits for the most urgent order and then for more orders until there are size orders or window has passed.
+// It returns false if ctx is done before the first order is queued.
+func (q *orderQueue) PopBatch(ctx context.Context, size uint, window time.Duration) ([]svc.OrderRequest, bool) {
+	first, ok := q.Pop(ctx)
+	if !ok {
+		return nil, false
+	}
+
+	batch := []svc.OrderRequest{first}
+	if size <= 1 {
+		return batch, true
+	}
+
+	windowCtx, cancel := context.WithTimeout(ctx, window)
+	defer cancel()
+
+	for uint(len(batch)) < size {
+		next, ok := q.Pop(windowCtx)
+		if !ok {
+			break
+		}
+		batch = append(batch, next)
+	}
+	return batch, true
+}
+
+// Done frees the room of an order that was taken from the queue, once the order has been handled.
+func (q *orderQueue) Done() {
+	q.mu.Lock()
+	if q.taken > 0 {
+		q.taken--
+	}
+	q.mu.Unlock()
+
+	wake(q.space)
+}
+
+func (q *orderQueue) Len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.orders.Len()
+}
+
+// wake wakes a goroutine that is waiting on c, if one is not already due to wake.
+func wake(c chan struct{}) {
+	select {
+	case c <- struct{}{}:
+	default:
+	}
+}
+
+// queuedOrders implements heap.Interface
+type queuedOrders []queuedOrder
+
+func (o queuedOrders) Len() int {
+	return len(o)
+}
+
+func (o queuedOrders) Less(i, j int) bool {
+	if o[i].dueAt.Equal(o[j].dueAt) {
+		return o[i].seq < o[j].seq
+	}
+	return o[i].dueAt.Before(o[j].dueAt)
+}
+
+func (o queuedOrders) Swap(i, j int) {
+	o[i], o[j] = o[j], o[i]
+}
+
+func (o *queuedOrders) Push(x interface{}) {
+	*o = append(*o, x.(queuedOrder))
+}
+
+func (o *queuedOrders) Pop() interface{} {
+	old := *o
+	n := len(old)
+	order := old[n-1]
+	*o = old[:n-1]
+	return order
+}
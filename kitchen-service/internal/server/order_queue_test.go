@@ -0,0 +1,225 @@
+package server
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+	svc "github.com/w-k-s/McMicroservices/kitchen-service/pkg/services"
+)
+
+type OrderQueueTestSuite struct {
+	suite.Suite
+}
+
+func TestOrderQueueTestSuite(t *testing.T) {
+	suite.Run(t, new(OrderQueueTestSuite))
+}
+
+// -- SUITE
+
+func (suite *OrderQueueTestSuite) Test_GIVEN_ordersWithDifferentPriorities_WHEN_ordersArePopped_THEN_earliestDeadlineIsFirst() {
+	// GIVEN
+	now := time.Now()
+	queue := newOrderQueue(time.Hour, 0, nil)
+	queue.Push(context.Background(), svc.OrderRequest{OrderId: 1, Priority: "DINE_IN", ReceivedAt: now})
+	queue.Push(context.Background(), svc.OrderRequest{OrderId: 2, Priority: "DELIVERY", ReceivedAt: now})
+	queue.Push(context.Background(), svc.OrderRequest{OrderId: 3, Priority: "VIP", ReceivedAt: now})
+
+	// WHEN
+	ids := suite.popAll(queue, 3)
+
+	// THEN
+	assert.Equal(suite.T(), []uint64{3, 2, 1}, ids)
+}
+
+func (suite *OrderQueueTestSuite) Test_GIVEN_promisedByTime_WHEN_ordersArePopped_THEN_promisedByTimeIsTheDeadline() {
+	// GIVEN
+	now := time.Now()
+	promisedBy := now.Add(time.Minute)
+	queue := newOrderQueue(time.Hour, 0, nil)
+	queue.Push(context.Background(), svc.OrderRequest{OrderId: 1, Priority: "VIP", ReceivedAt: now})
+	queue.Push(context.Background(), svc.OrderRequest{OrderId: 2, Priority: "DINE_IN", PromisedBy: &promisedBy, ReceivedAt: now})
+
+	// WHEN
+	ids := suite.popAll(queue, 2)
+
+	// THEN
+	assert.Equal(suite.T(), []uint64{2, 1}, ids)
+}
+
+func (suite *OrderQueueTestSuite) Test_GIVEN_orderThatHasWaitedForMaxWait_WHEN_urgentOrderArrives_THEN_waitingOrderIsNotStarved() {
+	// GIVEN
+	now := time.Now()
+	promisedBy := now.Add(24 * time.Hour)
+	queue := newOrderQueue(2*time.Minute, 0, nil)
+	queue.Push(context.Background(), svc.OrderRequest{OrderId: 1, Priority: "DINE_IN", PromisedBy: &promisedBy, ReceivedAt: now.Add(-time.Minute)})
+	queue.Push(context.Background(), svc.OrderRequest{OrderId: 2, Priority: "VIP", ReceivedAt: now})
+
+	// WHEN
+	ids := suite.popAll(queue, 2)
+
+	// THEN
+	assert.Equal(suite.T(), []uint64{1, 2}, ids)
+}
+
+func (suite *OrderQueueTestSuite) Test_GIVEN_equallyUrgentOrders_WHEN_ordersArePopped_THEN_ordersArePoppedInArrivalOrder() {
+	// GIVEN
+	now := time.Now()
+	queue := newOrderQueue(time.Hour, 0, nil)
+	for id := uint64(1); id <= 5; id++ {
+		queue.Push(context.Background(), svc.OrderRequest{OrderId: id, ReceivedAt: now})
+	}
+
+	// WHEN
+	ids := suite.popAll(queue, 5)
+
+	// THEN
+	assert.Equal(suite.T(), []uint64{1, 2, 3, 4, 5}, ids)
+}
+
+func (suite *OrderQueueTestSuite) Test_GIVEN_emptyQueue_WHEN_contextIsCancelled_THEN_popReturns() {
+	// GIVEN
+	queue := newOrderQueue(time.Hour, 0, nil)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	// WHEN
+	_, ok := queue.Pop(ctx)
+
+	// THEN
+	assert.False(suite.T(), ok)
+}
+
+func (suite *OrderQueueTestSuite) Test_GIVEN_moreOrdersThanBatchSize_WHEN_batchIsPopped_THEN_batchIsFull() {
+	// GIVEN
+	now := time.Now()
+	queue := newOrderQueue(time.Hour, 0, nil)
+	for id := uint64(1); id <= 5; id++ {
+		queue.Push(context.Background(), svc.OrderRequest{OrderId: id, ReceivedAt: now})
+	}
+
+	// WHEN
+	batch, ok := queue.PopBatch(context.Background(), 3, time.Second)
+
+	// THEN
+	assert.True(suite.T(), ok)
+	assert.Equal(suite.T(), []uint64{1, 2, 3}, orderIds(batch))
+	assert.Equal(suite.T(), 2, queue.Len())
+}
+
+func (suite *OrderQueueTestSuite) Test_GIVEN_fewerOrdersThanBatchSize_WHEN_windowPasses_THEN_partialBatchIsPopped() {
+	// GIVEN
+	now := time.Now()
+	queue := newOrderQueue(time.Hour, 0, nil)
+	queue.Push(context.Background(), svc.OrderRequest{OrderId: 1, ReceivedAt: now})
+	queue.Push(context.Background(), svc.OrderRequest{OrderId: 2, ReceivedAt: now})
+
+	// WHEN
+	batch, ok := queue.PopBatch(context.Background(), 10, 10*time.Millisecond)
+
+	// THEN
+	assert.True(suite.T(), ok)
+	assert.Equal(suite.T(), []uint64{1, 2}, orderIds(batch))
+}
+
+func (suite *OrderQueueTestSuite) Test_GIVEN_fullQueue_WHEN_orderIsPushed_THEN_pushWaitsForRoom() {
+	// GIVEN
+	now := time.Now()
+	queue := newOrderQueue(time.Hour, 1, nil)
+	assert.True(suite.T(), queue.Push(context.Background(), svc.OrderRequest{OrderId: 1, ReceivedAt: now}))
+
+	// WHEN
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	pushed := queue.Push(ctx, svc.OrderRequest{OrderId: 2, ReceivedAt: now})
+
+	// THEN
+	assert.False(suite.T(), pushed)
+	assert.Equal(suite.T(), 1, queue.Len())
+
+	go func() {
+		queue.Pop(context.Background())
+		time.Sleep(10 * time.Millisecond)
+		queue.Done()
+	}()
+	assert.True(suite.T(), queue.Push(context.Background(), svc.OrderRequest{OrderId: 3, ReceivedAt: now}))
+	assert.Equal(suite.T(), []uint64{3}, suite.popAll(queue, 1))
+}
+
+func (suite *OrderQueueTestSuite) Test_GIVEN_fullQueue_WHEN_orderIsPoppedButNotDone_THEN_pushStillWaitsForRoom() {
+	// GIVEN
+	now := time.Now()
+	queue := newOrderQueue(time.Hour, 1, nil)
+	assert.True(suite.T(), queue.Push(context.Background(), svc.OrderRequest{OrderId: 1, ReceivedAt: now}))
+
+	// WHEN
+	suite.popAll(queue, 1)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	pushed := queue.Push(ctx, svc.OrderRequest{OrderId: 2, ReceivedAt: now})
+
+	// THEN
+	assert.False(suite.T(), pushed)
+	queue.Done()
+	assert.True(suite.T(), queue.Push(context.Background(), svc.OrderRequest{OrderId: 3, ReceivedAt: now}))
+}
+
+func (suite *OrderQueueTestSuite) Test_GIVEN_queuedOrder_WHEN_deadlinePasses_THEN_orderIsReportedAtRisk() {
+	// GIVEN
+	now := time.Now()
+	promisedBy := now.Add(10 * time.Millisecond)
+	atRisk := make(chan uint64, 2)
+	queue := newOrderQueue(time.Hour, 0, func(req svc.OrderRequest, deadline time.Time) {
+		atRisk <- req.OrderId
+	})
+
+	// WHEN
+	queue.Push(context.Background(), svc.OrderRequest{OrderId: 1, PromisedBy: &promisedBy, ReceivedAt: now})
+
+	// THEN
+	select {
+	case id := <-atRisk:
+		assert.Equal(suite.T(), uint64(1), id)
+	case <-time.After(time.Second):
+		suite.Fail("queued order was not reported at risk")
+	}
+}
+
+func (suite *OrderQueueTestSuite) Test_GIVEN_poppedOrder_WHEN_deadlinePasses_THEN_orderIsNotReportedAtRisk() {
+	// GIVEN
+	now := time.Now()
+	promisedBy := now.Add(10 * time.Millisecond)
+	atRisk := make(chan uint64, 1)
+	queue := newOrderQueue(time.Hour, 0, func(req svc.OrderRequest, deadline time.Time) {
+		atRisk <- req.OrderId
+	})
+	queue.Push(context.Background(), svc.OrderRequest{OrderId: 1, PromisedBy: &promisedBy, ReceivedAt: now})
+
+	// WHEN
+	suite.popAll(queue, 1)
+	time.Sleep(20 * time.Millisecond)
+
+	// THEN
+	assert.Empty(suite.T(), atRisk)
+}
+
+func (suite *OrderQueueTestSuite) popAll(queue *orderQueue, n int) []uint64 {
+	ids := []uint64{}
+	for i := 0; i < n; i++ {
+		req, ok := queue.Pop(context.Background())
+		assert.True(suite.T(), ok)
+		ids = append(ids, req.OrderId)
+	}
+	return ids
+}
+
+func orderIds(reqs []svc.OrderRequest) []uint64 {
+	ids := []uint64{}
+	for _, req := range reqs {
+		ids = append(ids, req.OrderId)
+	}
+	return ids
+}
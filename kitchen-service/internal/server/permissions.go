@@ -0,0 +1,111 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/w-k-s/McMicroservices/kitchen-service/pkg/auth"
+	k "github.com/w-k-s/McMicroservices/kitchen-service/pkg/kitchen"
+)
+
+// routePermissions is the permission that a caller needs to make a request to a route, keyed by method and path template.
+// A route that is not listed here can not be used by anyone, and the application does not start.
+var routePermissions = map[string]auth.Permission{
+	"GET /kitchen/api/v1/stock":                                auth.ReadStock,
+	"POST /kitchen/api/v1/stock":                               auth.WriteStock,
+	"GET /kitchen/api/v1/stock/{name}":                         auth.ReadStock,
+	"PUT /kitchen/api/v1/stock/{name}":                         auth.WriteStock,
+	"DELETE /kitchen/api/v1/stock/{name}":                      auth.WriteStock,
+	"GET /kitchen/api/v1/stock/{name}/tags":                    auth.ReadStock,
+	"PUT /kitchen/api/v1/stock/{name}/tags":                    auth.WriteStock,
+	"GET /kitchen/api/v1/items/{name}":                         auth.ReadStock,
+	"PUT /kitchen/api/v1/items/{name}":                         auth.WriteStock,
+	"GET /kitchen/api/v1/locations/{location}/stock":           auth.ReadStock,
+	"POST /kitchen/api/v1/locations/{location}/stock":          auth.WriteStock,
+	"GET /kitchen/api/v1/locations/{location}/stock/{name}":    auth.ReadStock,
+	"PUT /kitchen/api/v1/locations/{location}/stock/{name}":    auth.WriteStock,
+	"DELETE /kitchen/api/v1/locations/{location}/stock/{name}": auth.WriteStock,
+	"POST /kitchen/api/v1/deliveries":                          auth.ReceiveStock,
+	"POST /kitchen/api/v1/transfers":                           auth.WriteStock,
+	"GET /kitchen/api/v1/transfers/{id:[0-9]+}":                auth.ReadStock,
+	"GET /kitchen/api/v1/substitutions":                        auth.ReadStock,
+	"PUT /kitchen/api/v1/substitutions/{name}/{substitute}":    auth.WriteStock,
+	"DELETE /kitchen/api/v1/substitutions/{name}/{substitute}": auth.WriteStock,
+	"GET /kitchen/api/v1/orders/{id:[0-9]+}":                   auth.ReadOrders,
+	"GET /kitchen/api/v1/reports/cogs":                         auth.ReadReports,
+	"GET /kitchen/api/v1/tickets/stream":                       auth.ReadOrders,
+	"POST /kitchen/api/v1/tickets/{orderId:[0-9]+}/bump":       auth.PrepareOrders,
+	"POST /kitchen/api/v1/tickets/{orderId:[0-9]+}/recall":     auth.PrepareOrders,
+	"GET /kitchen/api/v1/stations":                             auth.ReadStations,
+	"PUT /kitchen/api/v1/stations/{name}":                      auth.WriteStations,
+	"PUT /kitchen/api/v1/toppings/{name}/task":                 auth.WriteStations,
+	"DELETE /kitchen/api/v1/toppings/{name}/task":              auth.WriteStations,
+	"GET /kitchen/api/v1/suppliers":                            auth.ReadPurchasing,
+	"POST /kitchen/api/v1/suppliers":                           auth.WritePurchasing,
+	"GET /kitchen/api/v1/purchase-orders":                      auth.ReadPurchasing,
+	"POST /kitchen/api/v1/purchase-orders":                     auth.WritePurchasing,
+	"GET /kitchen/api/v1/purchase-orders/{id:[0-9]+}":          auth.ReadPurchasing,
+	"POST /kitchen/api/v1/purchase-orders/{id:[0-9]+}/cancel":  auth.WritePurchasing,
+}
+
+// routePermission returns the permission that is needed to make a request to the route with the method.
+func routePermission(route *mux.Route, method string) (auth.Permission, bool) {
+	template, err := route.GetPathTemplate()
+	if err != nil {
+		return "", false
+	}
+	permission, ok := routePermissions[method+" "+template]
+	return permission, ok
+}
+
+// checkRoutePermissions returns an error if a route of the router does not declare the permission it needs.
+func checkRoutePermissions(router *mux.Router) error {
+	return router.Walk(func(route *mux.Route, _ *mux.Router, _ []*mux.Route) error {
+		if route.GetHandler() == nil {
+			return nil
+		}
+		template, err := route.GetPathTemplate()
+		if err != nil {
+			return err
+		}
+		if isPublic(template) {
+			return nil
+		}
+		methods, err := route.GetMethods()
+		if err != nil {
+			return fmt.Errorf("route %q must be limited to methods so that it can declare permissions", template)
+		}
+		for _, method := range methods {
+			if _, ok := routePermission(route, method); !ok {
+				return fmt.Errorf("route %q does not declare the permission it needs", method+" "+template)
+			}
+		}
+		return nil
+	})
+}
+
+// authorizationMiddleware rejects requests from callers that do not have the permission that the route needs.
+// It must come after authenticationMiddleware, and only applies to requests that were authenticated.
+func authorizationMiddleware() mux.MiddlewareFunc {
+	return mux.MiddlewareFunc(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			route := mux.CurrentRoute(req)
+			if route == nil || isPublic(req.URL.Path) {
+				next.ServeHTTP(w, req)
+				return
+			}
+
+			permission, ok := routePermission(route, req.Method)
+			if !ok {
+				Handler{}.MustEncodeProblem(w, req, k.NewForbiddenError(fmt.Sprintf("%s %s does not declare a permission", req.Method, req.URL.Path)))
+				return
+			}
+			if err := auth.Authorize(req.Context(), permission); err != nil {
+				Handler{}.MustEncodeProblem(w, req, err)
+				return
+			}
+			next.ServeHTTP(w, req)
+		})
+	})
+}
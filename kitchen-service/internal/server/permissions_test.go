@@ -0,0 +1,133 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+	"github.com/w-k-s/McMicroservices/kitchen-service/pkg/auth"
+)
+
+type PermissionsTestSuite struct {
+	suite.Suite
+	router *mux.Router
+}
+
+func TestPermissionsTestSuite(t *testing.T) {
+	suite.Run(t, new(PermissionsTestSuite))
+}
+
+func (suite *PermissionsTestSuite) SetupTest() {
+	ok := func(w http.ResponseWriter, req *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}
+
+	suite.router = mux.NewRouter()
+	suite.router.Use(func(next http.Handler) http.Handler {
+		// Stands in for authenticationMiddleware
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			if role := req.Header.Get("X-Test-Role"); len(role) > 0 {
+				req = req.WithContext(auth.WithClaims(req.Context(), auth.Claims{Subject: "jack.torrence", Roles: []string{role}}))
+			}
+			next.ServeHTTP(w, req)
+		})
+	})
+	suite.router.Use(authorizationMiddleware())
+	suite.router.HandleFunc("/health", ok).Methods("GET")
+
+	stockRouter := suite.router.PathPrefix("/kitchen/api/v1/stock").Subrouter()
+	stockRouter.HandleFunc("", ok).Methods("GET")
+	stockRouter.HandleFunc("/{name}", ok).Methods("PUT")
+	suite.router.PathPrefix("/kitchen/api/v1/deliveries").Subrouter().HandleFunc("", ok).Methods("POST")
+	suite.router.PathPrefix("/kitchen/api/v1/tickets").Subrouter().HandleFunc("/{orderId:[0-9]+}/bump", ok).Methods("POST")
+	suite.router.PathPrefix("/kitchen/api/v1/purchase-orders").Subrouter().HandleFunc("", ok).Methods("POST")
+}
+
+func (suite *PermissionsTestSuite) status(method string, path string, role auth.Role) int {
+	req := httptest.NewRequest(method, path, nil)
+	req.Header.Set("X-Test-Role", string(role))
+	w := httptest.NewRecorder()
+	suite.router.ServeHTTP(w, req)
+	return w.Code
+}
+
+// -- SUITE
+
+func (suite *PermissionsTestSuite) Test_GIVEN_role_WHEN_routeIsRequested_THEN_matrixIsEnforced() {
+	type request struct {
+		method string
+		path   string
+	}
+	matrix := map[request]map[auth.Role]int{
+		{"GET", "/kitchen/api/v1/stock"}:            {auth.Viewer: 200, auth.Cook: 200, auth.Manager: 200, auth.Admin: 200},
+		{"PUT", "/kitchen/api/v1/stock/Cheese"}:     {auth.Viewer: 403, auth.Cook: 403, auth.Manager: 200, auth.Admin: 200},
+		{"POST", "/kitchen/api/v1/deliveries"}:      {auth.Viewer: 403, auth.Cook: 200, auth.Manager: 200, auth.Admin: 200},
+		{"POST", "/kitchen/api/v1/tickets/1/bump"}:  {auth.Viewer: 403, auth.Cook: 200, auth.Manager: 200, auth.Admin: 200},
+		{"POST", "/kitchen/api/v1/purchase-orders"}: {auth.Viewer: 403, auth.Cook: 403, auth.Manager: 200, auth.Admin: 200},
+		{"GET", "/health"}:                          {auth.Viewer: 200, auth.Cook: 200, auth.Manager: 200, auth.Admin: 200},
+	}
+
+	for req, statuses := range matrix {
+		for role, status := range statuses {
+			assert.Equal(suite.T(), status, suite.status(req.method, req.path, role), "%s %s as %s", req.method, req.path, role)
+		}
+	}
+}
+
+func (suite *PermissionsTestSuite) Test_GIVEN_callerWithoutPermission_WHEN_routeIsRequested_THEN_forbiddenProblemIsReturned() {
+	// GIVEN
+	req := httptest.NewRequest("PUT", "/kitchen/api/v1/stock/Cheese", nil)
+	req.Header.Set("X-Test-Role", string(auth.Viewer))
+
+	// WHEN
+	w := httptest.NewRecorder()
+	suite.router.ServeHTTP(w, req)
+
+	// THEN
+	assert.Equal(suite.T(), 403, w.Code)
+	assert.JSONEq(suite.T(), `{
+		"type": "/api/v1/problems/Forbidden",
+		"status": 403,
+		"instance": "/kitchen/api/v1/stock/Cheese",
+		"title": "Forbidden",
+		"detail": "\"jack.torrence\" does not have permission \"stock:write\""
+	}`, w.Body.String())
+}
+
+func (suite *PermissionsTestSuite) Test_GIVEN_unauthenticatedRequest_WHEN_routeIsRequested_THEN_requestIsAllowed() {
+	// WHEN
+	status := suite.status("PUT", "/kitchen/api/v1/stock/Cheese", "")
+
+	// THEN
+	assert.Equal(suite.T(), 200, status)
+}
+
+func (suite *PermissionsTestSuite) Test_GIVEN_routesWithPermissions_WHEN_checked_THEN_noErrorIsReturned() {
+	// WHEN
+	err := checkRoutePermissions(suite.router)
+
+	// THEN
+	assert.Nil(suite.T(), err)
+}
+
+func (suite *PermissionsTestSuite) Test_GIVEN_routeWithoutPermission_WHEN_checked_THEN_errorIsReturned() {
+	// GIVEN
+	suite.router.HandleFunc("/kitchen/api/v1/secrets", func(w http.ResponseWriter, req *http.Request) {}).
+		Methods("GET")
+
+	// WHEN
+	err := checkRoutePermissions(suite.router)
+
+	// THEN
+	assert.NotNil(suite.T(), err)
+	assert.Equal(suite.T(), `route "GET /kitchen/api/v1/secrets" does not declare the permission it needs`, err.Error())
+}
+
+func (suite *PermissionsTestSuite) Test_GIVEN_declaredPermissions_WHEN_checked_THEN_everyPermissionIsKnown() {
+	for route, permission := range routePermissions {
+		assert.Contains(suite.T(), auth.Permissions, permission, route)
+	}
+}
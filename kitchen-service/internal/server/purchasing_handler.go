@@ -0,0 +1,138 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+
+	k "github.com/w-k-s/McMicroservices/kitchen-service/pkg/kitchen"
+	svc "github.com/w-k-s/McMicroservices/kitchen-service/pkg/services"
+)
+
+type purchasingHandler struct {
+	Handler
+	purchasingSvc svc.PurchasingService
+}
+
+func NewPurchasingHandler(purchasingSvc svc.PurchasingService) purchasingHandler {
+	return purchasingHandler{
+		Handler{},
+		purchasingSvc,
+	}
+}
+
+func (p purchasingHandler) CreateSupplier(w http.ResponseWriter, req *http.Request) {
+	var (
+		supplierRequest svc.SupplierRequest
+		resp            svc.SupplierResponse
+		err             error
+	)
+
+	if ok := p.DecodeJsonOrSendBadRequest(w, req, &supplierRequest); !ok {
+		return
+	}
+
+	if resp, err = p.purchasingSvc.CreateSupplier(req.Context(), supplierRequest); err != nil {
+		p.MustEncodeProblem(w, req, err)
+		return
+	}
+
+	p.MustEncodeJson(w, resp, http.StatusCreated)
+}
+
+func (p purchasingHandler) GetSuppliers(w http.ResponseWriter, req *http.Request) {
+	var (
+		resp svc.SuppliersResponse
+		err  error
+	)
+
+	if resp, err = p.purchasingSvc.GetSuppliers(req.Context()); err != nil {
+		p.MustEncodeProblem(w, req, err)
+		return
+	}
+
+	p.MustEncodeJson(w, resp, http.StatusOK)
+}
+
+func (p purchasingHandler) CreatePurchaseOrder(w http.ResponseWriter, req *http.Request) {
+	var (
+		purchaseOrderRequest svc.PurchaseOrderRequest
+		resp                 svc.PurchaseOrderResponse
+		err                  error
+	)
+
+	if ok := p.DecodeJsonOrSendBadRequest(w, req, &purchaseOrderRequest); !ok {
+		return
+	}
+
+	if resp, err = p.purchasingSvc.CreatePurchaseOrder(req.Context(), purchaseOrderRequest); err != nil {
+		p.MustEncodeProblem(w, req, err)
+		return
+	}
+
+	p.MustEncodeJson(w, resp, http.StatusCreated)
+}
+
+func (p purchasingHandler) GetPurchaseOrders(w http.ResponseWriter, req *http.Request) {
+	var (
+		resp svc.PurchaseOrdersResponse
+		err  error
+	)
+
+	if resp, err = p.purchasingSvc.GetPurchaseOrders(req.Context(), req.URL.Query().Get("status")); err != nil {
+		p.MustEncodeProblem(w, req, err)
+		return
+	}
+
+	p.MustEncodeJson(w, resp, http.StatusOK)
+}
+
+func (p purchasingHandler) GetPurchaseOrder(w http.ResponseWriter, req *http.Request) {
+	var (
+		id   uint64
+		resp svc.PurchaseOrderResponse
+		err  error
+	)
+
+	if id, err = purchaseOrderId(req); err != nil {
+		p.MustEncodeProblem(w, req, err)
+		return
+	}
+
+	if resp, err = p.purchasingSvc.GetPurchaseOrder(req.Context(), id); err != nil {
+		p.MustEncodeProblem(w, req, err)
+		return
+	}
+
+	p.MustEncodeJson(w, resp, http.StatusOK)
+}
+
+func (p purchasingHandler) CancelPurchaseOrder(w http.ResponseWriter, req *http.Request) {
+	var (
+		id   uint64
+		resp svc.PurchaseOrderResponse
+		err  error
+	)
+
+	if id, err = purchaseOrderId(req); err != nil {
+		p.MustEncodeProblem(w, req, err)
+		return
+	}
+
+	if resp, err = p.purchasingSvc.CancelPurchaseOrder(req.Context(), id); err != nil {
+		p.MustEncodeProblem(w, req, err)
+		return
+	}
+
+	p.MustEncodeJson(w, resp, http.StatusOK)
+}
+
+func purchaseOrderId(req *http.Request) (uint64, error) {
+	id, err := strconv.ParseUint(mux.Vars(req)["id"], 10, 64)
+	if err != nil {
+		return 0, k.InvalidError{Cause: fmt.Errorf("invalid purchase order id %q", mux.Vars(req)["id"])}
+	}
+	return id, nil
+}
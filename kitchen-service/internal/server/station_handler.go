@@ -0,0 +1,82 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/gorilla/mux"
+
+	svc "github.com/w-k-s/McMicroservices/kitchen-service/pkg/services"
+)
+
+type stationHandler struct {
+	Handler
+	stationSvc svc.StationService
+}
+
+func NewStationHandler(stationSvc svc.StationService) stationHandler {
+	return stationHandler{
+		Handler{},
+		stationSvc,
+	}
+}
+
+func (s stationHandler) GetStations(w http.ResponseWriter, req *http.Request) {
+	var (
+		resp svc.StationsResponse
+		err  error
+	)
+
+	if resp, err = s.stationSvc.GetStations(req.Context(), req.URL.Query().Get("location")); err != nil {
+		s.MustEncodeProblem(w, req, err)
+		return
+	}
+
+	s.MustEncodeJson(w, resp, http.StatusOK)
+}
+
+func (s stationHandler) SaveStation(w http.ResponseWriter, req *http.Request) {
+	var (
+		stationRequest svc.StationRequest
+		resp           svc.StationResponse
+		err            error
+	)
+
+	if ok := s.DecodeJsonOrSendBadRequest(w, req, &stationRequest); !ok {
+		return
+	}
+
+	if resp, err = s.stationSvc.SaveStation(req.Context(), mux.Vars(req)["name"], stationRequest); err != nil {
+		s.MustEncodeProblem(w, req, err)
+		return
+	}
+
+	s.MustEncodeJson(w, resp, http.StatusOK)
+}
+
+func (s stationHandler) SaveToppingTask(w http.ResponseWriter, req *http.Request) {
+	var (
+		taskRequest svc.ToppingTaskRequest
+		resp        svc.ToppingTaskResponse
+		err         error
+	)
+
+	if ok := s.DecodeJsonOrSendBadRequest(w, req, &taskRequest); !ok {
+		return
+	}
+
+	if resp, err = s.stationSvc.SaveToppingTask(req.Context(), mux.Vars(req)["name"], taskRequest); err != nil {
+		s.MustEncodeProblem(w, req, err)
+		return
+	}
+
+	s.MustEncodeJson(w, resp, http.StatusOK)
+}
+
+func (s stationHandler) DeleteToppingTask(w http.ResponseWriter, req *http.Request) {
+	if err := s.stationSvc.DeleteToppingTask(req.Context(), mux.Vars(req)["name"]); err != nil {
+		s.MustEncodeProblem(w, req, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -4,13 +4,22 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"fmt"
+	"hash/fnv"
 	"net/http"
+	"strconv"
+	"strings"
 
 	"github.com/w-k-s/McMicroservices/kitchen-service/log"
 
 	"github.com/Shopify/sarama"
+	"github.com/gorilla/mux"
+	msg "github.com/w-k-s/McMicroservices/kitchen-service/internal/messages"
+	"github.com/w-k-s/McMicroservices/kitchen-service/internal/metrics"
+	k "github.com/w-k-s/McMicroservices/kitchen-service/pkg/kitchen"
 
 	svc "github.com/w-k-s/McMicroservices/kitchen-service/pkg/services"
+	"github.com/w-k-s/McMicroservices/kitchen-service/pkg/tracing"
 )
 
 const (
@@ -50,18 +59,363 @@ func (s stockHandler) Close() error {
 func (s stockHandler) GetStock(w http.ResponseWriter, req *http.Request) {
 
 	var (
-		resp svc.StockResponse
+		queryRequest svc.StockQueryRequest
+		resp         svc.StockResponse
+		err          error
+	)
+
+	if queryRequest, err = stockQueryRequest(req); err != nil {
+		s.MustEncodeProblem(w, req, err)
+		return
+	}
+
+	if resp, err = s.stockSvc.GetStock(req.Context(), queryRequest); err != nil {
+		s.MustEncodeProblem(w, req, err)
+		return
+	}
+
+	w.Header().Set("ETag", stockETag(resp.Stock))
+	s.MustEncodeJson(w, resp, http.StatusOK)
+}
+
+// stockQueryRequest reads the filters, sort order and page of a stock listing from the query string:
+// prefix, units[lt], sort, limit and after.
+func stockQueryRequest(req *http.Request) (svc.StockQueryRequest, error) {
+	query := req.URL.Query()
+	queryRequest := svc.StockQueryRequest{
+		Location:   mux.Vars(req)["location"],
+		NamePrefix: query.Get("prefix"),
+		Sort:       query.Get("sort"),
+		After:      query.Get("after"),
+	}
+
+	for param, value := range map[string]*uint{"units[lt]": &queryRequest.UnitsBelow, "limit": &queryRequest.Limit} {
+		if len(query.Get(param)) == 0 {
+			continue
+		}
+		parsed, err := strconv.ParseUint(query.Get(param), 10, 32)
+		if err != nil {
+			return svc.StockQueryRequest{}, k.InvalidError{
+				Cause:  fmt.Errorf("invalid %s %q", param, query.Get(param)),
+				Fields: map[string]string{param: fmt.Sprintf("%s must be a whole number", param)},
+			}
+		}
+		*value = uint(parsed)
+	}
+
+	return queryRequest, nil
+}
+
+func (s stockHandler) CreateStockItem(w http.ResponseWriter, req *http.Request) {
+
+	var (
+		itemRequest svc.StockItemRequest
+		resp        svc.StockItemResponse
+		err         error
+	)
+
+	if ok := s.DecodeJsonOrSendBadRequest(w, req, &itemRequest); !ok {
+		return
+	}
+
+	if resp, err = s.stockSvc.CreateStockItem(req.Context(), mux.Vars(req)["location"], itemRequest); err != nil {
+		s.MustEncodeProblem(w, req, err)
+		return
+	}
+
+	w.Header().Set("ETag", versionETag(resp.Version))
+	s.MustEncodeJson(w, resp, http.StatusCreated)
+}
+
+func (s stockHandler) GetStockItem(w http.ResponseWriter, req *http.Request) {
+
+	var (
+		resp svc.StockItemResponse
 		err  error
 	)
 
-	if resp, err = s.stockSvc.GetStock(req.Context()); err != nil {
+	vars := mux.Vars(req)
+	if resp, err = s.stockSvc.GetStockItem(req.Context(), vars["location"], vars["name"]); err != nil {
+		s.MustEncodeProblem(w, req, err)
+		return
+	}
+
+	w.Header().Set("ETag", versionETag(resp.Version))
+	s.MustEncodeJson(w, resp, http.StatusOK)
+}
+
+func (s stockHandler) SetStockItem(w http.ResponseWriter, req *http.Request) {
+
+	var (
+		setRequest svc.SetStockItemRequest
+		resp       svc.StockItemResponse
+		err        error
+	)
+
+	if ok := s.DecodeJsonOrSendBadRequest(w, req, &setRequest); !ok {
+		return
+	}
+
+	if setRequest.IfMatch, err = parseIfMatch(req.Header.Get("If-Match")); err != nil {
 		s.MustEncodeProblem(w, req, err)
 		return
 	}
 
+	vars := mux.Vars(req)
+	if resp, err = s.stockSvc.SetStockItem(req.Context(), vars["location"], vars["name"], setRequest); err != nil {
+		s.MustEncodeProblem(w, req, err)
+		return
+	}
+
+	w.Header().Set("ETag", versionETag(resp.Version))
 	s.MustEncodeJson(w, resp, http.StatusOK)
 }
 
+func (s stockHandler) DeleteStockItem(w http.ResponseWriter, req *http.Request) {
+
+	var (
+		ifMatch *uint64
+		err     error
+	)
+
+	if ifMatch, err = parseIfMatch(req.Header.Get("If-Match")); err != nil {
+		s.MustEncodeProblem(w, req, err)
+		return
+	}
+
+	vars := mux.Vars(req)
+	if err = s.stockSvc.DeleteStockItem(req.Context(), vars["location"], vars["name"], ifMatch); err != nil {
+		s.MustEncodeProblem(w, req, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// versionETag is the strong entity tag of a stock item at the version.
+func versionETag(version uint64) string {
+	return strconv.Quote(strconv.FormatUint(version, 10))
+}
+
+// stockETag is a weak entity tag that changes whenever any of the stock changes.
+func stockETag(stock []svc.StockItemResponse) string {
+	hash := fnv.New64a()
+	for _, item := range stock {
+		fmt.Fprintf(hash, "%s\x00%d\x00", item.Name, item.Version)
+	}
+	return "W/" + strconv.Quote(strconv.FormatUint(hash.Sum64(), 16))
+}
+
+// parseIfMatch returns the version in an If-Match header, svc.AnyVersion for "*", or nil if there is no header.
+// Only a single strong entity tag can be matched against the version of a stock item.
+func parseIfMatch(header string) (*uint64, error) {
+	header = strings.TrimSpace(header)
+	if len(header) == 0 {
+		return nil, nil
+	}
+
+	if header == "*" {
+		version := svc.AnyVersion
+		return &version, nil
+	}
+
+	if strings.HasPrefix(header, "W/") {
+		return nil, k.ConflictError{Cause: fmt.Errorf("weak entity tag %s can not be matched", header), Precondition: true}
+	}
+
+	tag, err := strconv.Unquote(header)
+	if err != nil || !strings.HasPrefix(header, `"`) {
+		return nil, k.InvalidError{Cause: fmt.Errorf("If-Match must be a single entity tag or *, not %s", header)}
+	}
+
+	version, err := strconv.ParseUint(tag, 10, 64)
+	if err != nil || version == 0 {
+		return nil, k.ConflictError{Cause: fmt.Errorf("entity tag %s is not a version of the stock", header), Precondition: true}
+	}
+	return &version, nil
+}
+
+func (s stockHandler) TransferStock(w http.ResponseWriter, req *http.Request) {
+
+	var (
+		transferRequest svc.TransferRequest
+		resp            svc.TransferResponse
+		err             error
+	)
+
+	if ok := s.DecodeJsonOrSendBadRequest(w, req, &transferRequest); !ok {
+		return
+	}
+
+	if resp, err = s.stockSvc.TransferStock(req.Context(), transferRequest); err != nil {
+		s.MustEncodeProblem(w, req, err)
+		return
+	}
+
+	s.MustEncodeJson(w, resp, http.StatusCreated)
+}
+
+func (s stockHandler) GetTransfer(w http.ResponseWriter, req *http.Request) {
+
+	var (
+		id   uint64
+		resp svc.TransferResponse
+		err  error
+	)
+
+	if id, err = strconv.ParseUint(mux.Vars(req)["id"], 10, 64); err != nil {
+		s.MustEncodeProblem(w, req, k.InvalidError{Cause: fmt.Errorf("invalid transfer id %q", mux.Vars(req)["id"])})
+		return
+	}
+
+	if resp, err = s.stockSvc.GetTransfer(req.Context(), id); err != nil {
+		s.MustEncodeProblem(w, req, err)
+		return
+	}
+
+	s.MustEncodeJson(w, resp, http.StatusOK)
+}
+
+func (s stockHandler) ReceiveDelivery(w http.ResponseWriter, req *http.Request) {
+
+	var (
+		deliveryRequest svc.StockRequest
+		resp            svc.DeliveryResponse
+		err             error
+	)
+
+	if ok := s.DecodeJsonOrSendBadRequest(w, req, &deliveryRequest); !ok {
+		return
+	}
+
+	if resp, err = s.stockSvc.ReceiveDelivery(req.Context(), req.Header.Get("Idempotency-Key"), deliveryRequest); err != nil {
+		s.MustEncodeProblem(w, req, err)
+		return
+	}
+
+	if resp.Replayed {
+		w.Header().Set("Idempotent-Replayed", "true")
+	}
+	s.MustEncodeJson(w, resp, http.StatusCreated)
+}
+
+func (s stockHandler) GetItem(w http.ResponseWriter, req *http.Request) {
+
+	var (
+		resp svc.ItemResponse
+		err  error
+	)
+
+	if resp, err = s.stockSvc.GetItem(req.Context(), mux.Vars(req)["name"]); err != nil {
+		s.MustEncodeProblem(w, req, err)
+		return
+	}
+
+	s.MustEncodeJson(w, resp, http.StatusOK)
+}
+
+func (s stockHandler) SaveItem(w http.ResponseWriter, req *http.Request) {
+
+	var (
+		itemRequest svc.ItemRequest
+		resp        svc.ItemResponse
+		err         error
+	)
+
+	if ok := s.DecodeJsonOrSendBadRequest(w, req, &itemRequest); !ok {
+		return
+	}
+
+	if resp, err = s.stockSvc.SaveItem(req.Context(), mux.Vars(req)["name"], itemRequest); err != nil {
+		s.MustEncodeProblem(w, req, err)
+		return
+	}
+
+	s.MustEncodeJson(w, resp, http.StatusOK)
+}
+
+func (s stockHandler) GetDietaryTags(w http.ResponseWriter, req *http.Request) {
+
+	var (
+		resp svc.DietaryTagsResponse
+		err  error
+	)
+
+	if resp, err = s.stockSvc.GetDietaryTags(req.Context(), mux.Vars(req)["name"]); err != nil {
+		s.MustEncodeProblem(w, req, err)
+		return
+	}
+
+	s.MustEncodeJson(w, resp, http.StatusOK)
+}
+
+func (s stockHandler) SetDietaryTags(w http.ResponseWriter, req *http.Request) {
+
+	var (
+		tagsRequest svc.DietaryTagsRequest
+		resp        svc.DietaryTagsResponse
+		err         error
+	)
+
+	if ok := s.DecodeJsonOrSendBadRequest(w, req, &tagsRequest); !ok {
+		return
+	}
+
+	if resp, err = s.stockSvc.SetDietaryTags(req.Context(), mux.Vars(req)["name"], tagsRequest); err != nil {
+		s.MustEncodeProblem(w, req, err)
+		return
+	}
+
+	s.MustEncodeJson(w, resp, http.StatusOK)
+}
+
+func (s stockHandler) GetSubstitutionRules(w http.ResponseWriter, req *http.Request) {
+
+	var (
+		resp svc.SubstitutionRulesResponse
+		err  error
+	)
+
+	if resp, err = s.stockSvc.GetSubstitutionRules(req.Context()); err != nil {
+		s.MustEncodeProblem(w, req, err)
+		return
+	}
+
+	s.MustEncodeJson(w, resp, http.StatusOK)
+}
+
+func (s stockHandler) SaveSubstitutionRule(w http.ResponseWriter, req *http.Request) {
+
+	var (
+		ruleRequest svc.SubstitutionRuleRequest
+		resp        svc.SubstitutionRuleResponse
+		err         error
+	)
+
+	if ok := s.DecodeJsonOrSendBadRequest(w, req, &ruleRequest); !ok {
+		return
+	}
+
+	vars := mux.Vars(req)
+	if resp, err = s.stockSvc.SaveSubstitutionRule(req.Context(), vars["name"], vars["substitute"], ruleRequest); err != nil {
+		s.MustEncodeProblem(w, req, err)
+		return
+	}
+
+	s.MustEncodeJson(w, resp, http.StatusOK)
+}
+
+func (s stockHandler) DeleteSubstitutionRule(w http.ResponseWriter, req *http.Request) {
+
+	vars := mux.Vars(req)
+	if err := s.stockSvc.DeleteSubstitutionRule(req.Context(), vars["name"], vars["substitute"]); err != nil {
+		s.MustEncodeProblem(w, req, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
 func (s stockHandler) listenForStockDeliveryEvents(ctx context.Context) {
 	var (
 		partitionList []int32
@@ -81,6 +435,7 @@ func (s stockHandler) listenForStockDeliveryEvents(ctx context.Context) {
 		pc, _ := s.consumer.ConsumePartition(TopicInventoryDelivery, partition, initialOffset)
 		go func(pc sarama.PartitionConsumer) {
 			for message := range pc.Messages() {
+				metrics.MessageConsumed(pc, message)
 				messageChannel <- message
 			}
 		}(pc)
@@ -93,22 +448,31 @@ func (s stockHandler) listenForStockDeliveryEvents(ctx context.Context) {
 			case <-ctx.Done():
 				return // returning not to leak the goroutine
 			case message := <-messageChannel:
-				s.receiveInventory(ctx, message.Value)
+				s.receiveInventory(ctx, message)
 				continue
 			}
 		}
 	}()
 }
 
-func (s stockHandler) receiveInventory(ctx context.Context, request []byte) {
-	log.InfoCtx(ctx).Msg("Inventory Received...")
-	decoder := json.NewDecoder(bytes.NewReader(request))
-	decoder.UseNumber()
-
+func (s stockHandler) receiveInventory(ctx context.Context, message *sarama.ConsumerMessage) {
 	var (
 		receiveInventoryRequest svc.StockRequest
 		err                     error
 	)
+	ctx, span := msg.StartConsumerSpan(ctx, message)
+	defer func() {
+		if err != nil {
+			metrics.MessagesFailed.WithLabelValues(message.Topic, metrics.OperationConsume).Inc()
+		}
+		tracing.End(span, err)
+	}()
+
+	request := message.Value
+	log.InfoCtx(ctx).Msg("Inventory Received...")
+	decoder := json.NewDecoder(bytes.NewReader(request))
+	decoder.UseNumber()
+
 	if err = decoder.Decode(&receiveInventoryRequest); err != nil {
 		log.ErrCtx(ctx, err).
 			Str("message", string(request)).
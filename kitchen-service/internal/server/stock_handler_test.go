@@ -0,0 +1,126 @@
+package server
+
+import (
+	"fmt"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+	k "github.com/w-k-s/McMicroservices/kitchen-service/pkg/kitchen"
+	svc "github.com/w-k-s/McMicroservices/kitchen-service/pkg/services"
+)
+
+type StockHandlerTestSuite struct {
+	suite.Suite
+}
+
+func TestStockHandlerTestSuite(t *testing.T) {
+	suite.Run(t, new(StockHandlerTestSuite))
+}
+
+// -- SUITE
+
+func (suite *StockHandlerTestSuite) Test_GIVEN_strongEntityTag_WHEN_ifMatchIsParsed_THEN_versionIsReturned() {
+	// GIVEN
+	header := versionETag(42)
+
+	// WHEN
+	version, err := parseIfMatch(header)
+
+	// THEN
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), `"42"`, header)
+	assert.Equal(suite.T(), uint64(42), *version)
+}
+
+func (suite *StockHandlerTestSuite) Test_GIVEN_wildcard_WHEN_ifMatchIsParsed_THEN_anyVersionIsReturned() {
+	// WHEN
+	version, err := parseIfMatch("*")
+
+	// THEN
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), svc.AnyVersion, *version)
+}
+
+func (suite *StockHandlerTestSuite) Test_GIVEN_noHeader_WHEN_ifMatchIsParsed_THEN_noVersionIsReturned() {
+	// WHEN
+	version, err := parseIfMatch("")
+
+	// THEN
+	assert.Nil(suite.T(), err)
+	assert.Nil(suite.T(), version)
+}
+
+func (suite *StockHandlerTestSuite) Test_GIVEN_weakEntityTag_WHEN_ifMatchIsParsed_THEN_preconditionFails() {
+	// WHEN
+	_, err := parseIfMatch(`W/"42"`)
+
+	// THEN
+	assert.Equal(suite.T(), 412, httpStatus(err))
+}
+
+func (suite *StockHandlerTestSuite) Test_GIVEN_severalEntityTags_WHEN_ifMatchIsParsed_THEN_requestIsInvalid() {
+	// WHEN
+	_, err := parseIfMatch(`"1", "2"`)
+
+	// THEN
+	assert.Equal(suite.T(), 400, httpStatus(err))
+}
+
+func (suite *StockHandlerTestSuite) Test_GIVEN_conflictError_WHEN_problemIsEncoded_THEN_statusIsConflictOrPreconditionFailed() {
+	// GIVEN
+	conflict := k.ConflictError{Cause: fmt.Errorf("stock has changed")}
+	precondition := k.ConflictError{Cause: fmt.Errorf("stock has changed"), Precondition: true}
+
+	// WHEN
+	conflictResponse := httptest.NewRecorder()
+	Handler{}.MustEncodeProblem(conflictResponse, httptest.NewRequest("PUT", "/kitchen/api/v1/stock/Cheese", nil), conflict)
+	preconditionResponse := httptest.NewRecorder()
+	Handler{}.MustEncodeProblem(preconditionResponse, httptest.NewRequest("PUT", "/kitchen/api/v1/stock/Cheese", nil), precondition)
+
+	// THEN
+	assert.Equal(suite.T(), 409, conflictResponse.Code)
+	assert.Equal(suite.T(), 412, preconditionResponse.Code)
+	assert.Contains(suite.T(), preconditionResponse.Body.String(), "Precondition Failed")
+}
+
+func (suite *StockHandlerTestSuite) Test_GIVEN_stock_WHEN_versionChanges_THEN_stockETagChanges() {
+	// GIVEN
+	stock := []svc.StockItemResponse{{Name: "Cheese", Units: 1, Version: 1}}
+	changed := []svc.StockItemResponse{{Name: "Cheese", Units: 1, Version: 2}}
+
+	// THEN
+	assert.Equal(suite.T(), stockETag(stock), stockETag(stock))
+	assert.NotEqual(suite.T(), stockETag(stock), stockETag(changed))
+}
+
+func (suite *StockHandlerTestSuite) Test_GIVEN_queryString_WHEN_stockQueryIsRead_THEN_filtersSortAndPageAreSet() {
+	// GIVEN
+	req := httptest.NewRequest("GET", "/kitchen/api/v1/stock?prefix=Che&units[lt]=10&sort=-units&limit=20&after=abc", nil)
+
+	// WHEN
+	queryRequest, err := stockQueryRequest(req)
+
+	// THEN
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), svc.StockQueryRequest{
+		NamePrefix: "Che",
+		UnitsBelow: 10,
+		Sort:       "-units",
+		Limit:      20,
+		After:      "abc",
+	}, queryRequest)
+}
+
+func (suite *StockHandlerTestSuite) Test_GIVEN_negativeLimit_WHEN_stockQueryIsRead_THEN_requestIsInvalid() {
+	// GIVEN
+	req := httptest.NewRequest("GET", "/kitchen/api/v1/stock?limit=-1", nil)
+
+	// WHEN
+	_, err := stockQueryRequest(req)
+
+	// THEN
+	assert.Equal(suite.T(), 400, httpStatus(err))
+	assert.Equal(suite.T(), "limit must be a whole number", errorFields(err)["limit"])
+}
@@ -0,0 +1,209 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/w-k-s/McMicroservices/kitchen-service/log"
+
+	k "github.com/w-k-s/McMicroservices/kitchen-service/pkg/kitchen"
+	svc "github.com/w-k-s/McMicroservices/kitchen-service/pkg/services"
+)
+
+const (
+	headerLastEventId = "Last-Event-ID"
+
+	// ticketStreamKeepAlive is the longest time between keep-alive comments on a stream.
+	ticketStreamKeepAlive = 15 * time.Second
+	ticketStreamRetry     = 3 * time.Second
+)
+
+type ticketHandler struct {
+	Handler
+	ticketSvc svc.TicketService
+	// streamDuration is how long a stream is open before it is ended. It is zero if streams are not ended.
+	streamDuration time.Duration
+	keepAlive      time.Duration
+}
+
+// NewTicketHandler ends streams before the server's write timeout elapses, because the connection is closed
+// without an error once it has.
+func NewTicketHandler(ticketSvc svc.TicketService, writeTimeout time.Duration) ticketHandler {
+	streamDuration, keepAlive := ticketStreamTimings(writeTimeout)
+	return ticketHandler{
+		Handler{},
+		ticketSvc,
+		streamDuration,
+		keepAlive,
+	}
+}
+
+// ticketStreamTimings ends a stream when 90% of the write timeout has elapsed,
+// and keeps it alive at least three times before then.
+func ticketStreamTimings(writeTimeout time.Duration) (streamDuration time.Duration, keepAlive time.Duration) {
+	if writeTimeout <= 0 {
+		return 0, ticketStreamKeepAlive
+	}
+	streamDuration = writeTimeout - writeTimeout/10
+	keepAlive = streamDuration / 3
+	if keepAlive > ticketStreamKeepAlive {
+		keepAlive = ticketStreamKeepAlive
+	}
+	return streamDuration, keepAlive
+}
+
+// StreamTickets sends ticket events to a kitchen display as Server-Sent Events.
+// The stream ends shortly before the server's write timeout elapses; browsers reconnect automatically
+// and send the Last-Event-ID header so that no ticket is missed.
+func (t ticketHandler) StreamTickets(w http.ResponseWriter, req *http.Request) {
+	var (
+		lastEventId uint64
+		stream      <-chan svc.TicketEventResponse
+		err         error
+	)
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		t.MustEncodeProblem(w, req, k.NewSystemError("streaming is not supported", fmt.Errorf("%T is not a http.Flusher", w)))
+		return
+	}
+
+	if lastEventId, err = ticketLastEventId(req); err != nil {
+		t.MustEncodeProblem(w, req, err)
+		return
+	}
+
+	if stream, err = t.ticketSvc.StreamTickets(req.Context(), svc.TicketStreamRequest{
+		Station:     req.URL.Query().Get("station"),
+		Location:    req.URL.Query().Get("location"),
+		LastEventId: lastEventId,
+	}); err != nil {
+		t.MustEncodeProblem(w, req, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+
+	if _, err = fmt.Fprintf(w, "retry: %d\n\n", ticketStreamRetry.Milliseconds()); err != nil {
+		log.ErrCtx(req.Context(), err).Msg("Failed to start ticket stream")
+		return
+	}
+	flusher.Flush()
+
+	keepAlive := time.NewTicker(t.keepAlive)
+	defer keepAlive.Stop()
+
+	var end <-chan time.Time
+	if t.streamDuration > 0 {
+		timer := time.NewTimer(t.streamDuration)
+		defer timer.Stop()
+		end = timer.C
+	}
+
+	for {
+		select {
+		case <-req.Context().Done():
+			return
+		case <-end:
+			return
+		case <-keepAlive.C:
+			if _, err = fmt.Fprint(w, ": keep-alive\n\n"); err != nil {
+				log.ErrCtx(req.Context(), err).Msg("Failed to keep ticket stream alive")
+				return
+			}
+			flusher.Flush()
+		case event, ok := <-stream:
+			if !ok {
+				return
+			}
+			if err = writeTicketEvent(w, event); err != nil {
+				log.ErrCtx(req.Context(), err).
+					UInt64("ticketEventId", event.Id).
+					Msg("Failed to send ticket event")
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
+
+func (t ticketHandler) BumpTicket(w http.ResponseWriter, req *http.Request) {
+	var (
+		orderId uint64
+		resp    svc.TicketsResponse
+		err     error
+	)
+
+	if orderId, err = ticketOrderId(req); err != nil {
+		t.MustEncodeProblem(w, req, err)
+		return
+	}
+
+	if resp, err = t.ticketSvc.BumpTicket(req.Context(), orderId, req.URL.Query().Get("station")); err != nil {
+		t.MustEncodeProblem(w, req, err)
+		return
+	}
+
+	t.MustEncodeJson(w, resp, http.StatusOK)
+}
+
+func (t ticketHandler) RecallTicket(w http.ResponseWriter, req *http.Request) {
+	var (
+		orderId uint64
+		resp    svc.TicketsResponse
+		err     error
+	)
+
+	if orderId, err = ticketOrderId(req); err != nil {
+		t.MustEncodeProblem(w, req, err)
+		return
+	}
+
+	if resp, err = t.ticketSvc.RecallTicket(req.Context(), orderId, req.URL.Query().Get("station")); err != nil {
+		t.MustEncodeProblem(w, req, err)
+		return
+	}
+
+	t.MustEncodeJson(w, resp, http.StatusOK)
+}
+
+func writeTicketEvent(w http.ResponseWriter, event svc.TicketEventResponse) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", event.Id, event.Type, data)
+	return err
+}
+
+// ticketLastEventId reads the id of the last event that a display received.
+// EventSource sends it as a header when it reconnects; it can also be given as a query parameter on the first connection.
+func ticketLastEventId(req *http.Request) (uint64, error) {
+	value := req.Header.Get(headerLastEventId)
+	if len(value) == 0 {
+		value = req.URL.Query().Get("lastEventId")
+	}
+	if len(value) == 0 {
+		return 0, nil
+	}
+	id, err := strconv.ParseUint(value, 10, 64)
+	if err != nil {
+		return 0, k.InvalidError{Cause: fmt.Errorf("invalid last event id %q", value)}
+	}
+	return id, nil
+}
+
+func ticketOrderId(req *http.Request) (uint64, error) {
+	id, err := strconv.ParseUint(mux.Vars(req)["orderId"], 10, 64)
+	if err != nil {
+		return 0, k.InvalidError{Cause: fmt.Errorf("invalid order id %q", mux.Vars(req)["orderId"])}
+	}
+	return id, nil
+}
@@ -0,0 +1,112 @@
+package server
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+	k "github.com/w-k-s/McMicroservices/kitchen-service/pkg/kitchen"
+	svc "github.com/w-k-s/McMicroservices/kitchen-service/pkg/services"
+)
+
+type TicketHandlerTestSuite struct {
+	suite.Suite
+}
+
+func TestTicketHandlerTestSuite(t *testing.T) {
+	suite.Run(t, new(TicketHandlerTestSuite))
+}
+
+type streamingTicketService struct {
+	svc.TicketService
+	events chan svc.TicketEventResponse
+}
+
+func (s streamingTicketService) StreamTickets(ctx context.Context, req svc.TicketStreamRequest) (<-chan svc.TicketEventResponse, error) {
+	return s.events, nil
+}
+
+// -- SUITE
+
+func (suite *TicketHandlerTestSuite) Test_GIVEN_ticketEvent_WHEN_eventIsWritten_THEN_eventIsFormattedAsServerSentEvent() {
+	// GIVEN
+	w := httptest.NewRecorder()
+	event := svc.TicketEventResponse{
+		Id:         12,
+		Type:       k.TicketCreated,
+		OrderId:    7,
+		Station:    k.DefaultStation,
+		Location:   k.DefaultLocation,
+		Items:      []string{"Cheese"},
+		OccurredAt: time.Date(2021, 1, 2, 3, 4, 5, 0, time.UTC),
+	}
+
+	// WHEN
+	err := writeTicketEvent(w, event)
+
+	// THEN
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), "id: 12\n"+
+		"event: ticket-created\n"+
+		"data: {\"id\":12,\"type\":\"ticket-created\",\"orderId\":7,\"station\":\"line\",\"location\":\"main\",\"items\":[\"Cheese\"],\"occurredAt\":\"2021-01-02T03:04:05Z\"}\n\n",
+		w.Body.String())
+}
+
+func (suite *TicketHandlerTestSuite) Test_GIVEN_lastEventIdHeader_WHEN_parsed_THEN_headerTakesPrecedenceOverQuery() {
+	// GIVEN
+	req := httptest.NewRequest("GET", "/kitchen/api/v1/tickets/stream?lastEventId=3", nil)
+	req.Header.Set(headerLastEventId, "9")
+
+	// WHEN
+	id, err := ticketLastEventId(req)
+
+	// THEN
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), uint64(9), id)
+}
+
+func (suite *TicketHandlerTestSuite) Test_GIVEN_invalidLastEventId_WHEN_parsed_THEN_invalidErrorIsReturned() {
+	// GIVEN
+	req := httptest.NewRequest("GET", "/kitchen/api/v1/tickets/stream?lastEventId=abc", nil)
+
+	// WHEN
+	_, err := ticketLastEventId(req)
+
+	// THEN
+	assert.NotNil(suite.T(), err)
+	assert.Equal(suite.T(), 400, httpStatus(err))
+}
+
+func (suite *TicketHandlerTestSuite) Test_GIVEN_writeTimeout_WHEN_streamTimingsAreCalculated_THEN_streamEndsAndIsKeptAliveBeforeTimeout() {
+	// WHEN
+	defaultDuration, defaultKeepAlive := ticketStreamTimings(10 * time.Second)
+	longDuration, longKeepAlive := ticketStreamTimings(5 * time.Minute)
+	unlimitedDuration, unlimitedKeepAlive := ticketStreamTimings(0)
+
+	// THEN
+	assert.Equal(suite.T(), 9*time.Second, defaultDuration)
+	assert.Equal(suite.T(), 3*time.Second, defaultKeepAlive)
+	assert.Equal(suite.T(), 270*time.Second, longDuration)
+	assert.Equal(suite.T(), ticketStreamKeepAlive, longKeepAlive)
+	assert.Equal(suite.T(), time.Duration(0), unlimitedDuration)
+	assert.Equal(suite.T(), ticketStreamKeepAlive, unlimitedKeepAlive)
+}
+
+func (suite *TicketHandlerTestSuite) Test_GIVEN_openStream_WHEN_writeTimeoutIsNear_THEN_streamIsKeptAliveAndEnded() {
+	// GIVEN
+	handler := NewTicketHandler(streamingTicketService{events: make(chan svc.TicketEventResponse)}, 300*time.Millisecond)
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest("GET", "/kitchen/api/v1/tickets/stream", nil)
+
+	// WHEN
+	start := time.Now()
+	handler.StreamTickets(w, req)
+
+	// THEN
+	assert.Less(suite.T(), int64(time.Since(start)), int64(300*time.Millisecond))
+	assert.Equal(suite.T(), "text/event-stream", w.Header().Get("Content-Type"))
+	assert.Contains(suite.T(), w.Body.String(), ": keep-alive\n\n")
+}
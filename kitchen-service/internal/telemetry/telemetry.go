@@ -0,0 +1,60 @@
+package telemetry
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+
+	cfg "github.com/w-k-s/McMicroservices/kitchen-service/internal/config"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.10.0"
+)
+
+// NewTracerProvider creates a provider that samples and exports spans as the telemetry config describes.
+// Spans are still created, so that their ids are logged and propagated, when the exporter is none.
+// The provider must be shut down so that spans that have not been exported yet are flushed.
+func NewTracerProvider(telemetryConfig cfg.TelemetryConfig) (*sdktrace.TracerProvider, error) {
+	opts := []sdktrace.TracerProviderOption{
+		sdktrace.WithResource(resource.NewWithAttributes(
+			semconv.SchemaURL,
+			semconv.ServiceNameKey.String(telemetryConfig.ServiceName()),
+		)),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(telemetryConfig.SampleRatio()))),
+	}
+
+	exporter, err := newExporter(telemetryConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create %s span exporter. Reason: %w", telemetryConfig.Exporter(), err)
+	}
+	if exporter != nil {
+		opts = append(opts, sdktrace.WithBatcher(exporter))
+	}
+	return sdktrace.NewTracerProvider(opts...), nil
+}
+
+func newExporter(telemetryConfig cfg.TelemetryConfig) (sdktrace.SpanExporter, error) {
+	switch telemetryConfig.Exporter() {
+	case cfg.ExporterOTLP:
+		opts := []otlptracehttp.Option{otlptracehttp.WithEndpoint(telemetryConfig.OTLPEndpoint())}
+		if telemetryConfig.OTLPInsecure() {
+			opts = append(opts, otlptracehttp.WithInsecure())
+		}
+		return otlptracehttp.New(context.Background(), opts...)
+	case cfg.ExporterStdout:
+		var w io.Writer = os.Stdout
+		if len(telemetryConfig.File()) > 0 {
+			file, err := os.OpenFile(telemetryConfig.File(), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+			if err != nil {
+				return nil, err
+			}
+			w = file
+		}
+		return stdouttrace.New(stdouttrace.WithWriter(w))
+	default:
+		return nil, nil
+	}
+}
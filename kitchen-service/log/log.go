@@ -2,7 +2,9 @@ package log
 
 import (
 	"context"
+	"fmt"
 	"io"
+	"strings"
 	"time"
 
 	"github.com/rs/zerolog"
@@ -37,6 +39,16 @@ func NewLogger(w io.Writer) Logger {
 	}
 }
 
+// SetLevel sets the least severe level that is logged by every logger, e.g. "info" or "error".
+func SetLevel(level string) error {
+	l, err := zerolog.ParseLevel(strings.ToLower(level))
+	if err != nil || l == zerolog.NoLevel {
+		return fmt.Errorf("unknown log level %q", level)
+	}
+	zerolog.SetGlobalLevel(l)
+	return nil
+}
+
 func Info() Event {
 	return &internalLogEvent{log.Info()}
 }
@@ -82,6 +94,17 @@ func WithFields(fields map[string]interface{}) Logger {
 	return &internalLogger{&l}
 }
 
+// WithContextFields returns a copy of ctx whose logger adds the fields to every event that is logged with ctx.
+// The fields are added to the global logger if ctx does not have a logger.
+func WithContextFields(ctx context.Context, fields map[string]interface{}) context.Context {
+	l := log.Ctx(ctx)
+	if l.GetLevel() == zerolog.Disabled {
+		l = &log.Logger
+	}
+	child := l.With().Fields(fields).Logger()
+	return child.WithContext(ctx)
+}
+
 func withLogger(ctx context.Context) Logger {
 	return &internalLogger{log.Ctx(ctx)}
 }
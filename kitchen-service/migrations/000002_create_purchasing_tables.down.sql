@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS kitchen.purchase_order_line;
+DROP TABLE IF EXISTS kitchen.purchase_order;
+DROP TABLE IF EXISTS kitchen.supplier;
@@ -0,0 +1,27 @@
+CREATE TABLE IF NOT EXISTS kitchen.supplier(
+   id BIGSERIAL PRIMARY KEY,
+   name VARCHAR (255) NOT NULL,
+   email VARCHAR (255),
+   CONSTRAINT uq_supplier_name UNIQUE(name)
+);
+
+CREATE TABLE IF NOT EXISTS kitchen.purchase_order(
+   id BIGSERIAL PRIMARY KEY,
+   supplier_id BIGINT NOT NULL,
+   status VARCHAR (32) NOT NULL,
+   expected_delivery_date DATE NOT NULL,
+   created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+   received_at TIMESTAMP WITH TIME ZONE,
+   CONSTRAINT fk_purchase_order_supplier FOREIGN KEY(supplier_id) REFERENCES kitchen.supplier(id)
+);
+
+CREATE INDEX IF NOT EXISTS ix_purchase_order_supplier_status ON kitchen.purchase_order(supplier_id, status);
+
+CREATE TABLE IF NOT EXISTS kitchen.purchase_order_line(
+   purchase_order_id BIGINT NOT NULL,
+   item_name VARCHAR (255) NOT NULL,
+   ordered_units INTEGER NOT NULL,
+   received_units INTEGER NOT NULL DEFAULT 0,
+   CONSTRAINT pk_purchase_order_line PRIMARY KEY(purchase_order_id, item_name),
+   CONSTRAINT fk_purchase_order_line_order FOREIGN KEY(purchase_order_id) REFERENCES kitchen.purchase_order(id) ON DELETE CASCADE
+);
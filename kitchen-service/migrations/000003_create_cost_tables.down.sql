@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS kitchen.kitchen_order_item;
+DROP TABLE IF EXISTS kitchen.kitchen_order;
+DROP TABLE IF EXISTS kitchen.stock_receipt;
+ALTER TABLE kitchen.stock DROP COLUMN IF EXISTS unit_cost;
@@ -0,0 +1,28 @@
+ALTER TABLE kitchen.stock ADD COLUMN IF NOT EXISTS unit_cost NUMERIC (14, 4) NOT NULL DEFAULT 0;
+
+CREATE TABLE IF NOT EXISTS kitchen.stock_receipt(
+   id BIGSERIAL PRIMARY KEY,
+   item_name VARCHAR (255) NOT NULL,
+   units INTEGER NOT NULL,
+   unit_cost NUMERIC (14, 4) NOT NULL,
+   received_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS kitchen.kitchen_order(
+   id BIGINT PRIMARY KEY,
+   status VARCHAR (32) NOT NULL,
+   failure_reason TEXT,
+   created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+   updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS ix_kitchen_order_created_at ON kitchen.kitchen_order(created_at);
+
+CREATE TABLE IF NOT EXISTS kitchen.kitchen_order_item(
+   order_id BIGINT NOT NULL,
+   item_name VARCHAR (255) NOT NULL,
+   units INTEGER NOT NULL,
+   unit_cost NUMERIC (14, 4) NOT NULL,
+   CONSTRAINT pk_kitchen_order_item PRIMARY KEY(order_id, item_name),
+   CONSTRAINT fk_kitchen_order_item_order FOREIGN KEY(order_id) REFERENCES kitchen.kitchen_order(id) ON DELETE CASCADE
+);
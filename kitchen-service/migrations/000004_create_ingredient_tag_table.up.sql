@@ -0,0 +1,5 @@
+CREATE TABLE IF NOT EXISTS kitchen.ingredient_tag(
+   item_name VARCHAR (255) NOT NULL,
+   tag VARCHAR (32) NOT NULL,
+   CONSTRAINT pk_ingredient_tag PRIMARY KEY(item_name, tag)
+);
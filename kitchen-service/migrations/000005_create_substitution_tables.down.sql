@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS kitchen.kitchen_order_substitution;
+DROP TABLE IF EXISTS kitchen.substitution_rule;
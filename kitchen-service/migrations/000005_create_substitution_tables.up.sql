@@ -0,0 +1,15 @@
+CREATE TABLE IF NOT EXISTS kitchen.substitution_rule(
+   item_name VARCHAR (255) NOT NULL,
+   substitute_name VARCHAR (255) NOT NULL,
+   priority INTEGER NOT NULL DEFAULT 0,
+   CONSTRAINT pk_substitution_rule PRIMARY KEY(item_name, substitute_name)
+);
+
+CREATE TABLE IF NOT EXISTS kitchen.kitchen_order_substitution(
+   order_id BIGINT NOT NULL,
+   item_name VARCHAR (255) NOT NULL,
+   substitute_name VARCHAR (255) NOT NULL,
+   units INTEGER NOT NULL,
+   CONSTRAINT pk_kitchen_order_substitution PRIMARY KEY(order_id, item_name, substitute_name),
+   CONSTRAINT fk_kitchen_order_substitution_order FOREIGN KEY(order_id) REFERENCES kitchen.kitchen_order(id) ON DELETE CASCADE
+);
@@ -0,0 +1,19 @@
+-- Stock can only be known by its name again once it is held at a single location.
+-- Stock that is held elsewhere is not deleted; it must be transferred to the main location first.
+DO $$
+BEGIN
+   IF EXISTS (SELECT 1 FROM kitchen.stock WHERE location <> 'main') THEN
+      RAISE EXCEPTION 'stock is held outside the main location. Transfer it to the main location before reverting locations';
+   END IF;
+END
+$$;
+
+DROP TABLE IF EXISTS kitchen.stock_movement;
+DROP TABLE IF EXISTS kitchen.stock_transfer;
+
+ALTER TABLE kitchen.kitchen_order DROP COLUMN IF EXISTS location;
+ALTER TABLE kitchen.stock_receipt DROP COLUMN IF EXISTS location;
+
+ALTER TABLE kitchen.stock DROP CONSTRAINT IF EXISTS uq_stock_location_name;
+ALTER TABLE kitchen.stock ADD CONSTRAINT uq_stock_name UNIQUE(item_name);
+ALTER TABLE kitchen.stock DROP COLUMN IF EXISTS location;
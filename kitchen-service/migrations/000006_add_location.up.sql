@@ -0,0 +1,27 @@
+ALTER TABLE kitchen.stock ADD COLUMN IF NOT EXISTS location VARCHAR (64) NOT NULL DEFAULT 'main';
+ALTER TABLE kitchen.stock DROP CONSTRAINT IF EXISTS uq_stock_name;
+ALTER TABLE kitchen.stock ADD CONSTRAINT uq_stock_location_name UNIQUE(location, item_name);
+
+ALTER TABLE kitchen.stock_receipt ADD COLUMN IF NOT EXISTS location VARCHAR (64) NOT NULL DEFAULT 'main';
+
+ALTER TABLE kitchen.kitchen_order ADD COLUMN IF NOT EXISTS location VARCHAR (64) NOT NULL DEFAULT 'main';
+
+CREATE TABLE IF NOT EXISTS kitchen.stock_transfer(
+   id BIGSERIAL PRIMARY KEY,
+   from_location VARCHAR (64) NOT NULL,
+   to_location VARCHAR (64) NOT NULL,
+   created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS kitchen.stock_movement(
+   id BIGSERIAL PRIMARY KEY,
+   transfer_id BIGINT NOT NULL,
+   location VARCHAR (64) NOT NULL,
+   item_name VARCHAR (255) NOT NULL,
+   units INTEGER NOT NULL,
+   unit_cost NUMERIC (14, 4) NOT NULL,
+   created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+   CONSTRAINT fk_stock_movement_transfer FOREIGN KEY(transfer_id) REFERENCES kitchen.stock_transfer(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS ix_stock_movement_location ON kitchen.stock_movement(location, created_at);
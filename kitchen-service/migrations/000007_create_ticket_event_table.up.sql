@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS kitchen.ticket_event(
+   id BIGSERIAL PRIMARY KEY,
+   type VARCHAR (32) NOT NULL,
+   order_id BIGINT NOT NULL,
+   station VARCHAR (64) NOT NULL,
+   location VARCHAR (64) NOT NULL,
+   items TEXT[] NOT NULL DEFAULT '{}',
+   created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+   CONSTRAINT fk_ticket_event_order FOREIGN KEY(order_id) REFERENCES kitchen.kitchen_order(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS ix_ticket_event_order_station ON kitchen.ticket_event(order_id, station, id);
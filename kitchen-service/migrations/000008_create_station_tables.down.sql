@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS kitchen.station_task;
+DROP TABLE IF EXISTS kitchen.topping_task;
+DROP TABLE IF EXISTS kitchen.station;
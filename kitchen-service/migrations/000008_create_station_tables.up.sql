@@ -0,0 +1,32 @@
+CREATE TABLE IF NOT EXISTS kitchen.station(
+   name VARCHAR (64) PRIMARY KEY,
+   capacity INTEGER NOT NULL,
+   created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+   updated_at TIMESTAMP WITH TIME ZONE,
+   CONSTRAINT ck_station_capacity CHECK (capacity > 0)
+);
+
+CREATE TABLE IF NOT EXISTS kitchen.topping_task(
+   item_name VARCHAR (255) PRIMARY KEY,
+   station VARCHAR (64) NOT NULL,
+   duration_seconds INTEGER NOT NULL,
+   created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+   updated_at TIMESTAMP WITH TIME ZONE,
+   CONSTRAINT ck_topping_task_duration CHECK (duration_seconds > 0),
+   CONSTRAINT fk_topping_task_station FOREIGN KEY(station) REFERENCES kitchen.station(name) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS kitchen.station_task(
+   id BIGSERIAL PRIMARY KEY,
+   order_id BIGINT NOT NULL,
+   item_name VARCHAR (255) NOT NULL,
+   station VARCHAR (64) NOT NULL,
+   location VARCHAR (64) NOT NULL,
+   slot INTEGER NOT NULL,
+   starts_at TIMESTAMP WITH TIME ZONE NOT NULL,
+   ends_at TIMESTAMP WITH TIME ZONE NOT NULL,
+   CONSTRAINT fk_station_task_order FOREIGN KEY(order_id) REFERENCES kitchen.kitchen_order(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS ix_station_task_load ON kitchen.station_task(location, station, ends_at);
+CREATE INDEX IF NOT EXISTS ix_station_task_order ON kitchen.station_task(order_id);
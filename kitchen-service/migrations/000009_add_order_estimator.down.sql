@@ -0,0 +1 @@
+ALTER TABLE kitchen.kitchen_order DROP COLUMN IF EXISTS estimator;
@@ -0,0 +1 @@
+ALTER TABLE kitchen.kitchen_order ADD COLUMN IF NOT EXISTS estimator VARCHAR (32);
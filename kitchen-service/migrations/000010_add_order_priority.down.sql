@@ -0,0 +1,2 @@
+ALTER TABLE kitchen.kitchen_order DROP COLUMN IF EXISTS deadline;
+ALTER TABLE kitchen.kitchen_order DROP COLUMN IF EXISTS priority;
@@ -0,0 +1,2 @@
+ALTER TABLE kitchen.kitchen_order ADD COLUMN IF NOT EXISTS priority VARCHAR (16) NOT NULL DEFAULT 'DINE_IN';
+ALTER TABLE kitchen.kitchen_order ADD COLUMN IF NOT EXISTS deadline TIMESTAMP WITH TIME ZONE;
@@ -0,0 +1,9 @@
+-- Merged spellings are not split again.
+DROP INDEX IF EXISTS kitchen.ux_stock_location_name;
+ALTER TABLE kitchen.stock DROP CONSTRAINT IF EXISTS uq_stock_location_item;
+ALTER TABLE kitchen.stock ADD CONSTRAINT uq_stock_location_name UNIQUE(location, item_name);
+ALTER TABLE kitchen.stock DROP CONSTRAINT IF EXISTS fk_stock_item;
+ALTER TABLE kitchen.stock DROP COLUMN IF EXISTS item_id;
+
+DROP TABLE IF EXISTS kitchen.item_alias;
+DROP TABLE IF EXISTS kitchen.item;
@@ -0,0 +1,161 @@
+CREATE TABLE IF NOT EXISTS kitchen.item(
+   id BIGSERIAL PRIMARY KEY,
+   sku VARCHAR (64),
+   name VARCHAR (255) NOT NULL,
+   created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+   updated_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS ux_item_name ON kitchen.item(LOWER(name));
+CREATE UNIQUE INDEX IF NOT EXISTS ux_item_sku ON kitchen.item(LOWER(sku)) WHERE sku IS NOT NULL;
+
+CREATE TABLE IF NOT EXISTS kitchen.item_alias(
+   alias VARCHAR (255) NOT NULL,
+   item_id BIGINT NOT NULL,
+   CONSTRAINT fk_item_alias_item FOREIGN KEY(item_id) REFERENCES kitchen.item(id) ON DELETE CASCADE
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS ux_item_alias ON kitchen.item_alias(LOWER(alias));
+CREATE INDEX IF NOT EXISTS ix_item_alias_item ON kitchen.item_alias(item_id);
+
+-- Names are normalized by trimming them and replacing each run of spaces with a single space.
+-- Spellings that differ only in case are the same item.
+CREATE OR REPLACE FUNCTION kitchen.item_key(name TEXT) RETURNS TEXT AS $$
+   SELECT LOWER(REGEXP_REPLACE(BTRIM(name), '\s+', ' ', 'g'))
+$$ LANGUAGE SQL IMMUTABLE;
+
+-- The canonical name of an item is the spelling with the most units in stock.
+INSERT INTO kitchen.item (name)
+SELECT DISTINCT ON (kitchen.item_key(s.item_name))
+   REGEXP_REPLACE(BTRIM(s.item_name), '\s+', ' ', 'g')
+FROM (
+   SELECT item_name, SUM(units) AS units FROM kitchen.stock GROUP BY item_name
+) s
+ORDER BY kitchen.item_key(s.item_name), s.units DESC, s.item_name
+ON CONFLICT DO NOTHING;
+
+-- Ingredients that are tagged, substituted, prepared at a station or on a purchase order may not be in stock.
+INSERT INTO kitchen.item (name)
+SELECT DISTINCT ON (kitchen.item_key(n.item_name))
+   REGEXP_REPLACE(BTRIM(n.item_name), '\s+', ' ', 'g')
+FROM (
+   SELECT item_name FROM kitchen.ingredient_tag
+   UNION SELECT item_name FROM kitchen.substitution_rule
+   UNION SELECT substitute_name FROM kitchen.substitution_rule
+   UNION SELECT item_name FROM kitchen.topping_task
+   UNION SELECT item_name FROM kitchen.purchase_order_line
+) n
+ORDER BY kitchen.item_key(n.item_name), n.item_name
+ON CONFLICT DO NOTHING;
+
+-- Spellings of the same item at a location are merged into one row
+-- that holds the units of all of them at their weighted average cost.
+CREATE TEMPORARY TABLE merged_stock AS
+SELECT
+   s.location,
+   i.id AS item_id,
+   i.name AS item_name,
+   SUM(s.units) AS units,
+   COALESCE(ROUND(
+      SUM(s.units * s.unit_cost) FILTER (WHERE s.unit_cost > 0 AND s.units > 0) /
+      NULLIF(SUM(s.units) FILTER (WHERE s.unit_cost > 0 AND s.units > 0), 0)
+   , 4), 0) AS unit_cost
+FROM
+   kitchen.stock s
+JOIN
+   kitchen.item i ON LOWER(i.name) = kitchen.item_key(s.item_name)
+GROUP BY
+   s.location, i.id, i.name;
+
+DELETE FROM kitchen.stock;
+
+ALTER TABLE kitchen.stock ADD COLUMN IF NOT EXISTS item_id BIGINT;
+
+INSERT INTO kitchen.stock (item_id, item_name, units, unit_cost, location)
+SELECT item_id, item_name, units, unit_cost, location FROM merged_stock;
+
+DROP TABLE merged_stock;
+
+ALTER TABLE kitchen.stock ALTER COLUMN item_id SET NOT NULL;
+ALTER TABLE kitchen.stock ADD CONSTRAINT fk_stock_item FOREIGN KEY(item_id) REFERENCES kitchen.item(id);
+ALTER TABLE kitchen.stock DROP CONSTRAINT IF EXISTS uq_stock_location_name;
+ALTER TABLE kitchen.stock ADD CONSTRAINT uq_stock_location_item UNIQUE(location, item_id);
+CREATE UNIQUE INDEX IF NOT EXISTS ux_stock_location_name ON kitchen.stock(location, LOWER(item_name));
+
+-- Tags, substitutions and preparation tasks use the canonical names.
+-- Where spellings of an item collide, the first spelling is kept.
+DELETE FROM kitchen.ingredient_tag t
+USING kitchen.ingredient_tag o
+WHERE kitchen.item_key(t.item_name) = kitchen.item_key(o.item_name)
+AND t.tag = o.tag
+AND t.item_name > o.item_name;
+
+UPDATE kitchen.ingredient_tag t
+SET item_name = i.name
+FROM kitchen.item i
+WHERE LOWER(i.name) = kitchen.item_key(t.item_name)
+AND t.item_name <> i.name;
+
+DELETE FROM kitchen.substitution_rule r
+USING kitchen.substitution_rule o
+WHERE kitchen.item_key(r.item_name) = kitchen.item_key(o.item_name)
+AND kitchen.item_key(r.substitute_name) = kitchen.item_key(o.substitute_name)
+AND (r.item_name, r.substitute_name) > (o.item_name, o.substitute_name);
+
+UPDATE kitchen.substitution_rule r
+SET item_name = i.name
+FROM kitchen.item i
+WHERE LOWER(i.name) = kitchen.item_key(r.item_name)
+AND r.item_name <> i.name;
+
+UPDATE kitchen.substitution_rule r
+SET substitute_name = i.name
+FROM kitchen.item i
+WHERE LOWER(i.name) = kitchen.item_key(r.substitute_name)
+AND r.substitute_name <> i.name;
+
+DELETE FROM kitchen.topping_task t
+USING kitchen.topping_task o
+WHERE kitchen.item_key(t.item_name) = kitchen.item_key(o.item_name)
+AND t.item_name > o.item_name;
+
+UPDATE kitchen.topping_task t
+SET item_name = i.name
+FROM kitchen.item i
+WHERE LOWER(i.name) = kitchen.item_key(t.item_name)
+AND t.item_name <> i.name;
+
+-- Purchase order lines use the canonical names so that deliveries are received against them under any spelling.
+-- Where spellings of an item collide on a purchase order, the first spelling is kept with the units of all of them.
+UPDATE kitchen.purchase_order_line l
+SET ordered_units = m.ordered_units, received_units = m.received_units
+FROM (
+   SELECT
+      purchase_order_id,
+      MIN(item_name) AS item_name,
+      SUM(ordered_units) AS ordered_units,
+      SUM(received_units) AS received_units
+   FROM
+      kitchen.purchase_order_line
+   GROUP BY
+      purchase_order_id, kitchen.item_key(item_name)
+   HAVING
+      COUNT(*) > 1
+) m
+WHERE l.purchase_order_id = m.purchase_order_id
+AND l.item_name = m.item_name;
+
+DELETE FROM kitchen.purchase_order_line l
+USING kitchen.purchase_order_line o
+WHERE l.purchase_order_id = o.purchase_order_id
+AND kitchen.item_key(l.item_name) = kitchen.item_key(o.item_name)
+AND l.item_name > o.item_name;
+
+UPDATE kitchen.purchase_order_line l
+SET item_name = i.name
+FROM kitchen.item i
+WHERE LOWER(i.name) = kitchen.item_key(l.item_name)
+AND l.item_name <> i.name;
+
+-- Orders, receipts and movements are records of what happened and keep the names they were made with.
+DROP FUNCTION kitchen.item_key(TEXT);
@@ -0,0 +1 @@
+ALTER TABLE kitchen.stock DROP COLUMN IF EXISTS version;
@@ -0,0 +1 @@
+DROP TABLE IF EXISTS kitchen.idempotency_key;
@@ -0,0 +1,8 @@
+CREATE TABLE IF NOT EXISTS kitchen.idempotency_key(
+   scope VARCHAR (64) NOT NULL,
+   key VARCHAR (255) NOT NULL,
+   request_hash CHAR (64) NOT NULL,
+   response BYTEA,
+   created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+   CONSTRAINT pk_idempotency_key PRIMARY KEY(scope, key)
+);
@@ -0,0 +1 @@
+DROP TABLE IF EXISTS kitchen.failed_delivery;
@@ -0,0 +1,6 @@
+CREATE TABLE IF NOT EXISTS kitchen.failed_delivery(
+   id BIGSERIAL PRIMARY KEY,
+   delivery JSONB NOT NULL,
+   reason TEXT NOT NULL,
+   created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
@@ -0,0 +1 @@
+ALTER TABLE kitchen.ticket_event DROP COLUMN IF EXISTS automatic;
@@ -0,0 +1 @@
+ALTER TABLE kitchen.ticket_event ADD COLUMN IF NOT EXISTS automatic BOOLEAN NOT NULL DEFAULT FALSE;
@@ -0,0 +1 @@
+DROP INDEX IF EXISTS kitchen.ix_idempotency_key_created_at;
@@ -0,0 +1 @@
+CREATE INDEX IF NOT EXISTS ix_idempotency_key_created_at ON kitchen.idempotency_key(created_at);
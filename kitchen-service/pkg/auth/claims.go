@@ -0,0 +1,72 @@
+package auth
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// Claims are what a verified token says about whoever made a request.
+type Claims struct {
+	Subject   string
+	Issuer    string
+	Audience  []string
+	Roles     []string
+	Scopes    []string
+	ExpiresAt time.Time
+	IssuedAt  time.Time
+}
+
+// TokenClaims are the claims of the tokens that the auth-service issues.
+type TokenClaims struct {
+	jwt.RegisteredClaims
+	// Roles can be a string or an array of strings.
+	Roles jwt.ClaimStrings `json:"roles"`
+	// Scope is a space-separated list of scopes.
+	Scope string `json:"scope"`
+}
+
+// Claims maps the claims of a verified token to what the kitchen knows about the caller.
+func (c TokenClaims) Claims() Claims {
+	return Claims{
+		Subject:   c.Subject,
+		Issuer:    c.Issuer,
+		Audience:  c.Audience,
+		Roles:     c.Roles,
+		Scopes:    strings.Fields(c.Scope),
+		ExpiresAt: numericDate(c.ExpiresAt),
+		IssuedAt:  numericDate(c.IssuedAt),
+	}
+}
+
+func numericDate(date *jwt.NumericDate) time.Time {
+	if date == nil {
+		return time.Time{}
+	}
+	return date.UTC()
+}
+
+type claimsKey struct{}
+
+// WithClaims returns a copy of ctx that carries the claims of the request that ctx belongs to.
+func WithClaims(ctx context.Context, claims Claims) context.Context {
+	return context.WithValue(ctx, claimsKey{}, claims)
+}
+
+// ClaimsFromContext returns the claims of the request, and false if the request was not authenticated.
+func ClaimsFromContext(ctx context.Context) (Claims, bool) {
+	claims, ok := ctx.Value(claimsKey{}).(Claims)
+	return claims, ok
+}
+
+// HasRole is true if role is one of the roles that were granted to the subject.
+func (c Claims) HasRole(role string) bool {
+	for _, granted := range c.Roles {
+		if granted == role {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,108 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/w-k-s/McMicroservices/kitchen-service/log"
+	k "github.com/w-k-s/McMicroservices/kitchen-service/pkg/kitchen"
+)
+
+// Role is a role that the auth-service grants in the roles claim of a token.
+type Role string
+
+const (
+	// Viewer can look at stock, orders and stations, e.g. a display at the pass.
+	Viewer Role = "viewer"
+	// Cook can also prepare orders and receive deliveries.
+	Cook Role = "cook"
+	// Manager can also adjust stock, set up stations and order from suppliers.
+	Manager Role = "manager"
+	// Admin can do anything.
+	Admin Role = "admin"
+)
+
+// Permission is what a caller needs to carry out an operation.
+type Permission string
+
+const (
+	ReadStock       Permission = "stock:read"
+	WriteStock      Permission = "stock:write"
+	ReceiveStock    Permission = "stock:receive"
+	ReadOrders      Permission = "orders:read"
+	PrepareOrders   Permission = "orders:prepare"
+	ReadReports     Permission = "reports:read"
+	ReadStations    Permission = "stations:read"
+	WriteStations   Permission = "stations:write"
+	ReadPurchasing  Permission = "purchasing:read"
+	WritePurchasing Permission = "purchasing:write"
+)
+
+// Permissions are every permission, in the order of the constants.
+var Permissions = []Permission{
+	ReadStock,
+	WriteStock,
+	ReceiveStock,
+	ReadOrders,
+	PrepareOrders,
+	ReadReports,
+	ReadStations,
+	WriteStations,
+	ReadPurchasing,
+	WritePurchasing,
+}
+
+var (
+	viewerPermissions  = []Permission{ReadStock, ReadOrders, ReadStations}
+	cookPermissions    = append([]Permission{PrepareOrders, ReceiveStock}, viewerPermissions...)
+	managerPermissions = append([]Permission{WriteStock, ReadReports, WriteStations, ReadPurchasing, WritePurchasing}, cookPermissions...)
+
+	rolePermissions = map[Role][]Permission{
+		Viewer:  viewerPermissions,
+		Cook:    cookPermissions,
+		Manager: managerPermissions,
+		Admin:   Permissions,
+	}
+)
+
+// Grants is true if the role has the permission. Unknown roles have no permissions.
+func (r Role) Grants(permission Permission) bool {
+	for _, granted := range rolePermissions[r] {
+		if granted == permission {
+			return true
+		}
+	}
+	return false
+}
+
+// Can is true if one of the roles that were granted to the subject has the permission.
+func (c Claims) Can(permission Permission) bool {
+	for _, role := range c.Roles {
+		if Role(role).Grants(permission) {
+			return true
+		}
+	}
+	return false
+}
+
+// Authorize returns a k.ForbiddenError if the caller whose claims are in ctx does not have the permission.
+// Denied attempts are written to the audit log.
+//
+// A context without claims belongs to the service itself, e.g. a message from the broker,
+// or to a request that was not authenticated because authentication is disabled. It is allowed.
+func Authorize(ctx context.Context, permission Permission) error {
+	claims, ok := ClaimsFromContext(ctx)
+	if !ok || claims.Can(permission) {
+		return nil
+	}
+
+	log.InfoCtx(ctx).
+		Str("audit", "access_denied").
+		Str("subject", claims.Subject).
+		Str("roles", strings.Join(claims.Roles, ",")).
+		Str("permission", string(permission)).
+		Msg("Permission denied")
+
+	return k.NewForbiddenError(fmt.Sprintf("%q does not have permission %q", claims.Subject, permission))
+}
@@ -0,0 +1,86 @@
+package auth
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+	k "github.com/w-k-s/McMicroservices/kitchen-service/pkg/kitchen"
+)
+
+type PolicyTestSuite struct {
+	suite.Suite
+}
+
+func TestPolicyTestSuite(t *testing.T) {
+	suite.Run(t, new(PolicyTestSuite))
+}
+
+// -- SUITE
+
+func (suite *PolicyTestSuite) Test_GIVEN_role_WHEN_permissionIsChecked_THEN_matrixIsEnforced() {
+	matrix := map[Permission][]Role{
+		ReadStock:       {Viewer, Cook, Manager, Admin},
+		ReadOrders:      {Viewer, Cook, Manager, Admin},
+		ReadStations:    {Viewer, Cook, Manager, Admin},
+		PrepareOrders:   {Cook, Manager, Admin},
+		ReceiveStock:    {Cook, Manager, Admin},
+		WriteStock:      {Manager, Admin},
+		ReadReports:     {Manager, Admin},
+		WriteStations:   {Manager, Admin},
+		ReadPurchasing:  {Manager, Admin},
+		WritePurchasing: {Manager, Admin},
+	}
+	assert.Len(suite.T(), matrix, len(Permissions))
+
+	for _, permission := range Permissions {
+		for _, role := range []Role{Viewer, Cook, Manager, Admin, Role("chef")} {
+			expected := false
+			for _, granted := range matrix[permission] {
+				expected = expected || granted == role
+			}
+			assert.Equal(suite.T(), expected, role.Grants(permission), "%s %s", role, permission)
+		}
+	}
+}
+
+func (suite *PolicyTestSuite) Test_GIVEN_severalRoles_WHEN_permissionIsChecked_THEN_anyRoleCanGrantIt() {
+	// GIVEN
+	claims := Claims{Subject: "dick.hallorann", Roles: []string{"viewer", "cook"}}
+
+	// THEN
+	assert.True(suite.T(), claims.Can(PrepareOrders))
+	assert.False(suite.T(), claims.Can(WriteStock))
+}
+
+func (suite *PolicyTestSuite) Test_GIVEN_callerWithoutPermission_WHEN_authorized_THEN_forbiddenErrorIsReturned() {
+	// GIVEN
+	ctx := WithClaims(context.Background(), Claims{Subject: "danny.torrence", Roles: []string{"viewer"}})
+
+	// WHEN
+	err := Authorize(ctx, WriteStock)
+
+	// THEN
+	assert.IsType(suite.T(), k.ForbiddenError{}, err)
+	assert.Equal(suite.T(), `"danny.torrence" does not have permission "stock:write"`, err.Error())
+}
+
+func (suite *PolicyTestSuite) Test_GIVEN_callerWithPermission_WHEN_authorized_THEN_noErrorIsReturned() {
+	// GIVEN
+	ctx := WithClaims(context.Background(), Claims{Subject: "jack.torrence", Roles: []string{"manager"}})
+
+	// WHEN
+	err := Authorize(ctx, WriteStock)
+
+	// THEN
+	assert.Nil(suite.T(), err)
+}
+
+func (suite *PolicyTestSuite) Test_GIVEN_contextWithoutClaims_WHEN_authorized_THEN_serviceItselfIsAllowed() {
+	// WHEN
+	err := Authorize(context.Background(), WritePurchasing)
+
+	// THEN
+	assert.Nil(suite.T(), err)
+}
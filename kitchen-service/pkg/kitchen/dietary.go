@@ -0,0 +1,135 @@
+package kitchen
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// DietaryTag describes an ingredient.
+// Allergen tags (gluten, dairy, nuts) mean that the ingredient contains the allergen.
+// Dietary tags (vegan, halal) mean that the ingredient is suitable for that diet.
+type DietaryTag string
+
+const (
+	DietaryTagGluten DietaryTag = "gluten"
+	DietaryTagDairy  DietaryTag = "dairy"
+	DietaryTagNuts   DietaryTag = "nuts"
+	DietaryTagVegan  DietaryTag = "vegan"
+	DietaryTagHalal  DietaryTag = "halal"
+)
+
+var dietaryTags = []DietaryTag{
+	DietaryTagGluten,
+	DietaryTagDairy,
+	DietaryTagNuts,
+	DietaryTagVegan,
+	DietaryTagHalal,
+}
+
+func ParseDietaryTag(s string) (DietaryTag, error) {
+	for _, tag := range dietaryTags {
+		if strings.EqualFold(string(tag), strings.TrimSpace(s)) {
+			return tag, nil
+		}
+	}
+	return "", InvalidError{Cause: fmt.Errorf("unknown dietary tag %q", s)}
+}
+
+func (t DietaryTag) IsAllergen() bool {
+	switch t {
+	case DietaryTagGluten, DietaryTagDairy, DietaryTagNuts:
+		return true
+	default:
+		return false
+	}
+}
+
+type DietaryTags []DietaryTag
+
+func ParseDietaryTags(values []string) (DietaryTags, error) {
+	tags := DietaryTags{}
+	invalid := []string{}
+	for _, value := range values {
+		tag, err := ParseDietaryTag(value)
+		if err != nil {
+			invalid = append(invalid, value)
+			continue
+		}
+		if !tags.Contains(tag) {
+			tags = append(tags, tag)
+		}
+	}
+	if len(invalid) > 0 {
+		return nil, InvalidError{
+			Cause:  fmt.Errorf("unknown dietary tags"),
+			Fields: map[string]string{"tags": fmt.Sprintf("Unknown tags %q. Tags must be one of %q", invalid, dietaryTags)},
+		}
+	}
+	sort.Slice(tags, func(i, j int) bool { return tags[i] < tags[j] })
+	return tags, nil
+}
+
+func (tags DietaryTags) Contains(tag DietaryTag) bool {
+	for _, t := range tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+func (tags DietaryTags) Strings() []string {
+	values := []string{}
+	for _, tag := range tags {
+		values = append(values, string(tag))
+	}
+	return values
+}
+
+// Violates lists the exclusions that an ingredient with these tags does not satisfy.
+// An ingredient violates an allergen exclusion if it contains the allergen,
+// and violates a dietary exclusion (e.g. vegan) if it is not tagged as suitable for that diet.
+func (tags DietaryTags) Violates(exclusions DietaryTags) DietaryTags {
+	violations := DietaryTags{}
+	for _, exclusion := range exclusions {
+		if exclusion.IsAllergen() == tags.Contains(exclusion) {
+			violations = append(violations, exclusion)
+		}
+	}
+	return violations
+}
+
+// CheckExclusions returns an InvalidError that lists every ingredient that conflicts with the exclusions.
+// tagsByIngredient holds the tags of each ingredient. Ingredients without tags are treated as untagged.
+func CheckExclusions(ingredients []string, tagsByIngredient map[string]DietaryTags, exclusions DietaryTags) error {
+	if len(exclusions) == 0 {
+		return nil
+	}
+
+	conflicts := map[string]string{}
+	for _, ingredient := range ingredients {
+		violations := tagsByIngredient[ingredient].Violates(exclusions)
+		if len(violations) == 0 {
+			continue
+		}
+		reasons := []string{}
+		for _, violation := range violations {
+			if violation.IsAllergen() {
+				reasons = append(reasons, fmt.Sprintf("contains %s", violation))
+			} else {
+				reasons = append(reasons, fmt.Sprintf("is not %s", violation))
+			}
+		}
+		conflicts[ingredient] = fmt.Sprintf("%s %s", ingredient, strings.Join(reasons, " and "))
+	}
+
+	if len(conflicts) == 0 {
+		return nil
+	}
+
+	return InvalidError{
+		Cause:  fmt.Errorf("order contains toppings that conflict with its exclusions"),
+		Fields: conflicts,
+	}
+}
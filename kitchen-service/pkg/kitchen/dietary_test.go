@@ -0,0 +1,79 @@
+package kitchen
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type DietaryTestSuite struct {
+	suite.Suite
+}
+
+func TestDietaryTestSuite(t *testing.T) {
+	suite.Run(t, new(DietaryTestSuite))
+}
+
+// -- SUITE
+
+func (suite *DietaryTestSuite) Test_GIVEN_repeatedTagsInAnyCase_WHEN_parsed_THEN_tagsAreDeduplicatedAndSorted() {
+	// WHEN
+	tags, err := ParseDietaryTags([]string{"Vegan", "dairy", "DAIRY"})
+
+	// THEN
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), DietaryTags{DietaryTagDairy, DietaryTagVegan}, tags)
+}
+
+func (suite *DietaryTestSuite) Test_GIVEN_unknownTag_WHEN_parsed_THEN_invalidErrorIsReturned() {
+	// WHEN
+	_, err := ParseDietaryTags([]string{"vegan", "keto"})
+
+	// THEN
+	assert.NotNil(suite.T(), err)
+	assert.IsType(suite.T(), InvalidError{}, err)
+	assert.Contains(suite.T(), err.(InvalidError).Fields["tags"], "keto")
+}
+
+func (suite *DietaryTestSuite) Test_GIVEN_ingredientContainingAllergen_WHEN_allergenIsExcluded_THEN_ingredientIsRejected() {
+	// GIVEN
+	tags := map[string]DietaryTags{
+		"Cheese": {DietaryTagDairy, DietaryTagHalal},
+		"Bun":    {DietaryTagGluten, DietaryTagVegan},
+	}
+
+	// WHEN
+	err := CheckExclusions([]string{"Bun", "Cheese"}, tags, DietaryTags{DietaryTagDairy})
+
+	// THEN
+	assert.NotNil(suite.T(), err)
+	assert.Equal(suite.T(), "order contains toppings that conflict with its exclusions", err.(InvalidError).Cause.Error())
+	assert.Equal(suite.T(), map[string]string{"Cheese": "Cheese contains dairy"}, err.(InvalidError).Fields)
+}
+
+func (suite *DietaryTestSuite) Test_GIVEN_ingredientsNotSuitableForDiet_WHEN_dietIsRequired_THEN_everyConflictIsListed() {
+	// GIVEN
+	tags := map[string]DietaryTags{
+		"Cheese":  {DietaryTagDairy},
+		"Lettuce": {DietaryTagVegan, DietaryTagHalal},
+	}
+
+	// WHEN
+	err := CheckExclusions([]string{"Cheese", "Lettuce", "Bacon"}, tags, DietaryTags{DietaryTagDairy, DietaryTagVegan})
+
+	// THEN
+	assert.NotNil(suite.T(), err)
+	assert.Equal(suite.T(), map[string]string{
+		"Cheese": "Cheese contains dairy and is not vegan",
+		"Bacon":  "Bacon is not vegan",
+	}, err.(InvalidError).Fields)
+}
+
+func (suite *DietaryTestSuite) Test_GIVEN_noExclusions_WHEN_checked_THEN_orderIsAccepted() {
+	// WHEN
+	err := CheckExclusions([]string{"Cheese"}, map[string]DietaryTags{"Cheese": {DietaryTagDairy}}, DietaryTags{})
+
+	// THEN
+	assert.Nil(suite.T(), err)
+}
@@ -80,3 +80,111 @@ func (s SystemError) IsSystemError() bool {
 func (i SystemError) ErrorTitle() string {
 	return "System Error"
 }
+
+type NotFoundError struct {
+	Cause error
+}
+
+func NewNotFoundError(message string) error {
+	return NotFoundError{Cause: fmt.Errorf("%s", message)}
+}
+
+func (n NotFoundError) Unwrap() error {
+	return n.Cause
+}
+
+func (n NotFoundError) Error() string {
+	return n.Cause.Error()
+}
+
+func (n NotFoundError) IsNotFoundError() bool {
+	return true
+}
+
+func (n NotFoundError) ErrorTitle() string {
+	return "Not Found"
+}
+
+// ConflictError is returned when something has changed since the version that a change to it was based on.
+type ConflictError struct {
+	Cause error
+	// Precondition is true if the version that the change was based on was a precondition of the request.
+	Precondition bool
+}
+
+func NewConflictError(message string) error {
+	return ConflictError{Cause: fmt.Errorf("%s", message)}
+}
+
+func (c ConflictError) Unwrap() error {
+	return c.Cause
+}
+
+func (c ConflictError) Error() string {
+	return c.Cause.Error()
+}
+
+func (c ConflictError) IsConflictError() bool {
+	return true
+}
+
+func (c ConflictError) IsPreconditionFailed() bool {
+	return c.Precondition
+}
+
+func (c ConflictError) ErrorTitle() string {
+	if c.Precondition {
+		return "Precondition Failed"
+	}
+	return "Conflict"
+}
+
+// UnauthorizedError is returned when a request does not prove who made it.
+type UnauthorizedError struct {
+	Cause error
+}
+
+func NewUnauthorizedError(message string) error {
+	return UnauthorizedError{Cause: fmt.Errorf("%s", message)}
+}
+
+func (u UnauthorizedError) Unwrap() error {
+	return u.Cause
+}
+
+func (u UnauthorizedError) Error() string {
+	return u.Cause.Error()
+}
+
+func (u UnauthorizedError) IsUnauthorizedError() bool {
+	return true
+}
+
+func (u UnauthorizedError) ErrorTitle() string {
+	return "Unauthorized"
+}
+
+// ForbiddenError is returned when whoever made a request is not allowed to make it.
+type ForbiddenError struct {
+	Cause error
+}
+
+func NewForbiddenError(message string) error {
+	return ForbiddenError{Cause: fmt.Errorf("%s", message)}
+}
+
+func (f ForbiddenError) Unwrap() error {
+	return f.Cause
+}
+
+func (f ForbiddenError) Error() string {
+	return f.Cause.Error()
+}
+
+func (f ForbiddenError) IsForbiddenError() bool {
+	return true
+}
+
+func (f ForbiddenError) ErrorTitle() string {
+	return "Forbidden"
+}
@@ -0,0 +1,47 @@
+package kitchen
+
+import (
+	"fmt"
+	"time"
+)
+
+// OrderExpiresAt is when an order becomes too old to prepare.
+// An order expires at expiresAt if it is set. Otherwise it expires maxAge after it was created,
+// or maxAge after it was received if its creation time is not known either.
+// The zero time is returned if the order never expires.
+func OrderExpiresAt(expiresAt time.Time, createdAt time.Time, receivedAt time.Time, maxAge time.Duration) time.Time {
+	switch {
+	case !expiresAt.IsZero():
+		return expiresAt
+	case maxAge <= 0:
+		return time.Time{}
+	case !createdAt.IsZero():
+		return createdAt.Add(maxAge)
+	case !receivedAt.IsZero():
+		return receivedAt.Add(maxAge)
+	default:
+		return time.Time{}
+	}
+}
+
+// ExpiredError means that an order was received too late to be prepared.
+type ExpiredError struct {
+	OrderId   uint64
+	ExpiredAt time.Time
+}
+
+func (e ExpiredError) Error() string {
+	return fmt.Sprintf("order %d expired at %s", e.OrderId, e.ExpiredAt.UTC().Format(time.RFC3339))
+}
+
+func (e ExpiredError) ErrorTitle() string {
+	return "Expired"
+}
+
+// CheckExpiry returns an ExpiredError if the order has expired by now.
+func CheckExpiry(orderId uint64, expiresAt time.Time, now time.Time) error {
+	if expiresAt.IsZero() || now.Before(expiresAt) {
+		return nil
+	}
+	return ExpiredError{orderId, expiresAt}
+}
@@ -0,0 +1,64 @@
+package kitchen
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type ExpiryTestSuite struct {
+	suite.Suite
+}
+
+func TestExpiryTestSuite(t *testing.T) {
+	suite.Run(t, new(ExpiryTestSuite))
+}
+
+// -- SUITE
+
+var expiryNow = time.Date(2022, 1, 1, 12, 0, 0, 0, time.UTC)
+
+func (suite *ExpiryTestSuite) Test_GIVEN_expiresAt_WHEN_expiryIsCalculated_THEN_expiresAtIsUsed() {
+	// WHEN
+	expiresAt := OrderExpiresAt(expiryNow.Add(time.Hour), expiryNow.Add(-time.Hour), expiryNow, time.Minute)
+
+	// THEN
+	assert.Equal(suite.T(), expiryNow.Add(time.Hour), expiresAt)
+}
+
+func (suite *ExpiryTestSuite) Test_GIVEN_createdAt_WHEN_expiryIsCalculated_THEN_maxAgeIsAddedToCreatedAt() {
+	// WHEN
+	expiresAt := OrderExpiresAt(time.Time{}, expiryNow.Add(-time.Hour), expiryNow, time.Minute)
+
+	// THEN
+	assert.Equal(suite.T(), expiryNow.Add(-time.Hour+time.Minute), expiresAt)
+}
+
+func (suite *ExpiryTestSuite) Test_GIVEN_noTimestamps_WHEN_expiryIsCalculated_THEN_maxAgeIsAddedToReceivedAt() {
+	// WHEN
+	expiresAt := OrderExpiresAt(time.Time{}, time.Time{}, expiryNow, time.Minute)
+
+	// THEN
+	assert.Equal(suite.T(), expiryNow.Add(time.Minute), expiresAt)
+}
+
+func (suite *ExpiryTestSuite) Test_GIVEN_noMaxAge_WHEN_expiryIsCalculated_THEN_orderNeverExpires() {
+	// WHEN
+	expiresAt := OrderExpiresAt(time.Time{}, expiryNow, expiryNow, 0)
+
+	// THEN
+	assert.True(suite.T(), expiresAt.IsZero())
+	assert.Nil(suite.T(), CheckExpiry(1, expiresAt, expiryNow))
+}
+
+func (suite *ExpiryTestSuite) Test_GIVEN_expiredOrder_WHEN_expiryIsChecked_THEN_expiredErrorIsReturned() {
+	// WHEN
+	err := CheckExpiry(7, expiryNow.Add(-time.Second), expiryNow)
+
+	// THEN
+	assert.IsType(suite.T(), ExpiredError{}, err)
+	assert.Equal(suite.T(), "order 7 expired at 2022-01-01T11:59:59Z", err.Error())
+	assert.Nil(suite.T(), CheckExpiry(7, expiryNow.Add(time.Second), expiryNow))
+}
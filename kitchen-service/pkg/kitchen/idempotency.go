@@ -0,0 +1,86 @@
+package kitchen
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"unicode"
+
+	"github.com/gobuffalo/validate"
+	"github.com/gobuffalo/validate/validators"
+)
+
+// IdempotentRequest is a request that is made at most once for each key in a scope.
+// The response to the request is stored so that it can be replayed when the request is retried.
+type IdempotentRequest struct {
+	scope       string
+	key         string
+	requestHash string
+	response    []byte
+}
+
+// NewIdempotentRequest identifies the request by the SHA-256 hash of its body, so that a key can not be reused for a different request.
+func NewIdempotentRequest(scope string, key string, body []byte) (IdempotentRequest, error) {
+	hash := sha256.Sum256(body)
+	return newIdempotentRequest(scope, key, hex.EncodeToString(hash[:]), nil)
+}
+
+// NewStoredIdempotentRequest restores a request that was stored with its hash and, once answered, its response.
+func NewStoredIdempotentRequest(scope string, key string, requestHash string, response []byte) (IdempotentRequest, error) {
+	return newIdempotentRequest(scope, key, requestHash, response)
+}
+
+func newIdempotentRequest(scope string, key string, requestHash string, response []byte) (IdempotentRequest, error) {
+	errors := validate.Validate(
+		&validators.StringIsPresent{Name: "Scope", Field: scope, Message: "Scope is required"},
+		&validators.StringLengthInRange{Name: "Key", Field: key, Min: 1, Max: 255, Message: "Idempotency key must be 1 and 255 characters long"},
+	)
+	for _, r := range key {
+		if r > unicode.MaxASCII || !unicode.IsPrint(r) {
+			errors.Add("key", "Idempotency key must only contain printable ASCII characters")
+			break
+		}
+	}
+
+	if err := invalidErrorWithFields("Invalid idempotency key", errors); err != nil {
+		return IdempotentRequest{}, err
+	}
+
+	return IdempotentRequest{scope, key, requestHash, response}, nil
+}
+
+// WithResponse returns a copy of the request that was answered with the response.
+func (r IdempotentRequest) WithResponse(response []byte) IdempotentRequest {
+	r.response = append([]byte{}, response...)
+	return r
+}
+
+// SameRequestAs is true if other has the same body as the request.
+func (r IdempotentRequest) SameRequestAs(other IdempotentRequest) bool {
+	return r.requestHash == other.requestHash
+}
+
+// Answered is true once the response to the request has been stored.
+func (r IdempotentRequest) Answered() bool {
+	return r.response != nil
+}
+
+func (r IdempotentRequest) Scope() string {
+	return r.scope
+}
+
+func (r IdempotentRequest) Key() string {
+	return r.key
+}
+
+func (r IdempotentRequest) RequestHash() string {
+	return r.requestHash
+}
+
+func (r IdempotentRequest) Response() []byte {
+	return append([]byte(nil), r.response...)
+}
+
+func (r IdempotentRequest) String() string {
+	return fmt.Sprintf("IdempotentRequest{scope: %q, key: %q, requestHash: %q}", r.scope, r.key, r.requestHash)
+}
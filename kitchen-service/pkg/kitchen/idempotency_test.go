@@ -0,0 +1,64 @@
+package kitchen
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type IdempotencyTestSuite struct {
+	suite.Suite
+}
+
+func TestIdempotencyTestSuite(t *testing.T) {
+	suite.Run(t, new(IdempotencyTestSuite))
+}
+
+// -- SUITE
+
+func (suite *IdempotencyTestSuite) Test_GIVEN_sameBody_WHEN_requestsAreCreated_THEN_requestsAreTheSame() {
+	// WHEN
+	first, err := NewIdempotentRequest("deliveries", "key-1", []byte(`{"stock":[]}`))
+	assert.Nil(suite.T(), err)
+	second, err := NewIdempotentRequest("deliveries", "key-1", []byte(`{"stock":[]}`))
+	assert.Nil(suite.T(), err)
+	different, err := NewIdempotentRequest("deliveries", "key-1", []byte(`{"stock":[{"name":"Cheese","units":1}]}`))
+	assert.Nil(suite.T(), err)
+
+	// THEN
+	assert.True(suite.T(), first.SameRequestAs(second))
+	assert.False(suite.T(), first.SameRequestAs(different))
+	assert.Equal(suite.T(), 64, len(first.RequestHash()))
+}
+
+func (suite *IdempotencyTestSuite) Test_GIVEN_blankKey_WHEN_requestIsCreated_THEN_errorIsReturned() {
+	// WHEN
+	_, err := NewIdempotentRequest("deliveries", "", []byte(`{}`))
+
+	// THEN
+	assert.NotNil(suite.T(), err)
+	assert.Equal(suite.T(), "Invalid idempotency key. Idempotency key must be 1 and 255 characters long", err.Error())
+}
+
+func (suite *IdempotencyTestSuite) Test_GIVEN_keyWithControlCharacters_WHEN_requestIsCreated_THEN_errorIsReturned() {
+	// WHEN
+	_, err := NewIdempotentRequest("deliveries", "key\n1", []byte(`{}`))
+
+	// THEN
+	assert.NotNil(suite.T(), err)
+	assert.Equal(suite.T(), "Invalid idempotency key. Idempotency key must only contain printable ASCII characters", err.Error())
+}
+
+func (suite *IdempotencyTestSuite) Test_GIVEN_request_WHEN_responseIsStored_THEN_requestIsAnswered() {
+	// GIVEN
+	request, _ := NewIdempotentRequest("deliveries", "key-1", []byte(`{}`))
+
+	// WHEN
+	answered := request.WithResponse([]byte(`{"stock":[]}`))
+
+	// THEN
+	assert.False(suite.T(), request.Answered())
+	assert.True(suite.T(), answered.Answered())
+	assert.Equal(suite.T(), `{"stock":[]}`, string(answered.Response()))
+}
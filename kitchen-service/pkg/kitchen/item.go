@@ -0,0 +1,133 @@
+package kitchen
+
+import (
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+
+	"github.com/gobuffalo/validate"
+	"github.com/gobuffalo/validate/validators"
+)
+
+// Item is the identity of an ingredient.
+// Names are compared without regard to case or repeated spaces, so "Tomatoes" and " tomatoes" are the same item.
+// An item is known by its canonical name, by its SKU and by any of its aliases.
+type Item struct {
+	id      uint64
+	sku     string
+	name    string
+	aliases []string
+}
+
+type ItemRecord interface {
+	Id() uint64
+	Sku() string
+	Name() string
+	Aliases() []string
+}
+
+var skuPattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+
+func NewItem(id uint64, sku string, name string, aliases []string) (Item, error) {
+	name = NormalizeItemName(name)
+	sku = strings.TrimSpace(sku)
+
+	errors := validate.Validate(
+		&validators.StringLengthInRange{Name: "Name", Field: name, Min: 1, Max: 25, Message: "Name must be 1 and 25 characters long"},
+		&validators.StringLengthInRange{Name: "Sku", Field: sku, Min: 0, Max: 64, Message: "SKU must be at most 64 characters long"},
+	)
+	if len(sku) > 0 && !skuPattern.MatchString(sku) {
+		errors.Add("sku", "SKU must only contain letters, digits, '.', '_' and '-'")
+	}
+
+	seen := map[string]bool{ItemKey(name): true}
+	normalized := []string{}
+	for _, alias := range aliases {
+		alias = NormalizeItemName(alias)
+		if len(alias) == 0 || len(alias) > 25 {
+			errors.Add("aliases", "Aliases must be 1 and 25 characters long")
+			continue
+		}
+		if seen[ItemKey(alias)] {
+			errors.Add("aliases", fmt.Sprintf("%q is already a name of %q", alias, name))
+			continue
+		}
+		seen[ItemKey(alias)] = true
+		normalized = append(normalized, alias)
+	}
+
+	if err := invalidErrorWithFields("Invalid item", errors); err != nil {
+		return Item{}, err
+	}
+
+	return Item{id, sku, name, normalized}, nil
+}
+
+func MustItem(item Item, err error) Item {
+	if err != nil {
+		log.Fatalf("Failed to create item. Reason: %q", err)
+	}
+	return item
+}
+
+func NewItemFromRecord(record ItemRecord) (Item, error) {
+	return NewItem(record.Id(), record.Sku(), record.Name(), record.Aliases())
+}
+
+// NormalizeItemName trims the name and replaces each run of spaces in it with a single space.
+func NormalizeItemName(name string) string {
+	return strings.Join(strings.Fields(name), " ")
+}
+
+// ItemKey is the same for every spelling of an item's name.
+func ItemKey(name string) string {
+	return strings.ToLower(NormalizeItemName(name))
+}
+
+// CanonicalNames returns the canonical name of the item of each of the names, given the items keyed by the ItemKey of the names.
+// Names that are not the name or alias of an item are returned as they are.
+func CanonicalNames(names []string, items map[string]Item) []string {
+	canonical := []string{}
+	for _, name := range names {
+		if item, ok := items[ItemKey(name)]; ok {
+			name = item.Name()
+		}
+		canonical = append(canonical, name)
+	}
+	return canonical
+}
+
+// Matches is true if name is the name or one of the aliases of the item.
+func (i Item) Matches(name string) bool {
+	key := ItemKey(name)
+	if key == ItemKey(i.name) {
+		return true
+	}
+	for _, alias := range i.aliases {
+		if key == ItemKey(alias) {
+			return true
+		}
+	}
+	return false
+}
+
+func (i Item) Id() uint64 {
+	return i.id
+}
+
+func (i Item) Sku() string {
+	return i.sku
+}
+
+func (i Item) Name() string {
+	return i.name
+}
+
+func (i Item) Aliases() []string {
+	return append([]string{}, i.aliases...)
+}
+
+func (i Item) String() string {
+	return fmt.Sprintf("Item{id: %d, sku: %q, name: %q, aliases: %q}", i.id, i.sku, i.name, i.aliases)
+}
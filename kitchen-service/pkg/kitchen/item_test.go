@@ -0,0 +1,84 @@
+package kitchen
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type ItemTestSuite struct {
+	suite.Suite
+}
+
+func TestItemTestSuite(t *testing.T) {
+	suite.Run(t, new(ItemTestSuite))
+}
+
+// -- SUITE
+
+func (suite *ItemTestSuite) Test_GIVEN_nameWithExtraSpaces_WHEN_itemIsCreated_THEN_nameIsNormalized() {
+	// WHEN
+	item, err := NewItem(1, "TOM-001", "  Cherry   Tomatoes ", []string{" cherries "})
+
+	// THEN
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), "Cherry Tomatoes", item.Name())
+	assert.Equal(suite.T(), "TOM-001", item.Sku())
+	assert.Equal(suite.T(), []string{"cherries"}, item.Aliases())
+}
+
+func (suite *ItemTestSuite) Test_GIVEN_differentCase_WHEN_itemIsMatched_THEN_nameAndAliasesMatch() {
+	// GIVEN
+	item, _ := NewItem(1, "", "Tomatoes", []string{"Tomato"})
+
+	// THEN
+	assert.True(suite.T(), item.Matches("tomatoes"))
+	assert.True(suite.T(), item.Matches(" TOMATO "))
+	assert.False(suite.T(), item.Matches("Potatoes"))
+}
+
+func (suite *ItemTestSuite) Test_GIVEN_aliasThatIsTheName_WHEN_itemIsCreated_THEN_errorIsReturned() {
+	// WHEN
+	_, err := NewItem(1, "", "Tomatoes", []string{"tomatoes"})
+
+	// THEN
+	assert.NotNil(suite.T(), err)
+	assert.Equal(suite.T(), "Invalid item. \"tomatoes\" is already a name of \"Tomatoes\"", err.Error())
+}
+
+func (suite *ItemTestSuite) Test_GIVEN_invalidSku_WHEN_itemIsCreated_THEN_errorIsReturned() {
+	// WHEN
+	_, err := NewItem(1, "TOM 001", "Tomatoes", nil)
+
+	// THEN
+	assert.NotNil(suite.T(), err)
+	assert.Equal(suite.T(), "Invalid item. SKU must only contain letters, digits, '.', '_' and '-'", err.Error())
+}
+
+func (suite *ItemTestSuite) Test_GIVEN_stockOfAnItem_WHEN_stockIsIdentified_THEN_canonicalNameIsUsed() {
+	// GIVEN
+	item, _ := NewItem(7, "TOM-001", "Tomatoes", nil)
+	stock := Must(NewStockItem("tomatoes", 2))
+
+	// WHEN
+	stock = stock.OfItem(item)
+
+	// THEN
+	assert.Equal(suite.T(), "Tomatoes", stock.Name())
+	assert.Equal(suite.T(), uint64(7), stock.ItemId())
+	assert.Equal(suite.T(), "TOM-001", stock.Sku())
+	assert.Equal(suite.T(), uint(2), stock.Units())
+}
+
+func (suite *ItemTestSuite) Test_GIVEN_namesAndAliases_WHEN_canonicalNamesAreFound_THEN_knownNamesAreReplacedByTheirItemName() {
+	// GIVEN
+	tomatoes, _ := NewItem(7, "TOM-001", "Tomatoes", []string{"Tomato"})
+	items := map[string]Item{ItemKey("tomato"): tomatoes}
+
+	// WHEN
+	names := CanonicalNames([]string{"tomato", "Olives"}, items)
+
+	// THEN
+	assert.Equal(suite.T(), []string{"Tomatoes", "Olives"}, names)
+}
@@ -0,0 +1,138 @@
+package kitchen
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/gobuffalo/validate"
+	"github.com/gobuffalo/validate/validators"
+)
+
+// Location identifies a kitchen.
+// Stock, deliveries and orders that do not specify a location belong to the DefaultLocation.
+type Location string
+
+const DefaultLocation Location = "main"
+
+var identifierPattern = regexp.MustCompile("^[a-z0-9][a-z0-9_-]{0,63}$")
+
+// ParseLocation returns the DefaultLocation if s is empty.
+// Locations are case-insensitive and are stored in lower case.
+func ParseLocation(s string) (Location, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if len(s) == 0 {
+		return DefaultLocation, nil
+	}
+	if !identifierPattern.MatchString(s) {
+		return "", InvalidError{
+			Cause:  fmt.Errorf("invalid location %q", s),
+			Fields: map[string]string{"location": "Location must be 1 to 64 letters, digits, hyphens or underscores"},
+		}
+	}
+	return Location(s), nil
+}
+
+// StockMovement is a change in the units of an item at a location.
+// Units are negative when stock leaves the location.
+type StockMovement struct {
+	Location Location
+	ItemName string
+	Units    int
+	UnitCost Money
+}
+
+// StockLevel is the number of units of an item at a location, including items that have run out.
+type StockLevel struct {
+	Location Location
+	ItemName string
+	Units    uint
+}
+
+// Transfer moves stock from one location to another.
+type Transfer struct {
+	id           uint64
+	fromLocation Location
+	toLocation   Location
+	stock        Stock
+	createdAt    time.Time
+}
+
+type TransferRecord interface {
+	Id() uint64
+	FromLocation() Location
+	ToLocation() Location
+	Stock() Stock
+	CreatedAt() time.Time
+}
+
+func NewTransfer(id uint64, fromLocation Location, toLocation Location, stock Stock, createdAt time.Time) (Transfer, error) {
+
+	errors := validate.Validate(
+		&validators.StringIsPresent{Name: "From Location", Field: string(fromLocation), Message: "From location is required"},
+		&validators.StringIsPresent{Name: "To Location", Field: string(toLocation), Message: "To location is required"},
+		&distinctLocationValidator{Name: "To Location", FromLocation: fromLocation, Field: toLocation},
+		&validators.IntIsGreaterThan{Name: "Stock", Field: len(stock), Compared: 0, Message: "At least one item must be transferred"},
+	)
+
+	if err := invalidErrorWithFields("Invalid transfer", errors); err != nil {
+		return Transfer{}, err
+	}
+
+	return Transfer{
+		id,
+		fromLocation,
+		toLocation,
+		stock,
+		createdAt,
+	}, nil
+}
+
+func NewTransferFromRecord(record TransferRecord) (Transfer, error) {
+	return NewTransfer(record.Id(), record.FromLocation(), record.ToLocation(), record.Stock(), record.CreatedAt())
+}
+
+type distinctLocationValidator struct {
+	Name         string
+	FromLocation Location
+	Field        Location
+}
+
+func (v *distinctLocationValidator) IsValid(errors *validate.Errors) {
+	if v.FromLocation == v.Field {
+		errors.Add(validators.GenerateKey(v.Name), "Stock can not be transferred to the location that it is transferred from")
+	}
+}
+
+func (t Transfer) Id() uint64 {
+	return t.id
+}
+
+func (t Transfer) FromLocation() Location {
+	return t.fromLocation
+}
+
+func (t Transfer) ToLocation() Location {
+	return t.toLocation
+}
+
+func (t Transfer) Stock() Stock {
+	return t.stock
+}
+
+func (t Transfer) CreatedAt() time.Time {
+	return t.createdAt
+}
+
+// Movements returns a pair of movements for each item: one out of the source location and one into the destination.
+func (t Transfer) Movements() []StockMovement {
+	movements := []StockMovement{}
+	for _, item := range t.stock {
+		movements = append(movements,
+			StockMovement{t.fromLocation, item.Name(), -int(item.Units()), item.UnitCost()},
+			StockMovement{t.toLocation, item.Name(), int(item.Units()), item.UnitCost()},
+		)
+	}
+	return movements
+}
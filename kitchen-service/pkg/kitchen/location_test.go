@@ -0,0 +1,70 @@
+package kitchen
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type LocationTestSuite struct {
+	suite.Suite
+}
+
+func TestLocationTestSuite(t *testing.T) {
+	suite.Run(t, new(LocationTestSuite))
+}
+
+// -- SUITE
+
+func (suite *LocationTestSuite) Test_GIVEN_noLocation_WHEN_parsed_THEN_defaultLocationIsReturned() {
+	// WHEN
+	location, err := ParseLocation("  ")
+
+	// THEN
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), DefaultLocation, location)
+}
+
+func (suite *LocationTestSuite) Test_GIVEN_mixedCaseLocation_WHEN_parsed_THEN_locationIsLowerCase() {
+	// WHEN
+	location, err := ParseLocation("Dubai-Marina")
+
+	// THEN
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), Location("dubai-marina"), location)
+}
+
+func (suite *LocationTestSuite) Test_GIVEN_locationWithSpaces_WHEN_parsed_THEN_errorIsReturned() {
+	// WHEN
+	_, err := ParseLocation("dubai marina")
+
+	// THEN
+	assert.NotNil(suite.T(), err)
+	assert.Equal(suite.T(), "Location must be 1 to 64 letters, digits, hyphens or underscores", err.(InvalidError).Fields["location"])
+}
+
+func (suite *LocationTestSuite) Test_GIVEN_sameSourceAndDestination_WHEN_transferIsCreated_THEN_errorIsReturned() {
+	// WHEN
+	_, err := NewTransfer(0, DefaultLocation, DefaultLocation, Stock{Must(NewStockItem("Cheese", 1))}, time.Now())
+
+	// THEN
+	assert.NotNil(suite.T(), err)
+	assert.Equal(suite.T(), "Stock can not be transferred to the location that it is transferred from", err.(InvalidError).Fields["to_location"])
+}
+
+func (suite *LocationTestSuite) Test_GIVEN_transfer_WHEN_movementsAreListed_THEN_eachItemHasAPairOfMovements() {
+	// GIVEN
+	cheese, _ := Must(NewStockItem("Cheese", 3)).WithUnitCost(Money(15000))
+	transfer, _ := NewTransfer(1, DefaultLocation, Location("marina"), Stock{cheese}, time.Now())
+
+	// WHEN
+	movements := transfer.Movements()
+
+	// THEN
+	assert.Equal(suite.T(), []StockMovement{
+		{DefaultLocation, "Cheese", -3, Money(15000)},
+		{Location("marina"), "Cheese", 3, Money(15000)},
+	}, movements)
+}
@@ -0,0 +1,94 @@
+package kitchen
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+const (
+	moneyScale     = 10000
+	moneyPrecision = 4
+)
+
+// Money is an amount in ten-thousandths of the currency unit.
+// Average costs are kept to four decimal places so that they are not rounded to the cent on every delivery.
+type Money int64
+
+func ParseMoney(s string) (Money, error) {
+	amount, ok := new(big.Rat).SetString(strings.TrimSpace(s))
+	if !ok {
+		return 0, InvalidError{Cause: fmt.Errorf("invalid amount %q", s)}
+	}
+	scaled := new(big.Rat).Mul(amount, big.NewRat(moneyScale, 1))
+	if !scaled.IsInt() {
+		return 0, InvalidError{Cause: fmt.Errorf("amount %q has more than %d decimal places", s, moneyPrecision)}
+	}
+	if !scaled.Num().IsInt64() {
+		return 0, InvalidError{Cause: fmt.Errorf("amount %q is too large", s)}
+	}
+	return Money(scaled.Num().Int64()), nil
+}
+
+func (m Money) Times(units uint) Money {
+	return m * Money(units)
+}
+
+func (m Money) String() string {
+	sign := ""
+	value := int64(m)
+	if value < 0 {
+		sign = "-"
+		value = -value
+	}
+	return fmt.Sprintf("%s%d.%04d", sign, value/moneyScale, value%moneyScale)
+}
+
+func (m Money) MarshalJSON() ([]byte, error) {
+	return []byte(m.String()), nil
+}
+
+func (m *Money) UnmarshalJSON(b []byte) error {
+	var number json.Number
+	if err := json.Unmarshal(b, &number); err != nil {
+		return fmt.Errorf("invalid amount %s. Reason: %w", string(b), err)
+	}
+	amount, err := ParseMoney(number.String())
+	if err != nil {
+		return err
+	}
+	*m = amount
+	return nil
+}
+
+func (m Money) Value() (driver.Value, error) {
+	return m.String(), nil
+}
+
+func (m *Money) Scan(src interface{}) error {
+	var (
+		amount Money
+		err    error
+	)
+	switch value := src.(type) {
+	case nil:
+		amount = 0
+	case []byte:
+		amount, err = ParseMoney(string(value))
+	case string:
+		amount, err = ParseMoney(value)
+	case int64:
+		amount = Money(value * moneyScale)
+	case float64:
+		amount, err = ParseMoney(fmt.Sprintf("%.4f", value))
+	default:
+		err = fmt.Errorf("can not scan %T into Money", src)
+	}
+	if err != nil {
+		return err
+	}
+	*m = amount
+	return nil
+}
@@ -0,0 +1,74 @@
+package kitchen
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type MoneyTestSuite struct {
+	suite.Suite
+}
+
+func TestMoneyTestSuite(t *testing.T) {
+	suite.Run(t, new(MoneyTestSuite))
+}
+
+// -- SUITE
+
+func (suite *MoneyTestSuite) Test_GIVEN_decimalAmount_WHEN_parsed_THEN_amountIsKeptToFourDecimalPlaces() {
+	// WHEN
+	amount, err := ParseMoney("1.25")
+
+	// THEN
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), Money(12500), amount)
+	assert.Equal(suite.T(), "1.2500", amount.String())
+}
+
+func (suite *MoneyTestSuite) Test_GIVEN_amountWithTooManyDecimalPlaces_WHEN_parsed_THEN_errorIsReturned() {
+	// WHEN
+	_, err := ParseMoney("0.00001")
+
+	// THEN
+	assert.NotNil(suite.T(), err)
+	assert.Equal(suite.T(), "amount \"0.00001\" has more than 4 decimal places", err.Error())
+}
+
+func (suite *MoneyTestSuite) Test_GIVEN_jsonNumber_WHEN_unmarshalled_THEN_amountIsParsed() {
+	// GIVEN
+	var item struct {
+		UnitCost Money `json:"unitCost"`
+	}
+
+	// WHEN
+	err := json.Unmarshal([]byte(`{"unitCost": 0.35}`), &item)
+
+	// THEN
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), Money(3500), item.UnitCost)
+}
+
+func (suite *MoneyTestSuite) Test_GIVEN_negativeAmount_WHEN_marshalled_THEN_jsonNumberIsWritten() {
+	// WHEN
+	bytes, err := json.Marshal(Money(-10050))
+
+	// THEN
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), "-1.0050", string(bytes))
+}
+
+func (suite *MoneyTestSuite) Test_GIVEN_orderItems_WHEN_costOfGoodsIsCalculated_THEN_unitCostsAreMultipliedByUnits() {
+	// GIVEN
+	order, err := NewOrder(1, OrderStatusReady, "", []OrderItem{
+		NewOrderItem("Cheese", 2, Money(12500)),
+		NewOrderItem("Tomato", 1, Money(3333)),
+	}, time.Now())
+
+	// THEN
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), "2.8333", order.CostOfGoods().String())
+}
@@ -1,9 +1,227 @@
 package kitchen
 
+import (
+	"fmt"
+	"time"
+
+	"github.com/gobuffalo/validate"
+	"github.com/gobuffalo/validate/validators"
+)
+
 type OrderStatus string
 
 const (
+	// OrderStatusQueued is an order that has been received but not scheduled yet. It is reported in events, but orders are not saved with it.
+	OrderStatusQueued    OrderStatus = "QUEUED"
 	OrderStatusPreparing OrderStatus = "PREPARING"
 	OrderStatusReady     OrderStatus = "READY"
 	OrderStatusFailed    OrderStatus = "FAILED"
 )
+
+// OrderItem is an ingredient consumed by an order.
+// unitCost is the average cost of the ingredient at the time that it was consumed.
+type OrderItem struct {
+	name     string
+	units    uint
+	unitCost Money
+}
+
+func NewOrderItem(name string, units uint, unitCost Money) OrderItem {
+	return OrderItem{name, units, unitCost}
+}
+
+func (i OrderItem) Name() string {
+	return i.name
+}
+
+func (i OrderItem) Units() uint {
+	return i.units
+}
+
+func (i OrderItem) UnitCost() Money {
+	return i.unitCost
+}
+
+func (i OrderItem) CostOfGoods() Money {
+	return i.unitCost.Times(i.units)
+}
+
+type Order struct {
+	id            uint64
+	status        OrderStatus
+	failureReason string
+	items         []OrderItem
+	substitutions Substitutions
+	tasks         Tasks
+	estimator     string
+	priority      OrderPriority
+	deadline      time.Time
+	location      Location
+	createdAt     time.Time
+}
+
+type OrderRecord interface {
+	Id() uint64
+	Status() OrderStatus
+	FailureReason() string
+	Items() []OrderItem
+	Substitutions() Substitutions
+	Tasks() Tasks
+	Estimator() string
+	Priority() OrderPriority
+	Deadline() time.Time
+	Location() Location
+	CreatedAt() time.Time
+}
+
+func NewOrder(id uint64, status OrderStatus, failureReason string, items []OrderItem, createdAt time.Time) (Order, error) {
+
+	errors := validate.Validate(
+		&validators.IntIsGreaterThan{Name: "Id", Field: int(id), Compared: 0, Message: "Order id is required"},
+		&validators.StringInclusion{Name: "Status", Field: string(status), List: []string{string(OrderStatusPreparing), string(OrderStatusReady), string(OrderStatusFailed)}, Message: fmt.Sprintf("Unknown order status %q", status)},
+	)
+
+	if err := invalidErrorWithFields("Invalid order", errors); err != nil {
+		return Order{}, err
+	}
+
+	return Order{
+		id,
+		status,
+		failureReason,
+		items,
+		Substitutions{},
+		Tasks{},
+		"",
+		DefaultOrderPriority,
+		time.Time{},
+		DefaultLocation,
+		createdAt,
+	}, nil
+}
+
+func NewOrderFromRecord(record OrderRecord) (Order, error) {
+	order, err := NewOrder(record.Id(), record.Status(), record.FailureReason(), record.Items(), record.CreatedAt())
+	if err != nil {
+		return Order{}, err
+	}
+	return order.WithSubstitutions(record.Substitutions()).WithTasks(record.Tasks()).EstimatedBy(record.Estimator()).WithDeadline(record.Priority(), record.Deadline()).AtLocation(record.Location()), nil
+}
+
+// WithSubstitutions returns a copy of the order in which some of the ingredients were substituted.
+// The items of the order are the ingredients that were actually used.
+func (o Order) WithSubstitutions(substitutions Substitutions) Order {
+	o.substitutions = substitutions
+	return o
+}
+
+func (o Order) Id() uint64 {
+	return o.id
+}
+
+func (o Order) Status() OrderStatus {
+	return o.status
+}
+
+func (o Order) FailureReason() string {
+	return o.failureReason
+}
+
+func (o Order) Items() []OrderItem {
+	return o.items
+}
+
+func (o Order) Substitutions() Substitutions {
+	return o.substitutions
+}
+
+// WithTasks returns a copy of the order that is prepared by the scheduled station tasks.
+func (o Order) WithTasks(tasks Tasks) Order {
+	o.tasks = tasks
+	return o
+}
+
+func (o Order) Tasks() Tasks {
+	return o.tasks
+}
+
+// EstimatedBy returns a copy of the order whose tasks were estimated by the named estimator.
+func (o Order) EstimatedBy(estimator string) Order {
+	o.estimator = estimator
+	return o
+}
+
+func (o Order) Estimator() string {
+	return o.estimator
+}
+
+// WithDeadline returns a copy of the order that must be ready by deadline.
+func (o Order) WithDeadline(priority OrderPriority, deadline time.Time) Order {
+	o.priority = priority
+	o.deadline = deadline
+	return o
+}
+
+func (o Order) Priority() OrderPriority {
+	return o.priority
+}
+
+// Deadline is the zero time if the order does not have a deadline.
+func (o Order) Deadline() time.Time {
+	return o.deadline
+}
+
+// AtRisk is true if the order is not expected to be ready by its deadline.
+func (o Order) AtRisk() bool {
+	readyAt := o.EstimatedReadyAt()
+	return !o.deadline.IsZero() && !readyAt.IsZero() && readyAt.After(o.deadline)
+}
+
+// EstimatedReadyAt is when the last station task of the order ends.
+// It is the zero time if the order has no tasks.
+func (o Order) EstimatedReadyAt() time.Time {
+	return o.tasks.EstimatedReadyAt()
+}
+
+// AtLocation returns a copy of the order that is prepared at location.
+func (o Order) AtLocation(location Location) Order {
+	o.location = location
+	return o
+}
+
+func (o Order) Location() Location {
+	return o.location
+}
+
+func (o Order) CreatedAt() time.Time {
+	return o.createdAt
+}
+
+func (o Order) CostOfGoods() Money {
+	var total Money
+	for _, item := range o.items {
+		total += item.CostOfGoods()
+	}
+	return total
+}
+
+type CostOfGoodsLine struct {
+	Name        string
+	Units       uint
+	CostOfGoods Money
+}
+
+// CostOfGoodsReport is the cost of the ingredients consumed by the orders prepared on a single day.
+type CostOfGoodsReport struct {
+	Date   time.Time
+	Orders uint
+	Lines  []CostOfGoodsLine
+}
+
+func (r CostOfGoodsReport) CostOfGoods() Money {
+	var total Money
+	for _, line := range r.Lines {
+		total += line.CostOfGoods
+	}
+	return total
+}
@@ -0,0 +1,58 @@
+package kitchen
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// OrderPriority is how urgently an order is needed.
+// Orders that are not given a promised-by time must be ready within the target time of their priority.
+type OrderPriority string
+
+const (
+	OrderPriorityVIP      OrderPriority = "VIP"
+	OrderPriorityDelivery OrderPriority = "DELIVERY"
+	OrderPriorityDineIn   OrderPriority = "DINE_IN"
+
+	DefaultOrderPriority = OrderPriorityDineIn
+)
+
+var orderPriorityTargets = map[OrderPriority]time.Duration{
+	OrderPriorityVIP:      5 * time.Minute,
+	OrderPriorityDelivery: 10 * time.Minute,
+	OrderPriorityDineIn:   15 * time.Minute,
+}
+
+// ParseOrderPriority returns the default priority if s is empty.
+func ParseOrderPriority(s string) (OrderPriority, error) {
+	s = strings.ToUpper(strings.TrimSpace(s))
+	if len(s) == 0 {
+		return DefaultOrderPriority, nil
+	}
+	priority := OrderPriority(strings.Replace(s, "-", "_", -1))
+	if _, ok := orderPriorityTargets[priority]; !ok {
+		return "", InvalidError{
+			Cause:  fmt.Errorf("unknown order priority %q", s),
+			Fields: map[string]string{"priority": "Priority must be one of VIP, DELIVERY or DINE_IN"},
+		}
+	}
+	return priority, nil
+}
+
+// Target is how long after it was received an order of this priority should be ready.
+func (p OrderPriority) Target() time.Duration {
+	if target, ok := orderPriorityTargets[p]; ok {
+		return target
+	}
+	return orderPriorityTargets[DefaultOrderPriority]
+}
+
+// Deadline is when an order must be ready: the time that it was promised by,
+// or the target time of its priority after it was received if it was not promised by a time.
+func (p OrderPriority) Deadline(promisedBy time.Time, receivedAt time.Time) time.Time {
+	if !promisedBy.IsZero() {
+		return promisedBy
+	}
+	return receivedAt.Add(p.Target())
+}
@@ -0,0 +1,72 @@
+package kitchen
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type PriorityTestSuite struct {
+	suite.Suite
+}
+
+func TestPriorityTestSuite(t *testing.T) {
+	suite.Run(t, new(PriorityTestSuite))
+}
+
+// -- SUITE
+
+func (suite *PriorityTestSuite) Test_GIVEN_emptyPriority_WHEN_parsed_THEN_defaultPriorityIsReturned() {
+	// WHEN
+	priority, err := ParseOrderPriority("")
+
+	// THEN
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), OrderPriorityDineIn, priority)
+}
+
+func (suite *PriorityTestSuite) Test_GIVEN_lowerCasePriority_WHEN_parsed_THEN_priorityIsReturned() {
+	// WHEN
+	priority, err := ParseOrderPriority("dine-in")
+
+	// THEN
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), OrderPriorityDineIn, priority)
+}
+
+func (suite *PriorityTestSuite) Test_GIVEN_unknownPriority_WHEN_parsed_THEN_errorIsReturned() {
+	// WHEN
+	_, err := ParseOrderPriority("urgent")
+
+	// THEN
+	assert.NotNil(suite.T(), err)
+	assert.Equal(suite.T(), "Priority must be one of VIP, DELIVERY or DINE_IN", err.(InvalidError).Fields["priority"])
+}
+
+func (suite *PriorityTestSuite) Test_GIVEN_noPromisedByTime_WHEN_deadlineIsCalculated_THEN_targetOfPriorityIsUsed() {
+	// GIVEN
+	receivedAt := time.Date(2022, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	// WHEN
+	deadline := OrderPriorityVIP.Deadline(time.Time{}, receivedAt)
+
+	// THEN
+	assert.Equal(suite.T(), receivedAt.Add(5*time.Minute), deadline)
+}
+
+func (suite *PriorityTestSuite) Test_GIVEN_orderExpectedAfterDeadline_WHEN_checked_THEN_orderIsAtRisk() {
+	// GIVEN
+	now := time.Date(2022, 1, 1, 12, 0, 0, 0, time.UTC)
+	order, _ := NewOrder(1, OrderStatusPreparing, "", []OrderItem{}, now)
+	tasks := Tasks{{OrderId: 1, ItemName: "Cheese", Station: DefaultStation, StartsAt: now, EndsAt: now.Add(10 * time.Minute)}}
+
+	// WHEN
+	atRisk := order.WithTasks(tasks).WithDeadline(OrderPriorityVIP, now.Add(5*time.Minute)).AtRisk()
+	onTime := order.WithTasks(tasks).WithDeadline(OrderPriorityDineIn, now.Add(15*time.Minute)).AtRisk()
+
+	// THEN
+	assert.True(suite.T(), atRisk)
+	assert.False(suite.T(), onTime)
+}
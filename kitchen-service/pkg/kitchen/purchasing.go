@@ -0,0 +1,282 @@
+package kitchen
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/gobuffalo/validate"
+	"github.com/gobuffalo/validate/validators"
+)
+
+type Supplier struct {
+	id    uint64
+	name  string
+	email string
+}
+
+type SupplierRecord interface {
+	Id() uint64
+	Name() string
+	Email() string
+}
+
+func NewSupplier(id uint64, name string, email string) (Supplier, error) {
+
+	errors := validate.Validate(
+		&validators.StringLengthInRange{Name: "Name", Field: name, Min: 1, Max: 255, Message: "Name must be 1 and 255 characters long"},
+	)
+	if len(email) > 0 {
+		errors.Append(validate.Validate(
+			&validators.EmailLike{Name: "Email", Field: email, Message: "Email must be a valid email address"},
+		))
+	}
+
+	if err := invalidErrorWithFields("Invalid supplier", errors); err != nil {
+		return Supplier{}, err
+	}
+
+	return Supplier{
+		id,
+		name,
+		email,
+	}, nil
+}
+
+func NewSupplierFromRecord(record SupplierRecord) (Supplier, error) {
+	return NewSupplier(record.Id(), record.Name(), record.Email())
+}
+
+func (s Supplier) Id() uint64 {
+	return s.id
+}
+
+func (s Supplier) Name() string {
+	return s.name
+}
+
+func (s Supplier) Email() string {
+	return s.email
+}
+
+type PurchaseOrderStatus string
+
+const (
+	PurchaseOrderStatusOpen      PurchaseOrderStatus = "OPEN"
+	PurchaseOrderStatusReceived  PurchaseOrderStatus = "RECEIVED"
+	PurchaseOrderStatusCancelled PurchaseOrderStatus = "CANCELLED"
+)
+
+// PurchaseOrderLine is a single item on a purchase order.
+// receivedUnits is zero until a delivery has been matched against the order.
+type PurchaseOrderLine struct {
+	itemName      string
+	orderedUnits  uint
+	receivedUnits uint
+}
+
+func NewPurchaseOrderLine(itemName string, orderedUnits uint, receivedUnits uint) (PurchaseOrderLine, error) {
+
+	errors := validate.Validate(
+		&validators.StringLengthInRange{Name: "Item Name", Field: itemName, Min: 1, Max: 25, Message: "Item name must be 1 and 25 characters long"},
+		&validators.IntIsGreaterThan{Name: "Units", Field: int(orderedUnits + receivedUnits), Compared: 0, Message: "Ordered units must be greater than 0"},
+	)
+
+	if err := invalidErrorWithFields("Invalid purchase order line", errors); err != nil {
+		return PurchaseOrderLine{}, err
+	}
+
+	return PurchaseOrderLine{
+		itemName,
+		orderedUnits,
+		receivedUnits,
+	}, nil
+}
+
+func (l PurchaseOrderLine) ItemName() string {
+	return l.itemName
+}
+
+func (l PurchaseOrderLine) OrderedUnits() uint {
+	return l.orderedUnits
+}
+
+func (l PurchaseOrderLine) ReceivedUnits() uint {
+	return l.receivedUnits
+}
+
+type PurchaseOrder struct {
+	id                   uint64
+	supplierId           uint64
+	status               PurchaseOrderStatus
+	expectedDeliveryDate time.Time
+	lines                []PurchaseOrderLine
+}
+
+type PurchaseOrderRecord interface {
+	Id() uint64
+	SupplierId() uint64
+	Status() PurchaseOrderStatus
+	ExpectedDeliveryDate() time.Time
+	Lines() []PurchaseOrderLine
+}
+
+func NewPurchaseOrder(
+	id uint64,
+	supplierId uint64,
+	status PurchaseOrderStatus,
+	expectedDeliveryDate time.Time,
+	lines []PurchaseOrderLine,
+) (PurchaseOrder, error) {
+
+	errors := validate.Validate(
+		&validators.IntIsGreaterThan{Name: "Supplier Id", Field: int(supplierId), Compared: 0, Message: "Supplier id is required"},
+		&validators.StringInclusion{Name: "Status", Field: string(status), List: []string{string(PurchaseOrderStatusOpen), string(PurchaseOrderStatusReceived), string(PurchaseOrderStatusCancelled)}, Message: fmt.Sprintf("Unknown purchase order status %q", status)},
+		&validators.TimeIsPresent{Name: "Expected Delivery Date", Field: expectedDeliveryDate, Message: "Expected delivery date is required"},
+		&purchaseOrderLinesValidator{Name: "Lines", Field: lines},
+	)
+
+	if err := invalidErrorWithFields("Invalid purchase order", errors); err != nil {
+		return PurchaseOrder{}, err
+	}
+
+	return PurchaseOrder{
+		id,
+		supplierId,
+		status,
+		expectedDeliveryDate,
+		lines,
+	}, nil
+}
+
+func NewPurchaseOrderFromRecord(record PurchaseOrderRecord) (PurchaseOrder, error) {
+	return NewPurchaseOrder(
+		record.Id(),
+		record.SupplierId(),
+		record.Status(),
+		record.ExpectedDeliveryDate(),
+		record.Lines(),
+	)
+}
+
+type purchaseOrderLinesValidator struct {
+	Name  string
+	Field []PurchaseOrderLine
+}
+
+func (v *purchaseOrderLinesValidator) IsValid(errors *validate.Errors) {
+	if len(v.Field) == 0 {
+		errors.Add(validators.GenerateKey(v.Name), "Purchase order must have at least one line")
+		return
+	}
+	seen := map[string]bool{}
+	for _, line := range v.Field {
+		key := ItemKey(line.ItemName())
+		if seen[key] {
+			errors.Add(validators.GenerateKey(v.Name), fmt.Sprintf("Item %q appears on more than one line", line.ItemName()))
+		}
+		seen[key] = true
+	}
+}
+
+func (po PurchaseOrder) Id() uint64 {
+	return po.id
+}
+
+func (po PurchaseOrder) SupplierId() uint64 {
+	return po.supplierId
+}
+
+func (po PurchaseOrder) Status() PurchaseOrderStatus {
+	return po.status
+}
+
+func (po PurchaseOrder) ExpectedDeliveryDate() time.Time {
+	return po.expectedDeliveryDate
+}
+
+func (po PurchaseOrder) Lines() []PurchaseOrderLine {
+	return po.lines
+}
+
+func (po PurchaseOrder) IsOpen() bool {
+	return po.status == PurchaseOrderStatusOpen
+}
+
+// Receive matches a delivery against the purchase order and returns the received order.
+// Delivered items are matched to lines by ItemKey, so a line is received whichever way the item is spelled.
+// Items that were delivered but not ordered are added as lines with zero ordered units,
+// so that they show up as over-deliveries when the order is reconciled.
+// A purchase order that is no longer open returns a ConflictError.
+func (po PurchaseOrder) Receive(delivery Stock) (PurchaseOrder, error) {
+	if !po.IsOpen() {
+		return PurchaseOrder{}, NewConflictError(fmt.Sprintf("purchase order %d is %s and can not receive deliveries", po.id, po.status))
+	}
+
+	received := map[string]uint{}
+	names := map[string]string{}
+	for _, item := range delivery {
+		key := ItemKey(item.Name())
+		received[key] += item.Units()
+		if _, ok := names[key]; !ok {
+			names[key] = item.Name()
+		}
+	}
+
+	lines := []PurchaseOrderLine{}
+	for _, line := range po.lines {
+		key := ItemKey(line.itemName)
+		lines = append(lines, PurchaseOrderLine{line.itemName, line.orderedUnits, line.receivedUnits + received[key]})
+		delete(received, key)
+	}
+
+	unexpected := []string{}
+	for key := range received {
+		unexpected = append(unexpected, key)
+	}
+	sort.Strings(unexpected)
+	for _, key := range unexpected {
+		lines = append(lines, PurchaseOrderLine{names[key], 0, received[key]})
+	}
+
+	return PurchaseOrder{
+		po.id,
+		po.supplierId,
+		PurchaseOrderStatusReceived,
+		po.expectedDeliveryDate,
+		lines,
+	}, nil
+}
+
+type DeliveryDiscrepancyKind string
+
+const (
+	OverDelivery  DeliveryDiscrepancyKind = "OVER"
+	UnderDelivery DeliveryDiscrepancyKind = "UNDER"
+)
+
+type DeliveryDiscrepancy struct {
+	ItemName      string
+	OrderedUnits  uint
+	ReceivedUnits uint
+	Kind          DeliveryDiscrepancyKind
+}
+
+// Discrepancies lists the lines where the received units differ from the ordered units.
+// An order that has not been received yet has no discrepancies.
+func (po PurchaseOrder) Discrepancies() []DeliveryDiscrepancy {
+	discrepancies := []DeliveryDiscrepancy{}
+	if po.status != PurchaseOrderStatusReceived {
+		return discrepancies
+	}
+
+	for _, line := range po.lines {
+		switch {
+		case line.receivedUnits > line.orderedUnits:
+			discrepancies = append(discrepancies, DeliveryDiscrepancy{line.itemName, line.orderedUnits, line.receivedUnits, OverDelivery})
+		case line.receivedUnits < line.orderedUnits:
+			discrepancies = append(discrepancies, DeliveryDiscrepancy{line.itemName, line.orderedUnits, line.receivedUnits, UnderDelivery})
+		}
+	}
+	return discrepancies
+}
@@ -0,0 +1,143 @@
+package kitchen
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type PurchasingTestSuite struct {
+	suite.Suite
+}
+
+func TestPurchasingTestSuite(t *testing.T) {
+	suite.Run(t, new(PurchasingTestSuite))
+}
+
+// -- SUITE
+
+func (suite *PurchasingTestSuite) Test_GIVEN_aBlankName_WHEN_supplierIsCreated_THEN_errorIsReturned() {
+	// WHEN
+	_, err := NewSupplier(0, "", "")
+
+	// THEN
+	assert.NotNil(suite.T(), err)
+	assert.Equal(suite.T(), "Invalid supplier. Name must be 1 and 255 characters long", err.Error())
+}
+
+func (suite *PurchasingTestSuite) Test_GIVEN_anInvalidEmail_WHEN_supplierIsCreated_THEN_errorIsReturned() {
+	// WHEN
+	_, err := NewSupplier(0, "Farm Fresh", "farmfresh")
+
+	// THEN
+	assert.NotNil(suite.T(), err)
+	assert.Equal(suite.T(), "Invalid supplier. Email must be a valid email address", err.Error())
+}
+
+func (suite *PurchasingTestSuite) Test_GIVEN_noLines_WHEN_purchaseOrderIsCreated_THEN_errorIsReturned() {
+	// WHEN
+	_, err := NewPurchaseOrder(0, 1, PurchaseOrderStatusOpen, time.Now(), []PurchaseOrderLine{})
+
+	// THEN
+	assert.NotNil(suite.T(), err)
+	assert.Equal(suite.T(), "Invalid purchase order. Purchase order must have at least one line", err.Error())
+}
+
+func (suite *PurchasingTestSuite) Test_GIVEN_repeatedItems_WHEN_purchaseOrderIsCreated_THEN_errorIsReturned() {
+	// GIVEN
+	lines := []PurchaseOrderLine{
+		mustLine(NewPurchaseOrderLine("Cheese", 5, 0)),
+		mustLine(NewPurchaseOrderLine("Cheese", 2, 0)),
+	}
+
+	// WHEN
+	_, err := NewPurchaseOrder(0, 1, PurchaseOrderStatusOpen, time.Now(), lines)
+
+	// THEN
+	assert.NotNil(suite.T(), err)
+	assert.Equal(suite.T(), "Invalid purchase order. Item \"Cheese\" appears on more than one line", err.Error())
+}
+
+func (suite *PurchasingTestSuite) Test_GIVEN_openPurchaseOrder_WHEN_deliveryIsReceived_THEN_overAndUnderDeliveriesAreFlagged() {
+	// GIVEN
+	po, err := NewPurchaseOrder(1, 1, PurchaseOrderStatusOpen, time.Now(), []PurchaseOrderLine{
+		mustLine(NewPurchaseOrderLine("Cheese", 5, 0)),
+		mustLine(NewPurchaseOrderLine("Tomato", 10, 0)),
+		mustLine(NewPurchaseOrderLine("Onion", 4, 0)),
+	})
+	assert.Nil(suite.T(), err)
+	assert.Empty(suite.T(), po.Discrepancies())
+
+	// WHEN
+	received, err := po.Receive(Stock{
+		Must(NewStockItem("Cheese", 5)),
+		Must(NewStockItem("Tomato", 7)),
+		Must(NewStockItem("Onion", 6)),
+		Must(NewStockItem("Basil", 1)),
+	})
+
+	// THEN
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), PurchaseOrderStatusReceived, received.Status())
+	assert.Equal(suite.T(), []DeliveryDiscrepancy{
+		{"Tomato", 10, 7, UnderDelivery},
+		{"Onion", 4, 6, OverDelivery},
+		{"Basil", 0, 1, OverDelivery},
+	}, received.Discrepancies())
+}
+
+func (suite *PurchasingTestSuite) Test_GIVEN_itemSpelledDifferently_WHEN_purchaseOrderIsCreated_THEN_errorIsReturned() {
+	// GIVEN
+	lines := []PurchaseOrderLine{
+		mustLine(NewPurchaseOrderLine("Cheese", 5, 0)),
+		mustLine(NewPurchaseOrderLine("cheese ", 2, 0)),
+	}
+
+	// WHEN
+	_, err := NewPurchaseOrder(0, 1, PurchaseOrderStatusOpen, time.Now(), lines)
+
+	// THEN
+	assert.NotNil(suite.T(), err)
+	assert.Equal(suite.T(), "Invalid purchase order. Item \"cheese \" appears on more than one line", err.Error())
+}
+
+func (suite *PurchasingTestSuite) Test_GIVEN_deliverySpelledDifferently_WHEN_deliveryIsReceived_THEN_deliveryIsMatchedToLine() {
+	// GIVEN
+	po, _ := NewPurchaseOrder(1, 1, PurchaseOrderStatusOpen, time.Now(), []PurchaseOrderLine{
+		mustLine(NewPurchaseOrderLine("Cheese", 5, 0)),
+	})
+
+	// WHEN
+	received, err := po.Receive(Stock{
+		Must(NewStockItem("cheese", 3)),
+		Must(NewStockItem("CHEESE", 2)),
+	})
+
+	// THEN
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), []PurchaseOrderLine{mustLine(NewPurchaseOrderLine("Cheese", 5, 5))}, received.Lines())
+	assert.Empty(suite.T(), received.Discrepancies())
+}
+
+func (suite *PurchasingTestSuite) Test_GIVEN_receivedPurchaseOrder_WHEN_deliveryIsReceived_THEN_errorIsReturned() {
+	// GIVEN
+	po, _ := NewPurchaseOrder(1, 1, PurchaseOrderStatusReceived, time.Now(), []PurchaseOrderLine{
+		mustLine(NewPurchaseOrderLine("Cheese", 5, 5)),
+	})
+
+	// WHEN
+	_, err := po.Receive(Stock{Must(NewStockItem("Cheese", 5))})
+
+	// THEN
+	assert.IsType(suite.T(), ConflictError{}, err)
+	assert.Equal(suite.T(), "purchase order 1 is RECEIVED and can not receive deliveries", err.Error())
+}
+
+func mustLine(line PurchaseOrderLine, err error) PurchaseOrderLine {
+	if err != nil {
+		panic(err)
+	}
+	return line
+}
@@ -0,0 +1,182 @@
+package kitchen
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/gobuffalo/validate"
+	"github.com/gobuffalo/validate/validators"
+)
+
+// KitchenStation is a part of the kitchen, such as an oven, grill or fryer,
+// that can work on as many tasks at the same time as its capacity.
+type KitchenStation struct {
+	name     Station
+	capacity uint
+}
+
+func NewKitchenStation(name Station, capacity uint) (KitchenStation, error) {
+
+	errors := validate.Validate(
+		&validators.StringIsPresent{Name: "Name", Field: string(name), Message: "Name is required"},
+		&validators.IntIsGreaterThan{Name: "Capacity", Field: int(capacity), Compared: 0, Message: "Capacity must be greater than 0"},
+	)
+
+	if err := invalidErrorWithFields("Invalid station", errors); err != nil {
+		return KitchenStation{}, err
+	}
+
+	return KitchenStation{
+		name,
+		capacity,
+	}, nil
+}
+
+func (s KitchenStation) Name() Station {
+	return s.name
+}
+
+func (s KitchenStation) Capacity() uint {
+	return s.capacity
+}
+
+// ToppingTask is the work that a station does to prepare one unit of a topping.
+type ToppingTask struct {
+	itemName string
+	station  Station
+	duration time.Duration
+}
+
+func NewToppingTask(itemName string, station Station, duration time.Duration) (ToppingTask, error) {
+
+	errors := validate.Validate(
+		&validators.StringLengthInRange{Name: "Item Name", Field: itemName, Min: 1, Max: 25, Message: "Item name must be 1 and 25 characters long"},
+		&validators.StringIsPresent{Name: "Station", Field: string(station), Message: "Station is required"},
+		&validators.IntIsGreaterThan{Name: "Duration", Field: int(duration / time.Second), Compared: 0, Message: "Duration must be at least 1 second"},
+	)
+
+	if err := invalidErrorWithFields("Invalid topping task", errors); err != nil {
+		return ToppingTask{}, err
+	}
+
+	return ToppingTask{
+		itemName,
+		station,
+		duration,
+	}, nil
+}
+
+func (t ToppingTask) ItemName() string {
+	return t.itemName
+}
+
+func (t ToppingTask) Station() Station {
+	return t.station
+}
+
+func (t ToppingTask) Duration() time.Duration {
+	return t.duration
+}
+
+// Task is a topping of an order that has been scheduled on a slot of a station.
+type Task struct {
+	OrderId  uint64
+	ItemName string
+	Station  Station
+	Slot     uint
+	StartsAt time.Time
+	EndsAt   time.Time
+}
+
+func (t Task) Duration() time.Duration {
+	return t.EndsAt.Sub(t.StartsAt)
+}
+
+// Tasks are the tasks of an order.
+type Tasks []Task
+
+// EstimatedReadyAt is when the last task of the order ends.
+func (tasks Tasks) EstimatedReadyAt() time.Time {
+	var readyAt time.Time
+	for _, task := range tasks {
+		if task.EndsAt.After(readyAt) {
+			readyAt = task.EndsAt
+		}
+	}
+	return readyAt
+}
+
+// ByStation groups the items of the tasks by station, in the order that the stations are first used.
+func (tasks Tasks) ByStation() ([]Station, map[Station][]string) {
+	stations := []Station{}
+	items := map[Station][]string{}
+	for _, task := range tasks {
+		if _, ok := items[task.Station]; !ok {
+			stations = append(stations, task.Station)
+		}
+		items[task.Station] = append(items[task.Station], task.ItemName)
+	}
+	return stations, items
+}
+
+// StationLoad is when each slot of a station at a location is next free.
+type StationLoad struct {
+	station KitchenStation
+	freeAt  []time.Time
+}
+
+// NewStationLoad creates the load of a station whose slots are busy until the times in busyUntil.
+// Slots that are not in busyUntil, or that are busy until a time before now, are free now.
+func NewStationLoad(station KitchenStation, busyUntil map[uint]time.Time, now time.Time) StationLoad {
+	freeAt := make([]time.Time, station.capacity)
+	for slot := range freeAt {
+		freeAt[slot] = now
+		if until, ok := busyUntil[uint(slot)]; ok && until.After(now) {
+			freeAt[slot] = until
+		}
+	}
+	return StationLoad{station, freeAt}
+}
+
+func (l StationLoad) Station() KitchenStation {
+	return l.station
+}
+
+// Assign schedules the task on the slot that is free soonest.
+func (l *StationLoad) Assign(orderId uint64, task ToppingTask) (Task, error) {
+	if task.station != l.station.name {
+		return Task{}, fmt.Errorf("task for %q belongs to station %q, not %q", task.itemName, task.station, l.station.name)
+	}
+
+	slot := 0
+	for i := range l.freeAt {
+		if l.freeAt[i].Before(l.freeAt[slot]) {
+			slot = i
+		}
+	}
+
+	startsAt := l.freeAt[slot]
+	endsAt := startsAt.Add(task.duration)
+	l.freeAt[slot] = endsAt
+
+	return Task{orderId, task.itemName, task.station, uint(slot), startsAt, endsAt}, nil
+}
+
+// BusyUntil is when every slot of the station is next free.
+func (l StationLoad) BusyUntil() time.Time {
+	var busyUntil time.Time
+	for _, freeAt := range l.freeAt {
+		if freeAt.After(busyUntil) {
+			busyUntil = freeAt
+		}
+	}
+	return busyUntil
+}
+
+// PreparationSample is how long a topping has actually taken to prepare,
+// measured from when its ticket was started until it was bumped.
+type PreparationSample struct {
+	ItemName string
+	Samples  uint
+	Average  time.Duration
+}
@@ -0,0 +1,108 @@
+package kitchen
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type StationTestSuite struct {
+	suite.Suite
+}
+
+func TestStationTestSuite(t *testing.T) {
+	suite.Run(t, new(StationTestSuite))
+}
+
+// -- SUITE
+
+func (suite *StationTestSuite) Test_GIVEN_zeroCapacity_WHEN_stationIsCreated_THEN_errorIsReturned() {
+	// WHEN
+	_, err := NewKitchenStation("oven", 0)
+
+	// THEN
+	assert.NotNil(suite.T(), err)
+	assert.Equal(suite.T(), "Capacity must be greater than 0", err.(InvalidError).Fields["capacity"])
+}
+
+func (suite *StationTestSuite) Test_GIVEN_durationShorterThanASecond_WHEN_toppingTaskIsCreated_THEN_errorIsReturned() {
+	// WHEN
+	_, err := NewToppingTask("Pepperoni", "oven", 500*time.Millisecond)
+
+	// THEN
+	assert.NotNil(suite.T(), err)
+	assert.Equal(suite.T(), "Duration must be at least 1 second", err.(InvalidError).Fields["duration"])
+}
+
+func (suite *StationTestSuite) Test_GIVEN_busySlot_WHEN_tasksAreAssigned_THEN_freeSlotIsUsedFirst() {
+	// GIVEN
+	now := time.Date(2022, 1, 1, 12, 0, 0, 0, time.UTC)
+	oven, _ := NewKitchenStation("oven", 2)
+	pepperoni, _ := NewToppingTask("Pepperoni", "oven", time.Minute)
+	load := NewStationLoad(oven, map[uint]time.Time{0: now.Add(2 * time.Minute)}, now)
+
+	// WHEN
+	first, firstErr := load.Assign(1, pepperoni)
+	second, secondErr := load.Assign(1, pepperoni)
+
+	// THEN
+	assert.Nil(suite.T(), firstErr)
+	assert.Equal(suite.T(), uint(1), first.Slot)
+	assert.Equal(suite.T(), now, first.StartsAt)
+	assert.Equal(suite.T(), now.Add(time.Minute), first.EndsAt)
+
+	assert.Nil(suite.T(), secondErr)
+	assert.Equal(suite.T(), uint(1), second.Slot)
+	assert.Equal(suite.T(), now.Add(2*time.Minute), second.EndsAt)
+
+	assert.Equal(suite.T(), now.Add(2*time.Minute), Tasks{first, second}.EstimatedReadyAt())
+	assert.Equal(suite.T(), now.Add(2*time.Minute), load.BusyUntil())
+}
+
+func (suite *StationTestSuite) Test_GIVEN_slotThatWasBusyInThePast_WHEN_loadIsCreated_THEN_slotIsFreeNow() {
+	// GIVEN
+	now := time.Date(2022, 1, 1, 12, 0, 0, 0, time.UTC)
+	fryer, _ := NewKitchenStation("fryer", 1)
+	fries, _ := NewToppingTask("Fries", "fryer", 3*time.Minute)
+
+	// WHEN
+	load := NewStationLoad(fryer, map[uint]time.Time{0: now.Add(-time.Minute)}, now)
+	task, err := load.Assign(1, fries)
+
+	// THEN
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), now, task.StartsAt)
+	assert.Equal(suite.T(), 3*time.Minute, task.Duration())
+}
+
+func (suite *StationTestSuite) Test_GIVEN_taskOfAnotherStation_WHEN_taskIsAssigned_THEN_errorIsReturned() {
+	// GIVEN
+	grill, _ := NewKitchenStation("grill", 1)
+	fries, _ := NewToppingTask("Fries", "fryer", 3*time.Minute)
+	load := NewStationLoad(grill, nil, time.Now())
+
+	// WHEN
+	_, err := load.Assign(1, fries)
+
+	// THEN
+	assert.NotNil(suite.T(), err)
+}
+
+func (suite *StationTestSuite) Test_GIVEN_tasksAtSeveralStations_WHEN_groupedByStation_THEN_stationsAreInOrderOfFirstUse() {
+	// GIVEN
+	tasks := Tasks{
+		{ItemName: "Patty", Station: "grill"},
+		{ItemName: "Fries", Station: "fryer"},
+		{ItemName: "Bacon", Station: "grill"},
+	}
+
+	// WHEN
+	stations, items := tasks.ByStation()
+
+	// THEN
+	assert.Equal(suite.T(), []Station{"grill", "fryer"}, stations)
+	assert.Equal(suite.T(), []string{"Patty", "Bacon"}, items["grill"])
+	assert.Equal(suite.T(), []string{"Fries"}, items["fryer"])
+}
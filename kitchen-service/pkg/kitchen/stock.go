@@ -9,32 +9,92 @@ import (
 )
 
 type StockItem struct {
-	name  string
-	units uint
+	itemId   uint64
+	sku      string
+	name     string
+	units    uint
+	unitCost Money
+	location Location
+	version  uint64
 }
 
 type StockItemRecord interface {
+	ItemId() uint64
+	Sku() string
 	Name() string
 	Units() uint
+	UnitCost() Money
+	Location() Location
+	Version() uint64
 }
 
+// NewStockItem creates stock of the item with the name. Leading, trailing and repeated spaces are removed from the name.
+// The stock is not matched to an item until it is stored.
 func NewStockItem(name string, units uint) (StockItem, error) {
+	return newStockItem(name, units, &validators.IntIsGreaterThan{Name: "Units", Field: int(units), Compared: 0, Message: "Units must be greater than 0"})
+}
+
+// NewStockLevel creates the stock of the item with the name that a location holds, which is 0 once the item has run out.
+func NewStockLevel(name string, units uint) (StockItem, error) {
+	return newStockItem(name, units)
+}
+
+func newStockItem(name string, units uint, checks ...validate.Validator) (StockItem, error) {
+	name = NormalizeItemName(name)
 
-	errors := validate.Validate(
+	checks = append([]validate.Validator{
 		&validators.StringLengthInRange{Name: "Name", Field: name, Min: 1, Max: 25, Message: "Name must be 1 and 25 characters long"},
-		&validators.IntIsGreaterThan{Name: "Units", Field: int(units), Compared: 0, Message: "Units must be greater than 0"},
-	)
+	}, checks...)
 
-	if err := invalidErrorWithFields("Invalid stock item", errors); err != nil {
+	if err := invalidErrorWithFields("Invalid stock item", validate.Validate(checks...)); err != nil {
 		return StockItem{}, err
 	}
 
 	return StockItem{
+		0,
+		"",
 		name,
 		units,
+		0,
+		DefaultLocation,
+		0,
 	}, nil
 }
 
+// WithUnitCost returns a copy of the item that costs unitCost per unit.
+// A unit cost of zero means that the cost is unknown.
+func (s StockItem) WithUnitCost(unitCost Money) (StockItem, error) {
+	if unitCost < 0 {
+		return StockItem{}, InvalidError{
+			Cause:  fmt.Errorf("Invalid stock item"),
+			Fields: map[string]string{"unit_cost": "Unit cost can not be negative"},
+		}
+	}
+	s.unitCost = unitCost
+	return s, nil
+}
+
+// OfItem returns a copy of the stock that is identified as the item and so known by its canonical name.
+func (s StockItem) OfItem(item Item) StockItem {
+	s.itemId = item.Id()
+	s.sku = item.Sku()
+	s.name = item.Name()
+	return s
+}
+
+// AtLocation returns a copy of the item that is stocked at location.
+func (s StockItem) AtLocation(location Location) StockItem {
+	s.location = location
+	return s
+}
+
+// AtVersion returns a copy of the item that was read at version.
+// A change to the item at a version is only made if the item has not changed since.
+func (s StockItem) AtVersion(version uint64) StockItem {
+	s.version = version
+	return s
+}
+
 func Must(item StockItem, err error) StockItem {
 	if err != nil {
 		log.Fatalf("Failed to create stock item. Reason: %q", err)
@@ -43,7 +103,27 @@ func Must(item StockItem, err error) StockItem {
 }
 
 func NewAccountFromRecord(record StockItemRecord) (StockItem, error) {
-	return NewStockItem(record.Name(), record.Units())
+	item, err := NewStockItem(record.Name(), record.Units())
+	if err != nil {
+		return StockItem{}, err
+	}
+	if item, err = item.WithUnitCost(record.UnitCost()); err != nil {
+		return StockItem{}, err
+	}
+	if record.ItemId() != 0 {
+		item.itemId = record.ItemId()
+		item.sku = record.Sku()
+	}
+	return item.AtLocation(record.Location()).AtVersion(record.Version()), nil
+}
+
+// ItemId is zero if the stock has not been matched to an item.
+func (s StockItem) ItemId() uint64 {
+	return s.itemId
+}
+
+func (s StockItem) Sku() string {
+	return s.sku
 }
 
 func (s StockItem) Name() string {
@@ -54,8 +134,21 @@ func (s StockItem) Units() uint {
 	return s.units
 }
 
+func (s StockItem) UnitCost() Money {
+	return s.unitCost
+}
+
+func (s StockItem) Location() Location {
+	return s.location
+}
+
+// Version is zero if the item was not read from stock.
+func (s StockItem) Version() uint64 {
+	return s.version
+}
+
 func (s StockItem) String() string {
-	return fmt.Sprintf("StockItem{name: %q, units: %d}", s.name, s.units)
+	return fmt.Sprintf("StockItem{itemId: %d, name: %q, units: %d, unitCost: %s, location: %q, version: %d}", s.itemId, s.name, s.units, s.unitCost, s.location, s.version)
 }
 
 type Stock []StockItem
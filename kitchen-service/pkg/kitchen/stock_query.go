@@ -0,0 +1,182 @@
+package kitchen
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// StockSort is the order in which stock is listed.
+// Stock with the same units is listed by name, in the same direction, so that every item has one place in the order.
+type StockSort string
+
+const (
+	SortByName            StockSort = "name"
+	SortByNameDescending  StockSort = "-name"
+	SortByUnits           StockSort = "units"
+	SortByUnitsDescending StockSort = "-units"
+)
+
+const (
+	DefaultStockPageSize uint = 100
+	MaxStockPageSize     uint = 1000
+)
+
+// ParseStockSort returns SortByName if s is empty.
+func ParseStockSort(s string) (StockSort, error) {
+	switch sort := StockSort(strings.ToLower(strings.TrimSpace(s))); sort {
+	case "":
+		return SortByName, nil
+	case SortByName, SortByNameDescending, SortByUnits, SortByUnitsDescending:
+		return sort, nil
+	default:
+		return "", InvalidError{
+			Cause:  fmt.Errorf("invalid sort %q", s),
+			Fields: map[string]string{"sort": "Sort must be one of name, -name, units or -units"},
+		}
+	}
+}
+
+func (s StockSort) Descending() bool {
+	return strings.HasPrefix(string(s), "-")
+}
+
+func (s StockSort) ByUnits() bool {
+	return strings.TrimPrefix(string(s), "-") == "units"
+}
+
+// StockCursor is the position of the last item of a page of stock. The next page starts after it.
+type StockCursor struct {
+	Sort  StockSort `json:"s"`
+	Name  string    `json:"n"`
+	Units uint      `json:"u"`
+}
+
+// Encode returns the cursor as an opaque string that can be passed back to ParseStockCursor.
+func (c StockCursor) Encode() string {
+	bytes, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(bytes)
+}
+
+// ParseStockCursor returns nil if s is empty.
+func ParseStockCursor(s string) (*StockCursor, error) {
+	if len(s) == 0 {
+		return nil, nil
+	}
+
+	invalid := InvalidError{
+		Cause:  fmt.Errorf("invalid cursor %q", s),
+		Fields: map[string]string{"after": "Cursor must be the nextCursor of a previous page"},
+	}
+
+	bytes, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, invalid
+	}
+
+	var cursor StockCursor
+	if err = json.Unmarshal(bytes, &cursor); err != nil || len(cursor.Name) == 0 {
+		return nil, invalid
+	}
+	if _, err = ParseStockSort(string(cursor.Sort)); err != nil {
+		return nil, invalid
+	}
+	return &cursor, nil
+}
+
+// StockQuery selects a page of the stock at a location.
+type StockQuery struct {
+	location   Location
+	namePrefix string
+	unitsBelow uint
+	sort       StockSort
+	limit      uint
+	after      *StockCursor
+}
+
+// NewStockQuery selects every item at the location, DefaultStockPageSize at a time.
+func NewStockQuery(location Location) StockQuery {
+	return StockQuery{
+		location: location,
+		sort:     SortByName,
+		limit:    DefaultStockPageSize,
+	}
+}
+
+// WithNamePrefix returns a copy of the query that only selects items whose names start with prefix, without regard to case.
+func (q StockQuery) WithNamePrefix(prefix string) StockQuery {
+	q.namePrefix = strings.TrimLeft(prefix, " ")
+	return q
+}
+
+// WithUnitsBelow returns a copy of the query that only selects items with fewer than units.
+// Zero selects items regardless of units.
+func (q StockQuery) WithUnitsBelow(units uint) StockQuery {
+	q.unitsBelow = units
+	return q
+}
+
+func (q StockQuery) SortedBy(sort StockSort) StockQuery {
+	q.sort = sort
+	return q
+}
+
+// Page returns a copy of the query that selects at most limit items after the cursor.
+// A limit of zero is the DefaultStockPageSize. A cursor must have been made with the same sort order as the query.
+func (q StockQuery) Page(limit uint, after *StockCursor) (StockQuery, error) {
+	if limit == 0 {
+		limit = DefaultStockPageSize
+	}
+	if limit > MaxStockPageSize {
+		return StockQuery{}, InvalidError{
+			Cause:  fmt.Errorf("invalid limit %d", limit),
+			Fields: map[string]string{"limit": fmt.Sprintf("Limit must be at most %d", MaxStockPageSize)},
+		}
+	}
+	if after != nil && after.Sort != q.sort {
+		return StockQuery{}, InvalidError{
+			Cause:  fmt.Errorf("cursor sorted by %q can not continue stock sorted by %q", after.Sort, q.sort),
+			Fields: map[string]string{"after": "Cursor must be used with the sort order of the page it came from"},
+		}
+	}
+	q.limit = limit
+	q.after = after
+	return q, nil
+}
+
+func (q StockQuery) Location() Location {
+	return q.location
+}
+
+func (q StockQuery) NamePrefix() string {
+	return q.namePrefix
+}
+
+func (q StockQuery) UnitsBelow() uint {
+	return q.unitsBelow
+}
+
+func (q StockQuery) Sort() StockSort {
+	return q.sort
+}
+
+func (q StockQuery) Limit() uint {
+	return q.limit
+}
+
+// After is nil for the first page.
+func (q StockQuery) After() *StockCursor {
+	return q.after
+}
+
+// CursorOf returns the cursor of the page that ends with item.
+func (q StockQuery) CursorOf(item StockItem) StockCursor {
+	return StockCursor{q.sort, item.Name(), item.Units()}
+}
+
+// StockPage is a page of stock and the cursor of the next page, which is nil on the last page.
+type StockPage struct {
+	Stock Stock
+	Next  *StockCursor
+}
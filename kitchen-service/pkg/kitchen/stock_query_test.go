@@ -0,0 +1,102 @@
+package kitchen
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type StockQueryTestSuite struct {
+	suite.Suite
+}
+
+func TestStockQueryTestSuite(t *testing.T) {
+	suite.Run(t, new(StockQueryTestSuite))
+}
+
+// -- SUITE
+
+func (suite *StockQueryTestSuite) Test_GIVEN_noSort_WHEN_parsed_THEN_stockIsSortedByName() {
+	// WHEN
+	sort, err := ParseStockSort("")
+
+	// THEN
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), SortByName, sort)
+	assert.False(suite.T(), sort.Descending())
+	assert.False(suite.T(), sort.ByUnits())
+}
+
+func (suite *StockQueryTestSuite) Test_GIVEN_descendingUnits_WHEN_parsed_THEN_sortIsDescendingByUnits() {
+	// WHEN
+	sort, err := ParseStockSort("-Units")
+
+	// THEN
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), SortByUnitsDescending, sort)
+	assert.True(suite.T(), sort.Descending())
+	assert.True(suite.T(), sort.ByUnits())
+}
+
+func (suite *StockQueryTestSuite) Test_GIVEN_unknownSort_WHEN_parsed_THEN_errorIsReturned() {
+	// WHEN
+	_, err := ParseStockSort("price")
+
+	// THEN
+	assert.NotNil(suite.T(), err)
+	assert.Equal(suite.T(), "Sort must be one of name, -name, units or -units", err.(InvalidError).Fields["sort"])
+}
+
+func (suite *StockQueryTestSuite) Test_GIVEN_cursor_WHEN_encodedAndParsed_THEN_cursorIsTheSame() {
+	// GIVEN
+	query := NewStockQuery(DefaultLocation).SortedBy(SortByUnits)
+	cursor := query.CursorOf(Must(NewStockItem("Cheese", 4)))
+
+	// WHEN
+	parsed, err := ParseStockCursor(cursor.Encode())
+
+	// THEN
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), StockCursor{SortByUnits, "Cheese", 4}, *parsed)
+}
+
+func (suite *StockQueryTestSuite) Test_GIVEN_malformedCursor_WHEN_parsed_THEN_errorIsReturned() {
+	// WHEN
+	_, err := ParseStockCursor("not a cursor")
+
+	// THEN
+	assert.NotNil(suite.T(), err)
+	assert.Equal(suite.T(), "Cursor must be the nextCursor of a previous page", err.(InvalidError).Fields["after"])
+}
+
+func (suite *StockQueryTestSuite) Test_GIVEN_noLimit_WHEN_paged_THEN_defaultPageSizeIsUsed() {
+	// WHEN
+	query, err := NewStockQuery(DefaultLocation).Page(0, nil)
+
+	// THEN
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), DefaultStockPageSize, query.Limit())
+	assert.Nil(suite.T(), query.After())
+}
+
+func (suite *StockQueryTestSuite) Test_GIVEN_limitAboveMaximum_WHEN_paged_THEN_errorIsReturned() {
+	// WHEN
+	_, err := NewStockQuery(DefaultLocation).Page(MaxStockPageSize+1, nil)
+
+	// THEN
+	assert.NotNil(suite.T(), err)
+	assert.Equal(suite.T(), "Limit must be at most 1000", err.(InvalidError).Fields["limit"])
+}
+
+func (suite *StockQueryTestSuite) Test_GIVEN_cursorOfAnotherSort_WHEN_paged_THEN_errorIsReturned() {
+	// GIVEN
+	cursor := NewStockQuery(DefaultLocation).CursorOf(Must(NewStockItem("Cheese", 4)))
+
+	// WHEN
+	_, err := NewStockQuery(DefaultLocation).SortedBy(SortByUnits).Page(10, &cursor)
+
+	// THEN
+	assert.NotNil(suite.T(), err)
+	assert.Equal(suite.T(), "Cursor must be used with the sort order of the page it came from", err.(InvalidError).Fields["after"])
+}
@@ -36,6 +36,24 @@ func (suite *StockTestSuite) Test_GIVEN_aBlankNameAndPositiveQuantity_WHEN_stock
 	assert.Equal(suite.T(), "Invalid stock item. Name must be 1 and 25 characters long", err.Error())
 }
 
+func (suite *StockTestSuite) Test_GIVEN_aNameOfSpaces_WHEN_stockItemIsCreated_THEN_errorIsReturned() {
+	// WHEN
+	_, err := NewStockItem("   ", 1)
+
+	// THEN
+	assert.NotNil(suite.T(), err)
+	assert.Equal(suite.T(), "Invalid stock item. Name must be 1 and 25 characters long", err.Error())
+}
+
+func (suite *StockTestSuite) Test_GIVEN_aNameWithExtraSpaces_WHEN_stockItemIsCreated_THEN_nameIsNormalized() {
+	// WHEN
+	item, err := NewStockItem(" Green  Peppers ", 1)
+
+	// THEN
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), "Green Peppers", item.Name())
+}
+
 func (suite *StockTestSuite) Test_GIVEN_aValidNameAndZeroQuantity_WHEN_stockItemIsCreated_THEN_errorIsReturned() {
 	// WHEN
 	_, err := NewStockItem("Cheese", 0)
@@ -44,3 +62,22 @@ func (suite *StockTestSuite) Test_GIVEN_aValidNameAndZeroQuantity_WHEN_stockItem
 	assert.NotNil(suite.T(), err)
 	assert.Equal(suite.T(), "Invalid stock item. Units must be greater than 0", err.Error())
 }
+
+func (suite *StockTestSuite) Test_GIVEN_aValidNameAndZeroQuantity_WHEN_stockLevelIsCreated_THEN_createdSuccessfully() {
+	// WHEN
+	item, err := NewStockLevel("Cheese", 0)
+
+	// THEN
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), "Cheese", item.Name())
+	assert.Equal(suite.T(), uint(0), item.Units())
+}
+
+func (suite *StockTestSuite) Test_GIVEN_aBlankNameAndZeroQuantity_WHEN_stockLevelIsCreated_THEN_errorIsReturned() {
+	// WHEN
+	_, err := NewStockLevel("", 0)
+
+	// THEN
+	assert.NotNil(suite.T(), err)
+	assert.Equal(suite.T(), "Invalid stock item. Name must be 1 and 25 characters long", err.Error())
+}
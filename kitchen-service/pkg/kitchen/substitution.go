@@ -0,0 +1,119 @@
+package kitchen
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/gobuffalo/validate"
+	"github.com/gobuffalo/validate/validators"
+)
+
+// SubstitutionRule allows substituteName to be used when itemName is out of stock.
+// Rules with a lower priority are tried first.
+type SubstitutionRule struct {
+	itemName       string
+	substituteName string
+	priority       uint
+}
+
+type SubstitutionRuleRecord interface {
+	ItemName() string
+	SubstituteName() string
+	Priority() uint
+}
+
+func NewSubstitutionRule(itemName string, substituteName string, priority uint) (SubstitutionRule, error) {
+
+	errors := validate.Validate(
+		&validators.StringLengthInRange{Name: "Item Name", Field: itemName, Min: 1, Max: 25, Message: "Item name must be 1 and 25 characters long"},
+		&validators.StringLengthInRange{Name: "Substitute Name", Field: substituteName, Min: 1, Max: 25, Message: "Substitute name must be 1 and 25 characters long"},
+		&distinctSubstituteValidator{Name: "Substitute Name", ItemName: itemName, Field: substituteName},
+	)
+
+	if err := invalidErrorWithFields("Invalid substitution rule", errors); err != nil {
+		return SubstitutionRule{}, err
+	}
+
+	return SubstitutionRule{
+		itemName,
+		substituteName,
+		priority,
+	}, nil
+}
+
+type distinctSubstituteValidator struct {
+	Name     string
+	ItemName string
+	Field    string
+}
+
+func (v *distinctSubstituteValidator) IsValid(errors *validate.Errors) {
+	if strings.EqualFold(v.ItemName, v.Field) {
+		errors.Add(validators.GenerateKey(v.Name), "An item can not be substituted with itself")
+	}
+}
+
+func NewSubstitutionRuleFromRecord(record SubstitutionRuleRecord) (SubstitutionRule, error) {
+	return NewSubstitutionRule(record.ItemName(), record.SubstituteName(), record.Priority())
+}
+
+func (r SubstitutionRule) ItemName() string {
+	return r.itemName
+}
+
+func (r SubstitutionRule) SubstituteName() string {
+	return r.substituteName
+}
+
+func (r SubstitutionRule) Priority() uint {
+	return r.priority
+}
+
+func (r SubstitutionRule) String() string {
+	return fmt.Sprintf("SubstitutionRule{itemName: %q, substituteName: %q, priority: %d}", r.itemName, r.substituteName, r.priority)
+}
+
+// SubstitutionRules are sorted in the order that they should be tried.
+type SubstitutionRules []SubstitutionRule
+
+func (rules SubstitutionRules) Len() int { return len(rules) }
+func (rules SubstitutionRules) Less(i, j int) bool {
+	if rules[i].priority != rules[j].priority {
+		return rules[i].priority < rules[j].priority
+	}
+	return rules[i].substituteName < rules[j].substituteName
+}
+func (rules SubstitutionRules) Swap(i, j int) { rules[i], rules[j] = rules[j], rules[i] }
+
+// For returns the rules for itemName in the order that they should be tried.
+func (rules SubstitutionRules) For(itemName string) SubstitutionRules {
+	matching := SubstitutionRules{}
+	for _, rule := range rules {
+		if rule.itemName == itemName {
+			matching = append(matching, rule)
+		}
+	}
+	sort.Sort(matching)
+	return matching
+}
+
+// Substitution records that units of Substitute were used in place of Original.
+type Substitution struct {
+	Original   string
+	Substitute string
+	Units      uint
+}
+
+// Substitutions combines repeated substitutions of the same item.
+type Substitutions []Substitution
+
+func (s Substitutions) Add(original string, substitute string, units uint) Substitutions {
+	for i := range s {
+		if s[i].Original == original && s[i].Substitute == substitute {
+			s[i].Units += units
+			return s
+		}
+	}
+	return append(s, Substitution{original, substitute, units})
+}
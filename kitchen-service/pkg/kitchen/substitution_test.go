@@ -0,0 +1,67 @@
+package kitchen
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type SubstitutionTestSuite struct {
+	suite.Suite
+}
+
+func TestSubstitutionTestSuite(t *testing.T) {
+	suite.Run(t, new(SubstitutionTestSuite))
+}
+
+// -- SUITE
+
+func (suite *SubstitutionTestSuite) Test_GIVEN_itemAsItsOwnSubstitute_WHEN_ruleIsCreated_THEN_errorIsReturned() {
+	// WHEN
+	_, err := NewSubstitutionRule("Mozzarella", "mozzarella", 1)
+
+	// THEN
+	assert.NotNil(suite.T(), err)
+	assert.Equal(suite.T(), "An item can not be substituted with itself", err.(InvalidError).Fields["substitute_name"])
+}
+
+func (suite *SubstitutionTestSuite) Test_GIVEN_rulesForSeveralItems_WHEN_rulesForAnItemAreRequested_THEN_rulesAreSortedByPriority() {
+	// GIVEN
+	rules := SubstitutionRules{
+		mustRule(NewSubstitutionRule("Mozzarella", "Halloumi", 2)),
+		mustRule(NewSubstitutionRule("Tomatoes", "Peppers", 1)),
+		mustRule(NewSubstitutionRule("Mozzarella", "Cheddar", 1)),
+		mustRule(NewSubstitutionRule("Mozzarella", "Brie", 2)),
+	}
+
+	// WHEN
+	matching := rules.For("Mozzarella")
+
+	// THEN
+	assert.Equal(suite.T(), 3, len(matching))
+	assert.Equal(suite.T(), "Cheddar", matching[0].SubstituteName())
+	assert.Equal(suite.T(), "Brie", matching[1].SubstituteName())
+	assert.Equal(suite.T(), "Halloumi", matching[2].SubstituteName())
+}
+
+func (suite *SubstitutionTestSuite) Test_GIVEN_repeatedSubstitutions_WHEN_added_THEN_unitsAreCombined() {
+	// WHEN
+	substitutions := Substitutions{}.
+		Add("Mozzarella", "Cheddar", 1).
+		Add("Tomatoes", "Peppers", 1).
+		Add("Mozzarella", "Cheddar", 1)
+
+	// THEN
+	assert.Equal(suite.T(), Substitutions{
+		{Original: "Mozzarella", Substitute: "Cheddar", Units: 2},
+		{Original: "Tomatoes", Substitute: "Peppers", Units: 1},
+	}, substitutions)
+}
+
+func mustRule(rule SubstitutionRule, err error) SubstitutionRule {
+	if err != nil {
+		panic(err)
+	}
+	return rule
+}
@@ -0,0 +1,177 @@
+package kitchen
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gobuffalo/validate"
+	"github.com/gobuffalo/validate/validators"
+)
+
+// Station is the part of the kitchen that works on a ticket.
+type Station string
+
+// DefaultStation prepares every order until orders are split between stations.
+const DefaultStation Station = "line"
+
+// ParseStation returns the DefaultStation if s is empty.
+// Stations are case-insensitive and are stored in lower case.
+func ParseStation(s string) (Station, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if len(s) == 0 {
+		return DefaultStation, nil
+	}
+	if !identifierPattern.MatchString(s) {
+		return "", InvalidError{
+			Cause:  fmt.Errorf("invalid station %q", s),
+			Fields: map[string]string{"station": "Station must be 1 to 64 letters, digits, hyphens or underscores"},
+		}
+	}
+	return Station(s), nil
+}
+
+type TicketEventType string
+
+const (
+	TicketCreated  TicketEventType = "ticket-created"
+	TicketStarted  TicketEventType = "ticket-started"
+	TicketBumped   TicketEventType = "ticket-bumped"
+	TicketRecalled TicketEventType = "ticket-recalled"
+)
+
+// Allows reports whether a ticket whose last event is t can move on to next.
+// A ticket is bumped off the display once it is complete and can be recalled to the display afterwards.
+func (t TicketEventType) Allows(next TicketEventType) bool {
+	switch t {
+	case TicketCreated:
+		return next == TicketStarted || next == TicketBumped
+	case TicketStarted:
+		return next == TicketBumped
+	case TicketBumped:
+		return next == TicketRecalled
+	case TicketRecalled:
+		return next == TicketStarted || next == TicketBumped
+	default:
+		return false
+	}
+}
+
+// TicketEvent is a change to the ticket that a kitchen display shows for an order at a station.
+// Only TicketCreated events list the items on the ticket.
+type TicketEvent struct {
+	id         uint64
+	eventType  TicketEventType
+	orderId    uint64
+	station    Station
+	location   Location
+	items      []string
+	occurredAt time.Time
+	automatic  bool
+}
+
+type TicketEventRecord interface {
+	Id() uint64
+	Type() TicketEventType
+	OrderId() uint64
+	Station() Station
+	Location() Location
+	Items() []string
+	OccurredAt() time.Time
+}
+
+func NewTicketEvent(id uint64, eventType TicketEventType, orderId uint64, station Station, location Location, items []string, occurredAt time.Time) (TicketEvent, error) {
+
+	errors := validate.Validate(
+		&validators.StringInclusion{Name: "Type", Field: string(eventType), List: []string{string(TicketCreated), string(TicketStarted), string(TicketBumped), string(TicketRecalled)}, Message: fmt.Sprintf("Unknown ticket event type %q", eventType)},
+		&validators.IntIsGreaterThan{Name: "Order Id", Field: int(orderId), Compared: 0, Message: "Order id is required"},
+		&validators.StringIsPresent{Name: "Station", Field: string(station), Message: "Station is required"},
+		&validators.StringIsPresent{Name: "Location", Field: string(location), Message: "Location is required"},
+	)
+
+	if err := invalidErrorWithFields("Invalid ticket event", errors); err != nil {
+		return TicketEvent{}, err
+	}
+
+	if items == nil {
+		items = []string{}
+	}
+
+	return TicketEvent{
+		id,
+		eventType,
+		orderId,
+		station,
+		location,
+		items,
+		occurredAt,
+		false,
+	}, nil
+}
+
+func NewTicketEventFromRecord(record TicketEventRecord) (TicketEvent, error) {
+	return NewTicketEvent(record.Id(), record.Type(), record.OrderId(), record.Station(), record.Location(), record.Items(), record.OccurredAt())
+}
+
+func (e TicketEvent) Id() uint64 {
+	return e.id
+}
+
+func (e TicketEvent) Type() TicketEventType {
+	return e.eventType
+}
+
+func (e TicketEvent) OrderId() uint64 {
+	return e.orderId
+}
+
+func (e TicketEvent) Station() Station {
+	return e.station
+}
+
+func (e TicketEvent) Location() Location {
+	return e.location
+}
+
+func (e TicketEvent) Items() []string {
+	return e.items
+}
+
+func (e TicketEvent) OccurredAt() time.Time {
+	return e.occurredAt
+}
+
+// Automatic is true if the kitchen made the change itself, e.g. by bumping a ticket when its order was estimated to be ready,
+// rather than a cook.
+func (e TicketEvent) Automatic() bool {
+	return e.automatic
+}
+
+// WithAutomatic returns the event, marked as made by the kitchen itself or by a cook.
+func (e TicketEvent) WithAutomatic(automatic bool) TicketEvent {
+	e.automatic = automatic
+	return e
+}
+
+// Next returns the event that moves this ticket on to eventType.
+func (e TicketEvent) Next(eventType TicketEventType, occurredAt time.Time) (TicketEvent, error) {
+	if !e.eventType.Allows(eventType) {
+		return TicketEvent{}, InvalidError{Cause: fmt.Errorf("ticket for order %d at %q can not be %s after it was %s", e.orderId, e.station, ticketAction(eventType), ticketAction(e.eventType))}
+	}
+	return NewTicketEvent(0, eventType, e.orderId, e.station, e.location, []string{}, occurredAt)
+}
+
+func ticketAction(eventType TicketEventType) string {
+	switch eventType {
+	case TicketCreated:
+		return "created"
+	case TicketStarted:
+		return "started"
+	case TicketBumped:
+		return "bumped"
+	case TicketRecalled:
+		return "recalled"
+	default:
+		return string(eventType)
+	}
+}
@@ -0,0 +1,70 @@
+package kitchen
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type TicketTestSuite struct {
+	suite.Suite
+}
+
+func TestTicketTestSuite(t *testing.T) {
+	suite.Run(t, new(TicketTestSuite))
+}
+
+// -- SUITE
+
+func (suite *TicketTestSuite) Test_GIVEN_createdTicket_WHEN_ticketIsStartedAndBumped_THEN_eventsAreCreated() {
+	// GIVEN
+	created, _ := NewTicketEvent(1, TicketCreated, 7, DefaultStation, DefaultLocation, []string{"Cheese"}, time.Now())
+
+	// WHEN
+	started, startErr := created.Next(TicketStarted, time.Now())
+	bumped, bumpErr := started.Next(TicketBumped, time.Now())
+
+	// THEN
+	assert.Nil(suite.T(), startErr)
+	assert.Nil(suite.T(), bumpErr)
+	assert.Equal(suite.T(), TicketBumped, bumped.Type())
+	assert.Equal(suite.T(), uint64(7), bumped.OrderId())
+	assert.Equal(suite.T(), DefaultStation, bumped.Station())
+	assert.Equal(suite.T(), []string{}, bumped.Items())
+}
+
+func (suite *TicketTestSuite) Test_GIVEN_openTicket_WHEN_ticketIsRecalled_THEN_errorIsReturned() {
+	// GIVEN
+	started, _ := NewTicketEvent(2, TicketStarted, 7, DefaultStation, DefaultLocation, nil, time.Now())
+
+	// WHEN
+	_, err := started.Next(TicketRecalled, time.Now())
+
+	// THEN
+	assert.NotNil(suite.T(), err)
+	assert.Equal(suite.T(), "ticket for order 7 at \"line\" can not be recalled after it was started", err.Error())
+}
+
+func (suite *TicketTestSuite) Test_GIVEN_bumpedTicket_WHEN_ticketIsRecalled_THEN_ticketCanBeBumpedAgain() {
+	// GIVEN
+	bumped, _ := NewTicketEvent(3, TicketBumped, 7, DefaultStation, DefaultLocation, nil, time.Now())
+
+	// WHEN
+	recalled, recallErr := bumped.Next(TicketRecalled, time.Now())
+
+	// THEN
+	assert.Nil(suite.T(), recallErr)
+	assert.True(suite.T(), recalled.Type().Allows(TicketBumped))
+	assert.False(suite.T(), recalled.Type().Allows(TicketCreated))
+}
+
+func (suite *TicketTestSuite) Test_GIVEN_unknownEventType_WHEN_eventIsCreated_THEN_errorIsReturned() {
+	// WHEN
+	_, err := NewTicketEvent(0, TicketEventType("ticket-burnt"), 7, DefaultStation, DefaultLocation, nil, time.Now())
+
+	// THEN
+	assert.NotNil(suite.T(), err)
+	assert.Equal(suite.T(), "Unknown ticket event type \"ticket-burnt\"", err.(InvalidError).Fields["type"])
+}
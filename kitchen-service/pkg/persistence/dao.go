@@ -4,6 +4,7 @@ import (
 	"context"
 	"database/sql"
 	"log"
+	"time"
 
 	k "github.com/w-k-s/McMicroservices/kitchen-service/pkg/kitchen"
 )
@@ -21,9 +22,112 @@ type StockTx interface {
 	Commit() error
 	Rollback() error
 
+	// Savepoint marks a point that later changes can be rolled back to without aborting the transaction.
+	// RollbackToSavepoint also releases the savepoint.
+	Savepoint(ctx context.Context, name string) error
+	RollbackToSavepoint(ctx context.Context, name string) error
+	ReleaseSavepoint(ctx context.Context, name string) error
+
+	// GetItems returns the item that each of the names is the name, an alias or the SKU of, keyed by the k.ItemKey of the name.
+	// Names are matched without regard to case. Names that are not known are left out.
+	GetItems(ctx context.Context, names []string) (map[string]k.Item, error)
+	// SaveItem creates the item or replaces the SKU and aliases of the item with the same name.
+	SaveItem(ctx context.Context, item k.Item) (k.Item, error)
+
+	// Increase and Decrease change the stock at the location of each item.
+	// Decrease changes nothing if there is not enough of any one of the items.
 	Increase(ctx context.Context, stock k.Stock) error
 	Decrease(ctx context.Context, decrease k.Stock) error
-	Get(ctx context.Context) (k.Stock, error)
+	// Get returns the page of the stock at a location that the query selects, filtered and sorted by the database.
+	Get(ctx context.Context, query k.StockQuery) (k.StockPage, error)
+	// GetStockLevels returns the units of every item at every location, sorted by location and name.
+	GetStockLevels(ctx context.Context) ([]k.StockLevel, error)
+	// GetStockItem returns the stock of the item with the name at the location, or a k.NotFoundError if there is none.
+	GetStockItem(ctx context.Context, name string, location k.Location) (k.StockItem, error)
+	// Set replaces the units and unit cost of the stock at its location.
+	// Stock that was read at a version is only replaced if it has not changed since, otherwise a k.ConflictError is returned.
+	// The stock is returned at its new version.
+	Set(ctx context.Context, item k.StockItem) (k.StockItem, error)
+	// Create adds stock of an item that is not in stock at its location yet, or returns a k.ConflictError if it is.
+	Create(ctx context.Context, item k.StockItem) (k.StockItem, error)
+	// Delete removes the item from stock at its location. Stock that was read at a version is only removed if it has not changed since.
+	Delete(ctx context.Context, item k.StockItem) error
+
+	Transfer(ctx context.Context, transfer k.Transfer) (k.Transfer, error)
+	GetTransfer(ctx context.Context, id uint64) (k.Transfer, error)
+
+	GetDietaryTags(ctx context.Context, names []string) (map[string]k.DietaryTags, error)
+	SetDietaryTags(ctx context.Context, name string, tags k.DietaryTags) error
+
+	GetSubstitutionRules(ctx context.Context) (k.SubstitutionRules, error)
+	SaveSubstitutionRule(ctx context.Context, rule k.SubstitutionRule) error
+	DeleteSubstitutionRule(ctx context.Context, itemName string, substituteName string) error
+}
+
+type PurchasingDao interface {
+	BeginTx() (PurchasingTx, error)
+}
+
+// PurchasingTx can also adjust stock so that a delivery and the purchase order it fulfils
+// are recorded in the same transaction.
+type PurchasingTx interface {
+	StockTx
+
+	SaveSupplier(ctx context.Context, supplier k.Supplier) (k.Supplier, error)
+	GetSupplier(ctx context.Context, id uint64) (k.Supplier, error)
+	GetSuppliers(ctx context.Context) ([]k.Supplier, error)
+	SavePurchaseOrder(ctx context.Context, po k.PurchaseOrder) (k.PurchaseOrder, error)
+	UpdatePurchaseOrder(ctx context.Context, po k.PurchaseOrder) error
+	GetPurchaseOrder(ctx context.Context, id uint64) (k.PurchaseOrder, error)
+	GetPurchaseOrders(ctx context.Context, status k.PurchaseOrderStatus) ([]k.PurchaseOrder, error)
+	GetOldestOpenPurchaseOrder(ctx context.Context, supplierId uint64) (k.PurchaseOrder, error)
+	// SaveFailedDelivery keeps a delivery that could not be received, with the reason, so that it can be received by hand.
+	SaveFailedDelivery(ctx context.Context, delivery []byte, reason string) error
+
+	// ClaimIdempotencyKey stores the request unless a request with its key was stored within the idempotency key retention,
+	// in which case the stored request is returned and claimed is false. Keys that are older than the retention are deleted.
+	ClaimIdempotencyKey(ctx context.Context, request k.IdempotentRequest) (stored k.IdempotentRequest, claimed bool, err error)
+	// SaveIdempotentResponse stores the response to a request that was claimed in the same transaction.
+	SaveIdempotentResponse(ctx context.Context, request k.IdempotentRequest) error
+}
+
+type OrderDao interface {
+	BeginTx() (OrderTx, error)
+}
+
+// OrderTx can also adjust stock so that an order and the ingredients it consumed
+// are recorded in the same transaction.
+type OrderTx interface {
+	StockTx
+
+	// SaveOrder records the order and the substitutions that were made.
+	// The unit cost of each item is the average cost of that item in stock when the order is saved.
+	SaveOrder(ctx context.Context, order k.Order) error
+	UpdateOrderStatus(ctx context.Context, id uint64, status k.OrderStatus, failureReason string) error
+	GetOrder(ctx context.Context, id uint64) (k.Order, error)
+	GetCostOfGoodsReport(ctx context.Context, day time.Time) (k.CostOfGoodsReport, error)
+
+	SaveTicketEvent(ctx context.Context, event k.TicketEvent) (k.TicketEvent, error)
+	// GetTicketEvents returns the events after afterId in the order that they occurred.
+	// Ids are not committed in order, so the events before afterId that were created up to overlap before it are also returned.
+	// An empty station or location matches every station or location.
+	GetTicketEvents(ctx context.Context, afterId uint64, overlap time.Duration, station k.Station, location k.Location) ([]k.TicketEvent, error)
+	// GetOpenTicketEvents returns every event of the tickets that have not been bumped.
+	GetOpenTicketEvents(ctx context.Context, station k.Station, location k.Location) ([]k.TicketEvent, error)
+	// GetLatestTicketEvents returns the last event of each of the order's tickets.
+	GetLatestTicketEvents(ctx context.Context, orderId uint64) ([]k.TicketEvent, error)
+
+	GetStations(ctx context.Context) ([]k.KitchenStation, error)
+	SaveStation(ctx context.Context, station k.KitchenStation) error
+	GetToppingTasks(ctx context.Context) ([]k.ToppingTask, error)
+	SaveToppingTask(ctx context.Context, task k.ToppingTask) error
+	DeleteToppingTask(ctx context.Context, itemName string) error
+	// GetStationLoad returns when each slot of the station at location is next free.
+	// The station is locked until the transaction ends so that tasks are not scheduled on the same slot concurrently.
+	GetStationLoad(ctx context.Context, station k.KitchenStation, location k.Location, now time.Time) (k.StationLoad, error)
+	// GetPreparationSamples returns how long each of the toppings has taken to prepare at the stations since a time,
+	// from the tickets that were bumped by a cook. Toppings that have not been prepared since then are not returned.
+	GetPreparationSamples(ctx context.Context, names []string, since time.Time) (map[string]k.PreparationSample, error)
 }
 
 func DeferRollback(tx Tx, reference string) {
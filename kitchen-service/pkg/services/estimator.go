@@ -0,0 +1,141 @@
+package services
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	k "github.com/w-k-s/McMicroservices/kitchen-service/pkg/kitchen"
+	db "github.com/w-k-s/McMicroservices/kitchen-service/pkg/persistence"
+)
+
+// PreparationEstimator estimates how long each topping of an order takes to prepare.
+// routes are the toppings that have been assigned to a station; the others are prepared at the default station.
+// One task is returned for each topping, in the same order.
+type PreparationEstimator interface {
+	Name() string
+	Estimate(ctx context.Context, tx db.OrderTx, toppings []string, routes map[string]k.ToppingTask) ([]k.ToppingTask, error)
+}
+
+// LinearEstimator takes perItem for each topping, plus base for the first.
+type LinearEstimator struct {
+	Base    time.Duration
+	PerItem time.Duration
+}
+
+func (e LinearEstimator) Name() string {
+	return "linear"
+}
+
+func (e LinearEstimator) Estimate(ctx context.Context, tx db.OrderTx, toppings []string, routes map[string]k.ToppingTask) ([]k.ToppingTask, error) {
+	tasks := []k.ToppingTask{}
+	for i, topping := range toppings {
+		duration := e.PerItem
+		if i == 0 {
+			duration += e.Base
+		}
+		task, err := k.NewToppingTask(topping, routeStation(topping, routes), duration)
+		if err != nil {
+			return nil, err
+		}
+		tasks = append(tasks, task)
+	}
+	return tasks, nil
+}
+
+// RecipeEstimator uses the duration that was set up for each topping's station task.
+// Toppings without a station task are estimated by Fallback.
+type RecipeEstimator struct {
+	Fallback LinearEstimator
+}
+
+func (e RecipeEstimator) Name() string {
+	return "recipe"
+}
+
+func (e RecipeEstimator) Estimate(ctx context.Context, tx db.OrderTx, toppings []string, routes map[string]k.ToppingTask) ([]k.ToppingTask, error) {
+	tasks, err := e.Fallback.Estimate(ctx, tx, toppings, routes)
+	if err != nil {
+		return nil, err
+	}
+	for i, topping := range toppings {
+		if route, ok := routes[topping]; ok {
+			tasks[i] = route
+		}
+	}
+	return tasks, nil
+}
+
+// LearnedEstimator uses the average time that each topping actually took to prepare over the last Window.
+// Toppings that have been prepared fewer than MinSamples times are estimated by Fallback.
+type LearnedEstimator struct {
+	Window     time.Duration
+	MinSamples uint
+	Fallback   PreparationEstimator
+}
+
+func (e LearnedEstimator) Name() string {
+	return "learned"
+}
+
+func (e LearnedEstimator) Estimate(ctx context.Context, tx db.OrderTx, toppings []string, routes map[string]k.ToppingTask) ([]k.ToppingTask, error) {
+	tasks, err := e.Fallback.Estimate(ctx, tx, toppings, routes)
+	if err != nil {
+		return nil, err
+	}
+
+	samples, err := tx.GetPreparationSamples(ctx, toppings, time.Now().Add(-e.Window))
+	if err != nil {
+		return nil, err
+	}
+
+	for i, topping := range toppings {
+		sample, ok := samples[topping]
+		if !ok || sample.Samples < e.MinSamples || sample.Average < time.Second {
+			continue
+		}
+		if tasks[i], err = k.NewToppingTask(topping, routeStation(topping, routes), sample.Average.Round(time.Second)); err != nil {
+			return nil, err
+		}
+	}
+	return tasks, nil
+}
+
+// ReloadableEstimator delegates to an estimator that can be replaced while orders are being scheduled,
+// e.g. when the preparation config is reloaded.
+type ReloadableEstimator struct {
+	mu        sync.RWMutex
+	estimator PreparationEstimator
+}
+
+func NewReloadableEstimator(estimator PreparationEstimator) *ReloadableEstimator {
+	return &ReloadableEstimator{estimator: estimator}
+}
+
+// Set replaces the estimator of the orders that are scheduled from now on.
+func (e *ReloadableEstimator) Set(estimator PreparationEstimator) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.estimator = estimator
+}
+
+func (e *ReloadableEstimator) get() PreparationEstimator {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.estimator
+}
+
+func (e *ReloadableEstimator) Name() string {
+	return e.get().Name()
+}
+
+func (e *ReloadableEstimator) Estimate(ctx context.Context, tx db.OrderTx, toppings []string, routes map[string]k.ToppingTask) ([]k.ToppingTask, error) {
+	return e.get().Estimate(ctx, tx, toppings, routes)
+}
+
+func routeStation(topping string, routes map[string]k.ToppingTask) k.Station {
+	if route, ok := routes[topping]; ok {
+		return route.Station()
+	}
+	return k.DefaultStation
+}
@@ -2,25 +2,74 @@ package services
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"time"
 
 	"github.com/w-k-s/McMicroservices/kitchen-service/log"
 
+	"github.com/w-k-s/McMicroservices/kitchen-service/pkg/auth"
 	k "github.com/w-k-s/McMicroservices/kitchen-service/pkg/kitchen"
 	db "github.com/w-k-s/McMicroservices/kitchen-service/pkg/persistence"
+	"github.com/w-k-s/McMicroservices/kitchen-service/pkg/tracing"
 )
 
+const reportDateLayout = "2006-01-02"
+
 type OrderRequest struct {
-	OrderId  uint64   `json:"id"`
-	Toppings []string `json:"toppings"`
+	OrderId            uint64   `json:"id"`
+	Toppings           []string `json:"toppings"`
+	Exclusions         []string `json:"exclusions,omitempty"`
+	AllowSubstitutions bool     `json:"allowSubstitutions,omitempty"`
+	// Location is the kitchen that prepares the order. Orders without a location are prepared at the default location.
+	Location string `json:"location,omitempty"`
+	// Priority is VIP, DELIVERY or DINE_IN. Orders without a priority are DINE_IN.
+	Priority string `json:"priority,omitempty"`
+	// PromisedBy is when the customer was told that the order would be ready.
+	// Orders that were not promised by a time must be ready within the target time of their priority.
+	PromisedBy *time.Time `json:"promisedBy,omitempty"`
+	// CreatedAt is when the customer placed the order.
+	CreatedAt *time.Time `json:"createdAt,omitempty"`
+	// ExpiresAt is when the order is too old to prepare.
+	ExpiresAt *time.Time `json:"expiresAt,omitempty"`
+	// ReceivedAt is when the kitchen received the order. The current time is used if it is not set.
+	ReceivedAt time.Time `json:"-"`
+	// Trace is the trace of the message that the order was received in.
+	Trace tracing.TraceContext `json:"-"`
+}
+
+// WithMaxAge returns a copy of the request that expires maxAge after the order was created,
+// or after it was received if the creation time is not known, unless the request already has an expiry time.
+func (req OrderRequest) WithMaxAge(maxAge time.Duration) OrderRequest {
+	var expiresAt, createdAt time.Time
+	if req.ExpiresAt != nil {
+		expiresAt = *req.ExpiresAt
+	}
+	if req.CreatedAt != nil {
+		createdAt = *req.CreatedAt
+	}
+	req.ExpiresAt = optionalTime(k.OrderExpiresAt(expiresAt, createdAt, req.ReceivedAt, maxAge))
+	return req
 }
 
-func (req OrderRequest) PreparationTime() time.Duration {
-	sum := 0
-	for _, topping := range req.Toppings {
-		sum += len(topping)
+// Deadline is when the order must be ready.
+func (req OrderRequest) Deadline() (k.OrderPriority, time.Time, error) {
+	priority, err := k.ParseOrderPriority(req.Priority)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	var promisedBy time.Time
+	if req.PromisedBy != nil {
+		promisedBy = *req.PromisedBy
+	}
+
+	receivedAt := req.ReceivedAt
+	if receivedAt.IsZero() {
+		receivedAt = time.Now()
 	}
-	return time.Duration(sum) * time.Second
+
+	return priority, priority.Deadline(promisedBy, receivedAt), nil
 }
 
 // TODO: Should be separate events (probs with an event wrapper). Will do later.
@@ -28,42 +77,254 @@ type OrderResponse struct {
 	OrderId       uint64        `json:"id"`
 	Status        k.OrderStatus `json:"status"`
 	FailureReason string        `json:"reason,omitempty"`
+	// Conflicts lists the toppings that conflict with the order's exclusions.
+	Conflicts map[string]string `json:"conflicts,omitempty"`
+	// Substitutions lists the toppings that were out of stock and what was used instead.
+	Substitutions []SubstitutionResponse `json:"substitutions,omitempty"`
+	// EstimatedReadyAt is when an order that is being prepared is expected to be ready.
+	EstimatedReadyAt *time.Time `json:"estimatedReadyAt,omitempty"`
+	// Deadline is when an order that is being prepared must be ready.
+	Deadline *time.Time `json:"deadline,omitempty"`
+	// AtRisk is true if the order is not expected to be ready by its deadline.
+	AtRisk bool `json:"atRisk,omitempty"`
+	// Expired is true if the order failed because it was received too late to be prepared.
+	Expired bool `json:"expired,omitempty"`
+}
+
+// OrderResult is the outcome of scheduling one order of a batch.
+// Err is set if the order failed.
+type OrderResult struct {
+	Response OrderResponse
+	Err      error
+}
+
+type SubstitutionResponse struct {
+	Original   string `json:"original"`
+	Substitute string `json:"substitute"`
+	Units      uint   `json:"units"`
+}
+
+type OrderItemResponse struct {
+	Name        string  `json:"name"`
+	Units       uint    `json:"units"`
+	UnitCost    k.Money `json:"unitCost"`
+	CostOfGoods k.Money `json:"costOfGoods"`
+}
+
+type TaskResponse struct {
+	ItemName string    `json:"itemName"`
+	Station  k.Station `json:"station"`
+	Slot     uint      `json:"slot"`
+	StartsAt time.Time `json:"startsAt"`
+	EndsAt   time.Time `json:"endsAt"`
+}
+
+type OrderRecordResponse struct {
+	OrderId          uint64                 `json:"id"`
+	Status           k.OrderStatus          `json:"status"`
+	FailureReason    string                 `json:"reason,omitempty"`
+	Location         k.Location             `json:"location"`
+	Items            []OrderItemResponse    `json:"items"`
+	Substitutions    []SubstitutionResponse `json:"substitutions,omitempty"`
+	Tasks            []TaskResponse         `json:"tasks,omitempty"`
+	Estimator        string                 `json:"estimator,omitempty"`
+	Priority         k.OrderPriority        `json:"priority"`
+	Deadline         *time.Time             `json:"deadline,omitempty"`
+	EstimatedReadyAt *time.Time             `json:"estimatedReadyAt,omitempty"`
+	CostOfGoods      k.Money                `json:"costOfGoods"`
+	CreatedAt        time.Time              `json:"createdAt"`
+}
+
+type CostOfGoodsLineResponse struct {
+	Name        string  `json:"name"`
+	Units       uint    `json:"units"`
+	CostOfGoods k.Money `json:"costOfGoods"`
+}
+
+type CostOfGoodsReportResponse struct {
+	Date        string                    `json:"date"`
+	Orders      uint                      `json:"orders"`
+	CostOfGoods k.Money                   `json:"costOfGoods"`
+	Items       []CostOfGoodsLineResponse `json:"items"`
 }
 
 type OrderService interface {
-	ProcessOrder(ctx context.Context, req OrderRequest) (OrderResponse, error)
+	// ScheduleOrder consumes the ingredients of an order and schedules its tasks on the kitchen's stations.
+	// An order that is accepted is PREPARING and the response estimates when it will be ready.
+	ScheduleOrder(ctx context.Context, req OrderRequest) (OrderResponse, error)
+	// ScheduleOrders schedules a batch of orders in a single transaction.
+	// An order that can not be prepared fails on its own without failing the rest of the batch.
+	// The results are in the same order as the requests.
+	ScheduleOrders(ctx context.Context, reqs []OrderRequest) []OrderResult
+	// PrepareOrder waits for the tasks of a scheduled order to finish and records that the order is ready.
+	PrepareOrder(ctx context.Context, scheduled OrderResponse) OrderResponse
+	GetOrder(ctx context.Context, id uint64) (OrderRecordResponse, error)
+	GetCostOfGoodsReport(ctx context.Context, date string) (CostOfGoodsReportResponse, error)
 }
 
 type orderService struct {
-	stockDao db.StockDao
+	orderDao  db.OrderDao
+	tickets   TicketPublisher
+	estimator PreparationEstimator
 }
 
-func MustOrderService(stockDao db.StockDao) OrderService {
-	if stockDao == nil {
-		log.Fatal("can not create account service. stockDao is nil")
+func MustOrderService(orderDao db.OrderDao, tickets TicketPublisher, estimator PreparationEstimator) OrderService {
+	if orderDao == nil {
+		log.Fatal("can not create account service. orderDao is nil")
 	}
-
-	return &orderService{
-		stockDao: stockDao,
+	if tickets == nil {
+		log.Fatal("can not create account service. tickets is nil")
+	}
+	if estimator == nil {
+		log.Fatal("can not create account service. estimator is nil")
 	}
+
+	return tracedOrderService{&orderService{
+		orderDao:  orderDao,
+		tickets:   tickets,
+		estimator: estimator,
+	}}
 }
 
 // I'm not happy with the return type.
 // The OrderResponse should be sent to a different topic depending upon whether error is nil or not. Can we improve this?
 // Can we return different event types and switch between topic based on the type of the event?
-func (svc orderService) ProcessOrder(ctx context.Context, req OrderRequest) (OrderResponse, error) {
+func (svc orderService) ScheduleOrder(ctx context.Context, req OrderRequest) (OrderResponse, error) {
+	result := svc.ScheduleOrders(ctx, []OrderRequest{req})[0]
+	return result.Response, result.Err
+}
+
+const savepointOrder = "schedule_order"
+
+// ScheduleOrders schedules a batch of orders in a single transaction.
+// Each order is scheduled under its own savepoint so that an order that can not be prepared
+// is rolled back and recorded as failed without aborting the rest of the batch.
+// If the batch can not be saved, every order in it fails.
+func (svc orderService) ScheduleOrders(ctx context.Context, reqs []OrderRequest) []OrderResult {
+	if err := auth.Authorize(ctx, auth.PrepareOrders); err != nil {
+		return failedOrderResults(reqs, err)
+	}
+
+	results := make([]OrderResult, len(reqs))
+
+	tx, err := svc.orderDao.BeginTx()
+	if err != nil {
+		return failedOrderResults(reqs, err)
+	}
+
+	defer db.DeferRollback(tx, "ScheduleOrders")
+
+	tickets := []k.TicketEvent{}
+	for i, req := range reqs {
+		var (
+			resp         OrderResponse
+			orderTickets []k.TicketEvent
+			orderErr     error
+			orderCtx     = tracing.WithTraceContext(ctx, req.Trace)
+		)
+
+		if err = tx.Savepoint(ctx, savepointOrder); err != nil {
+			return failedOrderResults(reqs, err)
+		}
+
+		if resp, orderTickets, orderErr = svc.scheduleOrder(orderCtx, tx, req); orderErr == nil {
+			if err = tx.ReleaseSavepoint(ctx, savepointOrder); err != nil {
+				return failedOrderResults(reqs, err)
+			}
+			tickets = append(tickets, orderTickets...)
+			results[i] = OrderResult{resp, nil}
+			continue
+		}
+
+		if err = tx.RollbackToSavepoint(ctx, savepointOrder); err != nil {
+			return failedOrderResults(reqs, err)
+		}
+
+		var rejection orderRejection
+		if errors.As(orderErr, &rejection) {
+			resp, orderErr = svc.failOrder(orderCtx, tx, req, rejection.reason)
+		}
+		results[i] = OrderResult{resp, orderErr}
+	}
+
+	if err = db.Commit(tx); err != nil {
+		log.ErrCtx(ctx, err).
+			Int32("orders", int32(len(reqs))).
+			Msg("Error committing orders")
+		return failedOrderResults(reqs, err)
+	}
+
+	svc.tickets.Publish(tickets...)
+
+	return results
+}
+
+// scheduleOrder schedules an order in tx.
+// The reason that an order can not be prepared is returned as an orderRejection.
+func (svc orderService) scheduleOrder(ctx context.Context, tx db.OrderTx, req OrderRequest) (OrderResponse, []k.TicketEvent, error) {
 
 	log.InfoCtx(ctx).
 		UInt64("orderId", req.OrderId).
+		Str("location", req.Location).
 		Struct("toppings", req.Toppings).
 		Msg("Processing order")
 
-	tx, err := svc.stockDao.BeginTx()
+	// Orders are redelivered when the consumer restarts from the oldest offset.
+	// An order that has been recorded already is not prepared again.
+	// An order that was still being prepared when the service stopped keeps its schedule and is then finished.
+	existing, err := tx.GetOrder(ctx, req.OrderId)
+	if err == nil {
+		log.InfoCtx(ctx).
+			UInt64("orderId", req.OrderId).
+			Str("status", string(existing.Status())).
+			Msg("Order has already been processed")
+		resp, err := existingOrderResponse(existing)
+		return resp, nil, err
+	} else if !isNotFound(err) {
+		return failedOrderResponse(req, err), nil, err
+	}
+
+	// Toppings are known by the canonical names of their items so that orders are recorded with the same names as stock.
+	items, err := tx.GetItems(ctx, req.Toppings)
 	if err != nil {
-		return OrderResponse{req.OrderId, k.OrderStatusFailed, err.Error()}, err
+		return failedOrderResponse(req, err), nil, err
 	}
+	toppings := k.CanonicalNames(req.Toppings, items)
 
-	defer db.DeferRollback(tx, "ProcessOrder")
+	if req.ExpiresAt != nil {
+		if err = k.CheckExpiry(req.OrderId, *req.ExpiresAt, time.Now()); err != nil {
+			log.ErrCtx(ctx, err).
+				UInt64("orderId", req.OrderId).
+				Msg("Order expired before it could be prepared")
+			return OrderResponse{}, nil, orderRejection{err}
+		}
+	}
+
+	var location k.Location
+	if location, err = k.ParseLocation(req.Location); err != nil {
+		return OrderResponse{}, nil, orderRejection{err}
+	}
+
+	var (
+		priority k.OrderPriority
+		deadline time.Time
+	)
+	if priority, deadline, err = req.Deadline(); err != nil {
+		return OrderResponse{}, nil, orderRejection{err}
+	}
+
+	var exclusions k.DietaryTags
+	if exclusions, err = k.ParseDietaryTags(req.Exclusions); err != nil {
+		return OrderResponse{}, nil, orderRejection{err}
+	}
+
+	if err = checkExclusions(ctx, tx, toppings, exclusions); err != nil {
+		log.ErrCtx(ctx, err).
+			UInt64("orderId", req.OrderId).
+			Struct("exclusions", exclusions.Strings()).
+			Msg("Order conflicts with its exclusions")
+		return OrderResponse{}, nil, orderRejection{err}
+	}
 
 	// Decrease the stock
 	var (
@@ -71,33 +332,572 @@ func (svc orderService) ProcessOrder(ctx context.Context, req OrderRequest) (Ord
 		item  k.StockItem
 	)
 
-	for _, topping := range req.Toppings {
+	for _, topping := range toppings {
 		if item, err = k.NewStockItem(topping, 1); err != nil {
-			return OrderResponse{req.OrderId, k.OrderStatusFailed, err.Error()}, err
+			return OrderResponse{}, nil, orderRejection{err}
 		}
-		stock = append(stock, item)
+		stock = append(stock, item.AtLocation(location))
 	}
 
-	if err = tx.Decrease(ctx, stock); err != nil {
+	var substitutions k.Substitutions
+	if stock, substitutions, err = decreaseStock(ctx, tx, stock, req.AllowSubstitutions, exclusions); err != nil {
 		log.ErrCtx(ctx, err).
 			UInt64("orderId", req.OrderId).
 			Msg("Error processing order")
-		return OrderResponse{req.OrderId, k.OrderStatusFailed, err.Error()}, err
+		return OrderResponse{}, nil, orderRejection{err}
 	}
 
-	if err = db.Commit(tx); err != nil {
+	var tasks k.Tasks
+	if tasks, err = scheduleTasks(ctx, tx, svc.estimator, req.OrderId, location, stock, time.Now()); err != nil {
+		log.ErrCtx(ctx, err).
+			UInt64("orderId", req.OrderId).
+			Msg("Error scheduling order")
+		return failedOrderResponse(req, err), nil, err
+	}
+
+	var order k.Order
+	if order, err = k.NewOrder(req.OrderId, k.OrderStatusPreparing, "", orderItems(stock), time.Now()); err != nil {
+		return failedOrderResponse(req, err), nil, err
+	}
+	order = order.WithSubstitutions(substitutions).WithTasks(tasks).EstimatedBy(svc.estimator.Name()).WithDeadline(priority, deadline).AtLocation(location)
+
+	if err = tx.SaveOrder(ctx, order); err != nil {
 		log.ErrCtx(ctx, err).
 			UInt64("orderId", req.OrderId).
-			Msg("Error committing stock")
-		return OrderResponse{req.OrderId, k.OrderStatusFailed, err.Error()}, err
+			Msg("Error recording order")
+		return failedOrderResponse(req, err), nil, err
+	}
+
+	var tickets []k.TicketEvent
+	if tickets, err = createTickets(ctx, tx, order); err != nil {
+		log.ErrCtx(ctx, err).
+			UInt64("orderId", req.OrderId).
+			Msg("Error creating ticket")
+		return failedOrderResponse(req, err), nil, err
 	}
 
-	// Wait
 	log.InfoCtx(ctx).
 		UInt64("orderId", req.OrderId).
-		Duration("PreparationTime", req.PreparationTime()).
+		Str("estimatedReadyAt", order.EstimatedReadyAt().Format(time.RFC3339)).
+		Str("estimator", order.Estimator()).
+		Str("deadline", order.Deadline().Format(time.RFC3339)).
+		Msg("Order scheduled")
+
+	if order.AtRisk() {
+		log.InfoCtx(ctx).
+			UInt64("orderId", req.OrderId).
+			Str("priority", string(order.Priority())).
+			Duration("lateBy", order.EstimatedReadyAt().Sub(order.Deadline())).
+			Msg("Order is not expected to be ready by its deadline")
+	}
+
+	return preparingOrderResponse(order), tickets, nil
+}
+
+// orderRejection is the reason that an order can not be prepared.
+// Rejected orders are recorded as failed, unlike orders that could not be scheduled because of a system error.
+type orderRejection struct {
+	reason error
+}
+
+func (r orderRejection) Error() string {
+	return r.reason.Error()
+}
+
+func (r orderRejection) Unwrap() error {
+	return r.reason
+}
+
+func failedOrderResponse(req OrderRequest, err error) OrderResponse {
+	return OrderResponse{OrderId: req.OrderId, Status: k.OrderStatusFailed, FailureReason: err.Error()}
+}
+
+func failedOrderResults(reqs []OrderRequest, err error) []OrderResult {
+	results := make([]OrderResult, len(reqs))
+	for i, req := range reqs {
+		results[i] = OrderResult{failedOrderResponse(req, err), err}
+	}
+	return results
+}
+
+func (svc orderService) PrepareOrder(ctx context.Context, scheduled OrderResponse) OrderResponse {
+	var preparationTime time.Duration
+	if scheduled.EstimatedReadyAt != nil {
+		preparationTime = time.Until(*scheduled.EstimatedReadyAt)
+	}
+
+	log.InfoCtx(ctx).
+		UInt64("orderId", scheduled.OrderId).
+		Duration("PreparationTime", preparationTime).
 		Msg("Preparing order")
-	time.Sleep(req.PreparationTime())
 
-	return OrderResponse{req.OrderId, k.OrderStatusReady, ""}, nil
+	if err := svc.updateTickets(ctx, scheduled.OrderId, k.TicketStarted); err != nil {
+		log.ErrCtx(ctx, err).
+			UInt64("orderId", scheduled.OrderId).
+			Msg("Error recording that order was started")
+	}
+
+	time.Sleep(preparationTime)
+
+	if err := svc.updateOrderStatus(ctx, scheduled.OrderId, k.OrderStatusReady, "", k.TicketBumped); err != nil {
+		log.ErrCtx(ctx, err).
+			UInt64("orderId", scheduled.OrderId).
+			Msg("Error recording that order is ready")
+	}
+
+	return OrderResponse{OrderId: scheduled.OrderId, Status: k.OrderStatusReady, Substitutions: scheduled.Substitutions}
+}
+
+// scheduleTasks splits an order into a task for each topping, estimates how long each task takes and schedules
+// each task on the slot of its station that is free soonest, taking the tasks of earlier orders into account.
+// Toppings that have not been assigned to a station are prepared at the default station.
+// Stations that have not been set up have a capacity of 1.
+func scheduleTasks(ctx context.Context, tx db.OrderTx, estimator PreparationEstimator, orderId uint64, location k.Location, stock k.Stock, now time.Time) (k.Tasks, error) {
+	stations, err := tx.GetStations(ctx)
+	if err != nil {
+		return nil, err
+	}
+	toppingTasks, err := tx.GetToppingTasks(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	stationsByName := map[k.Station]k.KitchenStation{}
+	for _, station := range stations {
+		stationsByName[station.Name()] = station
+	}
+	toppingTasksByItem := map[string]k.ToppingTask{}
+	for _, task := range toppingTasks {
+		toppingTasksByItem[task.ItemName()] = task
+	}
+
+	toppings := []string{}
+	for _, item := range stock {
+		toppings = append(toppings, item.Name())
+	}
+
+	estimates, err := estimator.Estimate(ctx, tx, toppings, toppingTasksByItem)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		loads = map[k.Station]*k.StationLoad{}
+		tasks = k.Tasks{}
+	)
+	for i, item := range stock {
+		toppingTask := estimates[i]
+
+		load, ok := loads[toppingTask.Station()]
+		if !ok {
+			station, ok := stationsByName[toppingTask.Station()]
+			if !ok {
+				if station, err = k.NewKitchenStation(toppingTask.Station(), 1); err != nil {
+					return nil, err
+				}
+			}
+
+			var stationLoad k.StationLoad
+			if stationLoad, err = tx.GetStationLoad(ctx, station, location, now); err != nil {
+				return nil, err
+			}
+			load = &stationLoad
+			loads[toppingTask.Station()] = load
+		}
+
+		for unit := uint(0); unit < item.Units(); unit++ {
+			var task k.Task
+			if task, err = load.Assign(orderId, toppingTask); err != nil {
+				return nil, err
+			}
+			tasks = append(tasks, task)
+		}
+	}
+
+	return tasks, nil
+}
+
+// createTickets creates a ticket at each station that has a task for the order.
+func createTickets(ctx context.Context, tx db.OrderTx, order k.Order) ([]k.TicketEvent, error) {
+	stations, items := order.Tasks().ByStation()
+
+	tickets := []k.TicketEvent{}
+	for _, station := range stations {
+		ticket, err := k.NewTicketEvent(0, k.TicketCreated, order.Id(), station, order.Location(), items[station], time.Now())
+		if err != nil {
+			return nil, err
+		}
+		if ticket, err = tx.SaveTicketEvent(ctx, ticket); err != nil {
+			return nil, err
+		}
+		tickets = append(tickets, ticket)
+	}
+	return tickets, nil
+}
+
+// failOrder records an order that could not be prepared in tx.
+// The reason the order failed is returned even if the failure could not be recorded.
+func (svc orderService) failOrder(ctx context.Context, tx db.OrderTx, req OrderRequest, reason error) (OrderResponse, error) {
+	resp := failedOrderResponse(req, reason)
+	var invalid k.InvalidError
+	if errors.As(reason, &invalid) && len(invalid.Fields) > 0 {
+		resp.Conflicts = invalid.Fields
+	}
+	var expired k.ExpiredError
+	resp.Expired = errors.As(reason, &expired)
+
+	order, err := k.NewOrder(req.OrderId, k.OrderStatusFailed, reason.Error(), []k.OrderItem{}, time.Now())
+	if err != nil {
+		return resp, reason
+	}
+	if location, err := k.ParseLocation(req.Location); err == nil {
+		order = order.AtLocation(location)
+	}
+
+	// The failure is recorded under a savepoint so that the rest of the batch is saved even if it can not be.
+	if err = tx.Savepoint(ctx, savepointOrder); err != nil {
+		log.ErrCtx(ctx, err).UInt64("orderId", req.OrderId).Msg("Error recording failed order")
+		return resp, reason
+	}
+	if err = tx.SaveOrder(ctx, order); err != nil {
+		log.ErrCtx(ctx, err).UInt64("orderId", req.OrderId).Msg("Error recording failed order")
+		err = tx.RollbackToSavepoint(ctx, savepointOrder)
+	} else {
+		err = tx.ReleaseSavepoint(ctx, savepointOrder)
+	}
+	if err != nil {
+		log.ErrCtx(ctx, err).UInt64("orderId", req.OrderId).Msg("Error recording failed order")
+	}
+
+	return resp, reason
+}
+
+// updateOrderStatus records the status of the order and moves its tickets on to ticketEvent in the same transaction.
+func (svc orderService) updateOrderStatus(ctx context.Context, id uint64, status k.OrderStatus, failureReason string, ticketEvent k.TicketEventType) error {
+	tx, err := svc.orderDao.BeginTx()
+	if err != nil {
+		return err
+	}
+
+	defer db.DeferRollback(tx, "updateOrderStatus")
+
+	if err = tx.UpdateOrderStatus(ctx, id, status, failureReason); err != nil {
+		return err
+	}
+
+	tickets, err := advanceOrderTickets(ctx, tx, id, ticketEvent)
+	if err != nil {
+		return err
+	}
+
+	if err = db.Commit(tx); err != nil {
+		return err
+	}
+
+	svc.tickets.Publish(tickets...)
+	return nil
+}
+
+func (svc orderService) updateTickets(ctx context.Context, id uint64, ticketEvent k.TicketEventType) error {
+	tx, err := svc.orderDao.BeginTx()
+	if err != nil {
+		return err
+	}
+
+	defer db.DeferRollback(tx, "updateTickets")
+
+	tickets, err := advanceOrderTickets(ctx, tx, id, ticketEvent)
+	if err != nil {
+		return err
+	}
+
+	if err = db.Commit(tx); err != nil {
+		return err
+	}
+
+	svc.tickets.Publish(tickets...)
+	return nil
+}
+
+// advanceOrderTickets moves the tickets of an order along with the order. The tickets are moved automatically, not by a cook,
+// so that the learned estimator does not learn from its own estimates.
+// Tickets that have already moved on, such as the tickets of an order that was redelivered, are left as they are.
+func advanceOrderTickets(ctx context.Context, tx db.OrderTx, id uint64, ticketEvent k.TicketEventType) ([]k.TicketEvent, error) {
+	tickets, err := advanceTickets(ctx, tx, id, "", ticketEvent, true)
+	if err != nil && (isInvalid(err) || isNotFound(err)) {
+		log.InfoCtx(ctx).
+			UInt64("orderId", id).
+			Str("ticketEvent", string(ticketEvent)).
+			Msg("Order tickets were not changed")
+		return []k.TicketEvent{}, nil
+	}
+	return tickets, err
+}
+
+func (svc orderService) GetOrder(ctx context.Context, id uint64) (OrderRecordResponse, error) {
+	if err := auth.Authorize(ctx, auth.ReadOrders); err != nil {
+		return OrderRecordResponse{}, err
+	}
+
+	tx, err := svc.orderDao.BeginTx()
+	if err != nil {
+		return OrderRecordResponse{}, err
+	}
+
+	defer db.DeferRollback(tx, "GetOrder")
+
+	order, err := tx.GetOrder(ctx, id)
+	if err != nil {
+		return OrderRecordResponse{}, err
+	}
+
+	if err = db.Commit(tx); err != nil {
+		return OrderRecordResponse{}, err
+	}
+
+	items := []OrderItemResponse{}
+	for _, item := range order.Items() {
+		items = append(items, OrderItemResponse{item.Name(), item.Units(), item.UnitCost(), item.CostOfGoods()})
+	}
+
+	tasks := []TaskResponse{}
+	for _, task := range order.Tasks() {
+		tasks = append(tasks, TaskResponse{task.ItemName, task.Station, task.Slot, task.StartsAt, task.EndsAt})
+	}
+
+	return OrderRecordResponse{
+		OrderId:          order.Id(),
+		Status:           order.Status(),
+		FailureReason:    order.FailureReason(),
+		Location:         order.Location(),
+		Items:            items,
+		Substitutions:    substitutionResponses(order.Substitutions()),
+		Tasks:            tasks,
+		Estimator:        order.Estimator(),
+		Priority:         order.Priority(),
+		Deadline:         optionalTime(order.Deadline()),
+		EstimatedReadyAt: estimatedReadyAt(order),
+		CostOfGoods:      order.CostOfGoods(),
+		CreatedAt:        order.CreatedAt(),
+	}, nil
+}
+
+func (svc orderService) GetCostOfGoodsReport(ctx context.Context, date string) (CostOfGoodsReportResponse, error) {
+	if err := auth.Authorize(ctx, auth.ReadReports); err != nil {
+		return CostOfGoodsReportResponse{}, err
+	}
+
+	day := time.Now().UTC()
+	if len(date) > 0 {
+		var err error
+		if day, err = time.Parse(reportDateLayout, date); err != nil {
+			return CostOfGoodsReportResponse{}, k.InvalidError{
+				Cause:  fmt.Errorf("invalid cost of goods report request"),
+				Fields: map[string]string{"date": "Date must be in the format YYYY-MM-DD"},
+			}
+		}
+	}
+
+	tx, err := svc.orderDao.BeginTx()
+	if err != nil {
+		return CostOfGoodsReportResponse{}, err
+	}
+
+	defer db.DeferRollback(tx, "GetCostOfGoodsReport")
+
+	report, err := tx.GetCostOfGoodsReport(ctx, day)
+	if err != nil {
+		return CostOfGoodsReportResponse{}, err
+	}
+
+	if err = db.Commit(tx); err != nil {
+		return CostOfGoodsReportResponse{}, err
+	}
+
+	items := []CostOfGoodsLineResponse{}
+	for _, line := range report.Lines {
+		items = append(items, CostOfGoodsLineResponse{line.Name, line.Units, line.CostOfGoods})
+	}
+
+	return CostOfGoodsReportResponse{
+		Date:        report.Date.Format(reportDateLayout),
+		Orders:      report.Orders,
+		CostOfGoods: report.CostOfGoods(),
+		Items:       items,
+	}, nil
+}
+
+// orderItems combines repeated toppings into a single item.
+func orderItems(stock k.Stock) []k.OrderItem {
+	units := map[string]uint{}
+	names := []string{}
+	for _, item := range stock {
+		if _, ok := units[item.Name()]; !ok {
+			names = append(names, item.Name())
+		}
+		units[item.Name()] += item.Units()
+	}
+
+	items := []k.OrderItem{}
+	for _, name := range names {
+		items = append(items, k.NewOrderItem(name, units[name], 0))
+	}
+	return items
+}
+
+// decreaseStock consumes the toppings of an order from stock.
+// When substitutions are allowed, a topping that is out of stock is replaced with the first of its substitutes,
+// in order of priority, that is in stock and does not conflict with the order's exclusions.
+// The stock that was consumed is returned along with the substitutions that were made.
+// Every change is made in tx so that the order is either prepared entirely or not at all.
+func decreaseStock(ctx context.Context, tx db.StockTx, stock k.Stock, allowSubstitutions bool, exclusions k.DietaryTags) (k.Stock, k.Substitutions, error) {
+	var (
+		consumed      = k.Stock{}
+		substitutions = k.Substitutions{}
+		rules         k.SubstitutionRules
+		err           error
+	)
+
+	// Most orders are in stock, so the whole order is taken at once
+	// and the toppings are only taken one by one to find substitutes for the toppings that are out of stock.
+	if err = tx.Decrease(ctx, stock); err == nil || !allowSubstitutions || !isInvalid(err) {
+		if err != nil {
+			return nil, nil, err
+		}
+		return stock, substitutions, nil
+	}
+
+	for _, item := range stock {
+		if err = tx.Decrease(ctx, k.Stock{item}); err == nil {
+			consumed = append(consumed, item)
+			continue
+		}
+
+		if !allowSubstitutions || !isInvalid(err) {
+			return nil, nil, err
+		}
+		insufficientStock := err
+
+		if rules == nil {
+			if rules, err = tx.GetSubstitutionRules(ctx); err != nil {
+				return nil, nil, err
+			}
+		}
+
+		var (
+			substitute k.StockItem
+			found      bool
+		)
+		if substitute, found, err = decreaseSubstitute(ctx, tx, item, rules.For(item.Name()), exclusions); err != nil {
+			return nil, nil, err
+		}
+		if !found {
+			return nil, nil, insufficientStock
+		}
+
+		log.InfoCtx(ctx).
+			Str("original", item.Name()).
+			Str("substitute", substitute.Name()).
+			Msg("Ingredient substituted")
+
+		consumed = append(consumed, substitute)
+		substitutions = substitutions.Add(item.Name(), substitute.Name(), substitute.Units())
+	}
+
+	return consumed, substitutions, nil
+}
+
+// decreaseSubstitute consumes the first substitute that is in stock.
+func decreaseSubstitute(ctx context.Context, tx db.StockTx, item k.StockItem, rules k.SubstitutionRules, exclusions k.DietaryTags) (k.StockItem, bool, error) {
+	for _, rule := range rules {
+		if err := checkExclusions(ctx, tx, []string{rule.SubstituteName()}, exclusions); err != nil {
+			if isInvalid(err) {
+				continue
+			}
+			return k.StockItem{}, false, err
+		}
+
+		substitute, err := k.NewStockItem(rule.SubstituteName(), item.Units())
+		if err != nil {
+			return k.StockItem{}, false, err
+		}
+		substitute = substitute.AtLocation(item.Location())
+
+		if err = tx.Decrease(ctx, k.Stock{substitute}); err == nil {
+			return substitute, true, nil
+		} else if !isInvalid(err) {
+			return k.StockItem{}, false, err
+		}
+	}
+	return k.StockItem{}, false, nil
+}
+
+func substitutionResponses(substitutions k.Substitutions) []SubstitutionResponse {
+	var responses []SubstitutionResponse
+	for _, substitution := range substitutions {
+		responses = append(responses, SubstitutionResponse{substitution.Original, substitution.Substitute, substitution.Units})
+	}
+	return responses
+}
+
+// checkExclusions rejects an order if any of its toppings conflicts with the customer's exclusions.
+func checkExclusions(ctx context.Context, tx db.StockTx, toppings []string, exclusions k.DietaryTags) error {
+	if len(exclusions) == 0 {
+		return nil
+	}
+
+	tags, err := tx.GetDietaryTags(ctx, toppings)
+	if err != nil {
+		return err
+	}
+
+	return k.CheckExclusions(toppings, tags, exclusions)
+}
+
+func existingOrderResponse(order k.Order) (OrderResponse, error) {
+	if order.Status() == k.OrderStatusPreparing {
+		return preparingOrderResponse(order), nil
+	}
+	resp := OrderResponse{OrderId: order.Id(), Status: order.Status(), FailureReason: order.FailureReason()}
+	if order.Status() == k.OrderStatusFailed {
+		return resp, k.InvalidError{Cause: fmt.Errorf("%s", order.FailureReason())}
+	}
+	return resp, nil
+}
+
+func preparingOrderResponse(order k.Order) OrderResponse {
+	readyAt := order.EstimatedReadyAt()
+	if readyAt.IsZero() {
+		readyAt = order.CreatedAt()
+	}
+	return OrderResponse{
+		OrderId:          order.Id(),
+		Status:           k.OrderStatusPreparing,
+		Substitutions:    substitutionResponses(order.Substitutions()),
+		EstimatedReadyAt: &readyAt,
+		Deadline:         optionalTime(order.Deadline()),
+		AtRisk:           order.AtRisk(),
+	}
+}
+
+// estimatedReadyAt is nil when the order has no tasks.
+func estimatedReadyAt(order k.Order) *time.Time {
+	return optionalTime(order.EstimatedReadyAt())
+}
+
+func optionalTime(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
+
+func isNotFound(err error) bool {
+	var notFound k.NotFoundError
+	return errors.As(err, &notFound)
+}
+
+func isInvalid(err error) bool {
+	var invalid k.InvalidError
+	return errors.As(err, &invalid)
 }
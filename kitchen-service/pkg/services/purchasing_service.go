@@ -0,0 +1,420 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/w-k-s/McMicroservices/kitchen-service/log"
+
+	"github.com/w-k-s/McMicroservices/kitchen-service/pkg/auth"
+	k "github.com/w-k-s/McMicroservices/kitchen-service/pkg/kitchen"
+	db "github.com/w-k-s/McMicroservices/kitchen-service/pkg/persistence"
+)
+
+const expectedDeliveryDateLayout = "2006-01-02"
+
+type SupplierRequest struct {
+	Name  string `json:"name"`
+	Email string `json:"email,omitempty"`
+}
+
+type SupplierResponse struct {
+	Id    uint64 `json:"id"`
+	Name  string `json:"name"`
+	Email string `json:"email,omitempty"`
+}
+
+type SuppliersResponse struct {
+	Suppliers []SupplierResponse `json:"suppliers"`
+}
+
+type PurchaseOrderLineRequest struct {
+	ItemName string `json:"itemName"`
+	Units    uint   `json:"units"`
+}
+
+type PurchaseOrderRequest struct {
+	SupplierId           uint64                     `json:"supplierId"`
+	ExpectedDeliveryDate string                     `json:"expectedDeliveryDate"`
+	Lines                []PurchaseOrderLineRequest `json:"lines"`
+}
+
+type PurchaseOrderLineResponse struct {
+	ItemName      string `json:"itemName"`
+	OrderedUnits  uint   `json:"orderedUnits"`
+	ReceivedUnits uint   `json:"receivedUnits"`
+}
+
+type DeliveryDiscrepancyResponse struct {
+	ItemName      string                    `json:"itemName"`
+	OrderedUnits  uint                      `json:"orderedUnits"`
+	ReceivedUnits uint                      `json:"receivedUnits"`
+	Kind          k.DeliveryDiscrepancyKind `json:"kind"`
+}
+
+type PurchaseOrderResponse struct {
+	Id                   uint64                        `json:"id"`
+	SupplierId           uint64                        `json:"supplierId"`
+	Status               k.PurchaseOrderStatus         `json:"status"`
+	ExpectedDeliveryDate string                        `json:"expectedDeliveryDate"`
+	Lines                []PurchaseOrderLineResponse   `json:"lines"`
+	Discrepancies        []DeliveryDiscrepancyResponse `json:"discrepancies"`
+}
+
+type PurchaseOrdersResponse struct {
+	PurchaseOrders []PurchaseOrderResponse `json:"purchaseOrders"`
+}
+
+type PurchasingService interface {
+	CreateSupplier(ctx context.Context, req SupplierRequest) (SupplierResponse, error)
+	GetSuppliers(ctx context.Context) (SuppliersResponse, error)
+	CreatePurchaseOrder(ctx context.Context, req PurchaseOrderRequest) (PurchaseOrderResponse, error)
+	GetPurchaseOrder(ctx context.Context, id uint64) (PurchaseOrderResponse, error)
+	GetPurchaseOrders(ctx context.Context, status string) (PurchaseOrdersResponse, error)
+	CancelPurchaseOrder(ctx context.Context, id uint64) (PurchaseOrderResponse, error)
+}
+
+type purchasingService struct {
+	purchasingDao db.PurchasingDao
+}
+
+func MustPurchasingService(purchasingDao db.PurchasingDao) PurchasingService {
+	if purchasingDao == nil {
+		log.Fatal("can not create purchasing service. purchasingDao is nil")
+	}
+	return &purchasingService{
+		purchasingDao: purchasingDao,
+	}
+}
+
+func (svc purchasingService) CreateSupplier(ctx context.Context, req SupplierRequest) (SupplierResponse, error) {
+	if err := auth.Authorize(ctx, auth.WritePurchasing); err != nil {
+		return SupplierResponse{}, err
+	}
+
+	supplier, err := k.NewSupplier(0, req.Name, req.Email)
+	if err != nil {
+		return SupplierResponse{}, err
+	}
+
+	tx, err := svc.purchasingDao.BeginTx()
+	if err != nil {
+		return SupplierResponse{}, err
+	}
+
+	defer db.DeferRollback(tx, "CreateSupplier")
+
+	if supplier, err = tx.SaveSupplier(ctx, supplier); err != nil {
+		return SupplierResponse{}, err
+	}
+
+	if err = db.Commit(tx); err != nil {
+		return SupplierResponse{}, err
+	}
+
+	return supplierResponse(supplier), nil
+}
+
+func (svc purchasingService) GetSuppliers(ctx context.Context) (SuppliersResponse, error) {
+	if err := auth.Authorize(ctx, auth.ReadPurchasing); err != nil {
+		return SuppliersResponse{}, err
+	}
+
+	tx, err := svc.purchasingDao.BeginTx()
+	if err != nil {
+		return SuppliersResponse{}, err
+	}
+
+	defer db.DeferRollback(tx, "GetSuppliers")
+
+	suppliers, err := tx.GetSuppliers(ctx)
+	if err != nil {
+		return SuppliersResponse{}, err
+	}
+
+	if err = db.Commit(tx); err != nil {
+		return SuppliersResponse{}, err
+	}
+
+	resp := SuppliersResponse{Suppliers: []SupplierResponse{}}
+	for _, supplier := range suppliers {
+		resp.Suppliers = append(resp.Suppliers, supplierResponse(supplier))
+	}
+	return resp, nil
+}
+
+func (svc purchasingService) CreatePurchaseOrder(ctx context.Context, req PurchaseOrderRequest) (PurchaseOrderResponse, error) {
+	if err := auth.Authorize(ctx, auth.WritePurchasing); err != nil {
+		return PurchaseOrderResponse{}, err
+	}
+
+	expectedDeliveryDate, err := time.Parse(expectedDeliveryDateLayout, req.ExpectedDeliveryDate)
+	if err != nil {
+		return PurchaseOrderResponse{}, k.InvalidError{
+			Cause:  fmt.Errorf("invalid purchase order"),
+			Fields: map[string]string{"expected_delivery_date": "Expected delivery date must be in the format YYYY-MM-DD"},
+		}
+	}
+
+	names := []string{}
+	for _, lineRequest := range req.Lines {
+		if _, err = k.NewPurchaseOrderLine(lineRequest.ItemName, lineRequest.Units, 0); err != nil {
+			return PurchaseOrderResponse{}, err
+		}
+		names = append(names, lineRequest.ItemName)
+	}
+
+	tx, err := svc.purchasingDao.BeginTx()
+	if err != nil {
+		return PurchaseOrderResponse{}, err
+	}
+
+	defer db.DeferRollback(tx, "CreatePurchaseOrder")
+
+	// Lines are ordered by the canonical name of the item so that deliveries under any of its names are received against them.
+	items, err := tx.GetItems(ctx, names)
+	if err != nil {
+		return PurchaseOrderResponse{}, err
+	}
+
+	lines := []k.PurchaseOrderLine{}
+	for i, name := range k.CanonicalNames(names, items) {
+		var line k.PurchaseOrderLine
+		if line, err = k.NewPurchaseOrderLine(name, req.Lines[i].Units, 0); err != nil {
+			return PurchaseOrderResponse{}, err
+		}
+		lines = append(lines, line)
+	}
+
+	po, err := k.NewPurchaseOrder(0, req.SupplierId, k.PurchaseOrderStatusOpen, expectedDeliveryDate, lines)
+	if err != nil {
+		return PurchaseOrderResponse{}, err
+	}
+
+	if _, err = tx.GetSupplier(ctx, po.SupplierId()); err != nil {
+		return PurchaseOrderResponse{}, err
+	}
+
+	if po, err = tx.SavePurchaseOrder(ctx, po); err != nil {
+		return PurchaseOrderResponse{}, err
+	}
+
+	if err = db.Commit(tx); err != nil {
+		return PurchaseOrderResponse{}, err
+	}
+
+	log.InfoCtx(ctx).
+		UInt64("purchaseOrderId", po.Id()).
+		UInt64("supplierId", po.SupplierId()).
+		Msg("Purchase order created")
+
+	return purchaseOrderResponse(po), nil
+}
+
+func (svc purchasingService) GetPurchaseOrder(ctx context.Context, id uint64) (PurchaseOrderResponse, error) {
+	if err := auth.Authorize(ctx, auth.ReadPurchasing); err != nil {
+		return PurchaseOrderResponse{}, err
+	}
+
+	tx, err := svc.purchasingDao.BeginTx()
+	if err != nil {
+		return PurchaseOrderResponse{}, err
+	}
+
+	defer db.DeferRollback(tx, "GetPurchaseOrder")
+
+	po, err := tx.GetPurchaseOrder(ctx, id)
+	if err != nil {
+		return PurchaseOrderResponse{}, err
+	}
+
+	if err = db.Commit(tx); err != nil {
+		return PurchaseOrderResponse{}, err
+	}
+
+	return purchaseOrderResponse(po), nil
+}
+
+func (svc purchasingService) GetPurchaseOrders(ctx context.Context, status string) (PurchaseOrdersResponse, error) {
+	if err := auth.Authorize(ctx, auth.ReadPurchasing); err != nil {
+		return PurchaseOrdersResponse{}, err
+	}
+
+	tx, err := svc.purchasingDao.BeginTx()
+	if err != nil {
+		return PurchaseOrdersResponse{}, err
+	}
+
+	defer db.DeferRollback(tx, "GetPurchaseOrders")
+
+	orders, err := tx.GetPurchaseOrders(ctx, k.PurchaseOrderStatus(status))
+	if err != nil {
+		return PurchaseOrdersResponse{}, err
+	}
+
+	if err = db.Commit(tx); err != nil {
+		return PurchaseOrdersResponse{}, err
+	}
+
+	resp := PurchaseOrdersResponse{PurchaseOrders: []PurchaseOrderResponse{}}
+	for _, po := range orders {
+		resp.PurchaseOrders = append(resp.PurchaseOrders, purchaseOrderResponse(po))
+	}
+	return resp, nil
+}
+
+func (svc purchasingService) CancelPurchaseOrder(ctx context.Context, id uint64) (PurchaseOrderResponse, error) {
+	if err := auth.Authorize(ctx, auth.WritePurchasing); err != nil {
+		return PurchaseOrderResponse{}, err
+	}
+
+	tx, err := svc.purchasingDao.BeginTx()
+	if err != nil {
+		return PurchaseOrderResponse{}, err
+	}
+
+	defer db.DeferRollback(tx, "CancelPurchaseOrder")
+
+	po, err := tx.GetPurchaseOrder(ctx, id)
+	if err != nil {
+		return PurchaseOrderResponse{}, err
+	}
+
+	if !po.IsOpen() {
+		return PurchaseOrderResponse{}, k.InvalidError{Cause: fmt.Errorf("purchase order %d is %s and can not be cancelled", po.Id(), po.Status())}
+	}
+
+	if po, err = k.NewPurchaseOrder(po.Id(), po.SupplierId(), k.PurchaseOrderStatusCancelled, po.ExpectedDeliveryDate(), po.Lines()); err != nil {
+		return PurchaseOrderResponse{}, err
+	}
+
+	if err = tx.UpdatePurchaseOrder(ctx, po); err != nil {
+		return PurchaseOrderResponse{}, err
+	}
+
+	if err = db.Commit(tx); err != nil {
+		return PurchaseOrderResponse{}, err
+	}
+
+	return purchaseOrderResponse(po), nil
+}
+
+// receiveAgainstPurchaseOrder matches a delivery with the purchase order it fulfils.
+// When only the supplier is known, the open purchase order with the earliest expected delivery date is used.
+// Deliveries that reference neither a purchase order nor a supplier, and deliveries from a supplier without open purchase orders, are not matched.
+// A purchase order or supplier that does not exist returns a k.NotFoundError, and a purchase order that is no longer open returns a k.ConflictError.
+func receiveAgainstPurchaseOrder(ctx context.Context, tx db.PurchasingTx, req StockRequest, received k.Stock) error {
+	var (
+		po  k.PurchaseOrder
+		err error
+	)
+
+	switch {
+	case req.PurchaseOrderId != 0:
+		if po, err = tx.GetPurchaseOrder(ctx, req.PurchaseOrderId); err != nil {
+			return err
+		}
+		if req.SupplierId != 0 && req.SupplierId != po.SupplierId() {
+			return k.InvalidError{Cause: fmt.Errorf("purchase order %d was not placed with supplier %d", po.Id(), req.SupplierId)}
+		}
+	case req.SupplierId != 0:
+		if _, err = tx.GetSupplier(ctx, req.SupplierId); err != nil {
+			return err
+		}
+		if po, err = tx.GetOldestOpenPurchaseOrder(ctx, req.SupplierId); err != nil {
+			if isNotFound(err) {
+				log.InfoCtx(ctx).
+					UInt64("supplierId", req.SupplierId).
+					Msg("Delivery does not match an open purchase order")
+				return nil
+			}
+			return err
+		}
+	default:
+		return nil
+	}
+
+	// The delivery is matched to the lines by the canonical name of each item, whichever name it was delivered under.
+	if received, err = identifyDelivery(ctx, tx, received); err != nil {
+		return err
+	}
+
+	if po, err = po.Receive(received); err != nil {
+		return err
+	}
+
+	if err = tx.UpdatePurchaseOrder(ctx, po); err != nil {
+		return err
+	}
+
+	discrepancies := po.Discrepancies()
+	if len(discrepancies) > 0 {
+		log.InfoCtx(ctx).
+			UInt64("purchaseOrderId", po.Id()).
+			Struct("discrepancies", discrepancies).
+			Msg("Delivery does not match purchase order")
+	} else {
+		log.InfoCtx(ctx).
+			UInt64("purchaseOrderId", po.Id()).
+			Msg("Delivery matches purchase order")
+	}
+
+	return nil
+}
+
+// identifyDelivery returns the delivered stock with each item known by its canonical name. Items that are not known keep their name.
+func identifyDelivery(ctx context.Context, tx db.PurchasingTx, delivery k.Stock) (k.Stock, error) {
+	names := []string{}
+	for _, stockItem := range delivery {
+		names = append(names, stockItem.Name())
+	}
+
+	items, err := tx.GetItems(ctx, names)
+	if err != nil {
+		return nil, err
+	}
+
+	identified := k.Stock{}
+	for _, stockItem := range delivery {
+		if item, ok := items[k.ItemKey(stockItem.Name())]; ok {
+			stockItem = stockItem.OfItem(item)
+		}
+		identified = append(identified, stockItem)
+	}
+	return identified, nil
+}
+
+func supplierResponse(supplier k.Supplier) SupplierResponse {
+	return SupplierResponse{
+		Id:    supplier.Id(),
+		Name:  supplier.Name(),
+		Email: supplier.Email(),
+	}
+}
+
+func purchaseOrderResponse(po k.PurchaseOrder) PurchaseOrderResponse {
+	lines := []PurchaseOrderLineResponse{}
+	for _, line := range po.Lines() {
+		lines = append(lines, PurchaseOrderLineResponse{line.ItemName(), line.OrderedUnits(), line.ReceivedUnits()})
+	}
+
+	discrepancies := []DeliveryDiscrepancyResponse{}
+	for _, discrepancy := range po.Discrepancies() {
+		discrepancies = append(discrepancies, DeliveryDiscrepancyResponse{
+			discrepancy.ItemName,
+			discrepancy.OrderedUnits,
+			discrepancy.ReceivedUnits,
+			discrepancy.Kind,
+		})
+	}
+
+	return PurchaseOrderResponse{
+		Id:                   po.Id(),
+		SupplierId:           po.SupplierId(),
+		Status:               po.Status(),
+		ExpectedDeliveryDate: po.ExpectedDeliveryDate().Format(expectedDeliveryDateLayout),
+		Lines:                lines,
+		Discrepancies:        discrepancies,
+	}
+}
@@ -0,0 +1,216 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/w-k-s/McMicroservices/kitchen-service/log"
+
+	"github.com/w-k-s/McMicroservices/kitchen-service/pkg/auth"
+	k "github.com/w-k-s/McMicroservices/kitchen-service/pkg/kitchen"
+	db "github.com/w-k-s/McMicroservices/kitchen-service/pkg/persistence"
+)
+
+type StationRequest struct {
+	Capacity uint `json:"capacity"`
+}
+
+type StationResponse struct {
+	Name     k.Station `json:"name"`
+	Capacity uint      `json:"capacity"`
+	// BusyUntil is when every slot of the station is free again, or now if the station is idle.
+	BusyUntil time.Time `json:"busyUntil"`
+}
+
+type ToppingTaskRequest struct {
+	Station         string `json:"station"`
+	DurationSeconds uint   `json:"durationSeconds"`
+}
+
+type ToppingTaskResponse struct {
+	ItemName        string    `json:"itemName"`
+	Station         k.Station `json:"station"`
+	DurationSeconds uint      `json:"durationSeconds"`
+}
+
+type StationsResponse struct {
+	Location k.Location            `json:"location"`
+	Stations []StationResponse     `json:"stations"`
+	Toppings []ToppingTaskResponse `json:"toppings"`
+}
+
+type StationService interface {
+	// GetStations lists the kitchen's stations with their current load at a location, and the toppings that each station prepares.
+	GetStations(ctx context.Context, location string) (StationsResponse, error)
+	SaveStation(ctx context.Context, name string, req StationRequest) (StationResponse, error)
+	// SaveToppingTask assigns a topping to a station. The station must have been set up.
+	SaveToppingTask(ctx context.Context, itemName string, req ToppingTaskRequest) (ToppingTaskResponse, error)
+	DeleteToppingTask(ctx context.Context, itemName string) error
+}
+
+type stationService struct {
+	orderDao db.OrderDao
+}
+
+func MustStationService(orderDao db.OrderDao) StationService {
+	if orderDao == nil {
+		log.Fatal("can not create station service. orderDao is nil")
+	}
+	return &stationService{
+		orderDao: orderDao,
+	}
+}
+
+func (svc stationService) GetStations(ctx context.Context, locationName string) (StationsResponse, error) {
+	if err := auth.Authorize(ctx, auth.ReadStations); err != nil {
+		return StationsResponse{}, err
+	}
+
+	location, err := k.ParseLocation(locationName)
+	if err != nil {
+		return StationsResponse{}, err
+	}
+
+	tx, err := svc.orderDao.BeginTx()
+	if err != nil {
+		return StationsResponse{}, err
+	}
+
+	defer db.DeferRollback(tx, "GetStations")
+
+	stations, err := tx.GetStations(ctx)
+	if err != nil {
+		return StationsResponse{}, err
+	}
+
+	resp := StationsResponse{Location: location, Stations: []StationResponse{}, Toppings: []ToppingTaskResponse{}}
+	now := time.Now()
+	for _, station := range stations {
+		var load k.StationLoad
+		if load, err = tx.GetStationLoad(ctx, station, location, now); err != nil {
+			return StationsResponse{}, err
+		}
+		resp.Stations = append(resp.Stations, StationResponse{station.Name(), station.Capacity(), load.BusyUntil()})
+	}
+
+	tasks, err := tx.GetToppingTasks(ctx)
+	if err != nil {
+		return StationsResponse{}, err
+	}
+	for _, task := range tasks {
+		resp.Toppings = append(resp.Toppings, toppingTaskResponse(task))
+	}
+
+	if err = db.Commit(tx); err != nil {
+		return StationsResponse{}, err
+	}
+
+	return resp, nil
+}
+
+func (svc stationService) SaveStation(ctx context.Context, name string, req StationRequest) (StationResponse, error) {
+	if err := auth.Authorize(ctx, auth.WriteStations); err != nil {
+		return StationResponse{}, err
+	}
+
+	stationName, err := k.ParseStation(name)
+	if err != nil {
+		return StationResponse{}, err
+	}
+
+	station, err := k.NewKitchenStation(stationName, req.Capacity)
+	if err != nil {
+		return StationResponse{}, err
+	}
+
+	tx, err := svc.orderDao.BeginTx()
+	if err != nil {
+		return StationResponse{}, err
+	}
+
+	defer db.DeferRollback(tx, "SaveStation")
+
+	if err = tx.SaveStation(ctx, station); err != nil {
+		return StationResponse{}, err
+	}
+
+	if err = db.Commit(tx); err != nil {
+		return StationResponse{}, err
+	}
+
+	return StationResponse{station.Name(), station.Capacity(), time.Now()}, nil
+}
+
+func (svc stationService) SaveToppingTask(ctx context.Context, itemName string, req ToppingTaskRequest) (ToppingTaskResponse, error) {
+	if err := auth.Authorize(ctx, auth.WriteStations); err != nil {
+		return ToppingTaskResponse{}, err
+	}
+
+	stationName, err := k.ParseStation(req.Station)
+	if err != nil {
+		return ToppingTaskResponse{}, err
+	}
+
+	task, err := k.NewToppingTask(itemName, stationName, time.Duration(req.DurationSeconds)*time.Second)
+	if err != nil {
+		return ToppingTaskResponse{}, err
+	}
+
+	tx, err := svc.orderDao.BeginTx()
+	if err != nil {
+		return ToppingTaskResponse{}, err
+	}
+
+	defer db.DeferRollback(tx, "SaveToppingTask")
+
+	stations, err := tx.GetStations(ctx)
+	if err != nil {
+		return ToppingTaskResponse{}, err
+	}
+	if !containsStation(stations, stationName) {
+		return ToppingTaskResponse{}, k.NewNotFoundError(fmt.Sprintf("station %q does not exist", stationName))
+	}
+
+	if err = tx.SaveToppingTask(ctx, task); err != nil {
+		return ToppingTaskResponse{}, err
+	}
+
+	if err = db.Commit(tx); err != nil {
+		return ToppingTaskResponse{}, err
+	}
+
+	return toppingTaskResponse(task), nil
+}
+
+func (svc stationService) DeleteToppingTask(ctx context.Context, itemName string) error {
+	if err := auth.Authorize(ctx, auth.WriteStations); err != nil {
+		return err
+	}
+
+	tx, err := svc.orderDao.BeginTx()
+	if err != nil {
+		return err
+	}
+
+	defer db.DeferRollback(tx, "DeleteToppingTask")
+
+	if err = tx.DeleteToppingTask(ctx, itemName); err != nil {
+		return err
+	}
+
+	return db.Commit(tx)
+}
+
+func containsStation(stations []k.KitchenStation, name k.Station) bool {
+	for _, station := range stations {
+		if station.Name() == name {
+			return true
+		}
+	}
+	return false
+}
+
+func toppingTaskResponse(task k.ToppingTask) ToppingTaskResponse {
+	return ToppingTaskResponse{task.ItemName(), task.Station(), uint(task.Duration() / time.Second)}
+}
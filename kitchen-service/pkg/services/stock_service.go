@@ -2,51 +2,212 @@ package services
 
 import (
 	"context"
-	"sort"
+	"encoding/json"
+	"fmt"
+	"time"
 
 	"github.com/w-k-s/McMicroservices/kitchen-service/log"
 
+	"github.com/w-k-s/McMicroservices/kitchen-service/pkg/auth"
 	k "github.com/w-k-s/McMicroservices/kitchen-service/pkg/kitchen"
 	db "github.com/w-k-s/McMicroservices/kitchen-service/pkg/persistence"
 )
 
 type StockItemResponse struct {
-	Name  string `json:"name"`
-	Units uint   `json:"units"`
+	Sku      string  `json:"sku,omitempty"`
+	Name     string  `json:"name"`
+	Units    uint    `json:"units"`
+	UnitCost k.Money `json:"unitCost,omitempty"`
+	// Version is sent as the ETag of the item rather than in the body.
+	Version uint64 `json:"-"`
+}
+
+type SetStockItemRequest struct {
+	Units    uint    `json:"units"`
+	UnitCost k.Money `json:"unitCost,omitempty"`
+	// IfMatch is the version of the stock that the request is based on, taken from the If-Match header.
+	// A nil IfMatch replaces the stock whatever its version. AnyVersion replaces the stock only if it exists.
+	IfMatch *uint64 `json:"-"`
+}
+
+// AnyVersion is the IfMatch of a request that is based on whichever version of the stock exists.
+const AnyVersion uint64 = 0
+
+type ItemRequest struct {
+	Sku     string   `json:"sku,omitempty"`
+	Aliases []string `json:"aliases"`
+}
+
+type ItemResponse struct {
+	Id      uint64   `json:"id"`
+	Sku     string   `json:"sku,omitempty"`
+	Name    string   `json:"name"`
+	Aliases []string `json:"aliases"`
 }
 
 type StockResponse struct {
 	Stock []StockItemResponse `json:"stock"`
+	// NextCursor is passed as After to get the next page. It is empty on the last page.
+	NextCursor string `json:"nextCursor,omitempty"`
+}
+
+type StockQueryRequest struct {
+	Location   string
+	NamePrefix string
+	// UnitsBelow only selects items with fewer units. Zero selects every item.
+	UnitsBelow uint
+	// Sort is name, units, -name or -units. The default is name.
+	Sort  string
+	Limit uint
+	After string
+}
+
+type TransferRequest struct {
+	FromLocation string             `json:"fromLocation"`
+	ToLocation   string             `json:"toLocation"`
+	Stock        []StockItemRequest `json:"stock"`
+}
+
+type TransferResponse struct {
+	Id           uint64              `json:"id"`
+	FromLocation k.Location          `json:"fromLocation"`
+	ToLocation   k.Location          `json:"toLocation"`
+	Stock        []StockItemResponse `json:"stock"`
+	Movements    []MovementResponse  `json:"movements"`
+	CreatedAt    time.Time           `json:"createdAt"`
+}
+
+type MovementResponse struct {
+	Location k.Location `json:"location"`
+	Name     string     `json:"name"`
+	Units    int        `json:"units"`
+	UnitCost k.Money    `json:"unitCost,omitempty"`
 }
 
 type StockItemRequest struct {
-	Name  string `json:"name"`
-	Units uint   `json:"units"`
+	Name     string  `json:"name"`
+	Units    uint    `json:"units"`
+	UnitCost k.Money `json:"unitCost,omitempty"`
 }
 
 type StockRequest struct {
-	Stock []StockItemRequest `json:"stock"`
+	Location        string             `json:"location,omitempty"`
+	PurchaseOrderId uint64             `json:"purchaseOrderId,omitempty"`
+	SupplierId      uint64             `json:"supplierId,omitempty"`
+	Stock           []StockItemRequest `json:"stock"`
+}
+
+type DeliveryResponse struct {
+	Location        k.Location          `json:"location"`
+	PurchaseOrderId uint64              `json:"purchaseOrderId,omitempty"`
+	SupplierId      uint64              `json:"supplierId,omitempty"`
+	Stock           []StockItemResponse `json:"stock"`
+	// Replayed is true if this is the stored response to an earlier delivery with the same idempotency key.
+	Replayed bool `json:"-"`
+}
+
+// deliveriesScope is the scope of the idempotency keys of deliveries.
+const deliveriesScope = "deliveries"
+
+type DietaryTagsRequest struct {
+	Tags []string `json:"tags"`
+}
+
+type DietaryTagsResponse struct {
+	Name string   `json:"name"`
+	Tags []string `json:"tags"`
+}
+
+type SubstitutionRuleRequest struct {
+	Priority uint `json:"priority"`
+}
+
+type SubstitutionRuleResponse struct {
+	ItemName       string `json:"itemName"`
+	SubstituteName string `json:"substituteName"`
+	Priority       uint   `json:"priority"`
+}
+
+type SubstitutionRulesResponse struct {
+	Rules []SubstitutionRuleResponse `json:"rules"`
 }
 
 type StockService interface {
-	GetStock(ctx context.Context) (StockResponse, error)
+	// GetStock returns a page of the stock at the location. The default location is used if location is empty.
+	GetStock(ctx context.Context, req StockQueryRequest) (StockResponse, error)
+	// GetStockItem returns the stock of the item at the location. The default location is used if location is empty.
+	GetStockItem(ctx context.Context, location string, name string) (StockItemResponse, error)
+	// CreateStockItem adds stock of an item that is not in stock at the location yet.
+	CreateStockItem(ctx context.Context, location string, req StockItemRequest) (StockItemResponse, error)
+	// SetStockItem replaces the units and unit cost of the item at the location.
+	// If the request is based on a version of the stock that is no longer current, a precondition failed k.ConflictError is returned.
+	SetStockItem(ctx context.Context, location string, name string, req SetStockItemRequest) (StockItemResponse, error)
+	// DeleteStockItem removes the item from stock at the location. ifMatch is as the IfMatch of SetStockItemRequest.
+	DeleteStockItem(ctx context.Context, location string, name string, ifMatch *uint64) error
 	ReceiveInventory(ctx context.Context, req StockRequest) error
+	// ReceiveDelivery receives inventory at most once for each idempotency key.
+	// A retry with the same key and request gets the response to the first attempt; a different request with the key is a k.ConflictError.
+	// An empty key receives the delivery every time.
+	ReceiveDelivery(ctx context.Context, idempotencyKey string, req StockRequest) (DeliveryResponse, error)
+	TransferStock(ctx context.Context, req TransferRequest) (TransferResponse, error)
+	GetTransfer(ctx context.Context, id uint64) (TransferResponse, error)
+	// GetItem returns the item that name is the name or an alias of, without regard to case.
+	GetItem(ctx context.Context, name string) (ItemResponse, error)
+	// SaveItem creates the item with the name or replaces the SKU and aliases of the item that already has the name.
+	SaveItem(ctx context.Context, name string, req ItemRequest) (ItemResponse, error)
+	GetDietaryTags(ctx context.Context, name string) (DietaryTagsResponse, error)
+	SetDietaryTags(ctx context.Context, name string, req DietaryTagsRequest) (DietaryTagsResponse, error)
+	GetSubstitutionRules(ctx context.Context) (SubstitutionRulesResponse, error)
+	SaveSubstitutionRule(ctx context.Context, itemName string, substituteName string, req SubstitutionRuleRequest) (SubstitutionRuleResponse, error)
+	DeleteSubstitutionRule(ctx context.Context, itemName string, substituteName string) error
 }
 
 type stockService struct {
-	stockDao db.StockDao
+	stockDao      db.StockDao
+	purchasingDao db.PurchasingDao
 }
 
-func MustStockService(stockDao db.StockDao) StockService {
+func MustStockService(stockDao db.StockDao, purchasingDao db.PurchasingDao) StockService {
 	if stockDao == nil {
 		log.Fatal("can not create account service. stockDao is nil")
 	}
-	return &stockService{
-		stockDao: stockDao,
+	if purchasingDao == nil {
+		log.Fatal("can not create account service. purchasingDao is nil")
 	}
+	return tracedStockService{&stockService{
+		stockDao:      stockDao,
+		purchasingDao: purchasingDao,
+	}}
 }
 
-func (svc stockService) GetStock(ctx context.Context) (StockResponse, error) {
+func (svc stockService) GetStock(ctx context.Context, req StockQueryRequest) (StockResponse, error) {
+	if err := auth.Authorize(ctx, auth.ReadStock); err != nil {
+		return StockResponse{}, err
+	}
+
+	location, err := k.ParseLocation(req.Location)
+	if err != nil {
+		return StockResponse{}, err
+	}
+
+	sort, err := k.ParseStockSort(req.Sort)
+	if err != nil {
+		return StockResponse{}, err
+	}
+
+	after, err := k.ParseStockCursor(req.After)
+	if err != nil {
+		return StockResponse{}, err
+	}
+
+	query, err := k.NewStockQuery(location).
+		WithNamePrefix(req.NamePrefix).
+		WithUnitsBelow(req.UnitsBelow).
+		SortedBy(sort).
+		Page(req.Limit, after)
+	if err != nil {
+		return StockResponse{}, err
+	}
 
 	tx, err := svc.stockDao.BeginTx()
 	if err != nil {
@@ -55,7 +216,7 @@ func (svc stockService) GetStock(ctx context.Context) (StockResponse, error) {
 
 	defer db.DeferRollback(tx, "GetStock")
 
-	stock, err := tx.Get(ctx)
+	page, err := tx.Get(ctx, query)
 	if err != nil {
 		return StockResponse{}, err
 	}
@@ -64,40 +225,698 @@ func (svc stockService) GetStock(ctx context.Context) (StockResponse, error) {
 		return StockResponse{}, err
 	}
 
-	sort.Sort(stock)
 	items := []StockItemResponse{}
-	for _, item := range stock {
-		items = append(items, StockItemResponse{item.Name(), item.Units()})
+	for _, item := range page.Stock {
+		items = append(items, stockItemResponse(item))
 	}
 
-	return StockResponse{items}, nil
+	resp := StockResponse{Stock: items}
+	if page.Next != nil {
+		resp.NextCursor = page.Next.Encode()
+	}
+	return resp, nil
 }
 
-func (svc stockService) ReceiveInventory(ctx context.Context, req StockRequest) error {
+func (svc stockService) GetStockItem(ctx context.Context, locationName string, name string) (StockItemResponse, error) {
+	if err := auth.Authorize(ctx, auth.ReadStock); err != nil {
+		return StockItemResponse{}, err
+	}
+
+	location, err := k.ParseLocation(locationName)
+	if err != nil {
+		return StockItemResponse{}, err
+	}
+
+	tx, err := svc.stockDao.BeginTx()
+	if err != nil {
+		return StockItemResponse{}, err
+	}
+
+	defer db.DeferRollback(tx, "GetStockItem")
+
+	item, err := tx.GetStockItem(ctx, name, location)
+	if err != nil {
+		return StockItemResponse{}, err
+	}
+
+	if err = db.Commit(tx); err != nil {
+		return StockItemResponse{}, err
+	}
+
+	return stockItemResponse(item), nil
+}
+
+func (svc stockService) SetStockItem(ctx context.Context, locationName string, name string, req SetStockItemRequest) (StockItemResponse, error) {
+	if err := auth.Authorize(ctx, auth.WriteStock); err != nil {
+		return StockItemResponse{}, err
+	}
+
+	location, err := k.ParseLocation(locationName)
+	if err != nil {
+		return StockItemResponse{}, err
+	}
+
+	item, err := k.NewStockLevel(name, req.Units)
+	if err != nil {
+		return StockItemResponse{}, err
+	}
+	if item, err = item.WithUnitCost(req.UnitCost); err != nil {
+		return StockItemResponse{}, err
+	}
+	item = item.AtLocation(location)
+
+	tx, err := svc.stockDao.BeginTx()
+	if err != nil {
+		return StockItemResponse{}, err
+	}
+
+	defer db.DeferRollback(tx, "SetStockItem")
+
+	if req.IfMatch != nil {
+		version := *req.IfMatch
+		if version == AnyVersion {
+			current, err := tx.GetStockItem(ctx, name, location)
+			if notFound, ok := err.(k.NotFoundError); ok {
+				return StockItemResponse{}, k.ConflictError{Cause: notFound.Cause, Precondition: true}
+			}
+			if err != nil {
+				return StockItemResponse{}, err
+			}
+			version = current.Version()
+		}
+		item = item.AtVersion(version)
+	}
+
+	if item, err = tx.Set(ctx, item); err != nil {
+		if conflict, ok := err.(k.ConflictError); ok && req.IfMatch != nil {
+			conflict.Precondition = true
+			return StockItemResponse{}, conflict
+		}
+		return StockItemResponse{}, err
+	}
+
+	if err = db.Commit(tx); err != nil {
+		return StockItemResponse{}, err
+	}
+
+	log.InfoCtx(ctx).
+		Str("name", item.Name()).
+		Str("location", string(item.Location())).
+		UInt64("version", item.Version()).
+		Msg("Stock set")
+
+	return stockItemResponse(item), nil
+}
+
+func (svc stockService) CreateStockItem(ctx context.Context, locationName string, req StockItemRequest) (StockItemResponse, error) {
+	if err := auth.Authorize(ctx, auth.WriteStock); err != nil {
+		return StockItemResponse{}, err
+	}
+
+	location, err := k.ParseLocation(locationName)
+	if err != nil {
+		return StockItemResponse{}, err
+	}
+
+	item, err := k.NewStockItem(req.Name, req.Units)
+	if err != nil {
+		return StockItemResponse{}, err
+	}
+	if item, err = item.WithUnitCost(req.UnitCost); err != nil {
+		return StockItemResponse{}, err
+	}
+
+	tx, err := svc.stockDao.BeginTx()
+	if err != nil {
+		return StockItemResponse{}, err
+	}
+
+	defer db.DeferRollback(tx, "CreateStockItem")
+
+	if item, err = tx.Create(ctx, item.AtLocation(location)); err != nil {
+		return StockItemResponse{}, err
+	}
+
+	if err = db.Commit(tx); err != nil {
+		return StockItemResponse{}, err
+	}
+
+	log.InfoCtx(ctx).
+		Str("name", item.Name()).
+		Str("location", string(item.Location())).
+		Msg("Stock created")
+
+	return stockItemResponse(item), nil
+}
+
+func (svc stockService) DeleteStockItem(ctx context.Context, locationName string, name string, ifMatch *uint64) error {
+	if err := auth.Authorize(ctx, auth.WriteStock); err != nil {
+		return err
+	}
+
+	location, err := k.ParseLocation(locationName)
+	if err != nil {
+		return err
+	}
+
+	item, err := k.NewStockItem(name, 1)
+	if err != nil {
+		return err
+	}
+	item = item.AtLocation(location)
 
 	tx, err := svc.stockDao.BeginTx()
 	if err != nil {
 		return err
 	}
 
+	defer db.DeferRollback(tx, "DeleteStockItem")
+
+	if ifMatch != nil && *ifMatch != AnyVersion {
+		item = item.AtVersion(*ifMatch)
+	}
+
+	if err = tx.Delete(ctx, item); err != nil {
+		if notFound, ok := err.(k.NotFoundError); ok && ifMatch != nil && *ifMatch == AnyVersion {
+			return k.ConflictError{Cause: notFound.Cause, Precondition: true}
+		}
+		if conflict, ok := err.(k.ConflictError); ok && ifMatch != nil {
+			conflict.Precondition = true
+			return conflict
+		}
+		return err
+	}
+
+	if err = db.Commit(tx); err != nil {
+		return err
+	}
+
+	log.InfoCtx(ctx).
+		Str("name", item.Name()).
+		Str("location", string(item.Location())).
+		Msg("Stock deleted")
+
+	return nil
+}
+
+func stockItemResponse(item k.StockItem) StockItemResponse {
+	return StockItemResponse{item.Sku(), item.Name(), item.Units(), item.UnitCost(), item.Version()}
+}
+
+// ReceiveInventory receives a delivery from the broker. The broker does not redeliver a message that could not be received,
+// so a delivery that fails is saved as a failed delivery, to be received by hand, before the error is returned.
+func (svc stockService) ReceiveInventory(ctx context.Context, req StockRequest) error {
+	if err := auth.Authorize(ctx, auth.ReceiveStock); err != nil {
+		return err
+	}
+
+	if err := svc.receiveInventory(ctx, req); err != nil {
+		if saveErr := svc.saveFailedDelivery(ctx, req, err); saveErr != nil {
+			log.ErrCtx(ctx, saveErr).
+				Str("reason", err.Error()).
+				Msg("Failed to save failed delivery")
+		}
+		return err
+	}
+
+	return nil
+}
+
+func (svc stockService) receiveInventory(ctx context.Context, req StockRequest) error {
+	tx, err := svc.purchasingDao.BeginTx()
+	if err != nil {
+		return err
+	}
+
 	defer db.DeferRollback(tx, "ReceiveInventory")
 
+	if _, err = receiveInventory(ctx, tx, req); err != nil {
+		return err
+	}
+
+	return db.Commit(tx)
+}
+
+// saveFailedDelivery saves the delivery in a transaction of its own, because the transaction that received it was rolled back.
+func (svc stockService) saveFailedDelivery(ctx context.Context, req StockRequest, reason error) error {
+	delivery, err := json.Marshal(req)
+	if err != nil {
+		return k.NewSystemError("failed to encode failed delivery", err)
+	}
+
+	tx, err := svc.purchasingDao.BeginTx()
+	if err != nil {
+		return err
+	}
+
+	defer db.DeferRollback(tx, "SaveFailedDelivery")
+
+	if err = tx.SaveFailedDelivery(ctx, delivery, reason.Error()); err != nil {
+		return err
+	}
+
+	if err = db.Commit(tx); err != nil {
+		return err
+	}
+
+	log.InfoCtx(ctx).
+		Str("reason", reason.Error()).
+		Msg("Failed delivery saved")
+
+	return nil
+}
+
+func (svc stockService) ReceiveDelivery(ctx context.Context, idempotencyKey string, req StockRequest) (DeliveryResponse, error) {
+	if err := auth.Authorize(ctx, auth.ReceiveStock); err != nil {
+		return DeliveryResponse{}, err
+	}
+
+	tx, err := svc.purchasingDao.BeginTx()
+	if err != nil {
+		return DeliveryResponse{}, err
+	}
+
+	defer db.DeferRollback(tx, "ReceiveDelivery")
+
+	var request k.IdempotentRequest
+	if len(idempotencyKey) > 0 {
+		var (
+			body    []byte
+			stored  k.IdempotentRequest
+			claimed bool
+		)
+		if body, err = json.Marshal(req); err != nil {
+			return DeliveryResponse{}, k.NewSystemError("failed to hash delivery", err)
+		}
+		if request, err = k.NewIdempotentRequest(deliveriesScope, idempotencyKey, body); err != nil {
+			return DeliveryResponse{}, err
+		}
+		if stored, claimed, err = tx.ClaimIdempotencyKey(ctx, request); err != nil {
+			return DeliveryResponse{}, err
+		}
+		if !claimed {
+			return replayDelivery(ctx, request, stored)
+		}
+	}
+
+	received, err := receiveInventory(ctx, tx, req)
+	if err != nil {
+		return DeliveryResponse{}, err
+	}
+
+	// The location was validated when the inventory was received.
+	location, _ := k.ParseLocation(req.Location)
+	resp := DeliveryResponse{
+		Location:        location,
+		PurchaseOrderId: req.PurchaseOrderId,
+		SupplierId:      req.SupplierId,
+		Stock:           []StockItemResponse{},
+	}
+	for _, item := range received {
+		resp.Stock = append(resp.Stock, stockItemResponse(item))
+	}
+
+	if len(idempotencyKey) > 0 {
+		var body []byte
+		if body, err = json.Marshal(resp); err != nil {
+			return DeliveryResponse{}, k.NewSystemError("failed to store delivery response", err)
+		}
+		if err = tx.SaveIdempotentResponse(ctx, request.WithResponse(body)); err != nil {
+			return DeliveryResponse{}, err
+		}
+	}
+
+	if err = db.Commit(tx); err != nil {
+		return DeliveryResponse{}, err
+	}
+
+	return resp, nil
+}
+
+// replayDelivery returns the stored response to an earlier delivery with the same idempotency key as request.
+func replayDelivery(ctx context.Context, request k.IdempotentRequest, stored k.IdempotentRequest) (DeliveryResponse, error) {
+	if !request.SameRequestAs(stored) {
+		return DeliveryResponse{}, k.NewConflictError(fmt.Sprintf("idempotency key %q was already used for a different delivery", request.Key()))
+	}
+	if !stored.Answered() {
+		return DeliveryResponse{}, k.NewConflictError(fmt.Sprintf("delivery with idempotency key %q is still being received", request.Key()))
+	}
+
+	var resp DeliveryResponse
+	if err := json.Unmarshal(stored.Response(), &resp); err != nil {
+		return DeliveryResponse{}, k.NewSystemError(fmt.Sprintf("failed to replay delivery with idempotency key %q", request.Key()), err)
+	}
+	resp.Replayed = true
+
+	log.InfoCtx(ctx).
+		Str("idempotencyKey", request.Key()).
+		Msg("Delivery replayed")
+
+	return resp, nil
+}
+
+// receiveInventory adds the delivery to stock and receives it against a purchase order. The stock that was received is returned.
+func receiveInventory(ctx context.Context, tx db.PurchasingTx, req StockRequest) (k.Stock, error) {
+
+	location, err := k.ParseLocation(req.Location)
+	if err != nil {
+		return nil, err
+	}
+
 	received := k.Stock{}
 	for _, requestItem := range req.Stock {
 		var stockItem k.StockItem
 		if stockItem, err = k.NewStockItem(requestItem.Name, requestItem.Units); err != nil {
-			return err
+			return nil, err
 		}
-		received = append(received, stockItem)
+		if stockItem, err = stockItem.WithUnitCost(requestItem.UnitCost); err != nil {
+			return nil, err
+		}
+		received = append(received, stockItem.AtLocation(location))
 	}
 
 	if err = tx.Increase(ctx, received); err != nil {
-		return err
+		return nil, err
+	}
+
+	if err = receiveAgainstPurchaseOrder(ctx, tx, req, received); err != nil {
+		return nil, err
+	}
+
+	return received, nil
+}
+
+func (svc stockService) TransferStock(ctx context.Context, req TransferRequest) (TransferResponse, error) {
+	if err := auth.Authorize(ctx, auth.WriteStock); err != nil {
+		return TransferResponse{}, err
+	}
+
+	fromLocation, err := k.ParseLocation(req.FromLocation)
+	if err != nil {
+		return TransferResponse{}, err
+	}
+
+	toLocation, err := k.ParseLocation(req.ToLocation)
+	if err != nil {
+		return TransferResponse{}, err
+	}
+
+	stock := k.Stock{}
+	for _, requestItem := range req.Stock {
+		var stockItem k.StockItem
+		if stockItem, err = k.NewStockItem(requestItem.Name, requestItem.Units); err != nil {
+			return TransferResponse{}, err
+		}
+		stock = append(stock, stockItem)
+	}
+
+	transfer, err := k.NewTransfer(0, fromLocation, toLocation, stock, time.Now())
+	if err != nil {
+		return TransferResponse{}, err
+	}
+
+	tx, err := svc.stockDao.BeginTx()
+	if err != nil {
+		return TransferResponse{}, err
+	}
+
+	defer db.DeferRollback(tx, "TransferStock")
+
+	if transfer, err = tx.Transfer(ctx, transfer); err != nil {
+		return TransferResponse{}, err
+	}
+
+	if err = db.Commit(tx); err != nil {
+		return TransferResponse{}, err
+	}
+
+	log.InfoCtx(ctx).
+		UInt64("transferId", transfer.Id()).
+		Str("fromLocation", string(transfer.FromLocation())).
+		Str("toLocation", string(transfer.ToLocation())).
+		Msg("Stock transferred")
+
+	return transferResponse(transfer), nil
+}
+
+func (svc stockService) GetTransfer(ctx context.Context, id uint64) (TransferResponse, error) {
+	if err := auth.Authorize(ctx, auth.ReadStock); err != nil {
+		return TransferResponse{}, err
+	}
+
+	tx, err := svc.stockDao.BeginTx()
+	if err != nil {
+		return TransferResponse{}, err
+	}
+
+	defer db.DeferRollback(tx, "GetTransfer")
+
+	transfer, err := tx.GetTransfer(ctx, id)
+	if err != nil {
+		return TransferResponse{}, err
+	}
+
+	if err = db.Commit(tx); err != nil {
+		return TransferResponse{}, err
+	}
+
+	return transferResponse(transfer), nil
+}
+
+func transferResponse(transfer k.Transfer) TransferResponse {
+	stock := []StockItemResponse{}
+	for _, item := range transfer.Stock() {
+		stock = append(stock, stockItemResponse(item))
+	}
+
+	movements := []MovementResponse{}
+	for _, movement := range transfer.Movements() {
+		movements = append(movements, MovementResponse{movement.Location, movement.ItemName, movement.Units, movement.UnitCost})
+	}
+
+	return TransferResponse{
+		Id:           transfer.Id(),
+		FromLocation: transfer.FromLocation(),
+		ToLocation:   transfer.ToLocation(),
+		Stock:        stock,
+		Movements:    movements,
+		CreatedAt:    transfer.CreatedAt(),
+	}
+}
+
+func (svc stockService) GetItem(ctx context.Context, name string) (ItemResponse, error) {
+	if err := auth.Authorize(ctx, auth.ReadStock); err != nil {
+		return ItemResponse{}, err
+	}
+
+	tx, err := svc.stockDao.BeginTx()
+	if err != nil {
+		return ItemResponse{}, err
+	}
+
+	defer db.DeferRollback(tx, "GetItem")
+
+	items, err := tx.GetItems(ctx, []string{name})
+	if err != nil {
+		return ItemResponse{}, err
+	}
+
+	if err = db.Commit(tx); err != nil {
+		return ItemResponse{}, err
+	}
+
+	item, ok := items[k.ItemKey(name)]
+	if !ok {
+		return ItemResponse{}, k.NewNotFoundError(fmt.Sprintf("item %q does not exist", name))
+	}
+
+	return itemResponse(item), nil
+}
+
+func (svc stockService) SaveItem(ctx context.Context, name string, req ItemRequest) (ItemResponse, error) {
+	if err := auth.Authorize(ctx, auth.WriteStock); err != nil {
+		return ItemResponse{}, err
+	}
+
+	item, err := k.NewItem(0, req.Sku, name, req.Aliases)
+	if err != nil {
+		return ItemResponse{}, err
+	}
+
+	tx, err := svc.stockDao.BeginTx()
+	if err != nil {
+		return ItemResponse{}, err
+	}
+
+	defer db.DeferRollback(tx, "SaveItem")
+
+	if item, err = tx.SaveItem(ctx, item); err != nil {
+		return ItemResponse{}, err
+	}
+
+	if err = db.Commit(tx); err != nil {
+		return ItemResponse{}, err
+	}
+
+	log.InfoCtx(ctx).
+		UInt64("id", item.Id()).
+		Str("name", item.Name()).
+		Str("sku", item.Sku()).
+		Struct("aliases", item.Aliases()).
+		Msg("Item saved")
+
+	return itemResponse(item), nil
+}
+
+func itemResponse(item k.Item) ItemResponse {
+	return ItemResponse{item.Id(), item.Sku(), item.Name(), item.Aliases()}
+}
+
+func (svc stockService) GetDietaryTags(ctx context.Context, name string) (DietaryTagsResponse, error) {
+	if err := auth.Authorize(ctx, auth.ReadStock); err != nil {
+		return DietaryTagsResponse{}, err
+	}
+
+	tx, err := svc.stockDao.BeginTx()
+	if err != nil {
+		return DietaryTagsResponse{}, err
+	}
+
+	defer db.DeferRollback(tx, "GetDietaryTags")
+
+	tags, err := tx.GetDietaryTags(ctx, []string{name})
+	if err != nil {
+		return DietaryTagsResponse{}, err
 	}
 
 	if err = db.Commit(tx); err != nil {
+		return DietaryTagsResponse{}, err
+	}
+
+	return DietaryTagsResponse{name, tags[name].Strings()}, nil
+}
+
+func (svc stockService) SetDietaryTags(ctx context.Context, name string, req DietaryTagsRequest) (DietaryTagsResponse, error) {
+	if err := auth.Authorize(ctx, auth.WriteStock); err != nil {
+		return DietaryTagsResponse{}, err
+	}
+
+	if _, err := k.NewItem(0, "", name, nil); err != nil {
+		return DietaryTagsResponse{}, err
+	}
+
+	tags, err := k.ParseDietaryTags(req.Tags)
+	if err != nil {
+		return DietaryTagsResponse{}, err
+	}
+
+	tx, err := svc.stockDao.BeginTx()
+	if err != nil {
+		return DietaryTagsResponse{}, err
+	}
+
+	defer db.DeferRollback(tx, "SetDietaryTags")
+
+	if err = tx.SetDietaryTags(ctx, name, tags); err != nil {
+		return DietaryTagsResponse{}, err
+	}
+
+	if err = db.Commit(tx); err != nil {
+		return DietaryTagsResponse{}, err
+	}
+
+	log.InfoCtx(ctx).
+		Str("name", name).
+		Struct("tags", tags.Strings()).
+		Msg("Dietary tags updated")
+
+	return DietaryTagsResponse{name, tags.Strings()}, nil
+}
+
+func (svc stockService) GetSubstitutionRules(ctx context.Context) (SubstitutionRulesResponse, error) {
+	if err := auth.Authorize(ctx, auth.ReadStock); err != nil {
+		return SubstitutionRulesResponse{}, err
+	}
+
+	tx, err := svc.stockDao.BeginTx()
+	if err != nil {
+		return SubstitutionRulesResponse{}, err
+	}
+
+	defer db.DeferRollback(tx, "GetSubstitutionRules")
+
+	rules, err := tx.GetSubstitutionRules(ctx)
+	if err != nil {
+		return SubstitutionRulesResponse{}, err
+	}
+
+	if err = db.Commit(tx); err != nil {
+		return SubstitutionRulesResponse{}, err
+	}
+
+	resp := SubstitutionRulesResponse{Rules: []SubstitutionRuleResponse{}}
+	for _, rule := range rules {
+		resp.Rules = append(resp.Rules, substitutionRuleResponse(rule))
+	}
+	return resp, nil
+}
+
+func (svc stockService) SaveSubstitutionRule(ctx context.Context, itemName string, substituteName string, req SubstitutionRuleRequest) (SubstitutionRuleResponse, error) {
+	if err := auth.Authorize(ctx, auth.WriteStock); err != nil {
+		return SubstitutionRuleResponse{}, err
+	}
+
+	rule, err := k.NewSubstitutionRule(itemName, substituteName, req.Priority)
+	if err != nil {
+		return SubstitutionRuleResponse{}, err
+	}
+
+	tx, err := svc.stockDao.BeginTx()
+	if err != nil {
+		return SubstitutionRuleResponse{}, err
+	}
+
+	defer db.DeferRollback(tx, "SaveSubstitutionRule")
+
+	if err = tx.SaveSubstitutionRule(ctx, rule); err != nil {
+		return SubstitutionRuleResponse{}, err
+	}
+
+	if err = db.Commit(tx); err != nil {
+		return SubstitutionRuleResponse{}, err
+	}
+
+	log.InfoCtx(ctx).
+		Str("itemName", rule.ItemName()).
+		Str("substituteName", rule.SubstituteName()).
+		Msg("Substitution rule saved")
+
+	return substitutionRuleResponse(rule), nil
+}
+
+func (svc stockService) DeleteSubstitutionRule(ctx context.Context, itemName string, substituteName string) error {
+	if err := auth.Authorize(ctx, auth.WriteStock); err != nil {
 		return err
 	}
 
-	return nil
+	tx, err := svc.stockDao.BeginTx()
+	if err != nil {
+		return err
+	}
+
+	defer db.DeferRollback(tx, "DeleteSubstitutionRule")
+
+	if err = tx.DeleteSubstitutionRule(ctx, itemName, substituteName); err != nil {
+		return err
+	}
+
+	return db.Commit(tx)
+}
+
+func substitutionRuleResponse(rule k.SubstitutionRule) SubstitutionRuleResponse {
+	return SubstitutionRuleResponse{
+		ItemName:       rule.ItemName(),
+		SubstituteName: rule.SubstituteName(),
+		Priority:       rule.Priority(),
+	}
 }
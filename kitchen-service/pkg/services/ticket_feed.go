@@ -0,0 +1,70 @@
+package services
+
+import (
+	"sync"
+
+	k "github.com/w-k-s/McMicroservices/kitchen-service/pkg/kitchen"
+)
+
+const ticketSubscriberBuffer = 64
+
+// TicketPublisher announces ticket events once they have been saved.
+type TicketPublisher interface {
+	Publish(events ...k.TicketEvent)
+}
+
+// TicketFeed delivers ticket events to the kitchen displays that are connected to this instance.
+// A display that falls too far behind is disconnected so that it can resume from the last event it received.
+type TicketFeed struct {
+	mu          sync.Mutex
+	subscribers map[chan k.TicketEvent]struct{}
+}
+
+func NewTicketFeed() *TicketFeed {
+	return &TicketFeed{
+		subscribers: map[chan k.TicketEvent]struct{}{},
+	}
+}
+
+func (f *TicketFeed) Publish(events ...k.TicketEvent) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for subscriber := range f.subscribers {
+		if !deliver(subscriber, events) {
+			delete(f.subscribers, subscriber)
+			close(subscriber)
+		}
+	}
+}
+
+// deliver reports whether the subscriber had room for every event.
+func deliver(subscriber chan k.TicketEvent, events []k.TicketEvent) bool {
+	for _, event := range events {
+		select {
+		case subscriber <- event:
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+func (f *TicketFeed) subscribe() chan k.TicketEvent {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	subscriber := make(chan k.TicketEvent, ticketSubscriberBuffer)
+	f.subscribers[subscriber] = struct{}{}
+	return subscriber
+}
+
+func (f *TicketFeed) unsubscribe(subscriber chan k.TicketEvent) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if _, ok := f.subscribers[subscriber]; ok {
+		delete(f.subscribers, subscriber)
+		close(subscriber)
+	}
+}
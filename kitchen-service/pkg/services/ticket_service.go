@@ -0,0 +1,277 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/w-k-s/McMicroservices/kitchen-service/log"
+
+	"github.com/w-k-s/McMicroservices/kitchen-service/pkg/auth"
+	k "github.com/w-k-s/McMicroservices/kitchen-service/pkg/kitchen"
+	db "github.com/w-k-s/McMicroservices/kitchen-service/pkg/persistence"
+)
+
+// ticketEventOverlap is how long before the last event that a display received the events are replayed from when it reconnects.
+// Ticket event ids are not committed in the order that they are assigned, so an event with an earlier id
+// can be committed after the display received the last event. Orders are scheduled in transactions that take seconds.
+const ticketEventOverlap = time.Minute
+
+// TicketStreamRequest selects the tickets shown on a kitchen display.
+// An empty station or location shows the tickets of every station or location.
+type TicketStreamRequest struct {
+	Station     string
+	Location    string
+	LastEventId uint64
+}
+
+type TicketEventResponse struct {
+	Id         uint64            `json:"id"`
+	Type       k.TicketEventType `json:"type"`
+	OrderId    uint64            `json:"orderId"`
+	Station    k.Station         `json:"station"`
+	Location   k.Location        `json:"location"`
+	Items      []string          `json:"items,omitempty"`
+	OccurredAt time.Time         `json:"occurredAt"`
+}
+
+type TicketsResponse struct {
+	Tickets []TicketEventResponse `json:"tickets"`
+}
+
+type TicketService interface {
+	// StreamTickets replays the events that a display missed since LastEventId and then delivers new events as they occur.
+	// A display without a LastEventId receives the events of the tickets that are still open.
+	// Events that occurred shortly before LastEventId are replayed as well, so a display ignores events whose ids it has seen.
+	// The channel is closed when ctx is done or when the display falls too far behind.
+	StreamTickets(ctx context.Context, req TicketStreamRequest) (<-chan TicketEventResponse, error)
+	// BumpTicket and RecallTicket change the order's ticket at the station, or every ticket of the order if station is empty.
+	BumpTicket(ctx context.Context, orderId uint64, station string) (TicketsResponse, error)
+	RecallTicket(ctx context.Context, orderId uint64, station string) (TicketsResponse, error)
+}
+
+type ticketService struct {
+	orderDao db.OrderDao
+	feed     *TicketFeed
+}
+
+func MustTicketService(orderDao db.OrderDao, feed *TicketFeed) TicketService {
+	if orderDao == nil {
+		log.Fatal("can not create ticket service. orderDao is nil")
+	}
+	if feed == nil {
+		log.Fatal("can not create ticket service. feed is nil")
+	}
+	return &ticketService{
+		orderDao: orderDao,
+		feed:     feed,
+	}
+}
+
+func (svc ticketService) StreamTickets(ctx context.Context, req TicketStreamRequest) (<-chan TicketEventResponse, error) {
+	if err := auth.Authorize(ctx, auth.ReadOrders); err != nil {
+		return nil, err
+	}
+
+	var (
+		station  k.Station
+		location k.Location
+		err      error
+	)
+
+	if len(req.Station) > 0 {
+		if station, err = k.ParseStation(req.Station); err != nil {
+			return nil, err
+		}
+	}
+	if len(req.Location) > 0 {
+		if location, err = k.ParseLocation(req.Location); err != nil {
+			return nil, err
+		}
+	}
+
+	// Subscribe before loading the missed events so that no event falls between the two.
+	subscriber := svc.feed.subscribe()
+
+	missed, err := svc.missedTicketEvents(ctx, req.LastEventId, station, location)
+	if err != nil {
+		svc.feed.unsubscribe(subscriber)
+		return nil, err
+	}
+
+	stream := make(chan TicketEventResponse)
+	go func() {
+		defer close(stream)
+		defer svc.feed.unsubscribe(subscriber)
+
+		send := func(event k.TicketEvent) bool {
+			select {
+			case stream <- ticketEventResponse(event):
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		}
+
+		replayed := map[uint64]bool{}
+		for _, event := range missed {
+			if !send(event) {
+				return
+			}
+			replayed[event.Id()] = true
+		}
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case event, ok := <-subscriber:
+				if !ok {
+					log.InfoCtx(ctx).Msg("Ticket display fell behind and was disconnected")
+					return
+				}
+				if replayed[event.Id()] {
+					continue
+				}
+				if (len(station) > 0 && event.Station() != station) || (len(location) > 0 && event.Location() != location) {
+					continue
+				}
+				if !send(event) {
+					return
+				}
+			}
+		}
+	}()
+
+	return stream, nil
+}
+
+func (svc ticketService) missedTicketEvents(ctx context.Context, lastEventId uint64, station k.Station, location k.Location) ([]k.TicketEvent, error) {
+	tx, err := svc.orderDao.BeginTx()
+	if err != nil {
+		return nil, err
+	}
+
+	defer db.DeferRollback(tx, "missedTicketEvents")
+
+	var events []k.TicketEvent
+	if lastEventId == 0 {
+		events, err = tx.GetOpenTicketEvents(ctx, station, location)
+	} else {
+		events, err = tx.GetTicketEvents(ctx, lastEventId, ticketEventOverlap, station, location)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err = db.Commit(tx); err != nil {
+		return nil, err
+	}
+	return events, nil
+}
+
+func (svc ticketService) BumpTicket(ctx context.Context, orderId uint64, station string) (TicketsResponse, error) {
+	if err := auth.Authorize(ctx, auth.PrepareOrders); err != nil {
+		return TicketsResponse{}, err
+	}
+
+	return svc.changeTicket(ctx, orderId, station, k.TicketBumped)
+}
+
+func (svc ticketService) RecallTicket(ctx context.Context, orderId uint64, station string) (TicketsResponse, error) {
+	if err := auth.Authorize(ctx, auth.PrepareOrders); err != nil {
+		return TicketsResponse{}, err
+	}
+
+	return svc.changeTicket(ctx, orderId, station, k.TicketRecalled)
+}
+
+func (svc ticketService) changeTicket(ctx context.Context, orderId uint64, stationName string, eventType k.TicketEventType) (TicketsResponse, error) {
+	var (
+		station k.Station
+		err     error
+	)
+
+	if len(stationName) > 0 {
+		if station, err = k.ParseStation(stationName); err != nil {
+			return TicketsResponse{}, err
+		}
+	}
+
+	tx, err := svc.orderDao.BeginTx()
+	if err != nil {
+		return TicketsResponse{}, err
+	}
+
+	defer db.DeferRollback(tx, "changeTicket")
+
+	events, err := advanceTickets(ctx, tx, orderId, station, eventType, false)
+	if err != nil {
+		return TicketsResponse{}, err
+	}
+
+	if err = db.Commit(tx); err != nil {
+		return TicketsResponse{}, err
+	}
+
+	svc.feed.Publish(events...)
+
+	resp := TicketsResponse{Tickets: []TicketEventResponse{}}
+	for _, event := range events {
+		resp.Tickets = append(resp.Tickets, ticketEventResponse(event))
+	}
+	return resp, nil
+}
+
+// advanceTickets moves the order's ticket at the station, or every ticket of the order if station is empty, on to eventType.
+// automatic is true if the kitchen moves the tickets itself rather than a cook.
+// Tickets that can not move on to eventType are left as they are.
+// An error is returned if no ticket was changed.
+func advanceTickets(ctx context.Context, tx db.OrderTx, orderId uint64, station k.Station, eventType k.TicketEventType, automatic bool) ([]k.TicketEvent, error) {
+	latest, err := tx.GetLatestTicketEvents(ctx, orderId)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		advanced = []k.TicketEvent{}
+		refusal  = k.NewNotFoundError(fmt.Sprintf("order %d does not have a ticket", orderId))
+	)
+	if len(station) > 0 {
+		refusal = k.NewNotFoundError(fmt.Sprintf("order %d does not have a ticket at %q", orderId, station))
+	}
+
+	for _, ticket := range latest {
+		if len(station) > 0 && ticket.Station() != station {
+			continue
+		}
+
+		next, err := ticket.Next(eventType, time.Now())
+		if err != nil {
+			refusal = err
+			continue
+		}
+
+		if next, err = tx.SaveTicketEvent(ctx, next.WithAutomatic(automatic)); err != nil {
+			return nil, err
+		}
+		advanced = append(advanced, next)
+	}
+
+	if len(advanced) == 0 {
+		return nil, refusal
+	}
+	return advanced, nil
+}
+
+func ticketEventResponse(event k.TicketEvent) TicketEventResponse {
+	return TicketEventResponse{
+		Id:         event.Id(),
+		Type:       event.Type(),
+		OrderId:    event.OrderId(),
+		Station:    event.Station(),
+		Location:   event.Location(),
+		Items:      event.Items(),
+		OccurredAt: event.OccurredAt(),
+	}
+}
@@ -0,0 +1,162 @@
+package services
+
+import (
+	"context"
+
+	"github.com/w-k-s/McMicroservices/kitchen-service/pkg/tracing"
+)
+
+// tracedStockService starts a span, named after the method, for each call to the StockService.
+type tracedStockService struct {
+	StockService
+}
+
+func (s tracedStockService) GetStock(ctx context.Context, req StockQueryRequest) (StockResponse, error) {
+	ctx, span := tracing.Start(ctx, "StockService.GetStock")
+	resp, err := s.StockService.GetStock(ctx, req)
+	tracing.End(span, err)
+	return resp, err
+}
+
+func (s tracedStockService) GetStockItem(ctx context.Context, location string, name string) (StockItemResponse, error) {
+	ctx, span := tracing.Start(ctx, "StockService.GetStockItem")
+	resp, err := s.StockService.GetStockItem(ctx, location, name)
+	tracing.End(span, err)
+	return resp, err
+}
+
+func (s tracedStockService) CreateStockItem(ctx context.Context, location string, req StockItemRequest) (StockItemResponse, error) {
+	ctx, span := tracing.Start(ctx, "StockService.CreateStockItem")
+	resp, err := s.StockService.CreateStockItem(ctx, location, req)
+	tracing.End(span, err)
+	return resp, err
+}
+
+func (s tracedStockService) SetStockItem(ctx context.Context, location string, name string, req SetStockItemRequest) (StockItemResponse, error) {
+	ctx, span := tracing.Start(ctx, "StockService.SetStockItem")
+	resp, err := s.StockService.SetStockItem(ctx, location, name, req)
+	tracing.End(span, err)
+	return resp, err
+}
+
+func (s tracedStockService) DeleteStockItem(ctx context.Context, location string, name string, ifMatch *uint64) error {
+	ctx, span := tracing.Start(ctx, "StockService.DeleteStockItem")
+	err := s.StockService.DeleteStockItem(ctx, location, name, ifMatch)
+	tracing.End(span, err)
+	return err
+}
+
+func (s tracedStockService) ReceiveInventory(ctx context.Context, req StockRequest) error {
+	ctx, span := tracing.Start(ctx, "StockService.ReceiveInventory")
+	err := s.StockService.ReceiveInventory(ctx, req)
+	tracing.End(span, err)
+	return err
+}
+
+func (s tracedStockService) ReceiveDelivery(ctx context.Context, idempotencyKey string, req StockRequest) (DeliveryResponse, error) {
+	ctx, span := tracing.Start(ctx, "StockService.ReceiveDelivery")
+	resp, err := s.StockService.ReceiveDelivery(ctx, idempotencyKey, req)
+	tracing.End(span, err)
+	return resp, err
+}
+
+func (s tracedStockService) TransferStock(ctx context.Context, req TransferRequest) (TransferResponse, error) {
+	ctx, span := tracing.Start(ctx, "StockService.TransferStock")
+	resp, err := s.StockService.TransferStock(ctx, req)
+	tracing.End(span, err)
+	return resp, err
+}
+
+func (s tracedStockService) GetTransfer(ctx context.Context, id uint64) (TransferResponse, error) {
+	ctx, span := tracing.Start(ctx, "StockService.GetTransfer")
+	resp, err := s.StockService.GetTransfer(ctx, id)
+	tracing.End(span, err)
+	return resp, err
+}
+
+func (s tracedStockService) GetItem(ctx context.Context, name string) (ItemResponse, error) {
+	ctx, span := tracing.Start(ctx, "StockService.GetItem")
+	resp, err := s.StockService.GetItem(ctx, name)
+	tracing.End(span, err)
+	return resp, err
+}
+
+func (s tracedStockService) SaveItem(ctx context.Context, name string, req ItemRequest) (ItemResponse, error) {
+	ctx, span := tracing.Start(ctx, "StockService.SaveItem")
+	resp, err := s.StockService.SaveItem(ctx, name, req)
+	tracing.End(span, err)
+	return resp, err
+}
+
+func (s tracedStockService) GetDietaryTags(ctx context.Context, name string) (DietaryTagsResponse, error) {
+	ctx, span := tracing.Start(ctx, "StockService.GetDietaryTags")
+	resp, err := s.StockService.GetDietaryTags(ctx, name)
+	tracing.End(span, err)
+	return resp, err
+}
+
+func (s tracedStockService) SetDietaryTags(ctx context.Context, name string, req DietaryTagsRequest) (DietaryTagsResponse, error) {
+	ctx, span := tracing.Start(ctx, "StockService.SetDietaryTags")
+	resp, err := s.StockService.SetDietaryTags(ctx, name, req)
+	tracing.End(span, err)
+	return resp, err
+}
+
+func (s tracedStockService) GetSubstitutionRules(ctx context.Context) (SubstitutionRulesResponse, error) {
+	ctx, span := tracing.Start(ctx, "StockService.GetSubstitutionRules")
+	resp, err := s.StockService.GetSubstitutionRules(ctx)
+	tracing.End(span, err)
+	return resp, err
+}
+
+func (s tracedStockService) SaveSubstitutionRule(ctx context.Context, itemName string, substituteName string, req SubstitutionRuleRequest) (SubstitutionRuleResponse, error) {
+	ctx, span := tracing.Start(ctx, "StockService.SaveSubstitutionRule")
+	resp, err := s.StockService.SaveSubstitutionRule(ctx, itemName, substituteName, req)
+	tracing.End(span, err)
+	return resp, err
+}
+
+func (s tracedStockService) DeleteSubstitutionRule(ctx context.Context, itemName string, substituteName string) error {
+	ctx, span := tracing.Start(ctx, "StockService.DeleteSubstitutionRule")
+	err := s.StockService.DeleteSubstitutionRule(ctx, itemName, substituteName)
+	tracing.End(span, err)
+	return err
+}
+
+// tracedOrderService starts a span, named after the method, for each call to the OrderService.
+type tracedOrderService struct {
+	OrderService
+}
+
+func (s tracedOrderService) ScheduleOrder(ctx context.Context, req OrderRequest) (OrderResponse, error) {
+	ctx, span := tracing.Start(ctx, "OrderService.ScheduleOrder")
+	resp, err := s.OrderService.ScheduleOrder(ctx, req)
+	tracing.End(span, err)
+	return resp, err
+}
+
+func (s tracedOrderService) ScheduleOrders(ctx context.Context, reqs []OrderRequest) []OrderResult {
+	ctx, span := tracing.Start(ctx, "OrderService.ScheduleOrders")
+	defer span.End()
+	return s.OrderService.ScheduleOrders(ctx, reqs)
+}
+
+func (s tracedOrderService) PrepareOrder(ctx context.Context, scheduled OrderResponse) OrderResponse {
+	ctx, span := tracing.Start(ctx, "OrderService.PrepareOrder")
+	defer span.End()
+	return s.OrderService.PrepareOrder(ctx, scheduled)
+}
+
+func (s tracedOrderService) GetOrder(ctx context.Context, id uint64) (OrderRecordResponse, error) {
+	ctx, span := tracing.Start(ctx, "OrderService.GetOrder")
+	resp, err := s.OrderService.GetOrder(ctx, id)
+	tracing.End(span, err)
+	return resp, err
+}
+
+func (s tracedOrderService) GetCostOfGoodsReport(ctx context.Context, date string) (CostOfGoodsReportResponse, error) {
+	ctx, span := tracing.Start(ctx, "OrderService.GetCostOfGoodsReport")
+	resp, err := s.OrderService.GetCostOfGoodsReport(ctx, date)
+	tracing.End(span, err)
+	return resp, err
+}
@@ -0,0 +1,210 @@
+package tracing
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"sync"
+
+	"github.com/w-k-s/McMicroservices/kitchen-service/log"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	// HeaderTraceParent is the W3C Trace Context header, e.g. 00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01.
+	// The same name is used for HTTP headers and Kafka record headers.
+	HeaderTraceParent = "traceparent"
+	// HeaderRequestId identifies a request, and the messages that were published because of it, across services.
+	HeaderRequestId = "X-Request-ID"
+
+	instrumentationName = "github.com/w-k-s/McMicroservices/kitchen-service"
+	maxRequestIdLength  = 128
+)
+
+var (
+	traceParentPattern = regexp.MustCompile(`^([0-9a-f]{2})-([0-9a-f]{32})-([0-9a-f]{16})-([0-9a-f]{2})(-.*)?$`)
+	requestIdPattern   = regexp.MustCompile(`^[\x21-\x7e]+$`)
+
+	providerMu sync.RWMutex
+	// provider generates span ids even when spans are not exported, so that they can be logged and propagated.
+	provider trace.TracerProvider = sdktrace.NewTracerProvider()
+)
+
+// SetTracerProvider sets the provider that spans are started with, e.g. one that exports them to a collector.
+func SetTracerProvider(tp trace.TracerProvider) {
+	providerMu.Lock()
+	defer providerMu.Unlock()
+	provider = tp
+	otel.SetTracerProvider(tp)
+}
+
+func tracer() trace.Tracer {
+	providerMu.RLock()
+	defer providerMu.RUnlock()
+	return provider.Tracer(instrumentationName)
+}
+
+// TraceContext identifies the span of work that the kitchen is doing for a request or a message,
+// the trace that the span is part of, and the request that started the trace.
+type TraceContext struct {
+	traceId   string
+	spanId    string
+	parentId  string
+	flags     trace.TraceFlags
+	requestId string
+	remote    bool
+}
+
+// ParseTraceParent parses a W3C traceparent. Versions after 00 are parsed as version 00, as the specification requires.
+func ParseTraceParent(traceParent string) (TraceContext, error) {
+	parts := traceParentPattern.FindStringSubmatch(traceParent)
+	if parts == nil {
+		return TraceContext{}, fmt.Errorf("invalid traceparent %q", traceParent)
+	}
+	version, traceId, spanId, flags, rest := parts[1], parts[2], parts[3], parts[4], parts[5]
+	if version == "ff" || (version == "00" && len(rest) > 0) {
+		return TraceContext{}, fmt.Errorf("invalid traceparent %q", traceParent)
+	}
+	if isZero(traceId) || isZero(spanId) {
+		return TraceContext{}, fmt.Errorf("invalid traceparent %q", traceParent)
+	}
+	decoded, err := hex.DecodeString(flags)
+	if err != nil || len(decoded) == 0 {
+		return TraceContext{}, fmt.Errorf("invalid traceparent %q", traceParent)
+	}
+	var sampled trace.TraceFlags
+	if decoded[0]&0x01 == 0x01 {
+		sampled = trace.FlagsSampled
+	}
+	return TraceContext{traceId: traceId, spanId: spanId, flags: sampled}, nil
+}
+
+func (t TraceContext) TraceId() string {
+	return t.traceId
+}
+
+func (t TraceContext) SpanId() string {
+	return t.spanId
+}
+
+// ParentId is the span that started this span, or empty if the trace started here.
+func (t TraceContext) ParentId() string {
+	return t.parentId
+}
+
+func (t TraceContext) RequestId() string {
+	return t.requestId
+}
+
+// Sampled is true if the trace is recorded.
+func (t TraceContext) Sampled() bool {
+	return t.flags.IsSampled()
+}
+
+// IsValid is false if there is no span, e.g. for the zero TraceContext.
+func (t TraceContext) IsValid() bool {
+	return len(t.traceId) > 0 && len(t.spanId) > 0
+}
+
+// TraceParent is the traceparent that makes this span the parent of the requests and messages that it sends.
+func (t TraceContext) TraceParent() string {
+	return fmt.Sprintf("00-%s-%s-%s", t.traceId, t.spanId, t.flags)
+}
+
+func (t TraceContext) spanContext() trace.SpanContext {
+	traceId, _ := trace.TraceIDFromHex(t.traceId)
+	spanId, _ := trace.SpanIDFromHex(t.spanId)
+	return trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    traceId,
+		SpanID:     spanId,
+		TraceFlags: t.flags,
+		Remote:     t.remote,
+	})
+}
+
+type traceContextKey struct{}
+
+// Extract returns a copy of ctx in which the next span continues the trace in the traceparent of a request or message,
+// or starts a new trace if the traceparent is not valid.
+// If requestId is not valid, the trace id is used as the request id.
+func Extract(ctx context.Context, traceParent string, requestId string) context.Context {
+	parent, err := ParseTraceParent(traceParent)
+	if err != nil {
+		parent = TraceContext{}
+	}
+	if validRequestId(requestId) {
+		parent.requestId = requestId
+	}
+	parent.remote = true
+	return context.WithValue(ctx, traceContextKey{}, parent)
+}
+
+// Start starts a span as a child of the span in ctx. The returned context carries the span and logs its ids with every event.
+// The span must be ended, e.g. with End.
+func Start(ctx context.Context, name string, opts ...trace.SpanStartOption) (context.Context, trace.Span) {
+	parent, _ := FromContext(ctx)
+	if sc := parent.spanContext(); sc.IsValid() {
+		ctx = trace.ContextWithSpanContext(ctx, sc)
+	}
+
+	ctx, span := tracer().Start(ctx, name, opts...)
+
+	sc := span.SpanContext()
+	requestId := parent.requestId
+	if len(requestId) == 0 {
+		requestId = sc.TraceID().String()
+	}
+	return WithTraceContext(ctx, TraceContext{
+		traceId:   sc.TraceID().String(),
+		spanId:    sc.SpanID().String(),
+		parentId:  parent.spanId,
+		flags:     sc.TraceFlags(),
+		requestId: requestId,
+	}), span
+}
+
+// End records err, if it is not nil, on the span and ends the span.
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// WithTraceContext returns a copy of ctx that carries the trace context and logs its ids with every event.
+// ctx is returned as it is if the trace context is not valid.
+func WithTraceContext(ctx context.Context, t TraceContext) context.Context {
+	if !t.IsValid() {
+		return ctx
+	}
+	ctx = context.WithValue(ctx, traceContextKey{}, t)
+	return log.WithContextFields(ctx, map[string]interface{}{
+		"traceId":   t.traceId,
+		"spanId":    t.spanId,
+		"requestId": t.requestId,
+	})
+}
+
+// FromContext returns the trace context of the span in ctx, and false if ctx does not have a span.
+func FromContext(ctx context.Context) (TraceContext, bool) {
+	t, ok := ctx.Value(traceContextKey{}).(TraceContext)
+	return t, ok && t.IsValid()
+}
+
+func validRequestId(requestId string) bool {
+	return len(requestId) > 0 && len(requestId) <= maxRequestIdLength && requestIdPattern.MatchString(requestId)
+}
+
+func isZero(hexId string) bool {
+	for _, c := range hexId {
+		if c != '0' {
+			return false
+		}
+	}
+	return true
+}
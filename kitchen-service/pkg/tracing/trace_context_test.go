@@ -0,0 +1,178 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+type TraceContextTestSuite struct {
+	suite.Suite
+	recorder *tracetest.SpanRecorder
+}
+
+func TestTraceContextTestSuite(t *testing.T) {
+	suite.Run(t, new(TraceContextTestSuite))
+}
+
+const traceParent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+
+func (suite *TraceContextTestSuite) SetupTest() {
+	suite.recorder = tracetest.NewSpanRecorder()
+	SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(suite.recorder)))
+}
+
+// -- SUITE
+
+func (suite *TraceContextTestSuite) Test_GIVEN_validTraceParent_WHEN_parsed_THEN_idsAreReturned() {
+	// WHEN
+	trace, err := ParseTraceParent(traceParent)
+
+	// THEN
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), "4bf92f3577b34da6a3ce929d0e0e4736", trace.TraceId())
+	assert.Equal(suite.T(), "00f067aa0ba902b7", trace.SpanId())
+	assert.True(suite.T(), trace.Sampled())
+	assert.Equal(suite.T(), traceParent, trace.TraceParent())
+}
+
+func (suite *TraceContextTestSuite) Test_GIVEN_invalidTraceParent_WHEN_parsed_THEN_errorIsReturned() {
+	for _, invalid := range []string{
+		"",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7",
+		"00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01",
+		"00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01",
+		"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-future",
+	} {
+		_, err := ParseTraceParent(invalid)
+		assert.NotNil(suite.T(), err, invalid)
+	}
+}
+
+func (suite *TraceContextTestSuite) Test_GIVEN_futureVersion_WHEN_parsed_THEN_fieldsOfVersionZeroAreReturned() {
+	// WHEN
+	trace, err := ParseTraceParent("01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00-future")
+
+	// THEN
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), "4bf92f3577b34da6a3ce929d0e0e4736", trace.TraceId())
+	assert.False(suite.T(), trace.Sampled())
+}
+
+func (suite *TraceContextTestSuite) Test_GIVEN_flagsWithHexLetter_WHEN_parsed_THEN_sampledBitIsRead() {
+	// WHEN
+	sampled, sampledErr := ParseTraceParent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-0b")
+	notSampled, notSampledErr := ParseTraceParent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-0a")
+
+	// THEN
+	assert.Nil(suite.T(), sampledErr)
+	assert.Nil(suite.T(), notSampledErr)
+	assert.True(suite.T(), sampled.Sampled())
+	assert.False(suite.T(), notSampled.Sampled())
+}
+
+func (suite *TraceContextTestSuite) Test_GIVEN_zeroTraceContext_WHEN_sampledIsChecked_THEN_traceIsNotSampled() {
+	// THEN
+	assert.False(suite.T(), TraceContext{}.Sampled())
+}
+
+func (suite *TraceContextTestSuite) Test_GIVEN_traceParent_WHEN_spanIsStarted_THEN_spanContinuesTrace() {
+	// GIVEN
+	ctx := Extract(context.Background(), traceParent, "order-42")
+
+	// WHEN
+	ctx, span := Start(ctx, "GET /kitchen/api/v1/stock")
+	span.End()
+
+	// THEN
+	trace, ok := FromContext(ctx)
+	assert.True(suite.T(), ok)
+	assert.Equal(suite.T(), "4bf92f3577b34da6a3ce929d0e0e4736", trace.TraceId())
+	assert.Equal(suite.T(), "00f067aa0ba902b7", trace.ParentId())
+	assert.NotEqual(suite.T(), "00f067aa0ba902b7", trace.SpanId())
+	assert.Len(suite.T(), trace.SpanId(), 16)
+	assert.Equal(suite.T(), "order-42", trace.RequestId())
+	assert.True(suite.T(), strings.HasPrefix(trace.TraceParent(), "00-4bf92f3577b34da6a3ce929d0e0e4736-"))
+
+	ended := suite.recorder.Ended()
+	assert.Len(suite.T(), ended, 1)
+	assert.Equal(suite.T(), "GET /kitchen/api/v1/stock", ended[0].Name())
+	assert.Equal(suite.T(), "00f067aa0ba902b7", ended[0].Parent().SpanID().String())
+	assert.True(suite.T(), ended[0].Parent().IsRemote())
+}
+
+func (suite *TraceContextTestSuite) Test_GIVEN_noTraceParent_WHEN_spanIsStarted_THEN_newTraceIsStarted() {
+	// WHEN
+	ctx, span := Start(Extract(context.Background(), "", ""), "order_created receive")
+	span.End()
+
+	// THEN
+	trace, ok := FromContext(ctx)
+	assert.True(suite.T(), ok)
+	assert.Len(suite.T(), trace.TraceId(), 32)
+	assert.Equal(suite.T(), "", trace.ParentId())
+	assert.Equal(suite.T(), trace.TraceId(), trace.RequestId())
+	_, err := ParseTraceParent(trace.TraceParent())
+	assert.Nil(suite.T(), err)
+}
+
+func (suite *TraceContextTestSuite) Test_GIVEN_invalidRequestId_WHEN_spanIsStarted_THEN_traceIdIsUsedAsRequestId() {
+	for _, invalid := range []string{"order 42", "order\n42", strings.Repeat("x", 129)} {
+		// WHEN
+		ctx, span := Start(Extract(context.Background(), traceParent, invalid), "GET /health")
+		span.End()
+
+		// THEN
+		trace, _ := FromContext(ctx)
+		assert.Equal(suite.T(), "4bf92f3577b34da6a3ce929d0e0e4736", trace.RequestId())
+	}
+}
+
+func (suite *TraceContextTestSuite) Test_GIVEN_span_WHEN_childSpanIsStarted_THEN_childSharesTraceAndRequestId() {
+	// GIVEN
+	ctx, parent := Start(Extract(context.Background(), traceParent, "order-42"), "StockService.GetStock")
+	parentTrace, _ := FromContext(ctx)
+
+	// WHEN
+	ctx, child := Start(ctx, "SELECT")
+	End(child, errors.New("connection reset"))
+	parent.End()
+
+	// THEN
+	childTrace, _ := FromContext(ctx)
+	assert.Equal(suite.T(), parentTrace.TraceId(), childTrace.TraceId())
+	assert.Equal(suite.T(), parentTrace.SpanId(), childTrace.ParentId())
+	assert.Equal(suite.T(), "order-42", childTrace.RequestId())
+
+	ended := suite.recorder.Ended()
+	assert.Len(suite.T(), ended, 2)
+	assert.Equal(suite.T(), "SELECT", ended[0].Name())
+	assert.Equal(suite.T(), codes.Error, ended[0].Status().Code)
+	assert.Equal(suite.T(), parentTrace.SpanId(), ended[0].Parent().SpanID().String())
+	assert.False(suite.T(), ended[0].Parent().IsRemote())
+}
+
+func (suite *TraceContextTestSuite) Test_GIVEN_traceContext_WHEN_addedToContext_THEN_itCanBeRetrieved() {
+	// GIVEN
+	trace, _ := ParseTraceParent(traceParent)
+
+	// WHEN
+	ctx := WithTraceContext(context.Background(), trace)
+
+	// THEN
+	retrieved, ok := FromContext(ctx)
+	assert.True(suite.T(), ok)
+	assert.Equal(suite.T(), trace, retrieved)
+
+	_, ok = FromContext(WithTraceContext(context.Background(), TraceContext{}))
+	assert.False(suite.T(), ok)
+}
@@ -7,6 +7,7 @@ import (
 	"log"
 	"os"
 	"testing"
+	"time"
 
 	tc "github.com/testcontainers/testcontainers-go"
 	"github.com/testcontainers/testcontainers-go/wait"
@@ -33,8 +34,15 @@ var (
 
 func init() {
 	var (
-		serverConfig cfg.ServerConfig
-		err          error
+		serverConfig    cfg.ServerConfig
+		prepConfig      cfg.PreparationConfig
+		queueConfig     cfg.QueueConfig
+		stockConfig     cfg.StockConfig
+		authConfig      cfg.AuthConfig
+		telemetryConfig cfg.TelemetryConfig
+		loggingConfig   cfg.LoggingConfig
+		reloadConfig    cfg.ReloadConfig
+		err             error
 	)
 	if serverConfig, err = cfg.NewServerConfigBuilder().
 		SetPort(9898).
@@ -42,10 +50,56 @@ func init() {
 		log.Fatalf("failed to create server. Reason: %q", err)
 	}
 
+	if prepConfig, err = cfg.NewPreparationConfigBuilder().
+		SetEstimator(cfg.EstimatorLinear).
+		SetPerItem(5 * time.Second).
+		SetLearnedWindow(7 * 24 * time.Hour).
+		SetLearnedMinSamples(5).
+		Build(); err != nil {
+		log.Fatalf("failed to create preparation config. Reason: %q", err)
+	}
+
+	if queueConfig, err = cfg.NewQueueConfig(5*time.Minute, 0, 1, 50*time.Millisecond, 1000); err != nil {
+		log.Fatalf("failed to create queue config. Reason: %q", err)
+	}
+
+	if stockConfig, err = cfg.NewStockConfig(db.DefaultBulkIncreaseThreshold, db.DefaultIdempotencyKeyRetention); err != nil {
+		log.Fatalf("failed to create stock config. Reason: %q", err)
+	}
+
+	if authConfig, err = cfg.NewAuthConfigBuilder().
+		SetDisabled(true).
+		Build(); err != nil {
+		log.Fatalf("failed to create auth config. Reason: %q", err)
+	}
+
+	if telemetryConfig, err = cfg.NewTelemetryConfigBuilder().
+		SetExporter(string(cfg.ExporterNone)).
+		SetServiceName("kitchen-service").
+		SetSampleRatio(1).
+		Build(); err != nil {
+		log.Fatalf("failed to create telemetry config. Reason: %q", err)
+	}
+
+	if loggingConfig, err = cfg.NewLoggingConfig("info"); err != nil {
+		log.Fatalf("failed to create logging config. Reason: %q", err)
+	}
+
+	if reloadConfig, err = cfg.NewReloadConfig(true, 0); err != nil {
+		log.Fatalf("failed to create reload config. Reason: %q", err)
+	}
+
 	if testConfig, _ = cfg.NewConfig(
 		serverConfig,
 		requestKafkaTestContainer(),
 		requestDatabaseTestContainer(),
+		prepConfig,
+		queueConfig,
+		stockConfig,
+		authConfig,
+		telemetryConfig,
+		loggingConfig,
+		reloadConfig,
 	); err != nil {
 		log.Fatalf("Failed to configure application for tests. Reason: %s", err)
 	}
@@ -132,4 +186,37 @@ func clearTables() {
 	if _, err := testDB.Exec("DELETE FROM kitchen.stock"); err != nil {
 		log.Print("Failed to delete stock table: %w", err)
 	}
+	if _, err := testDB.Exec("DELETE FROM kitchen.item"); err != nil {
+		log.Print("Failed to delete item table: %w", err)
+	}
+	if _, err := testDB.Exec("DELETE FROM kitchen.stock_transfer"); err != nil {
+		log.Print("Failed to delete stock transfer table: %w", err)
+	}
+	if _, err := testDB.Exec("DELETE FROM kitchen.stock_receipt"); err != nil {
+		log.Print("Failed to delete stock receipt table: %w", err)
+	}
+	if _, err := testDB.Exec("DELETE FROM kitchen.ingredient_tag"); err != nil {
+		log.Print("Failed to delete ingredient tag table: %w", err)
+	}
+	if _, err := testDB.Exec("DELETE FROM kitchen.substitution_rule"); err != nil {
+		log.Print("Failed to delete substitution rule table: %w", err)
+	}
+	if _, err := testDB.Exec("DELETE FROM kitchen.kitchen_order"); err != nil {
+		log.Print("Failed to delete kitchen order table: %w", err)
+	}
+	if _, err := testDB.Exec("DELETE FROM kitchen.station"); err != nil {
+		log.Print("Failed to delete station table: %w", err)
+	}
+	if _, err := testDB.Exec("DELETE FROM kitchen.purchase_order"); err != nil {
+		log.Print("Failed to delete purchase order table: %w", err)
+	}
+	if _, err := testDB.Exec("DELETE FROM kitchen.supplier"); err != nil {
+		log.Print("Failed to delete supplier table: %w", err)
+	}
+	if _, err := testDB.Exec("DELETE FROM kitchen.idempotency_key"); err != nil {
+		log.Print("Failed to delete idempotency key table: %w", err)
+	}
+	if _, err := testDB.Exec("DELETE FROM kitchen.failed_delivery"); err != nil {
+		log.Print("Failed to delete failed delivery table: %w", err)
+	}
 }
@@ -0,0 +1,283 @@
+package test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+	db "github.com/w-k-s/McMicroservices/kitchen-service/internal/persistence"
+	k "github.com/w-k-s/McMicroservices/kitchen-service/pkg/kitchen"
+	dao "github.com/w-k-s/McMicroservices/kitchen-service/pkg/persistence"
+)
+
+type OrderDaoTestSuite struct {
+	suite.Suite
+	orderDao dao.OrderDao
+}
+
+func TestOrderDaoTestSuite(t *testing.T) {
+	suite.Run(t, new(OrderDaoTestSuite))
+}
+
+// -- SETUP
+
+func (suite *OrderDaoTestSuite) SetupTest() {
+	suite.orderDao = db.MustOpenOrderDao(testDB)
+}
+
+// -- TEARDOWN
+
+func (suite *OrderDaoTestSuite) TearDownTest() {
+	clearTables()
+}
+
+// -- SUITE
+
+func (suite *OrderDaoTestSuite) Test_GIVEN_deliveriesWithDifferentCosts_WHEN_orderIsSaved_THEN_costOfGoodsUsesWeightedAverageCost() {
+	// GIVEN
+	ctx := context.Background()
+	deliveryTx, _ := suite.orderDao.BeginTx()
+	cheese, _ := k.Must(k.NewStockItem("Cheese", 2)).WithUnitCost(k.Money(10000))
+	moreCheese, _ := k.Must(k.NewStockItem("Cheese", 2)).WithUnitCost(k.Money(20000))
+	assert.Nil(suite.T(), deliveryTx.Increase(ctx, k.Stock{cheese}), "Increase returned error")
+	assert.Nil(suite.T(), deliveryTx.Increase(ctx, k.Stock{moreCheese}), "Increase returned error")
+	assert.Nil(suite.T(), deliveryTx.Commit(), "Commit returned error")
+
+	// WHEN
+	orderTx, _ := suite.orderDao.BeginTx()
+	order, _ := k.NewOrder(1, k.OrderStatusPreparing, "", []k.OrderItem{k.NewOrderItem("Cheese", 2, 0)}, time.Now())
+	assert.Nil(suite.T(), orderTx.Decrease(ctx, k.Stock{k.Must(k.NewStockItem("Cheese", 2))}), "Decrease returned error")
+	assert.Nil(suite.T(), orderTx.SaveOrder(ctx, order), "SaveOrder returned error")
+	assert.Nil(suite.T(), orderTx.Commit(), "Commit returned error")
+
+	// THEN
+	getTx, _ := suite.orderDao.BeginTx()
+	saved, err := getTx.GetOrder(ctx, 1)
+	report, reportErr := getTx.GetCostOfGoodsReport(ctx, time.Now().UTC())
+	assert.Nil(suite.T(), getTx.Commit())
+
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), k.OrderStatusPreparing, saved.Status())
+	assert.Equal(suite.T(), "1.5000", saved.Items()[0].UnitCost().String())
+	assert.Equal(suite.T(), "3.0000", saved.CostOfGoods().String())
+
+	assert.Nil(suite.T(), reportErr)
+	assert.Equal(suite.T(), uint(1), report.Orders)
+	assert.Equal(suite.T(), "3.0000", report.CostOfGoods().String())
+}
+
+func (suite *OrderDaoTestSuite) Test_GIVEN_orderDoesNotExist_WHEN_orderIsLoaded_THEN_notFoundErrorIsReturned() {
+	// WHEN
+	getTx, _ := suite.orderDao.BeginTx()
+	_, err := getTx.GetOrder(context.Background(), 404)
+	assert.Nil(suite.T(), getTx.Commit())
+
+	// THEN
+	assert.NotNil(suite.T(), err)
+	assert.IsType(suite.T(), k.NotFoundError{}, err)
+}
+
+func (suite *OrderDaoTestSuite) Test_GIVEN_orderWithSubstitutions_WHEN_orderIsSaved_THEN_substitutionsAreLoaded() {
+	// GIVEN
+	ctx := context.Background()
+	order, _ := k.NewOrder(1, k.OrderStatusPreparing, "", []k.OrderItem{k.NewOrderItem("Cheddar", 2, 0)}, time.Now())
+	order = order.WithSubstitutions(k.Substitutions{}.Add("Mozzarella", "Cheddar", 2))
+
+	// WHEN
+	orderTx, _ := suite.orderDao.BeginTx()
+	assert.Nil(suite.T(), orderTx.SaveOrder(ctx, order), "SaveOrder returned error")
+	assert.Nil(suite.T(), orderTx.Commit(), "Commit returned error")
+
+	// THEN
+	getTx, _ := suite.orderDao.BeginTx()
+	saved, err := getTx.GetOrder(ctx, 1)
+	assert.Nil(suite.T(), getTx.Commit())
+
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), k.Substitutions{{Original: "Mozzarella", Substitute: "Cheddar", Units: 2}}, saved.Substitutions())
+}
+
+func (suite *OrderDaoTestSuite) Test_GIVEN_bumpedAndOpenTickets_WHEN_openTicketsAreLoaded_THEN_onlyOpenTicketsAreReturned() {
+	// GIVEN
+	ctx := context.Background()
+	tx, _ := suite.orderDao.BeginTx()
+	for _, id := range []uint64{1, 2} {
+		order, _ := k.NewOrder(id, k.OrderStatusPreparing, "", []k.OrderItem{}, time.Now())
+		assert.Nil(suite.T(), tx.SaveOrder(ctx, order), "SaveOrder returned error")
+
+		created, _ := k.NewTicketEvent(0, k.TicketCreated, id, k.DefaultStation, k.DefaultLocation, []string{"Cheese"}, time.Now())
+		_, err := tx.SaveTicketEvent(ctx, created)
+		assert.Nil(suite.T(), err, "SaveTicketEvent returned error")
+	}
+	bumped, _ := k.NewTicketEvent(0, k.TicketBumped, 1, k.DefaultStation, k.DefaultLocation, nil, time.Now())
+	bumped, err := tx.SaveTicketEvent(ctx, bumped)
+	assert.Nil(suite.T(), err, "SaveTicketEvent returned error")
+	assert.Nil(suite.T(), tx.Commit(), "Commit returned error")
+
+	// WHEN
+	getTx, _ := suite.orderDao.BeginTx()
+	open, openErr := getTx.GetOpenTicketEvents(ctx, k.DefaultStation, "")
+	missed, missedErr := getTx.GetTicketEvents(ctx, bumped.Id()-1, 0, "", k.DefaultLocation)
+	latest, latestErr := getTx.GetLatestTicketEvents(ctx, 1)
+	assert.Nil(suite.T(), getTx.Commit())
+
+	// THEN
+	assert.Nil(suite.T(), openErr)
+	assert.Equal(suite.T(), 1, len(open))
+	assert.Equal(suite.T(), uint64(2), open[0].OrderId())
+	assert.Equal(suite.T(), []string{"Cheese"}, open[0].Items())
+
+	assert.Nil(suite.T(), missedErr)
+	assert.Equal(suite.T(), 1, len(missed))
+	assert.Equal(suite.T(), k.TicketBumped, missed[0].Type())
+
+	assert.Nil(suite.T(), latestErr)
+	assert.Equal(suite.T(), k.TicketBumped, latest[0].Type())
+}
+
+func (suite *OrderDaoTestSuite) Test_GIVEN_earlierEventCommittedLater_WHEN_eventsAfterLaterEventAreLoaded_THEN_earlierEventIsReturned() {
+	// GIVEN
+	ctx := context.Background()
+	orderTx, _ := suite.orderDao.BeginTx()
+	for _, id := range []uint64{1, 2} {
+		order, _ := k.NewOrder(id, k.OrderStatusPreparing, "", []k.OrderItem{}, time.Now())
+		assert.Nil(suite.T(), orderTx.SaveOrder(ctx, order), "SaveOrder returned error")
+	}
+	assert.Nil(suite.T(), orderTx.Commit(), "Commit returned error")
+
+	earlierTx, _ := suite.orderDao.BeginTx()
+	earlier, _ := k.NewTicketEvent(0, k.TicketCreated, 1, k.DefaultStation, k.DefaultLocation, []string{"Cheese"}, time.Now())
+	earlier, err := earlierTx.SaveTicketEvent(ctx, earlier)
+	assert.Nil(suite.T(), err, "SaveTicketEvent returned error")
+
+	laterTx, _ := suite.orderDao.BeginTx()
+	later, _ := k.NewTicketEvent(0, k.TicketCreated, 2, k.DefaultStation, k.DefaultLocation, []string{"Olives"}, time.Now())
+	later, err = laterTx.SaveTicketEvent(ctx, later)
+	assert.Nil(suite.T(), err, "SaveTicketEvent returned error")
+	assert.Nil(suite.T(), laterTx.Commit(), "Commit returned error")
+	assert.Nil(suite.T(), earlierTx.Commit(), "Commit returned error")
+
+	// WHEN
+	getTx, _ := suite.orderDao.BeginTx()
+	missed, missedErr := getTx.GetTicketEvents(ctx, later.Id(), time.Minute, "", "")
+	withoutOverlap, withoutOverlapErr := getTx.GetTicketEvents(ctx, later.Id(), 0, "", "")
+	assert.Nil(suite.T(), getTx.Commit())
+
+	// THEN
+	assert.Less(suite.T(), earlier.Id(), later.Id())
+	assert.Nil(suite.T(), missedErr)
+	assert.Equal(suite.T(), 1, len(missed))
+	assert.Equal(suite.T(), earlier.Id(), missed[0].Id())
+	assert.Nil(suite.T(), withoutOverlapErr)
+	assert.Empty(suite.T(), withoutOverlap)
+}
+
+func (suite *OrderDaoTestSuite) Test_GIVEN_scheduledTasks_WHEN_stationLoadIsLoaded_THEN_busySlotsAreReturned() {
+	// GIVEN
+	ctx := context.Background()
+	now := time.Now().UTC().Truncate(time.Second)
+	oven, _ := k.NewKitchenStation("oven", 2)
+	pizza, _ := k.NewToppingTask("Pepperoni", "oven", time.Minute)
+
+	tx, _ := suite.orderDao.BeginTx()
+	assert.Nil(suite.T(), tx.SaveStation(ctx, oven), "SaveStation returned error")
+	assert.Nil(suite.T(), tx.SaveToppingTask(ctx, pizza), "SaveToppingTask returned error")
+
+	load, err := tx.GetStationLoad(ctx, oven, k.DefaultLocation, now)
+	assert.Nil(suite.T(), err, "GetStationLoad returned error")
+	task, _ := load.Assign(1, pizza)
+
+	order, _ := k.NewOrder(1, k.OrderStatusPreparing, "", []k.OrderItem{}, now)
+	assert.Nil(suite.T(), tx.SaveOrder(ctx, order.WithTasks(k.Tasks{task})), "SaveOrder returned error")
+	assert.Nil(suite.T(), tx.Commit(), "Commit returned error")
+
+	// WHEN
+	getTx, _ := suite.orderDao.BeginTx()
+	saved, savedErr := getTx.GetOrder(ctx, 1)
+	tasks, tasksErr := getTx.GetToppingTasks(ctx)
+	reloaded, loadErr := getTx.GetStationLoad(ctx, oven, k.DefaultLocation, now)
+	assert.Nil(suite.T(), getTx.Commit())
+
+	// THEN
+	assert.Nil(suite.T(), savedErr)
+	assert.Equal(suite.T(), now.Add(time.Minute), saved.EstimatedReadyAt().UTC())
+
+	assert.Nil(suite.T(), tasksErr)
+	assert.Equal(suite.T(), []k.ToppingTask{pizza}, tasks)
+
+	assert.Nil(suite.T(), loadErr)
+	next, _ := reloaded.Assign(2, pizza)
+	assert.Equal(suite.T(), uint(1), next.Slot)
+	assert.Equal(suite.T(), now, next.StartsAt)
+}
+
+func (suite *OrderDaoTestSuite) Test_GIVEN_bumpedTicket_WHEN_preparationSamplesAreLoaded_THEN_timeIsSharedBetweenItems() {
+	// GIVEN
+	ctx := context.Background()
+	startedAt := time.Now().Add(-time.Hour)
+	tx, _ := suite.orderDao.BeginTx()
+	order, _ := k.NewOrder(1, k.OrderStatusReady, "", []k.OrderItem{}, startedAt)
+	assert.Nil(suite.T(), tx.SaveOrder(ctx, order.EstimatedBy("learned")), "SaveOrder returned error")
+	for _, event := range []struct {
+		eventType  k.TicketEventType
+		occurredAt time.Time
+	}{
+		{k.TicketCreated, startedAt},
+		{k.TicketStarted, startedAt},
+		{k.TicketBumped, startedAt.Add(time.Minute)},
+	} {
+		ticket, _ := k.NewTicketEvent(0, event.eventType, 1, "grill", k.DefaultLocation, []string{"Patty", "Bacon"}, event.occurredAt)
+		_, err := tx.SaveTicketEvent(ctx, ticket)
+		assert.Nil(suite.T(), err, "SaveTicketEvent returned error")
+	}
+	assert.Nil(suite.T(), tx.Commit(), "Commit returned error")
+
+	// WHEN
+	getTx, _ := suite.orderDao.BeginTx()
+	samples, err := getTx.GetPreparationSamples(ctx, []string{"Patty", "Cheese"}, startedAt.Add(-time.Minute))
+	saved, _ := getTx.GetOrder(ctx, 1)
+	assert.Nil(suite.T(), getTx.Commit())
+
+	// THEN
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), 1, len(samples))
+	assert.Equal(suite.T(), uint(1), samples["Patty"].Samples)
+	assert.Equal(suite.T(), 30*time.Second, samples["Patty"].Average.Round(time.Second))
+	assert.Equal(suite.T(), "learned", saved.Estimator())
+}
+
+func (suite *OrderDaoTestSuite) Test_GIVEN_automaticallyBumpedTicket_WHEN_preparationSamplesAreLoaded_THEN_ticketIsNotASample() {
+	// GIVEN
+	ctx := context.Background()
+	startedAt := time.Now().Add(-time.Hour)
+	tx, _ := suite.orderDao.BeginTx()
+	order, _ := k.NewOrder(1, k.OrderStatusReady, "", []k.OrderItem{}, startedAt)
+	assert.Nil(suite.T(), tx.SaveOrder(ctx, order.EstimatedBy("learned")), "SaveOrder returned error")
+	for _, event := range []struct {
+		eventType  k.TicketEventType
+		occurredAt time.Time
+	}{
+		{k.TicketCreated, startedAt},
+		{k.TicketStarted, startedAt},
+		{k.TicketBumped, startedAt.Add(time.Minute)},
+	} {
+		ticket, _ := k.NewTicketEvent(0, event.eventType, 1, "grill", k.DefaultLocation, []string{"Patty"}, event.occurredAt)
+		_, err := tx.SaveTicketEvent(ctx, ticket.WithAutomatic(true))
+		assert.Nil(suite.T(), err, "SaveTicketEvent returned error")
+	}
+	assert.Nil(suite.T(), tx.Commit(), "Commit returned error")
+
+	// WHEN
+	getTx, _ := suite.orderDao.BeginTx()
+	samples, err := getTx.GetPreparationSamples(ctx, []string{"Patty"}, startedAt.Add(-time.Minute))
+	events, _ := getTx.GetLatestTicketEvents(ctx, 1)
+	assert.Nil(suite.T(), getTx.Commit())
+
+	// THEN
+	assert.Nil(suite.T(), err)
+	assert.Empty(suite.T(), samples)
+	assert.Equal(suite.T(), 1, len(events))
+	assert.True(suite.T(), events[0].Automatic())
+}
@@ -2,6 +2,7 @@ package test
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"log"
 	"net/http"
@@ -23,7 +24,7 @@ import (
 func Test_GIVEN_sufficientStock_WHEN_orderIsReceived_THEN_orderIsProcessedSuccessfully(t *testing.T) {
 
 	var (
-		stockDao     = db.MustOpenStockDao(testDB)
+		stockDao     = db.MustOpenStockDao(testDB, db.NewBulkIncreaseThreshold(db.DefaultBulkIncreaseThreshold))
 		testConsumer = mocks.NewConsumer(t, nil)
 		testProducer = mocks.NewSyncProducer(t, nil)
 		testApp      *app.App
@@ -43,6 +44,20 @@ func Test_GIVEN_sufficientStock_WHEN_orderIsReceived_THEN_orderIsProcessedSucces
 		t.Errorf("Failed to commit stock update. Reason: %q", err)
 	}
 
+	testProducer.ExpectSendMessageWithCheckerFunctionAndSucceed(func(body []byte) error {
+		var preparing struct {
+			Id               uint64     `json:"id"`
+			Status           string     `json:"status"`
+			EstimatedReadyAt *time.Time `json:"estimatedReadyAt"`
+		}
+		if err := json.Unmarshal(body, &preparing); err != nil {
+			return err
+		}
+		if preparing.Id != 1 || preparing.Status != "PREPARING" || preparing.EstimatedReadyAt == nil {
+			return fmt.Errorf("Expected order 1 to be preparing with an estimated ready time. Got %q", string(body))
+		}
+		return nil
+	})
 	testProducer.ExpectSendMessageWithCheckerFunctionAndSucceed(func(body []byte) error {
 		expected := "{\"id\":1,\"status\":\"READY\"}"
 		actual := string(body)
@@ -166,3 +181,60 @@ func Test_GIVEN_insufficientStock_WHEN_orderIsReceived_THEN_orderIsRejected(t *t
 	clearTables()
 	testApp.Close()
 }
+
+func Test_GIVEN_expiredOrder_WHEN_orderIsReceived_THEN_orderIsRejected(t *testing.T) {
+	var (
+		testConsumer = mocks.NewConsumer(t, nil)
+		testProducer = mocks.NewSyncProducer(t, nil)
+		testApp      *app.App
+		err          error
+	)
+
+	// GIVEN
+	testProducer.ExpectSendMessageWithCheckerFunctionAndSucceed(func(body []byte) error {
+		expected := "{\"id\":1,\"status\":\"FAILED\",\"reason\":\"order 1 expired at 2020-01-01T00:00:00Z\",\"expired\":true}"
+		actual := string(body)
+		if expected != actual {
+			return fmt.Errorf("Expected %q. Got %q", expected, actual)
+		}
+		return nil
+	})
+
+	mockProducerFactory := func(brokerConfig cfg.BrokerConfig) (sarama.SyncProducer, error) {
+		return testProducer, nil
+	}
+
+	testConsumer.SetTopicMetadata(map[string][]int32{
+		app.TopicCreateOrder:       {0},
+		app.TopicInventoryDelivery: {0},
+	})
+	partitionConsumer := testConsumer.ExpectConsumePartition(app.TopicCreateOrder, 0, sarama.OffsetOldest)
+	_ = testConsumer.ExpectConsumePartition(app.TopicInventoryDelivery, 0, sarama.OffsetOldest)
+	mockConsumerFactory := func(brokerConfig cfg.BrokerConfig) (sarama.Consumer, error) {
+		return testConsumer, nil
+	}
+
+	if testApp, err =
+		app.NewAppBuilder(testConfig).
+			SetConsumerFactory(mockConsumerFactory).
+			SetProducerFactory(mockProducerFactory).
+			Build(); err != nil {
+		log.Fatalf("Failed to initialize application for tests. Reason: %s", err)
+	}
+
+	// WHEN
+	partitionConsumer.
+		YieldMessage(&sarama.ConsumerMessage{
+			Topic:     app.TopicCreateOrder,
+			Partition: 0,
+			Value:     []byte(`{"id":1,"toppings":["Tomatoes"],"expiresAt":"2020-01-01T00:00:00Z"}`),
+		})
+
+	// THEN
+	// -- Wait for all expectations to be met
+	time.Sleep(30 * time.Second)
+
+	// TearDown
+	clearTables()
+	testApp.Close()
+}
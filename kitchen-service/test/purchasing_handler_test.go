@@ -0,0 +1,354 @@
+package test
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Shopify/sarama"
+	"github.com/Shopify/sarama/mocks"
+	"github.com/stretchr/testify/assert"
+	cfg "github.com/w-k-s/McMicroservices/kitchen-service/internal/config"
+	app "github.com/w-k-s/McMicroservices/kitchen-service/internal/server"
+)
+
+// -- SUITE
+
+func Test_GIVEN_openPurchaseOrder_WHEN_deliveryIsReceivedForSupplier_THEN_discrepanciesAreFlagged(t *testing.T) {
+	var (
+		testConsumer = mocks.NewConsumer(t, nil)
+		testProducer = mocks.NewSyncProducer(t, nil)
+		testApp      *app.App
+		err          error
+	)
+
+	// GIVEN
+	mockProducerFactory := func(brokerConfig cfg.BrokerConfig) (sarama.SyncProducer, error) {
+		return testProducer, nil
+	}
+
+	testConsumer.SetTopicMetadata(map[string][]int32{
+		app.TopicCreateOrder:       {0},
+		app.TopicInventoryDelivery: {0},
+	})
+	_ = testConsumer.ExpectConsumePartition(app.TopicCreateOrder, 0, sarama.OffsetOldest)
+	partitionConsumer := testConsumer.ExpectConsumePartition(app.TopicInventoryDelivery, 0, sarama.OffsetOldest)
+	mockConsumerFactory := func(brokerConfig cfg.BrokerConfig) (sarama.Consumer, error) {
+		return testConsumer, nil
+	}
+
+	if testApp, err =
+		app.NewAppBuilder(testConfig).
+			SetConsumerFactory(mockConsumerFactory).
+			SetProducerFactory(mockProducerFactory).
+			Build(); err != nil {
+		log.Fatalf("Failed to initialize application for tests. Reason: %s", err)
+	}
+
+	var supplier struct {
+		Id uint64 `json:"id"`
+	}
+	r, _ := http.NewRequest("POST", "/kitchen/api/v1/suppliers", strings.NewReader(`{"name":"Farm Fresh","email":"orders@farmfresh.com"}`))
+	w := httptest.NewRecorder()
+	testApp.Router().ServeHTTP(w, r)
+	assert.Equal(t, 201, w.Code)
+	assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &supplier))
+
+	var purchaseOrder struct {
+		Id uint64 `json:"id"`
+	}
+	r, _ = http.NewRequest("POST", "/kitchen/api/v1/purchase-orders", strings.NewReader(fmt.Sprintf(`{
+		"supplierId": %d,
+		"expectedDeliveryDate": "2022-05-01",
+		"lines": [{"itemName":"Cheese","units":5},{"itemName":"Donuts","units":10}]
+	}`, supplier.Id)))
+	w = httptest.NewRecorder()
+	testApp.Router().ServeHTTP(w, r)
+	assert.Equal(t, 201, w.Code)
+	assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &purchaseOrder))
+
+	// WHEN
+	partitionConsumer.
+		YieldMessage(&sarama.ConsumerMessage{
+			Topic:     app.TopicInventoryDelivery,
+			Partition: 0,
+			Value:     []byte(fmt.Sprintf(`{"supplierId":%d,"stock":[{"name":"Cheese","units":6},{"name":"Donuts","units":7}]}`, supplier.Id)),
+		})
+
+	time.Sleep(5 * time.Second)
+
+	// THEN
+	r, _ = http.NewRequest("GET", fmt.Sprintf("/kitchen/api/v1/purchase-orders/%d", purchaseOrder.Id), nil)
+	w = httptest.NewRecorder()
+	testApp.Router().ServeHTTP(w, r)
+
+	assert.Equal(t, 200, w.Code)
+	assert.JSONEq(t, fmt.Sprintf(`{
+		"id": %d,
+		"supplierId": %d,
+		"status": "RECEIVED",
+		"expectedDeliveryDate": "2022-05-01",
+		"lines": [
+			{"itemName": "Cheese", "orderedUnits": 5, "receivedUnits": 6},
+			{"itemName": "Donuts", "orderedUnits": 10, "receivedUnits": 7}
+		],
+		"discrepancies": [
+			{"itemName": "Cheese", "orderedUnits": 5, "receivedUnits": 6, "kind": "OVER"},
+			{"itemName": "Donuts", "orderedUnits": 10, "receivedUnits": 7, "kind": "UNDER"}
+		]
+	}`, purchaseOrder.Id, supplier.Id), w.Body.String())
+
+	// TearDown
+	clearTables()
+	testApp.Close()
+}
+
+func Test_GIVEN_itemWithAliasAndSku_WHEN_itIsOrderedAndDeliveredUnderOtherNames_THEN_deliveryIsReceivedAgainstTheLine(t *testing.T) {
+	var (
+		testConsumer = mocks.NewConsumer(t, nil)
+		testProducer = mocks.NewSyncProducer(t, nil)
+		testApp      *app.App
+		err          error
+	)
+
+	// GIVEN
+	mockProducerFactory := func(brokerConfig cfg.BrokerConfig) (sarama.SyncProducer, error) {
+		return testProducer, nil
+	}
+
+	testConsumer.SetTopicMetadata(map[string][]int32{
+		app.TopicCreateOrder:       {0},
+		app.TopicInventoryDelivery: {0},
+	})
+	_ = testConsumer.ExpectConsumePartition(app.TopicCreateOrder, 0, sarama.OffsetOldest)
+	_ = testConsumer.ExpectConsumePartition(app.TopicInventoryDelivery, 0, sarama.OffsetOldest)
+	mockConsumerFactory := func(brokerConfig cfg.BrokerConfig) (sarama.Consumer, error) {
+		return testConsumer, nil
+	}
+
+	if testApp, err =
+		app.NewAppBuilder(testConfig).
+			SetConsumerFactory(mockConsumerFactory).
+			SetProducerFactory(mockProducerFactory).
+			Build(); err != nil {
+		log.Fatalf("Failed to initialize application for tests. Reason: %s", err)
+	}
+
+	r, _ := http.NewRequest("PUT", "/kitchen/api/v1/items/Cheese", strings.NewReader(`{"sku":"CHS-001","aliases":["Mozzarella"]}`))
+	w := httptest.NewRecorder()
+	testApp.Router().ServeHTTP(w, r)
+	assert.Equal(t, 200, w.Code)
+
+	var supplier struct {
+		Id uint64 `json:"id"`
+	}
+	r, _ = http.NewRequest("POST", "/kitchen/api/v1/suppliers", strings.NewReader(`{"name":"Farm Fresh","email":"orders@farmfresh.com"}`))
+	w = httptest.NewRecorder()
+	testApp.Router().ServeHTTP(w, r)
+	assert.Equal(t, 201, w.Code)
+	assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &supplier))
+
+	var purchaseOrder struct {
+		Id uint64 `json:"id"`
+	}
+	r, _ = http.NewRequest("POST", "/kitchen/api/v1/purchase-orders", strings.NewReader(fmt.Sprintf(`{
+		"supplierId": %d,
+		"expectedDeliveryDate": "2022-05-01",
+		"lines": [{"itemName":"mozzarella","units":5}]
+	}`, supplier.Id)))
+	w = httptest.NewRecorder()
+	testApp.Router().ServeHTTP(w, r)
+	assert.Equal(t, 201, w.Code)
+	assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &purchaseOrder))
+
+	// WHEN
+	r, _ = http.NewRequest("POST", "/kitchen/api/v1/deliveries", strings.NewReader(fmt.Sprintf(
+		`{"purchaseOrderId":%d,"stock":[{"name":"chs-001","units":3},{"name":"CHEESE","units":2}]}`,
+		purchaseOrder.Id,
+	)))
+	r.Header.Set("Idempotency-Key", "delivery-1")
+	w = httptest.NewRecorder()
+	testApp.Router().ServeHTTP(w, r)
+	assert.Equal(t, 201, w.Code)
+
+	// THEN
+	r, _ = http.NewRequest("GET", fmt.Sprintf("/kitchen/api/v1/purchase-orders/%d", purchaseOrder.Id), nil)
+	w = httptest.NewRecorder()
+	testApp.Router().ServeHTTP(w, r)
+
+	assert.Equal(t, 200, w.Code)
+	assert.JSONEq(t, fmt.Sprintf(`{
+		"id": %d,
+		"supplierId": %d,
+		"status": "RECEIVED",
+		"expectedDeliveryDate": "2022-05-01",
+		"lines": [
+			{"itemName": "Cheese", "orderedUnits": 5, "receivedUnits": 5}
+		],
+		"discrepancies": []
+	}`, purchaseOrder.Id, supplier.Id), w.Body.String())
+
+	// TearDown
+	clearTables()
+	testApp.Close()
+}
+
+func Test_GIVEN_existingSupplier_WHEN_supplierWithSameNameIsCreated_THEN_conflictIsReturned(t *testing.T) {
+	var (
+		testConsumer = mocks.NewConsumer(t, nil)
+		testProducer = mocks.NewSyncProducer(t, nil)
+		testApp      *app.App
+		err          error
+	)
+
+	// GIVEN
+	mockProducerFactory := func(brokerConfig cfg.BrokerConfig) (sarama.SyncProducer, error) {
+		return testProducer, nil
+	}
+
+	testConsumer.SetTopicMetadata(map[string][]int32{
+		app.TopicCreateOrder:       {0},
+		app.TopicInventoryDelivery: {0},
+	})
+	_ = testConsumer.ExpectConsumePartition(app.TopicCreateOrder, 0, sarama.OffsetOldest)
+	_ = testConsumer.ExpectConsumePartition(app.TopicInventoryDelivery, 0, sarama.OffsetOldest)
+	mockConsumerFactory := func(brokerConfig cfg.BrokerConfig) (sarama.Consumer, error) {
+		return testConsumer, nil
+	}
+
+	if testApp, err =
+		app.NewAppBuilder(testConfig).
+			SetConsumerFactory(mockConsumerFactory).
+			SetProducerFactory(mockProducerFactory).
+			Build(); err != nil {
+		log.Fatalf("Failed to initialize application for tests. Reason: %s", err)
+	}
+
+	r, _ := http.NewRequest("POST", "/kitchen/api/v1/suppliers", strings.NewReader(`{"name":"Farm Fresh"}`))
+	w := httptest.NewRecorder()
+	testApp.Router().ServeHTTP(w, r)
+	assert.Equal(t, 201, w.Code)
+
+	// WHEN
+	r, _ = http.NewRequest("POST", "/kitchen/api/v1/suppliers", strings.NewReader(`{"name":"Farm Fresh"}`))
+	w = httptest.NewRecorder()
+	testApp.Router().ServeHTTP(w, r)
+
+	// THEN
+	assert.Equal(t, 409, w.Code)
+	assert.Contains(t, w.Body.String(), `"detail":"supplier \"Farm Fresh\" already exists"`)
+
+	// TearDown
+	clearTables()
+	testApp.Close()
+}
+
+func Test_GIVEN_unknownSupplier_WHEN_purchaseOrderIsCreated_THEN_notFoundIsReturned(t *testing.T) {
+	var (
+		testConsumer = mocks.NewConsumer(t, nil)
+		testProducer = mocks.NewSyncProducer(t, nil)
+		testApp      *app.App
+		err          error
+	)
+
+	// GIVEN
+	mockProducerFactory := func(brokerConfig cfg.BrokerConfig) (sarama.SyncProducer, error) {
+		return testProducer, nil
+	}
+
+	testConsumer.SetTopicMetadata(map[string][]int32{
+		app.TopicCreateOrder:       {0},
+		app.TopicInventoryDelivery: {0},
+	})
+	_ = testConsumer.ExpectConsumePartition(app.TopicCreateOrder, 0, sarama.OffsetOldest)
+	_ = testConsumer.ExpectConsumePartition(app.TopicInventoryDelivery, 0, sarama.OffsetOldest)
+	mockConsumerFactory := func(brokerConfig cfg.BrokerConfig) (sarama.Consumer, error) {
+		return testConsumer, nil
+	}
+
+	if testApp, err =
+		app.NewAppBuilder(testConfig).
+			SetConsumerFactory(mockConsumerFactory).
+			SetProducerFactory(mockProducerFactory).
+			Build(); err != nil {
+		log.Fatalf("Failed to initialize application for tests. Reason: %s", err)
+	}
+
+	// WHEN
+	r, _ := http.NewRequest("POST", "/kitchen/api/v1/purchase-orders", strings.NewReader(`{
+		"supplierId": 404,
+		"expectedDeliveryDate": "2022-05-01",
+		"lines": [{"itemName":"Cheese","units":5}]
+	}`))
+	w := httptest.NewRecorder()
+	testApp.Router().ServeHTTP(w, r)
+
+	// THEN
+	assert.Equal(t, 404, w.Code)
+	assert.Contains(t, w.Body.String(), `"detail":"supplier 404 does not exist"`)
+
+	// TearDown
+	clearTables()
+	testApp.Close()
+}
+
+func Test_GIVEN_unknownPurchaseOrder_WHEN_deliveryIsReceived_THEN_failedDeliveryIsSaved(t *testing.T) {
+	var (
+		testConsumer = mocks.NewConsumer(t, nil)
+		testProducer = mocks.NewSyncProducer(t, nil)
+		testApp      *app.App
+		err          error
+	)
+
+	// GIVEN
+	mockProducerFactory := func(brokerConfig cfg.BrokerConfig) (sarama.SyncProducer, error) {
+		return testProducer, nil
+	}
+
+	testConsumer.SetTopicMetadata(map[string][]int32{
+		app.TopicCreateOrder:       {0},
+		app.TopicInventoryDelivery: {0},
+	})
+	_ = testConsumer.ExpectConsumePartition(app.TopicCreateOrder, 0, sarama.OffsetOldest)
+	partitionConsumer := testConsumer.ExpectConsumePartition(app.TopicInventoryDelivery, 0, sarama.OffsetOldest)
+	mockConsumerFactory := func(brokerConfig cfg.BrokerConfig) (sarama.Consumer, error) {
+		return testConsumer, nil
+	}
+
+	if testApp, err =
+		app.NewAppBuilder(testConfig).
+			SetConsumerFactory(mockConsumerFactory).
+			SetProducerFactory(mockProducerFactory).
+			Build(); err != nil {
+		log.Fatalf("Failed to initialize application for tests. Reason: %s", err)
+	}
+
+	// WHEN
+	partitionConsumer.
+		YieldMessage(&sarama.ConsumerMessage{
+			Topic:     app.TopicInventoryDelivery,
+			Partition: 0,
+			Value:     []byte(`{"purchaseOrderId":404,"stock":[{"name":"Cheese","units":6}]}`),
+		})
+
+	time.Sleep(5 * time.Second)
+
+	// THEN
+	var reason string
+	assert.Nil(t, testDB.QueryRow("SELECT reason FROM kitchen.failed_delivery").Scan(&reason))
+	assert.Equal(t, "purchase order 404 does not exist", reason)
+
+	r, _ := http.NewRequest("GET", "/kitchen/api/v1/stock/Cheese", nil)
+	w := httptest.NewRecorder()
+	testApp.Router().ServeHTTP(w, r)
+	assert.Equal(t, 404, w.Code)
+
+	// TearDown
+	clearTables()
+	testApp.Close()
+}
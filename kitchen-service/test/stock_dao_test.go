@@ -2,8 +2,10 @@ package test
 
 import (
 	"context"
+	"fmt"
 	"sort"
 	"testing"
+	"time"
 
 	_ "github.com/lib/pq"
 	"github.com/stretchr/testify/assert"
@@ -25,7 +27,7 @@ func TestStockDaoTestSuite(t *testing.T) {
 // -- SETUP
 
 func (suite *StockDaoTestSuite) SetupTest() {
-	suite.stockDao = db.MustOpenStockDao(testDB)
+	suite.stockDao = db.MustOpenStockDao(testDB, db.NewBulkIncreaseThreshold(db.DefaultBulkIncreaseThreshold))
 }
 
 // -- TEARDOWN
@@ -49,7 +51,8 @@ func (suite *StockDaoTestSuite) Test_GIVEN_noStock_WHEN_stockIsAdded_THEN_totalS
 
 	// THEN
 	getTx, _ := suite.stockDao.BeginTx()
-	stock, err := getTx.Get(ctx)
+	stockPage, err := getTx.Get(ctx, k.NewStockQuery(k.DefaultLocation))
+	stock := stockPage.Stock
 	assert.Nil(suite.T(), getTx.Commit())
 	sort.Sort(stock)
 
@@ -79,7 +82,8 @@ func (suite *StockDaoTestSuite) Test_GIVEN_stock_WHEN_stockIsAdded_THEN_totalSto
 
 	// THEN
 	getTx, _ := suite.stockDao.BeginTx()
-	stock, err := getTx.Get(ctx)
+	stockPage, err := getTx.Get(ctx, k.NewStockQuery(k.DefaultLocation))
+	stock := stockPage.Stock
 	assert.Nil(suite.T(), getTx.Commit())
 	sort.Sort(stock)
 
@@ -90,6 +94,42 @@ func (suite *StockDaoTestSuite) Test_GIVEN_stock_WHEN_stockIsAdded_THEN_totalSto
 	assert.Equal(suite.T(), uint(10), stock[1].Units())
 }
 
+func (suite *StockDaoTestSuite) Test_GIVEN_largeDelivery_WHEN_stockIsAddedInBulk_THEN_totalStockAndCostAreCorrect() {
+	// GIVEN
+	ctx := context.Background()
+	givenTx, _ := suite.stockDao.BeginTx()
+	assert.Nil(suite.T(), givenTx.Increase(ctx, k.Stock{
+		k.Must(k.Must(k.NewStockItem("Cheese", 10)).WithUnitCost(20000)),
+	}), "Increase returned error")
+	assert.Nil(suite.T(), givenTx.Commit(), "Commit returned error")
+
+	// WHEN
+	increaseTx, _ := db.MustOpenStockDao(testDB, db.NewBulkIncreaseThreshold(0)).BeginTx()
+	assert.Nil(suite.T(), increaseTx.Increase(ctx, k.Stock{
+		k.Must(k.Must(k.NewStockItem("Cheese", 10)).WithUnitCost(40000)),
+		k.Must(k.NewStockItem("Donuts", 3)),
+		k.Must(k.NewStockItem("Donuts", 4)),
+	}), "Increase returned error")
+	assert.Nil(suite.T(), increaseTx.Increase(ctx, k.Stock{
+		k.Must(k.NewStockItem("Donuts", 1)),
+	}), "Increase returned error")
+	assert.Nil(suite.T(), increaseTx.Commit(), "Commit returned error")
+
+	// THEN
+	getTx, _ := suite.stockDao.BeginTx()
+	stockPage, err := getTx.Get(ctx, k.NewStockQuery(k.DefaultLocation))
+	stock := stockPage.Stock
+	assert.Nil(suite.T(), getTx.Commit())
+	sort.Sort(stock)
+
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), "Cheese", stock[0].Name())
+	assert.Equal(suite.T(), uint(20), stock[0].Units())
+	assert.Equal(suite.T(), k.Money(30000), stock[0].UnitCost())
+	assert.Equal(suite.T(), "Donuts", stock[1].Name())
+	assert.Equal(suite.T(), uint(8), stock[1].Units())
+}
+
 func (suite *StockDaoTestSuite) Test_GIVEN_stock_WHEN_stockIsDecreased_THEN_totalStockIsCorrect() {
 	// GIVEN
 	ctx := context.Background()
@@ -109,7 +149,8 @@ func (suite *StockDaoTestSuite) Test_GIVEN_stock_WHEN_stockIsDecreased_THEN_tota
 
 	// THEN
 	getTx, _ := suite.stockDao.BeginTx()
-	stock, err := getTx.Get(ctx)
+	stockPage, err := getTx.Get(ctx, k.NewStockQuery(k.DefaultLocation))
+	stock := stockPage.Stock
 	assert.Nil(suite.T(), getTx.Commit())
 	sort.Sort(stock)
 
@@ -120,6 +161,32 @@ func (suite *StockDaoTestSuite) Test_GIVEN_stock_WHEN_stockIsDecreased_THEN_tota
 	assert.Equal(suite.T(), uint(5), stock[1].Units())
 }
 
+func (suite *StockDaoTestSuite) Test_GIVEN_stock_WHEN_itemRunsOut_THEN_itemIsStillListedWithZeroUnits() {
+	// GIVEN
+	ctx := context.Background()
+	givenTx, _ := suite.stockDao.BeginTx()
+	assert.Nil(suite.T(), givenTx.Increase(ctx, k.Stock{k.Must(k.NewStockItem("Cheese", 5))}), "Increase returned error")
+	assert.Nil(suite.T(), givenTx.Commit(), "Commit returned error")
+
+	// WHEN
+	decreaseTx, _ := suite.stockDao.BeginTx()
+	assert.Nil(suite.T(), decreaseTx.Decrease(ctx, k.Stock{k.Must(k.NewStockItem("Cheese", 5))}), "Decrease returned error")
+	assert.Nil(suite.T(), decreaseTx.Commit(), "Commit returned error")
+
+	// THEN
+	getTx, _ := suite.stockDao.BeginTx()
+	stockPage, err := getTx.Get(ctx, k.NewStockQuery(k.DefaultLocation))
+	assert.Nil(suite.T(), err)
+	item, err := getTx.GetStockItem(ctx, "Cheese", k.DefaultLocation)
+	assert.Nil(suite.T(), err)
+	assert.Nil(suite.T(), getTx.Commit())
+
+	assert.Equal(suite.T(), 1, len(stockPage.Stock))
+	assert.Equal(suite.T(), "Cheese", stockPage.Stock[0].Name())
+	assert.Equal(suite.T(), uint(0), stockPage.Stock[0].Units())
+	assert.Equal(suite.T(), uint(0), item.Units())
+}
+
 func (suite *StockDaoTestSuite) Test_GIVEN_stock_WHEN_stockIsDecreasedBeyondAvailability_THEN_errorIsReturned() {
 	// GIVEN
 	ctx := context.Background()
@@ -142,3 +209,395 @@ func (suite *StockDaoTestSuite) Test_GIVEN_stock_WHEN_stockIsDecreasedBeyondAvai
 
 	assert.Equal(suite.T(), "insufficient stock of \"Cheese\"", err.Error())
 }
+
+func (suite *StockDaoTestSuite) Test_GIVEN_oneItemIsShort_WHEN_stockIsDecreased_THEN_noItemIsDecreased() {
+	// GIVEN
+	ctx := context.Background()
+	givenTx, _ := suite.stockDao.BeginTx()
+
+	item1, _ := k.NewStockItem("Cheese", 5)
+	item2, _ := k.NewStockItem("Donuts", 7)
+	assert.Nil(suite.T(), givenTx.Increase(ctx, k.Stock{item1, item2}), "Increase returned error")
+	assert.Nil(suite.T(), givenTx.Commit(), "Commit returned error")
+
+	// WHEN
+	decreaseTx, _ := suite.stockDao.BeginTx()
+	item1Decrease, _ := k.NewStockItem("Cheese", 2)
+	item2Decrease, _ := k.NewStockItem("Donuts", 4)
+	err := decreaseTx.Decrease(ctx, k.Stock{item1Decrease, item2Decrease, item2Decrease})
+	assert.Nil(suite.T(), decreaseTx.Commit(), "Commit returned error")
+
+	// THEN
+	assert.Equal(suite.T(), "insufficient stock of \"Donuts\"", err.Error())
+
+	getTx, _ := suite.stockDao.BeginTx()
+	stockPage, err := getTx.Get(ctx, k.NewStockQuery(k.DefaultLocation))
+	stock := stockPage.Stock
+	assert.Nil(suite.T(), getTx.Commit())
+	sort.Sort(stock)
+
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), uint(5), stock[0].Units())
+	assert.Equal(suite.T(), uint(7), stock[1].Units())
+}
+
+func (suite *StockDaoTestSuite) Test_GIVEN_namesThatDifferInCase_WHEN_stockIsChanged_THEN_theSameItemIsChanged() {
+	// GIVEN
+	ctx := context.Background()
+	givenTx, _ := suite.stockDao.BeginTx()
+	assert.Nil(suite.T(), givenTx.Increase(ctx, k.Stock{k.Must(k.NewStockItem("Tomatoes", 2))}), "Increase returned error")
+	assert.Nil(suite.T(), givenTx.Commit(), "Commit returned error")
+
+	// WHEN
+	changeTx, _ := suite.stockDao.BeginTx()
+	assert.Nil(suite.T(), changeTx.Increase(ctx, k.Stock{k.Must(k.NewStockItem("tomatoes", 3))}), "Increase returned error")
+	assert.Nil(suite.T(), changeTx.Decrease(ctx, k.Stock{k.Must(k.NewStockItem(" TOMATOES ", 4))}), "Decrease returned error")
+	assert.Nil(suite.T(), changeTx.Commit(), "Commit returned error")
+
+	// THEN
+	getTx, _ := suite.stockDao.BeginTx()
+	stockPage, err := getTx.Get(ctx, k.NewStockQuery(k.DefaultLocation))
+	stock := stockPage.Stock
+	assert.Nil(suite.T(), getTx.Commit())
+
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), 1, len(stock))
+	assert.Equal(suite.T(), "Tomatoes", stock[0].Name())
+	assert.Equal(suite.T(), uint(1), stock[0].Units())
+	assert.NotZero(suite.T(), stock[0].ItemId())
+}
+
+func (suite *StockDaoTestSuite) Test_GIVEN_itemWithAlias_WHEN_stockIsDecreasedByAlias_THEN_itemIsDecreased() {
+	// GIVEN
+	ctx := context.Background()
+	givenTx, _ := suite.stockDao.BeginTx()
+	assert.Nil(suite.T(), givenTx.Increase(ctx, k.Stock{k.Must(k.NewStockItem("Tomatoes", 2))}), "Increase returned error")
+	item, err := givenTx.SaveItem(ctx, k.MustItem(k.NewItem(0, "TOM-001", "tomatoes", []string{"Tomato"})))
+	assert.Nil(suite.T(), err, "SaveItem returned error")
+	assert.Nil(suite.T(), givenTx.Commit(), "Commit returned error")
+
+	// WHEN
+	decreaseTx, _ := suite.stockDao.BeginTx()
+	assert.Nil(suite.T(), decreaseTx.Decrease(ctx, k.Stock{k.Must(k.NewStockItem("tomato", 1))}), "Decrease returned error")
+	items, err := decreaseTx.GetItems(ctx, []string{"TOMATO", "Potatoes"})
+	assert.Nil(suite.T(), decreaseTx.Commit(), "Commit returned error")
+
+	// THEN
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), "Tomatoes", item.Name())
+	assert.Equal(suite.T(), map[string]k.Item{"tomato": item}, items)
+
+	getTx, _ := suite.stockDao.BeginTx()
+	stockPage, err := getTx.Get(ctx, k.NewStockQuery(k.DefaultLocation))
+	stock := stockPage.Stock
+	assert.Nil(suite.T(), getTx.Commit())
+
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), uint(1), stock[0].Units())
+	assert.Equal(suite.T(), "TOM-001", stock[0].Sku())
+}
+
+func (suite *StockDaoTestSuite) Test_GIVEN_itemWithSku_WHEN_itemsAreRequestedBySku_THEN_itemIsReturned() {
+	// GIVEN
+	ctx := context.Background()
+	givenTx, _ := suite.stockDao.BeginTx()
+	item, err := givenTx.SaveItem(ctx, k.MustItem(k.NewItem(0, "TOM-001", "Tomatoes", []string{"Tomato"})))
+	assert.Nil(suite.T(), err, "SaveItem returned error")
+	assert.Nil(suite.T(), givenTx.Commit(), "Commit returned error")
+
+	// WHEN
+	getTx, _ := suite.stockDao.BeginTx()
+	items, err := getTx.GetItems(ctx, []string{"tom-001", "TOM-002"})
+	assert.Nil(suite.T(), getTx.Commit(), "Commit returned error")
+
+	// THEN
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), map[string]k.Item{"tom-001": item}, items)
+}
+
+func (suite *StockDaoTestSuite) Test_GIVEN_aliasThatIsAnotherItem_WHEN_itemIsSaved_THEN_errorIsReturned() {
+	// GIVEN
+	ctx := context.Background()
+	givenTx, _ := suite.stockDao.BeginTx()
+	assert.Nil(suite.T(), givenTx.Increase(ctx, k.Stock{
+		k.Must(k.NewStockItem("Tomatoes", 2)),
+		k.Must(k.NewStockItem("Potatoes", 2)),
+	}), "Increase returned error")
+	assert.Nil(suite.T(), givenTx.Commit(), "Commit returned error")
+
+	// WHEN
+	saveTx, _ := suite.stockDao.BeginTx()
+	_, err := saveTx.SaveItem(ctx, k.MustItem(k.NewItem(0, "", "Tomatoes", []string{"potatoes"})))
+	assert.Nil(suite.T(), saveTx.Rollback())
+
+	// THEN
+	assert.NotNil(suite.T(), err)
+	assert.Equal(suite.T(), map[string]string{"aliases": "\"potatoes\" is already a name of \"Potatoes\""}, err.(k.InvalidError).Fields)
+}
+
+func (suite *StockDaoTestSuite) Test_GIVEN_expiredIdempotencyKey_WHEN_anotherKeyIsClaimed_THEN_expiredKeyIsDeleted() {
+	// GIVEN
+	ctx := context.Background()
+	purchasingDao := db.MustOpenPurchasingDao(testDB, db.NewBulkIncreaseThreshold(db.DefaultBulkIncreaseThreshold), db.NewIdempotencyKeyRetention(time.Hour))
+	expired, _ := k.NewIdempotentRequest("deliveries", "delivery-1", []byte(`{}`))
+	request, _ := k.NewIdempotentRequest("deliveries", "delivery-2", []byte(`{}`))
+
+	givenTx, _ := purchasingDao.BeginTx()
+	_, claimed, err := givenTx.ClaimIdempotencyKey(ctx, expired)
+	assert.Nil(suite.T(), err, "ClaimIdempotencyKey returned error")
+	assert.True(suite.T(), claimed)
+	assert.Nil(suite.T(), givenTx.Commit(), "Commit returned error")
+	_, err = testDB.Exec("UPDATE kitchen.idempotency_key SET created_at = NOW() - INTERVAL '2 hours'")
+	assert.Nil(suite.T(), err)
+
+	// WHEN
+	claimTx, _ := purchasingDao.BeginTx()
+	_, claimed, err = claimTx.ClaimIdempotencyKey(ctx, request)
+	assert.Nil(suite.T(), claimTx.Commit(), "Commit returned error")
+
+	// THEN
+	assert.Nil(suite.T(), err)
+	assert.True(suite.T(), claimed)
+
+	var keys []string
+	rows, err := testDB.Query("SELECT key FROM kitchen.idempotency_key")
+	assert.Nil(suite.T(), err)
+	defer rows.Close()
+	for rows.Next() {
+		var key string
+		assert.Nil(suite.T(), rows.Scan(&key))
+		keys = append(keys, key)
+	}
+	assert.Equal(suite.T(), []string{"delivery-2"}, keys)
+}
+
+func (suite *StockDaoTestSuite) Test_GIVEN_stockReadAtVersion_WHEN_stockChangesBeforeItIsSet_THEN_conflictIsReturned() {
+	// GIVEN
+	ctx := context.Background()
+	givenTx, _ := suite.stockDao.BeginTx()
+	assert.Nil(suite.T(), givenTx.Increase(ctx, k.Stock{k.Must(k.NewStockItem("Cheese", 5))}), "Increase returned error")
+	read, err := givenTx.GetStockItem(ctx, "cheese", k.DefaultLocation)
+	assert.Nil(suite.T(), err, "GetStockItem returned error")
+	assert.Nil(suite.T(), givenTx.Commit(), "Commit returned error")
+
+	decreaseTx, _ := suite.stockDao.BeginTx()
+	assert.Nil(suite.T(), decreaseTx.Decrease(ctx, k.Stock{k.Must(k.NewStockItem("Cheese", 1))}), "Decrease returned error")
+	assert.Nil(suite.T(), decreaseTx.Commit(), "Commit returned error")
+
+	// WHEN
+	setTx, _ := suite.stockDao.BeginTx()
+	_, err = setTx.Set(ctx, k.Must(k.NewStockItem("Cheese", 10)).AtVersion(read.Version()))
+	assert.Nil(suite.T(), setTx.Rollback())
+
+	// THEN
+	assert.True(suite.T(), err.(k.ConflictError).IsConflictError())
+	assert.Equal(suite.T(), "stock of \"Cheese\" at \"main\" is at version 2, not version 1", err.Error())
+
+	getTx, _ := suite.stockDao.BeginTx()
+	current, err := getTx.GetStockItem(ctx, "Cheese", k.DefaultLocation)
+	assert.Nil(suite.T(), err)
+	set, err := getTx.Set(ctx, k.Must(k.NewStockItem("Cheese", 10)).AtVersion(current.Version()))
+	assert.Nil(suite.T(), getTx.Commit())
+
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), uint(10), set.Units())
+	assert.Equal(suite.T(), uint64(3), set.Version())
+}
+
+func (suite *StockDaoTestSuite) Test_GIVEN_stock_WHEN_pagesOfFilteredStockAreRequested_THEN_eachPageIsSortedAndFiltered() {
+	// GIVEN
+	ctx := context.Background()
+	givenTx, _ := suite.stockDao.BeginTx()
+	assert.Nil(suite.T(), givenTx.Increase(ctx, k.Stock{
+		k.Must(k.NewStockItem("Cheddar", 3)),
+		k.Must(k.NewStockItem("Cheese", 1)),
+		k.Must(k.NewStockItem("Cherries", 20)),
+		k.Must(k.NewStockItem("Chervil", 3)),
+		k.Must(k.NewStockItem("Donuts", 1)),
+	}), "Increase returned error")
+	assert.Nil(suite.T(), givenTx.Commit(), "Commit returned error")
+
+	// WHEN
+	getTx, _ := suite.stockDao.BeginTx()
+	query := k.NewStockQuery(k.DefaultLocation).
+		WithNamePrefix("che").
+		WithUnitsBelow(10).
+		SortedBy(k.SortByUnitsDescending)
+	firstQuery, _ := query.Page(2, nil)
+	firstPage, err := getTx.Get(ctx, firstQuery)
+	assert.Nil(suite.T(), err)
+	secondQuery, _ := query.Page(2, firstPage.Next)
+	secondPage, err := getTx.Get(ctx, secondQuery)
+	assert.Nil(suite.T(), err)
+	assert.Nil(suite.T(), getTx.Commit())
+
+	// THEN
+	names := func(stock k.Stock) []string {
+		names := []string{}
+		for _, item := range stock {
+			names = append(names, item.Name())
+		}
+		return names
+	}
+	assert.Equal(suite.T(), []string{"Chervil", "Cheddar"}, names(firstPage.Stock))
+	assert.Equal(suite.T(), &k.StockCursor{Sort: k.SortByUnitsDescending, Name: "Cheddar", Units: 3}, firstPage.Next)
+	assert.Equal(suite.T(), []string{"Cheese"}, names(secondPage.Stock))
+	assert.Nil(suite.T(), secondPage.Next)
+}
+
+func (suite *StockDaoTestSuite) Test_GIVEN_itemInStock_WHEN_itemIsCreatedAgain_THEN_conflictIsReturned() {
+	// GIVEN
+	ctx := context.Background()
+	givenTx, _ := suite.stockDao.BeginTx()
+	created, err := givenTx.Create(ctx, k.Must(k.NewStockItem("Cheese", 5)))
+	assert.Nil(suite.T(), err, "Create returned error")
+	assert.Nil(suite.T(), givenTx.Commit(), "Commit returned error")
+
+	// WHEN
+	createTx, _ := suite.stockDao.BeginTx()
+	_, err = createTx.Create(ctx, k.Must(k.NewStockItem("CHEESE", 2)))
+	assert.Nil(suite.T(), createTx.Rollback())
+
+	// THEN
+	assert.Equal(suite.T(), uint64(1), created.Version())
+	assert.True(suite.T(), err.(k.ConflictError).IsConflictError())
+	assert.Equal(suite.T(), "\"Cheese\" is already in stock at \"main\"", err.Error())
+}
+
+func (suite *StockDaoTestSuite) Test_GIVEN_itemInStock_WHEN_itemIsDeleted_THEN_itemIsNoLongerInStock() {
+	// GIVEN
+	ctx := context.Background()
+	givenTx, _ := suite.stockDao.BeginTx()
+	assert.Nil(suite.T(), givenTx.Increase(ctx, k.Stock{k.Must(k.NewStockItem("Cheese", 5))}), "Increase returned error")
+	assert.Nil(suite.T(), givenTx.Commit(), "Commit returned error")
+
+	// WHEN
+	deleteTx, _ := suite.stockDao.BeginTx()
+	staleErr := deleteTx.Delete(ctx, k.Must(k.NewStockItem("Cheese", 1)).AtVersion(7))
+	err := deleteTx.Delete(ctx, k.Must(k.NewStockItem("cheese", 1)))
+	assert.Nil(suite.T(), deleteTx.Commit())
+
+	// THEN
+	assert.True(suite.T(), staleErr.(k.ConflictError).IsConflictError())
+	assert.Nil(suite.T(), err)
+
+	getTx, _ := suite.stockDao.BeginTx()
+	_, err = getTx.GetStockItem(ctx, "Cheese", k.DefaultLocation)
+	assert.Nil(suite.T(), getTx.Commit())
+
+	assert.True(suite.T(), err.(k.NotFoundError).IsNotFoundError())
+}
+
+func (suite *StockDaoTestSuite) Test_GIVEN_taggedIngredient_WHEN_tagsAreReplaced_THEN_onlyNewTagsAreReturned() {
+	// GIVEN
+	ctx := context.Background()
+	givenTx, _ := suite.stockDao.BeginTx()
+	assert.Nil(suite.T(), givenTx.SetDietaryTags(ctx, "Cheese", k.DietaryTags{k.DietaryTagDairy, k.DietaryTagVegan}))
+	assert.Nil(suite.T(), givenTx.Commit(), "Commit returned error")
+
+	// WHEN
+	setTx, _ := suite.stockDao.BeginTx()
+	assert.Nil(suite.T(), setTx.SetDietaryTags(ctx, "Cheese", k.DietaryTags{k.DietaryTagDairy, k.DietaryTagHalal}))
+	assert.Nil(suite.T(), setTx.Commit(), "Commit returned error")
+
+	// THEN
+	getTx, _ := suite.stockDao.BeginTx()
+	tags, err := getTx.GetDietaryTags(ctx, []string{"Cheese", "Donuts"})
+	assert.Nil(suite.T(), getTx.Commit())
+
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), map[string]k.DietaryTags{
+		"Cheese": {k.DietaryTagDairy, k.DietaryTagHalal},
+	}, tags)
+}
+
+func (suite *StockDaoTestSuite) Test_GIVEN_stockAtTwoLocations_WHEN_stockIsTransferred_THEN_eachLocationIsUpdated() {
+	// GIVEN
+	ctx := context.Background()
+	marina := k.Location("marina")
+	givenTx, _ := suite.stockDao.BeginTx()
+	cheese, _ := k.Must(k.NewStockItem("Cheese", 5)).WithUnitCost(k.Money(20000))
+	assert.Nil(suite.T(), givenTx.Increase(ctx, k.Stock{cheese, k.Must(k.NewStockItem("Cheese", 1)).AtLocation(marina)}), "Increase returned error")
+	assert.Nil(suite.T(), givenTx.Commit(), "Commit returned error")
+
+	// WHEN
+	transfer, _ := k.NewTransfer(0, k.DefaultLocation, marina, k.Stock{k.Must(k.NewStockItem("Cheese", 2))}, time.Now())
+	transferTx, _ := suite.stockDao.BeginTx()
+	transfer, err := transferTx.Transfer(ctx, transfer)
+	assert.Nil(suite.T(), err, "Transfer returned error")
+	assert.Nil(suite.T(), transferTx.Commit(), "Commit returned error")
+
+	// THEN
+	getTx, _ := suite.stockDao.BeginTx()
+	mainStockPage, _ := getTx.Get(ctx, k.NewStockQuery(k.DefaultLocation))
+	mainStock := mainStockPage.Stock
+	marinaStockPage, _ := getTx.Get(ctx, k.NewStockQuery(marina))
+	marinaStock := marinaStockPage.Stock
+	saved, err := getTx.GetTransfer(ctx, transfer.Id())
+	assert.Nil(suite.T(), getTx.Commit())
+
+	assert.Equal(suite.T(), uint(3), mainStock[0].Units())
+	assert.Equal(suite.T(), uint(3), marinaStock[0].Units())
+	assert.Equal(suite.T(), "2.0000", marinaStock[0].UnitCost().String())
+
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), 2, len(saved.Movements()))
+	assert.Equal(suite.T(), -2, saved.Movements()[0].Units)
+	assert.Equal(suite.T(), 2, saved.Movements()[1].Units)
+}
+
+func (suite *StockDaoTestSuite) Test_GIVEN_insufficientStockAtSource_WHEN_stockIsTransferred_THEN_errorIsReturned() {
+	// GIVEN
+	ctx := context.Background()
+	transfer, _ := k.NewTransfer(0, k.DefaultLocation, k.Location("marina"), k.Stock{k.Must(k.NewStockItem("Cheese", 2))}, time.Now())
+
+	// WHEN
+	transferTx, _ := suite.stockDao.BeginTx()
+	_, err := transferTx.Transfer(ctx, transfer)
+	assert.Nil(suite.T(), transferTx.Rollback())
+
+	// THEN
+	assert.NotNil(suite.T(), err)
+	assert.Equal(suite.T(), "insufficient stock of \"Cheese\"", err.Error())
+}
+
+func BenchmarkStockIncreaseRowByRow(b *testing.B) {
+	benchmarkStockIncrease(b, 5000, 2000)
+}
+
+func BenchmarkStockIncreaseInBulk(b *testing.B) {
+	benchmarkStockIncrease(b, 0, 2000)
+}
+
+// benchmarkStockIncrease delivers items to stock that already holds half of them.
+// Each delivery is rolled back so that every iteration starts from the same stock.
+func benchmarkStockIncrease(b *testing.B, threshold int, items int) {
+	ctx := context.Background()
+	stockDao := db.MustOpenStockDao(testDB, db.NewBulkIncreaseThreshold(threshold))
+	defer clearTables()
+
+	delivery := k.Stock{}
+	for i := 0; i < items; i++ {
+		item := k.Must(k.NewStockItem(fmt.Sprintf("Item %04d", i), 10))
+		delivery = append(delivery, k.Must(item.WithUnitCost(k.Money(10000+i))))
+	}
+
+	givenTx, _ := stockDao.BeginTx()
+	if err := givenTx.Increase(ctx, delivery[:items/2]); err != nil {
+		b.Fatalf("Failed to add stock. Reason: %s", err)
+	}
+	if err := givenTx.Commit(); err != nil {
+		b.Fatalf("Failed to commit stock. Reason: %s", err)
+	}
+
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		tx, _ := stockDao.BeginTx()
+		if err := tx.Increase(ctx, delivery); err != nil {
+			b.Fatalf("Failed to increase stock. Reason: %s", err)
+		}
+		if err := tx.Rollback(); err != nil {
+			b.Fatalf("Failed to roll back stock. Reason: %s", err)
+		}
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"log"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 
@@ -77,3 +78,175 @@ func Test_GIVEN_noStock_WHEN_stockIsAdded_THEN_totalStockIsCorrect(t *testing.T)
 	clearTables()
 	testApp.Close()
 }
+
+func Test_GIVEN_deliveryWithIdempotencyKey_WHEN_deliveryIsRetried_THEN_stockIsReceivedOnce(t *testing.T) {
+	var (
+		testConsumer = mocks.NewConsumer(t, nil)
+		testProducer = mocks.NewSyncProducer(t, nil)
+		testApp      *app.App
+		err          error
+	)
+
+	// GIVEN
+	mockProducerFactory := func(brokerConfig cfg.BrokerConfig) (sarama.SyncProducer, error) {
+		return testProducer, nil
+	}
+
+	testConsumer.SetTopicMetadata(map[string][]int32{
+		app.TopicCreateOrder:       {0},
+		app.TopicInventoryDelivery: {0},
+	})
+	_ = testConsumer.ExpectConsumePartition(app.TopicCreateOrder, 0, sarama.OffsetOldest)
+	_ = testConsumer.ExpectConsumePartition(app.TopicInventoryDelivery, 0, sarama.OffsetOldest)
+	mockConsumerFactory := func(brokerConfig cfg.BrokerConfig) (sarama.Consumer, error) {
+		return testConsumer, nil
+	}
+
+	if testApp, err =
+		app.NewAppBuilder(testConfig).
+			SetConsumerFactory(mockConsumerFactory).
+			SetProducerFactory(mockProducerFactory).
+			Build(); err != nil {
+		log.Fatalf("Failed to initialize application for tests. Reason: %s", err)
+	}
+
+	deliver := func(body string) *httptest.ResponseRecorder {
+		r, _ := http.NewRequest("POST", "/kitchen/api/v1/deliveries", strings.NewReader(body))
+		r.Header.Set("Idempotency-Key", "delivery-1")
+		w := httptest.NewRecorder()
+		testApp.Router().ServeHTTP(w, r)
+		return w
+	}
+
+	// WHEN
+	first := deliver(`{"stock":[{"name":"Cheese","units":5}]}`)
+	retry := deliver(`{ "stock": [{"name": "Cheese", "units": 5}] }`)
+	reused := deliver(`{"stock":[{"name":"Cheese","units":50}]}`)
+
+	// THEN
+	assert.Equal(t, 201, first.Code)
+	assert.Equal(t, 201, retry.Code)
+	assert.Equal(t, "true", retry.Header().Get("Idempotent-Replayed"))
+	assert.JSONEq(t, first.Body.String(), retry.Body.String())
+	assert.Equal(t, 409, reused.Code)
+
+	r, _ := http.NewRequest("GET", "/kitchen/api/v1/stock", nil)
+	w := httptest.NewRecorder()
+	testApp.Router().ServeHTTP(w, r)
+
+	assert.Equal(t, 200, w.Code)
+	assert.JSONEq(t, `{
+		"stock": [{
+			"name": "Cheese",
+			"units": 5
+		}]
+	}`, w.Body.String())
+
+	// TearDown
+	clearTables()
+	testApp.Close()
+}
+
+func Test_GIVEN_ingredient_WHEN_dietaryTagsAreSet_THEN_tagsAreReturned(t *testing.T) {
+	var (
+		testConsumer = mocks.NewConsumer(t, nil)
+		testProducer = mocks.NewSyncProducer(t, nil)
+		testApp      *app.App
+		err          error
+	)
+
+	// GIVEN
+	mockProducerFactory := func(brokerConfig cfg.BrokerConfig) (sarama.SyncProducer, error) {
+		return testProducer, nil
+	}
+
+	testConsumer.SetTopicMetadata(map[string][]int32{
+		app.TopicCreateOrder:       {0},
+		app.TopicInventoryDelivery: {0},
+	})
+	_ = testConsumer.ExpectConsumePartition(app.TopicCreateOrder, 0, sarama.OffsetOldest)
+	_ = testConsumer.ExpectConsumePartition(app.TopicInventoryDelivery, 0, sarama.OffsetOldest)
+	mockConsumerFactory := func(brokerConfig cfg.BrokerConfig) (sarama.Consumer, error) {
+		return testConsumer, nil
+	}
+
+	if testApp, err =
+		app.NewAppBuilder(testConfig).
+			SetConsumerFactory(mockConsumerFactory).
+			SetProducerFactory(mockProducerFactory).
+			Build(); err != nil {
+		log.Fatalf("Failed to initialize application for tests. Reason: %s", err)
+	}
+
+	// WHEN
+	r, _ := http.NewRequest("PUT", "/kitchen/api/v1/stock/Cheese/tags", strings.NewReader(`{"tags":["dairy","halal"]}`))
+	w := httptest.NewRecorder()
+	testApp.Router().ServeHTTP(w, r)
+
+	// THEN
+	assert.Equal(t, 200, w.Code)
+	assert.JSONEq(t, `{"name":"Cheese","tags":["dairy","halal"]}`, w.Body.String())
+
+	r, _ = http.NewRequest("GET", "/kitchen/api/v1/stock/Cheese/tags", nil)
+	w = httptest.NewRecorder()
+	testApp.Router().ServeHTTP(w, r)
+
+	assert.Equal(t, 200, w.Code)
+	assert.JSONEq(t, `{"name":"Cheese","tags":["dairy","halal"]}`, w.Body.String())
+
+	// TearDown
+	clearTables()
+	testApp.Close()
+}
+
+func Test_GIVEN_itemHasRunOut_WHEN_stockIsSetToZeroUnits_THEN_itemIsListedWithZeroUnits(t *testing.T) {
+	var (
+		testConsumer = mocks.NewConsumer(t, nil)
+		testProducer = mocks.NewSyncProducer(t, nil)
+		testApp      *app.App
+		err          error
+	)
+
+	// GIVEN
+	mockProducerFactory := func(brokerConfig cfg.BrokerConfig) (sarama.SyncProducer, error) {
+		return testProducer, nil
+	}
+
+	testConsumer.SetTopicMetadata(map[string][]int32{
+		app.TopicCreateOrder:       {0},
+		app.TopicInventoryDelivery: {0},
+	})
+	_ = testConsumer.ExpectConsumePartition(app.TopicCreateOrder, 0, sarama.OffsetOldest)
+	_ = testConsumer.ExpectConsumePartition(app.TopicInventoryDelivery, 0, sarama.OffsetOldest)
+	mockConsumerFactory := func(brokerConfig cfg.BrokerConfig) (sarama.Consumer, error) {
+		return testConsumer, nil
+	}
+
+	if testApp, err =
+		app.NewAppBuilder(testConfig).
+			SetConsumerFactory(mockConsumerFactory).
+			SetProducerFactory(mockProducerFactory).
+			Build(); err != nil {
+		log.Fatalf("Failed to initialize application for tests. Reason: %s", err)
+	}
+
+	// WHEN
+	r, _ := http.NewRequest("PUT", "/kitchen/api/v1/stock/Cheese", strings.NewReader(`{"units":0}`))
+	w := httptest.NewRecorder()
+	testApp.Router().ServeHTTP(w, r)
+
+	// THEN
+	assert.Equal(t, 200, w.Code)
+	assert.JSONEq(t, `{"name":"Cheese","units":0}`, w.Body.String())
+
+	r, _ = http.NewRequest("GET", "/kitchen/api/v1/stock", nil)
+	w = httptest.NewRecorder()
+	testApp.Router().ServeHTTP(w, r)
+
+	assert.Equal(t, 200, w.Code)
+	assert.Contains(t, w.Body.String(), `{"name":"Cheese","units":0}`)
+
+	// TearDown
+	clearTables()
+	testApp.Close()
+}